@@ -15,31 +15,84 @@ import (
 // correct state and the session is returned. Otherwise, a new session is
 // created and returned.
 //
+// The session's before_start hooks are run before the session is created, and
+// abort the process if any of them fail. The after_start hooks are run when
+// the session is ready. If any of them fail, an error is returned but the
+// session is left running. Hooks are not run for existing sessions.
+//
 // If the provided configuration is invalid, an error is returned. Caller can
 // check for validity beforehand by calling [config.Config.Validate] if needed.
+//
+// Returns [ErrMultipleSessions] if the configuration has more than one session
+// (see [Up]).
 func Apply(ctx context.Context, cfg *Config, runner tmux.Runner) (*tmux.Session, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
+	cfg, err := singleSessionCfg(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration file: %w", err)
 	}
 
-	sCfg := cfg.Session
+	session, err := findSession(ctx, runner, cfg.Session.Name)
+	if err != nil {
+		return nil, err
+	}
 
-	sessions, err := tmux.GetSessions(ctx, runner)
+	if session != nil {
+		return session, nil
+	}
+
+	return applySessionCfg(ctx, runner, cfg.Session, cfg.Redact)
+}
+
+// findSession returns the current tmux session with the provided name, or nil
+// if no such session exists.
+//
+// If no tmux server is running, there are no sessions and nil is returned.
+func findSession(ctx context.Context, r tmux.Runner, name string) (*tmux.Session, error) {
+	session, err := tmux.FindSession(ctx, r, name)
 	if err != nil {
+		if errors.Is(err, tmux.ErrSessionNotFound) || errors.Is(err, tmux.ErrNoServer) {
+			return nil, nil
+		}
+
 		return nil, fmt.Errorf("getting current tmux sessions: %w", err)
 	}
 
-	for _, s := range sessions {
-		if s.Name() == sCfg.Name {
-			return s, nil
-		}
+	return session, nil
+}
+
+// applySessionCfg creates a new tmux session with windows and panes from the
+// provided configuration.
+//
+// Environment variables are loaded from env files and commands before the
+// hooks are run, and are passed to the hooks along with the session
+// environment variables. Secrets are registered for redaction with the
+// provided patterns for names of environment variables (see [redactSecrets]).
+//
+// If any step fails, the session is closed before the error is returned. The
+// session is left running if an after_start hook fails.
+func applySessionCfg(ctx context.Context, r tmux.Runner, cfg SessionConfig, redact []string) (*tmux.Session, error) {
+	cfg, err := loadEnv(ctx, r, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	redactSecrets(r, cfg, redact)
+
+	env := mergeMap(cfg.loadedEnv, cfg.Env)
+
+	if err := runHooks(ctx, r, HookBeforeStart, env, cfg.BeforeStart); err != nil {
+		return nil, err
 	}
 
-	session, err := tmux.NewSession(runner, makeSessionOpts(cfg.Session)...)
+	session, err := tmux.NewSession(r, makeSessionOpts(cfg)...)
 	if err != nil {
 		return fatalf(session, "creating session: %w", err)
 	}
@@ -48,8 +101,8 @@ func Apply(ctx context.Context, cfg *Config, runner tmux.Runner) (*tmux.Session,
 		return fatalf(session, "applying %s: %w", session, err)
 	}
 
-	for _, wCfg := range sCfg.Windows {
-		if _, err := applyWindowCfg(ctx, runner, session, wCfg); err != nil {
+	for _, wCfg := range cfg.Windows {
+		if _, err := applyWindowCfg(ctx, r, session, wCfg); err != nil {
 			return fatalf(session, "applying window configuration: %w", err)
 		}
 	}
@@ -58,6 +111,10 @@ func Apply(ctx context.Context, cfg *Config, runner tmux.Runner) (*tmux.Session,
 		return fatalf(session, "selecting active window: %w", err)
 	}
 
+	if err := runHooks(ctx, r, HookAfterStart, env, cfg.AfterStart); err != nil {
+		return nil, err
+	}
+
 	return session, nil
 }
 
@@ -93,6 +150,10 @@ func applyWindowCfg(ctx context.Context, r tmux.Runner, s *tmux.Session, cfg Win
 		}
 	}
 
+	if err := win.SelectLayout(ctx); err != nil {
+		return nil, fmt.Errorf("selecting layout for %s: %w", win, err)
+	}
+
 	return win, nil
 }
 
@@ -138,10 +199,18 @@ func makeSessionOpts(sCfg SessionConfig) []tmux.SessionOption {
 		opts = append(opts, tmux.SessionWithOnAnyCommand(sCfg.OnAny))
 	}
 
+	if len(sCfg.loadedEnv) != 0 {
+		opts = append(opts, tmux.SessionWithRawEnv(sCfg.loadedEnv))
+	}
+
 	if len(sCfg.Env) != 0 {
 		opts = append(opts, tmux.SessionWithEnv(sCfg.Env))
 	}
 
+	if sCfg.Width != 0 || sCfg.Height != 0 {
+		opts = append(opts, tmux.SessionWithSize(sCfg.Width, sCfg.Height))
+	}
+
 	return opts
 }
 
@@ -164,10 +233,22 @@ func makeWindowOpts(wCfg WindowConfig) []tmux.WindowOption {
 		opts = append(opts, tmux.WindowAsActive())
 	}
 
+	if len(wCfg.loadedEnv) != 0 {
+		opts = append(opts, tmux.WindowWithRawEnv(wCfg.loadedEnv))
+	}
+
 	if len(wCfg.Env) != 0 {
 		opts = append(opts, tmux.WindowWithEnv(wCfg.Env))
 	}
 
+	if wCfg.Layout != "" {
+		opts = append(opts, tmux.WindowWithLayout(wCfg.Layout))
+	}
+
+	if wCfg.MainPaneSize != "" {
+		opts = append(opts, tmux.WindowWithMainPaneSize(wCfg.MainPaneSize))
+	}
+
 	return opts
 }
 
@@ -198,6 +279,10 @@ func makePaneOpts(pCfg PaneConfig) []tmux.PaneOption {
 		opts = append(opts, tmux.PaneAsActive())
 	}
 
+	if len(pCfg.loadedEnv) != 0 {
+		opts = append(opts, tmux.PaneWithRawEnv(pCfg.loadedEnv))
+	}
+
 	if len(pCfg.Env) != 0 {
 		opts = append(opts, tmux.PaneWithEnv(pCfg.Env))
 	}
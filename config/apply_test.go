@@ -3,20 +3,22 @@ package config_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/internal/faketmux"
 	"github.com/michenriksen/tmpl/internal/testutils"
 	"github.com/michenriksen/tmpl/tmux"
 
 	"github.com/stretchr/testify/require"
 )
 
-// stubCmd represents an entry for a stubbed command defined in
-// testdata/apply-stubcmds.json.
+// stubCmd represents an entry for a stubbed command defined in a stub command
+// file in the testdata directory.
 //
 // See [loadStubCmds] for more information.
 type stubCmd struct {
@@ -36,9 +38,196 @@ func TestApply(t *testing.T) {
 	cfg, err := config.FromFile(filepath.Join("testdata", "apply.yaml"))
 	require.NoError(t, err)
 
-	expectedCmds := loadStubCmds(t)
+	expectedCmds := loadStubCmds(t, "apply-stubcmds.json")
 
-	var mockCmdRunner tmux.OSCommandRunner = func(_ context.Context, name string, args ...string) ([]byte, error) {
+	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)))
+	require.NoError(t, err)
+
+	session, err := config.Apply(context.Background(), cfg, cmd)
+	require.NoError(t, err)
+
+	requireStubCmdsSeen(t, expectedCmds)
+
+	require.Equal(t, "tmpl_test_session", session.Name())
+}
+
+func TestApply_NoServer(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "project", "cmd"), 0755))
+
+	// Stub HOME and current working directory for consistent test results.
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	cfg, err := config.FromFile(filepath.Join("testdata", "apply.yaml"))
+	require.NoError(t, err)
+
+	expectedCmds := loadStubCmds(t, "apply-stubcmds.json")
+
+	// The list-sessions command fails as no tmux server is running, which
+	// means that the session does not exist and must be created.
+	for args, cmd := range expectedCmds {
+		if strings.HasPrefix(args, "list-sessions ") {
+			cmd.Output = "no server running on /tmp/tmux-1000/default"
+			cmd.Err = errors.New("exit status 1")
+		}
+	}
+
+	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)))
+	require.NoError(t, err)
+
+	session, err := config.Apply(context.Background(), cfg, cmd)
+	require.NoError(t, err)
+
+	requireStubCmdsSeen(t, expectedCmds)
+
+	require.Equal(t, "tmpl_test_session", session.Name())
+}
+
+func TestApply_FakeTmux(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "project", "cmd"), 0755))
+
+	// Stub HOME and current working directory for consistent test results.
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	cfg, err := config.FromFile(filepath.Join("testdata", "apply.yaml"))
+	require.NoError(t, err)
+
+	srv, err := faketmux.NewServer(faketmux.WithWorkingDir(dir))
+	require.NoError(t, err)
+
+	_, err = config.Apply(context.Background(), cfg, srv)
+	require.NoError(t, err)
+
+	session, ok := srv.Session("tmpl_test_session")
+	require.True(t, ok)
+
+	require.Len(t, session.Windows, 3)
+
+	code := session.Windows[0]
+	require.Equal(t, "code", code.Name)
+	require.Equal(t, 0, code.Index)
+	require.True(t, code.Active)
+	require.Equal(t, "510a,80x24,0,0{48x24,0,0,1,31x24,49,0,2}", code.Layout)
+	require.Len(t, code.Panes, 2)
+	require.True(t, code.Panes[0].Active)
+	require.Equal(t, filepath.Join(dir, "project"), code.Panes[0].Path)
+	require.Equal(t, []string{"~/project/scripts/boostrap.sh", "echo 'on_window'", "nvim ."}, code.Panes[0].Commands)
+	require.Equal(t, map[string]string{"APP_ENV": "testing"}, code.Panes[1].Env)
+	require.Equal(t, []string{"~/project/scripts/boostrap.sh", "echo 'on_pane'", "./scripts/autorun-tests.sh"}, code.Panes[1].Commands)
+
+	server := session.Windows[1]
+	require.Equal(t, "server", server.Name)
+	require.Equal(t, 1, server.Index)
+	require.False(t, server.Active)
+	require.Len(t, server.Panes, 1)
+	require.Equal(t, filepath.Join(dir, "project", "cmd"), server.Panes[0].Path)
+	require.Equal(t, map[string]string{"APP_ENV": "development", "PORT": "8080"}, server.Panes[0].Env)
+	require.Equal(t, []string{"~/project/scripts/boostrap.sh", "echo 'on_window'", "./server"}, server.Panes[0].Commands)
+
+	logs := session.Windows[2]
+	require.Equal(t, "prod_logs", logs.Name)
+	require.Equal(t, 2, logs.Index)
+	require.Len(t, logs.Panes, 1)
+	require.Equal(t, []string{
+		"~/project/scripts/boostrap.sh", "echo 'on_window'", "ssh user@host", "cd /var/logs", "tail -f app.log",
+	}, logs.Panes[0].Commands)
+}
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "project", "cmd"), 0755))
+
+	// Stub HOME and current working directory for consistent test results.
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	cfg, err := config.FromFile(filepath.Join("testdata", "apply.yaml"))
+	require.NoError(t, err)
+
+	expectedCmds := loadStubCmds(t, "sync-stubcmds.json")
+
+	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)))
+	require.NoError(t, err)
+
+	session, res, err := config.Sync(context.Background(), cfg, cmd)
+	require.NoError(t, err)
+
+	requireStubCmdsSeen(t, expectedCmds)
+
+	require.Equal(t, "tmpl_test_session", session.Name())
+	require.False(t, res.Created)
+	require.Len(t, res.Windows, 1)
+	require.Equal(t, "tmpl_test_session:prod_logs", res.Windows[0].Name())
+	require.Len(t, res.Panes, 1)
+	require.Equal(t, "tmpl_test_session:code.1", res.Panes[0].Name())
+	require.Equal(t, 3, session.NumWindows())
+}
+
+func TestSync_NestedPanes(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	writeCfg := func(t *testing.T, panes string) *config.Config {
+		t.Helper()
+
+		cfgPath := filepath.Join(t.TempDir(), "tmpl.yaml")
+		testutils.WriteFile(t, []byte("session:\n  name: nested\n  windows:\n    - name: code\n      panes:\n"+panes), cfgPath)
+
+		cfg, err := config.FromFile(cfgPath)
+		require.NoError(t, err)
+
+		return cfg
+	}
+
+	srv, err := faketmux.NewServer(faketmux.WithWorkingDir(dir))
+	require.NoError(t, err)
+
+	// Pane b is split from pane a, and pane c from the initial pane, so tmux
+	// gives pane c a lower index than panes a and b.
+	_, err = config.Apply(context.Background(), writeCfg(t, `
+        - command: a
+          panes:
+            - command: b
+        - command: c
+`), srv)
+	require.NoError(t, err)
+
+	_, res, err := config.Sync(context.Background(), writeCfg(t, `
+        - command: a
+          panes:
+            - command: b
+            - command: d
+        - command: c
+`), srv)
+	require.NoError(t, err)
+
+	require.Len(t, res.Panes, 1)
+
+	session, ok := srv.Session("nested")
+	require.True(t, ok)
+
+	var cmds []string
+
+	for _, p := range session.Windows[0].Panes {
+		cmds = append(cmds, strings.Join(p.Commands, ","))
+	}
+
+	// Pane d is split from pane a, so it comes right after it.
+	require.Equal(t, []string{"", "c", "a", "d", "b"}, cmds)
+}
+
+// newStubCmdRunner returns a [tmux.OSCommandRunner] that fails the test if it
+// receives a command that is not in the provided stub commands, or if it
+// receives the same command more than once.
+func newStubCmdRunner(t *testing.T, expectedCmds map[string]*stubCmd) tmux.OSCommandRunner {
+	t.Helper()
+
+	return func(_ context.Context, name string, args ...string) ([]byte, error) {
 		argStr := strings.Join(args, " ")
 
 		cmd, ok := expectedCmds[argStr]
@@ -57,42 +246,52 @@ func TestApply(t *testing.T) {
 
 		return []byte(cmd.Output), cmd.Err
 	}
+}
 
-	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(mockCmdRunner))
-	require.NoError(t, err)
-
-	session, err := config.Apply(context.Background(), cfg, cmd)
-	require.NoError(t, err)
+// requireStubCmdsSeen fails the test if any of the provided stub commands were
+// not run.
+func requireStubCmdsSeen(t *testing.T, expectedCmds map[string]*stubCmd) {
+	t.Helper()
 
 	for args, cmd := range expectedCmds {
 		if !cmd.seen {
 			t.Fatalf("expected command was not run: %s", args)
 		}
 	}
+}
+
+// expandSetEnv returns the value of the environment variable with the provided
+// name, or a reference to it if it is not set.
+func expandSetEnv(name string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
 
-	require.Equal(t, "tmpl_test_session", session.Name())
+	return "$" + name
 }
 
-// loadStubCmds loads the stub commands defined in testdata/apply-stubcmds.json.
+// loadStubCmds loads the stub commands defined in the provided JSON file in the
+// testdata directory.
 //
 // Commands are defined as a map of expected tmux command line arguments mapped
 // to a stubCmd struct containing optional stub output and error.
 //
-// The map keys and stub outputs are expanded using os.ExpandEnv before being
-// returned. This allows for using environment variables in the stub commands to
-// ensure consistent test results.
-func loadStubCmds(t *testing.T) map[string]*stubCmd {
-	data := testutils.ReadFile(t, "testdata", "apply-stubcmds.json")
+// Environment variables in the map keys are expanded before being returned.
+// This allows for using environment variables in the stub commands to ensure
+// consistent test results. References to variables that are not set, such as
+// the session ID $1, are left as they are.
+func loadStubCmds(t *testing.T, file string) map[string]*stubCmd {
+	data := testutils.ReadFile(t, "testdata", file)
 
 	var cmds map[string]*stubCmd
 
 	if err := json.Unmarshal(data, &cmds); err != nil {
-		t.Fatalf("error decoding apply-stubcmds.json: %v", err)
+		t.Fatalf("error decoding %s: %v", file, err)
 	}
 
 	expanded := make(map[string]*stubCmd, len(cmds))
 	for args, cmd := range cmds {
-		expanded[os.ExpandEnv(args)] = cmd
+		expanded[os.Expand(args, expandSetEnv)] = cmd
 	}
 
 	t.Logf("loaded %d stub commands", len(cmds))
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// FromSession creates a configuration from the running tmux session with the
+// provided name.
+//
+// The configuration describes the session's windows and panes with their
+// current working directories and active flags, and the current layout of
+// windows with more than one pane. The working directory of a window is the
+// current working directory of its initial pane.
+//
+// Window names must be unique in a configuration, so windows named the same
+// as an earlier window get a numeric suffix, e.g. "zsh-2" for the second
+// window named "zsh".
+//
+// Returns [ErrSessionNotFound] if no session with the provided name exists.
+func FromSession(ctx context.Context, runner tmux.Runner, name string) (*Config, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	session, err := findSession(ctx, runner, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, name)
+	}
+
+	windows, err := tmux.GetWindows(ctx, runner, session)
+	if err != nil {
+		return nil, fmt.Errorf("getting windows for %s: %w", session, err)
+	}
+
+	cfg := &Config{Session: SessionConfig{Name: session.Name(), Path: session.Path()}}
+	names := uniqueWindowNames(windows)
+
+	for i, win := range windows {
+		panes, err := tmux.GetPanes(ctx, runner, win)
+		if err != nil {
+			return nil, fmt.Errorf("getting panes for %s: %w", win, err)
+		}
+
+		wCfg := WindowConfig{Name: names[i], Path: cfg.Session.Path, Active: win.IsActive()}
+
+		if len(panes) != 0 && panes[0].CurrentPath() != "" {
+			wCfg.Path = panes[0].CurrentPath()
+		}
+
+		if len(panes) > 1 {
+			wCfg.Layout = win.Layout()
+
+			for _, pane := range panes[1:] {
+				pCfg := PaneConfig{Path: wCfg.Path, Active: pane.IsActive()}
+
+				if pane.CurrentPath() != "" {
+					pCfg.Path = pane.CurrentPath()
+				}
+
+				wCfg.Panes = append(wCfg.Panes, pCfg)
+			}
+		}
+
+		cfg.Session.Windows = append(cfg.Session.Windows, wCfg)
+	}
+
+	return cfg, nil
+}
+
+// uniqueWindowNames returns the short names of the provided windows, with a
+// numeric suffix added to names already used by an earlier window. Suffixes
+// are chosen so that the names do not clash with the names of other windows.
+func uniqueWindowNames(windows []*tmux.Window) []string {
+	taken := make(map[string]bool, len(windows))
+
+	for _, win := range windows {
+		taken[win.ShortName()] = true
+	}
+
+	seen := make(map[string]bool, len(windows))
+	names := make([]string, len(windows))
+
+	for i, win := range windows {
+		name := win.ShortName()
+
+		if seen[name] {
+			n := 2
+			for taken[fmt.Sprintf("%s-%d", name, n)] {
+				n++
+			}
+
+			name = fmt.Sprintf("%s-%d", name, n)
+			taken[name] = true
+		}
+
+		seen[name] = true
+		names[i] = name
+	}
+
+	return names
+}
+
+// Encode writes the configuration to w in YAML format.
+//
+// Window and pane paths are written relative to the path they inherit from if
+// they are located below it, and are left out if they are the same.
+func (c *Config) Encode(w io.Writer) error {
+	cfg := *c
+	cfg.Session.Windows = make([]WindowConfig, len(c.Session.Windows))
+
+	for i, wCfg := range c.Session.Windows {
+		wCfg.Panes = relPaneCfgs(wCfg.Panes, wCfg.Path)
+		wCfg.Path = relPath(wCfg.Path, c.Session.Path)
+		cfg.Session.Windows[i] = wCfg
+	}
+
+	if _, err := io.WriteString(w, "---\n"); err != nil {
+		return fmt.Errorf("writing configuration: %w", err)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(&cfg); err != nil {
+		return fmt.Errorf("writing configuration: %w", err)
+	}
+
+	return enc.Close() //nolint:wrapcheck // Nothing to add.
+}
+
+// relPaneCfgs returns a copy of the provided pane configurations with paths
+// made relative to the parent path.
+func relPaneCfgs(panes []PaneConfig, parentPath string) []PaneConfig {
+	if len(panes) == 0 {
+		return nil
+	}
+
+	res := make([]PaneConfig, len(panes))
+
+	for i, pCfg := range panes {
+		pCfg.Panes = relPaneCfgs(pCfg.Panes, pCfg.Path)
+		pCfg.Path = relPath(pCfg.Path, parentPath)
+		res[i] = pCfg
+	}
+
+	return res
+}
+
+// relPath returns p relative to the parent path if p is located below it.
+//
+// If p is the same as the parent path, an empty string is returned. If p is not
+// located below the parent path, it is returned unchanged.
+func relPath(p, parentPath string) string {
+	if p == parentPath {
+		return ""
+	}
+
+	if p == "" || parentPath == "" {
+		return p
+	}
+
+	rel, err := filepath.Rel(parentPath, p)
+	if err != nil || !filepath.IsLocal(rel) {
+		return p
+	}
+
+	return rel
+}
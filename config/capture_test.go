@@ -0,0 +1,101 @@
+package config_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/internal/faketmux"
+)
+
+func TestFromSession_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	other := t.TempDir()
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app", "cmd"), 0o744))
+
+	cfgPath := filepath.Join(dir, "applied.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`---
+session:
+  name: capture_test
+  windows:
+    - name: app
+      path: app
+      panes:
+        - path: cmd
+    - name: other
+      path: `+other+`
+`), 0o600))
+
+	cfg, err := config.FromFile(cfgPath)
+	require.NoError(t, err)
+
+	srv, err := faketmux.NewServer(faketmux.WithWorkingDir(dir))
+	require.NoError(t, err)
+
+	_, err = config.Apply(context.Background(), cfg, srv)
+	require.NoError(t, err)
+
+	captured, err := config.FromSession(context.Background(), srv, "capture_test")
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, captured.Encode(buf))
+	require.Contains(t, buf.String(), "path: app\n")
+	require.Contains(t, buf.String(), "path: cmd\n")
+	require.Contains(t, buf.String(), "path: "+other+"\n")
+
+	capturedPath := filepath.Join(dir, "captured.yaml")
+	require.NoError(t, os.WriteFile(capturedPath, buf.Bytes(), 0o600))
+
+	loaded, err := config.FromFile(capturedPath)
+	require.NoError(t, err)
+	require.NoError(t, loaded.Validate())
+
+	require.Len(t, loaded.Session.Windows, 2)
+	require.Equal(t, filepath.Join(dir, "app"), loaded.Session.Windows[0].Path)
+	require.Len(t, loaded.Session.Windows[0].Panes, 1)
+	require.Equal(t, filepath.Join(dir, "app", "cmd"), loaded.Session.Windows[0].Panes[0].Path)
+	require.Equal(t, other, loaded.Session.Windows[1].Path)
+}
+
+func TestFromSession_DuplicateWindowNames(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	srv, err := faketmux.NewServer(faketmux.WithWorkingDir(dir))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	for _, args := range [][]string{
+		{"new-session", "-d", "-s", "dupes", "-n", "zsh", "-c", dir},
+		{"new-window", "-t", "dupes:", "-n", "zsh-2", "-c", dir},
+		{"new-window", "-t", "dupes:", "-n", "zsh", "-c", dir},
+		{"new-window", "-t", "dupes:", "-n", "zsh", "-c", dir},
+	} {
+		_, err := srv.Run(ctx, args...)
+		require.NoError(t, err)
+	}
+
+	captured, err := config.FromSession(ctx, srv, "dupes")
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(captured.Session.Windows))
+	for _, w := range captured.Session.Windows {
+		names = append(names, w.Name)
+	}
+
+	require.Equal(t, []string{"zsh", "zsh-2", "zsh-3", "zsh-4"}, names)
+	require.NoError(t, captured.Validate())
+}
@@ -8,6 +8,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
@@ -21,17 +24,56 @@ var specialCharsRegexp = regexp.MustCompile(`[^\w_]+`)
 
 // Config represents a session configuration loaded from a YAML file.
 type Config struct {
-	path        string
-	Session     SessionConfig `yaml:"session"`      // Session configuration.
-	Tmux        string        `yaml:"tmux"`         // Path to tmux executable.
-	TmuxOptions []string      `yaml:"tmux_options"` // Additional tmux options.
+	path            string
+	sources         []*Config // Decoded configuration files, in the order they were merged.
+	profile         string
+	disabledWindows []string
+	skipped         []SkippedItem
+	Session         SessionConfig            `yaml:"session,omitempty"`         // Session configuration.
+	Sessions        []SessionConfig          `yaml:"sessions,omitempty"`        // Session configurations.
+	Tmux            string                   `yaml:"tmux,omitempty"`            // Path to tmux executable.
+	TmuxOptions     []string                 `yaml:"tmux_options,omitempty"`    // Additional tmux options.
+	ControlMode     *bool                    `yaml:"control_mode,omitempty"`    // Whether to run tmux commands over a control mode connection.
+	Attach          *bool                    `yaml:"attach,omitempty"`          // Whether to attach to the session.
+	Vars            map[string]string        `yaml:"vars,omitempty"`            // Template variables.
+	TemplateDelims  []string                 `yaml:"template_delims,omitempty"` // Custom left and right template action delimiters.
+	Extends         string                   `yaml:"extends,omitempty"`         // Path to configuration file to extend.
+	Profiles        map[string]ProfileConfig `yaml:"profiles,omitempty"`        // Named profiles.
+	Redact          []string                 `yaml:"redact,omitempty"`          // Additional patterns for names of environment variables to redact from logs.
 }
 
 // FromFile loads a session configuration from provided file path.
 //
 // File is expected to be in YAML format.
-func FromFile(cfgPath string) (*Config, error) {
-	cfg, err := load(cfgPath)
+//
+// If the configuration extends another configuration file, it is merged on
+// top of it, and if a local override file exists next to the configuration
+// file (see [LocalConfigPath]), it is merged on top of the result. See
+// [Config.Sources] for the paths of all files read.
+//
+// Variables from the vars section, and any provided with [WithVars], are
+// expanded in all string fields of the session and profile configurations
+// before default values are set, and windows and panes configured with
+// for_each are generated (see [ForEachConfig]). If a field cannot be expanded,
+// a [TemplateError] is returned.
+//
+// If a profile is selected with [WithProfile], it is applied to the session
+// configurations after variables are expanded. Disabled windows are removed
+// after any profile is applied.
+//
+// Windows and panes with conditions that do not hold (see [WhenConfig]) are
+// removed after default values are set. See [Config.Skipped] for the removed
+// windows and panes.
+func FromFile(cfgPath string, opts ...FromFileOption) (*Config, error) {
+	o := &fromFileOpts{}
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, fmt.Errorf("applying option: %w", err)
+		}
+	}
+
+	cfg, err := load(cfgPath, o)
 	if err != nil {
 		return nil, err
 	}
@@ -39,12 +81,115 @@ func FromFile(cfgPath string) (*Config, error) {
 	return cfg, nil
 }
 
+// fromFileOpts holds options for [FromFile].
+type fromFileOpts struct {
+	vars    map[string]string
+	profile string
+}
+
+// FromFileOption configures how [FromFile] loads a configuration.
+type FromFileOption func(*fromFileOpts) error
+
+// WithVars configures [FromFile] to expand the provided variables in the
+// configuration, overriding variables with the same name in the vars section.
+func WithVars(vars map[string]string) FromFileOption {
+	return func(o *fromFileOpts) error {
+		o.vars = vars
+		return nil
+	}
+}
+
+// WithProfile configures [FromFile] to apply the profile with the provided
+// name. If name is empty, no profile is applied.
+func WithProfile(name string) FromFileOption {
+	return func(o *fromFileOpts) error {
+		o.profile = name
+		return nil
+	}
+}
+
 // Path returns the path to the configuration file from which the configuration
 // was loaded.
 func (c *Config) Path() string {
 	return c.path
 }
 
+// Sources returns the paths to all configuration files the configuration was
+// merged from, in the order they were applied. The extended configuration
+// files come first, followed by the file the configuration was loaded from and
+// the local override file, if any.
+func (c *Config) Sources() []string {
+	paths := make([]string, len(c.sources))
+
+	for i, src := range c.sources {
+		paths[i] = src.path
+	}
+
+	return paths
+}
+
+// Profile returns the name of the profile applied to the configuration, or an
+// empty string if no profile was applied.
+func (c *Config) Profile() string {
+	return c.profile
+}
+
+// Skipped returns the windows and panes that were not included in the
+// configuration when it was loaded, because the conditions in their when
+// configuration do not hold.
+func (c *Config) Skipped() []SkippedItem {
+	return c.skipped
+}
+
+// SessionConfigs returns the configurations for all sessions, which is either
+// the sessions list or the single session configuration.
+func (c *Config) SessionConfigs() []SessionConfig {
+	if len(c.Sessions) != 0 {
+		return c.Sessions
+	}
+
+	return []SessionConfig{c.Session}
+}
+
+// withSession returns a copy of the configuration with the provided session
+// configuration as its single session.
+func (c *Config) withSession(sCfg SessionConfig) *Config {
+	res := *c
+	res.Session = sCfg
+	res.Sessions = nil
+
+	return &res
+}
+
+// singleSessionCfg returns a configuration with a single session for
+// functions that operate on one session.
+//
+// A configuration with exactly one session in its sessions list is converted
+// to the single session form. Returns [ErrMultipleSessions] if the
+// configuration has more than one session.
+func singleSessionCfg(cfg *Config) (*Config, error) {
+	switch len(cfg.Sessions) {
+	case 0:
+		return cfg, nil
+	case 1:
+		return cfg.withSession(cfg.Sessions[0]), nil
+	default:
+		return nil, ErrMultipleSessions
+	}
+}
+
+// ShouldAttach returns true if the client should be attached to the session
+// after the configuration is applied, which is the default.
+func (c *Config) ShouldAttach() bool {
+	return c.Attach == nil || *c.Attach
+}
+
+// UseControlMode returns true if tmux commands should be run over a control
+// mode connection, which is off by default.
+func (c *Config) UseControlMode() bool {
+	return c.ControlMode != nil && *c.ControlMode
+}
+
 // NumWindows returns the number of window configurations for the session.
 func (c *Config) NumWindows() int {
 	n := len(c.Session.Windows)
@@ -75,15 +220,86 @@ func (c *Config) NumPanes() int {
 // and the window configurations.
 //
 // Any environment variables defined in the session configuration will be
-// inherited by all windows and panes.
+// inherited by all windows and panes. Environment variables can also be loaded
+// from dotenv files and the output of host commands when the session is
+// created. Within a session, window or pane configuration, variables from env
+// files are overridden by variables from env_from commands, which are
+// overridden by variables in env.
+//
+// The width and height set the initial size of the session, which is used for
+// calculating percentage pane sizes. If not set, tmux decides the size.
+//
+// Hooks are commands run on the host by tmpl itself at different points of the
+// session lifecycle (see [HookConfig]).
+//
+// If the session is marked as secret, the commands and environment variable
+// values of the session and all its windows and panes are redacted from logs.
+// Windows and panes can also be marked as secret on their own, which includes
+// their nested panes.
 type SessionConfig struct {
-	Name     string            `yaml:"name"`      // Session name.
-	Path     string            `yaml:"path"`      // Session directory.
-	OnWindow string            `yaml:"on_window"` // Shell command to run in all windows.
-	OnPane   string            `yaml:"on_pane"`   // Shell command to run in all panes.
-	OnAny    string            `yaml:"on_any"`    // Shell command to run in all windows and panes.
-	Env      map[string]string `yaml:"env"`       // Session environment variables.
-	Windows  []WindowConfig    `yaml:"windows"`   // Window configurations.
+	Name     string                   `yaml:"name,omitempty"`      // Session name.
+	Path     string                   `yaml:"path,omitempty"`      // Session directory.
+	OnWindow string                   `yaml:"on_window,omitempty"` // Shell command to run in all windows.
+	OnPane   string                   `yaml:"on_pane,omitempty"`   // Shell command to run in all panes.
+	OnAny    string                   `yaml:"on_any,omitempty"`    // Shell command to run in all windows and panes.
+	Env      map[string]string        `yaml:"env,omitempty"`       // Session environment variables.
+	EnvFile  StringList               `yaml:"env_file,omitempty"`  // Dotenv files to load session environment variables from.
+	EnvFrom  map[string]EnvFromConfig `yaml:"env_from,omitempty"`  // Commands to load session environment variable values from.
+	Width    int                      `yaml:"width,omitempty"`     // Initial session width in columns.
+	Height   int                      `yaml:"height,omitempty"`    // Initial session height in lines.
+	Windows  []WindowConfig           `yaml:"windows,omitempty"`   // Window configurations.
+	Secret   bool                     `yaml:"secret,omitempty"`    // Whether commands and environment variable values are redacted from logs.
+
+	BeforeStart []HookConfig `yaml:"before_start,omitempty"` // Hooks to run before the session is created.
+	AfterStart  []HookConfig `yaml:"after_start,omitempty"`  // Hooks to run after the session is created.
+	OnStop      []HookConfig `yaml:"on_stop,omitempty"`      // Hooks to run when the session is stopped.
+
+	loadedEnv map[string]string // Variables loaded from env files and commands.
+	set       map[string]bool   // Names of the boolean flags set in the decoded file.
+}
+
+// UnmarshalYAML implements the function form of the [yaml.Unmarshaler]
+// interface, which unlike [yaml.Node.Decode] keeps the strict field checking
+// of the decoder.
+//
+// The boolean flags set in the YAML mapping are recorded, so that a
+// configuration merged on top of another can set them to false.
+func (s *SessionConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	// The type has the fields of SessionConfig without this method, and
+	// names the configuration in errors about unknown fields.
+	type sessionConfig SessionConfig
+
+	if err := unmarshal((*sessionConfig)(s)); err != nil {
+		return err //nolint:wrapcheck // Error is reported by the decoder.
+	}
+
+	var err error
+
+	s.set, err = setFlags(unmarshal, "secret")
+
+	return err
+}
+
+// HookConfig represents a command that is run on the host at a defined point
+// of the session lifecycle, as opposed to commands that are typed into tmux
+// windows and panes.
+//
+// The command is run with the shell in the hook directory, which defaults to
+// the session path. It inherits the environment of tmpl and the session
+// environment variables, which are overridden by variables defined in the hook
+// configuration if they have the same name.
+//
+// If the command does not finish within the timeout, it is killed. The timeout
+// defaults to [DefaultHookTimeout].
+//
+// If the hook is marked as secret, the command and the values of the hook
+// environment variables are redacted from logs.
+type HookConfig struct {
+	Command string            `yaml:"command"`           // Shell command to run.
+	Path    string            `yaml:"path,omitempty"`    // Hook working directory.
+	Env     map[string]string `yaml:"env,omitempty"`     // Hook environment variables.
+	Timeout time.Duration     `yaml:"timeout,omitempty"` // Maximum run time of the command.
+	Secret  bool              `yaml:"secret,omitempty"`  // Whether the command and environment variable values are redacted from logs.
 }
 
 // WindowConfig represents a tmux window configuration. It contains the name of
@@ -92,17 +308,75 @@ type SessionConfig struct {
 //
 // If a path is not specified, a window will inherit the session path.
 //
+// Disabled windows are not created unless enabled by a profile (see
+// [ProfileConfig]), and windows with conditions are only created if the
+// conditions hold (see [WhenConfig]).
+//
+// If a layout is specified, the panes are arranged with it after all of them
+// have been created.
+//
 // Any environment variables defined in the window configuration will be
 // inherited by all panes. If a variable is defined in both the session and
 // window configuration, the window variable will take precedence.
 type WindowConfig struct {
-	Name     string            `yaml:"name"`     // Window name.
-	Path     string            `yaml:"path"`     // Window directory.
-	Command  string            `yaml:"command"`  // Command to run in the window.
-	Commands []string          `yaml:"commands"` // Commands to run in the window.
-	Env      map[string]string `yaml:"env"`      // Window environment variables.
-	Panes    []PaneConfig      `yaml:"panes"`    // Pane configurations.
-	Active   bool              `yaml:"active"`   // Whether the window should be selected.
+	Name         string                   `yaml:"name,omitempty"`           // Window name.
+	Path         string                   `yaml:"path,omitempty"`           // Window directory.
+	Command      string                   `yaml:"command,omitempty"`        // Command to run in the window.
+	Commands     []string                 `yaml:"commands,omitempty"`       // Commands to run in the window.
+	Env          map[string]string        `yaml:"env,omitempty"`            // Window environment variables.
+	EnvFile      StringList               `yaml:"env_file,omitempty"`       // Dotenv files to load window environment variables from.
+	EnvFrom      map[string]EnvFromConfig `yaml:"env_from,omitempty"`       // Commands to load window environment variable values from.
+	Layout       string                   `yaml:"layout,omitempty"`         // Preset layout name or custom layout string.
+	MainPaneSize string                   `yaml:"main_pane_size,omitempty"` // Main pane size for main-* layouts.
+	Panes        []PaneConfig             `yaml:"panes,omitempty"`          // Pane configurations.
+	Active       bool                     `yaml:"active,omitempty"`         // Whether the window should be selected.
+	Disabled     bool                     `yaml:"disabled,omitempty"`       // Whether the window should not be created.
+	When         *WhenConfig              `yaml:"when,omitempty"`           // Conditions for creating the window.
+	ForEach      *ForEachConfig           `yaml:"for_each,omitempty"`       // Items to generate windows for.
+	Secret       bool                     `yaml:"secret,omitempty"`         // Whether commands and environment variable values are redacted from logs.
+
+	loadedEnv map[string]string // Variables loaded from env files and commands.
+	set       map[string]bool   // Names of the boolean flags set in the decoded file.
+}
+
+// UnmarshalYAML implements the function form of the [yaml.Unmarshaler]
+// interface (see [SessionConfig.UnmarshalYAML]).
+func (w *WindowConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	type windowConfig WindowConfig
+
+	if err := unmarshal((*windowConfig)(w)); err != nil {
+		return err //nolint:wrapcheck // Error is reported by the decoder.
+	}
+
+	var err error
+
+	w.set, err = setFlags(unmarshal, "active", "disabled", "secret")
+
+	return err
+}
+
+// setFlags returns the names of the provided boolean flags that are set in the
+// YAML mapping decoded by unmarshal, or nil if none of them are set.
+func setFlags(unmarshal func(any) error, names ...string) (map[string]bool, error) {
+	var fields map[string]any
+
+	if err := unmarshal(&fields); err != nil {
+		return nil, err //nolint:wrapcheck // Error is reported by the decoder.
+	}
+
+	var res map[string]bool
+
+	for _, name := range names {
+		if _, ok := fields[name]; ok {
+			if res == nil {
+				res = make(map[string]bool, len(names))
+			}
+
+			res[name] = true
+		}
+	}
+
+	return res, nil
 }
 
 // PaneConfig represents a tmux pane configuration. It contains the path to the
@@ -115,15 +389,25 @@ type WindowConfig struct {
 // Any inherited environment variables from the window or session will be
 // overridden by variables defined in the pane configuration if they have the
 // same name.
+//
+// Panes with conditions are only created if the conditions hold (see
+// [WhenConfig]).
 type PaneConfig struct {
-	Env        map[string]string `yaml:"env"`        // Pane environment variables.
-	Path       string            `yaml:"path"`       // Pane directory.
-	Command    string            `yaml:"command"`    // Command to run in the pane.
-	Commands   []string          `yaml:"commands"`   // Commands to run in the pane.
-	Size       string            `yaml:"size"`       // Pane size (cells or percentage)
-	Horizontal bool              `yaml:"horizontal"` // Whether the pane should be split horizontally.
-	Panes      []PaneConfig      `yaml:"panes"`      // Pane configurations.
-	Active     bool              `yaml:"active"`     // Whether the pane should be selected.
+	Env        map[string]string        `yaml:"env,omitempty"`        // Pane environment variables.
+	EnvFile    StringList               `yaml:"env_file,omitempty"`   // Dotenv files to load pane environment variables from.
+	EnvFrom    map[string]EnvFromConfig `yaml:"env_from,omitempty"`   // Commands to load pane environment variable values from.
+	Path       string                   `yaml:"path,omitempty"`       // Pane directory.
+	Command    string                   `yaml:"command,omitempty"`    // Command to run in the pane.
+	Commands   []string                 `yaml:"commands,omitempty"`   // Commands to run in the pane.
+	Size       string                   `yaml:"size,omitempty"`       // Pane size (cells or percentage)
+	Horizontal bool                     `yaml:"horizontal,omitempty"` // Whether the pane should be split horizontally.
+	Panes      []PaneConfig             `yaml:"panes,omitempty"`      // Pane configurations.
+	Active     bool                     `yaml:"active,omitempty"`     // Whether the pane should be selected.
+	When       *WhenConfig              `yaml:"when,omitempty"`       // Conditions for creating the pane.
+	ForEach    *ForEachConfig           `yaml:"for_each,omitempty"`   // Items to generate panes for.
+	Secret     bool                     `yaml:"secret,omitempty"`     // Whether commands and environment variable values are redacted from logs.
+
+	loadedEnv map[string]string // Variables loaded from env files and commands.
 }
 
 // FindConfigFile searches for a configuration file starting from the provided
@@ -161,9 +445,63 @@ func FindConfigFile(dir string) (string, error) {
 	}
 }
 
-// load reads and decodes a YAML configuration file into a Config struct and
-// sets default values.
-func load(cfgPath string) (*Config, error) {
+// load reads and decodes a YAML configuration file into a Config struct,
+// merges it with any extended and local configuration files, expands
+// variables, applies the selected profile, sets default values and removes
+// windows and panes with conditions that do not hold.
+func load(cfgPath string, opts *fromFileOpts) (*Config, error) {
+	cfg, err := decodeFile(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, sources, err := resolveExtends(cfg, cfgPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	localPath := LocalConfigPath(cfgPath)
+
+	local, err := decodeFile(localPath)
+
+	switch {
+	case err == nil:
+		if local.Extends != "" {
+			return nil, fmt.Errorf("%s: %w", localPath, ErrLocalExtends)
+		}
+
+		cfg = mergeConfig(cfg, local)
+		sources = append(sources, local)
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, ErrEmptyConfig):
+	default:
+		return nil, err
+	}
+
+	cfg.path = cfgPath
+	cfg.sources = sources
+
+	if err := expandVars(cfg, opts.vars); err != nil {
+		return nil, err
+	}
+
+	if err := applyProfile(cfg, opts.profile); err != nil {
+		return nil, err
+	}
+
+	if err := setDefaults(cfg); err != nil {
+		return nil, fmt.Errorf("setting default values: %w", err)
+	}
+
+	if err := evaluateConditions(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// decodeFile reads and decodes a YAML configuration file into a Config struct
+// without any further processing.
+func decodeFile(cfgPath string) (*Config, error) {
 	var cfg Config
 
 	f, err := os.Open(cfgPath)
@@ -190,11 +528,62 @@ func load(cfgPath string) (*Config, error) {
 
 	cfg.path = cfgPath
 
-	if err := setDefaults(&cfg); err != nil {
-		return nil, fmt.Errorf("setting default values: %w", err)
+	return &cfg, nil
+}
+
+// resolveExtends loads the configuration extended by cfg, if any, and merges
+// cfg on top of it. Extended configurations can themselves extend other
+// configurations.
+//
+// The extends path is resolved against the directory of the configuration
+// file extending it, unless it is absolute or starts with a tilde.
+//
+// Returns the merged configuration and all the decoded configuration files it
+// was merged from, with the most extended file first. The chain
+// argument holds the paths of the files extending cfg, and is used to detect
+// cycles.
+func resolveExtends(cfg *Config, cfgPath string, chain []string) (*Config, []*Config, error) {
+	absPath, err := filepath.Abs(cfgPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting absolute path for %s: %w", cfgPath, err)
 	}
 
-	return &cfg, nil
+	if slices.Contains(chain, absPath) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrExtendsCycle, strings.Join(append(chain, absPath), " -> "))
+	}
+
+	if cfg.Extends == "" {
+		return cfg, []*Config{cfg}, nil
+	}
+
+	basePath, err := resolvePath(cfg.Extends, filepath.Dir(cfgPath))
+	if err != nil {
+		return nil, nil, fmt.Errorf("expanding extends path in %s: %w", cfgPath, err)
+	}
+
+	base, err := decodeFile(basePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading configuration extended by %s: %w", cfgPath, err)
+	}
+
+	base, sources, err := resolveExtends(base, basePath, append(chain, absPath))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mergeConfig(base, cfg), append(sources, cfg), nil
+}
+
+// LocalConfigPath returns the path to the local override file for the
+// configuration file at the provided path.
+//
+// The local override file is located next to the configuration file and has
+// the same name with ".local" inserted before the extension, e.g.
+// ".tmpl.local.yaml" for ".tmpl.yaml".
+func LocalConfigPath(cfgPath string) string {
+	ext := filepath.Ext(cfgPath)
+
+	return strings.TrimSuffix(cfgPath, ext) + ".local" + ext
 }
 
 // setDefaults sets default values for blank configuration fields.
@@ -204,53 +593,156 @@ func load(cfgPath string) (*Config, error) {
 // - Session.Name: defaults to <current directory name>.
 // - Session.Path: defaults to current working directory.
 // - Window.Path: defaults to Session.Path.
-// - Pane.Path: defaults to Window.Path.
+// - Pane.Path: defaults to Window.Path, or parent Pane.Path for nested panes.
+// - Hook.Path: defaults to Session.Path.
+// - Hook.Timeout: defaults to [DefaultHookTimeout].
+//
+// Sessions in the sessions list get the same defaults, except for the name,
+// which must be set for each of them.
+//
+// Relative session paths are resolved against the current working directory.
+// Relative window and hook paths are resolved against the session path, and
+// relative pane paths are resolved against the window or parent pane path.
 func setDefaults(cfg *Config) error {
 	wd, err := env.Getwd()
 	if err != nil {
 		return fmt.Errorf("getting current working directory: %w", err)
 	}
 
+	if len(cfg.Sessions) != 0 {
+		for i := range cfg.Sessions {
+			if err := setSessionDefaults(&cfg.Sessions[i], wd); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
 	if cfg.Session.Name == "" {
 		name := specialCharsRegexp.ReplaceAllString(filepath.Base(wd), "_")
 		cfg.Session.Name = name
 	}
 
-	if cfg.Session.Path == "" {
-		cfg.Session.Path = wd
+	return setSessionDefaults(&cfg.Session, wd)
+}
+
+// setSessionDefaults sets default values for blank fields in the provided
+// session configuration and its window, pane and hook configurations.
+func setSessionDefaults(sCfg *SessionConfig, wd string) error {
+	var err error
+
+	if sCfg.Path == "" {
+		sCfg.Path = wd
 	} else {
-		if cfg.Session.Path, err = env.AbsPath(cfg.Session.Path); err != nil {
+		if sCfg.Path, err = env.AbsPath(sCfg.Path); err != nil {
 			return fmt.Errorf("expanding session path: %w", err)
 		}
 	}
 
-	for i, w := range cfg.Session.Windows {
-		if w.Path == "" {
-			w.Path = cfg.Session.Path
-		} else {
-			if w.Path, err = env.AbsPath(w.Path); err != nil {
-				return fmt.Errorf("expanding window path: %w", err)
-			}
+	if err := resolveEnvFiles(sCfg.EnvFile, sCfg.Path); err != nil {
+		return err
+	}
+
+	for i, w := range sCfg.Windows {
+		if w.Path, err = resolvePath(w.Path, sCfg.Path); err != nil {
+			return fmt.Errorf("expanding window path: %w", err)
 		}
 
-		for j, p := range w.Panes {
-			if p.Path == "" {
-				p.Path = w.Path
-			} else {
-				if p.Path, err = env.AbsPath(p.Path); err != nil {
-					return fmt.Errorf("expanding pane path: %w", err)
-				}
-			}
+		if err := resolveEnvFiles(w.EnvFile, w.Path); err != nil {
+			return err
+		}
+
+		if err := setPaneDefaults(w.Panes, w.Path); err != nil {
+			return err
+		}
+
+		sCfg.Windows[i] = w
+	}
 
-			w.Panes[j] = p
+	for _, hooks := range [][]HookConfig{sCfg.BeforeStart, sCfg.AfterStart, sCfg.OnStop} {
+		if err := setHookDefaults(hooks, sCfg.Path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setHookDefaults sets default values for blank fields in the provided hook
+// configurations.
+func setHookDefaults(hooks []HookConfig, sessionPath string) error {
+	var err error
+
+	for i, h := range hooks {
+		if h.Path, err = resolvePath(h.Path, sessionPath); err != nil {
+			return fmt.Errorf("expanding hook path: %w", err)
+		}
+
+		if h.Timeout == 0 {
+			h.Timeout = DefaultHookTimeout
 		}
 
-		cfg.Session.Windows[i] = w
+		hooks[i] = h
 	}
 
 	return nil
 }
 
+// setPaneDefaults sets default values for blank fields in the provided pane
+// configurations and their nested pane configurations.
+func setPaneDefaults(panes []PaneConfig, parentPath string) error {
+	var err error
+
+	for i, p := range panes {
+		if p.Path, err = resolvePath(p.Path, parentPath); err != nil {
+			return fmt.Errorf("expanding pane path: %w", err)
+		}
+
+		if err := resolveEnvFiles(p.EnvFile, p.Path); err != nil {
+			return err
+		}
+
+		if err := setPaneDefaults(p.Panes, p.Path); err != nil {
+			return err
+		}
+
+		panes[i] = p
+	}
+
+	return nil
+}
+
+// resolveEnvFiles resolves the provided env file paths in place against the
+// provided path of the session, window or pane configuration they belong to.
+func resolveEnvFiles(files StringList, dir string) error {
+	var err error
+
+	for i, name := range files {
+		if files[i], err = resolvePath(name, dir); err != nil {
+			return fmt.Errorf("expanding env file path: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// resolvePath returns the absolute path for p, resolving relative paths
+// against the provided parent path.
+//
+// If p is empty, the parent path is returned.
+func resolvePath(p, parentPath string) (string, error) {
+	if p == "" {
+		return parentPath, nil
+	}
+
+	if filepath.IsAbs(p) || strings.HasPrefix(p, "~") {
+		return env.AbsPath(p) //nolint:wrapcheck // Wrapping is done by caller.
+	}
+
+	return filepath.Join(parentPath, p), nil
+}
+
 // ConfigFileName returns the name of the configuration that.
 //
 // Returns the value of the TMPL_CONFIG_NAME environment variable if set,
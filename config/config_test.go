@@ -19,18 +19,83 @@ func TestFromFile(t *testing.T) {
 	tt := []struct {
 		name      string
 		file      string
+		opts      []config.FromFileOption
 		assertErr testutils.ErrorAssertion
 	}{
-		{"full config", "full.yaml", nil},
-		{"minimal config", "minimal.yaml", nil},
-		{"tilde home paths", "tilde.yaml", nil},
-		{"empty config", "empty.yaml", testutils.RequireErrorIs(config.ErrEmptyConfig)},
-		{"broken", "broken.yaml", testutils.RequireErrorContains("decoding error:")},
+		{"full config", "full.yaml", nil, nil},
+		{"minimal config", "minimal.yaml", nil, nil},
+		{"tilde home paths", "tilde.yaml", nil, nil},
+		{"relative paths", "relative.yaml", nil, nil},
+		{"variables", "vars.yaml", nil, nil},
+		{
+			"variables with overrides",
+			"vars.yaml",
+			[]config.FromFileOption{config.WithVars(map[string]string{"service": "payments", "region": "eu"})},
+			nil,
+		},
+		{
+			"undefined variable",
+			"vars-undefined.yaml",
+			nil,
+			testutils.RequireErrorContains(`command: map has no entry for key "port"`),
+		},
+		{
+			"template syntax error",
+			"vars-syntax-error.yaml",
+			nil,
+			testutils.RequireErrorContains("name: unclosed action"),
+		},
+		{"literal template actions without variables", "vars-literal.yaml", nil, nil},
+		{"custom template delimiters", "vars-delims.yaml", nil, nil},
+		{
+			"invalid template delimiters",
+			"vars-delims-invalid.yaml",
+			nil,
+			testutils.RequireErrorContains("template_delims: must be a left and a right delimiter"),
+		},
+		{"multiple sessions", "sessions.yaml", nil, nil},
+		{"env files", "env-files.yaml", nil, nil},
+		{"profiles", "profiles.yaml", nil, nil},
+		{"minimal profile", "profiles.yaml", []config.FromFileOption{config.WithProfile("minimal")}, nil},
+		{"full profile", "profiles.yaml", []config.FromFileOption{config.WithProfile("full")}, nil},
+		{
+			"unknown profile",
+			"profiles.yaml",
+			[]config.FromFileOption{config.WithProfile("nope")},
+			testutils.RequireErrorIs(config.ErrUnknownProfile),
+		},
+		{"extends with local override", filepath.Join("extends", "project.yaml"), nil, nil},
+		{
+			"extends cycle",
+			filepath.Join("extends", "cycle-a.yaml"),
+			nil,
+			testutils.RequireErrorIs(config.ErrExtendsCycle),
+		},
+		{
+			"extends missing file",
+			filepath.Join("extends", "missing.yaml"),
+			nil,
+			testutils.RequireErrorContains("loading configuration extended by testdata/extends/missing.yaml"),
+		},
+		{
+			"local override with extends",
+			filepath.Join("extends", "local-extends.yaml"),
+			nil,
+			testutils.RequireErrorIs(config.ErrLocalExtends),
+		},
+		{"empty config", "empty.yaml", nil, testutils.RequireErrorIs(config.ErrEmptyConfig)},
+		{"broken", "broken.yaml", nil, testutils.RequireErrorContains("decoding error:")},
+		{
+			"unknown window field",
+			"unknown-window-field.yaml",
+			nil,
+			testutils.RequireErrorContains("line 5: field actve not found"),
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			cfg, err := config.FromFile(filepath.Join("testdata", tc.file))
+			cfg, err := config.FromFile(filepath.Join("testdata", tc.file), tc.opts...)
 
 			if tc.assertErr != nil {
 				require.Error(t, err, "expected error")
@@ -49,6 +114,92 @@ func TestFromFile(t *testing.T) {
 	}
 }
 
+func TestFromFile_Sources(t *testing.T) {
+	t.Setenv("HOME", "/Users/johndoe")
+	t.Setenv("TMPL_PWD", "/Users/johndoe/project")
+
+	cfg, err := config.FromFile(filepath.Join("testdata", "extends", "project.yaml"))
+	require.NoError(t, err)
+
+	require.Equal(t, filepath.Join("testdata", "extends", "project.yaml"), cfg.Path())
+	require.Equal(t, []string{
+		filepath.Join("testdata", "extends", "base.yaml"),
+		filepath.Join("testdata", "extends", "project.yaml"),
+		filepath.Join("testdata", "extends", "project.local.yaml"),
+	}, cfg.Sources())
+}
+
+func TestConfig_Source(t *testing.T) {
+	t.Setenv("HOME", "/Users/johndoe")
+	t.Setenv("TMPL_PWD", "/Users/johndoe/project")
+
+	cfg, err := config.FromFile(filepath.Join("testdata", "extends", "project.yaml"))
+	require.NoError(t, err)
+
+	tt := []struct {
+		field string
+		want  string
+	}{
+		{"session.name", "project.yaml"},
+		{"session.path", "project.yaml"},
+		{"session.env.APP_ENV", "base.yaml"},
+		{"session.env.LOG_LEVEL", "project.yaml"},
+		{"session.windows.0.command", "base.yaml"},
+		{"session.windows.0.active", "project.local.yaml"},
+		{"session.windows.0.panes.0.command", "base.yaml"},
+		{"session.windows.1.command", "project.yaml"},
+		{"session.windows.2.command", "project.local.yaml"},
+		{"session.windows.3.name", "project.yaml"},
+		{"tmux_options", "base.yaml"},
+		{"control_mode", "project.local.yaml"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.field, func(t *testing.T) {
+			require.Equal(t, filepath.Join("testdata", "extends", tc.want), cfg.Source(tc.field))
+		})
+	}
+}
+
+func TestFromFile_Profile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	tt := []struct {
+		profile     string
+		wantWindows []string
+		wantEnv     string
+		wantHook    string
+	}{
+		{"", []string{"code", "tests", "db"}, "development", "docker compose up -d"},
+		{"minimal", []string{"code", "tests"}, "minimal", `echo "hello"`},
+		{"full", []string{"code", "tests", "logs", "db"}, "development", "docker compose up -d"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.profile, func(t *testing.T) {
+			cfg, err := config.FromFile(filepath.Join("testdata", "profiles.yaml"), config.WithProfile(tc.profile))
+			require.NoError(t, err)
+			require.NoError(t, cfg.Validate())
+
+			require.Equal(t, tc.profile, cfg.Profile())
+
+			windows := make([]string, 0, len(cfg.Session.Windows))
+			for _, w := range cfg.Session.Windows {
+				windows = append(windows, w.Name)
+			}
+
+			require.Equal(t, tc.wantWindows, windows)
+			require.Equal(t, tc.wantEnv, cfg.Session.Env["APP_ENV"])
+			require.Len(t, cfg.Session.BeforeStart, 1)
+			require.Equal(t, tc.wantHook, cfg.Session.BeforeStart[0].Command)
+			require.Equal(t, dir, cfg.Session.BeforeStart[0].Path)
+		})
+	}
+}
+
 func TestFindConfigFile_TraverseDirectories(t *testing.T) {
 	dir := t.TempDir()
 	require.NoError(t, os.MkdirAll(filepath.Join(dir, "subdir", "second subdir", "third subdir"), 0o744))
@@ -0,0 +1,418 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// DiffStatus describes how a window or pane in a running tmux session differs
+// from its configuration.
+type DiffStatus string
+
+const (
+	DiffEqual   DiffStatus = "equal"   // Exists and matches the configuration.
+	DiffChanged DiffStatus = "changed" // Exists but differs from the configuration.
+	DiffMissing DiffStatus = "missing" // Configured but does not exist.
+	DiffExtra   DiffStatus = "extra"   // Exists but is not configured.
+)
+
+// SessionDiff describes the differences between a running tmux session and
+// its configuration.
+type SessionDiff struct {
+	Name    string       `json:"name"`    // Session name.
+	Exists  bool         `json:"exists"`  // Whether the session is running.
+	Windows []WindowDiff `json:"windows"` // Window differences.
+}
+
+// HasChanges returns true if any window or pane differs from the
+// configuration.
+func (d *SessionDiff) HasChanges() bool {
+	if !d.Exists {
+		return true
+	}
+
+	for _, w := range d.Windows {
+		if w.HasChanges() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WindowDiff describes the differences between a window in a running tmux
+// session and its configuration.
+type WindowDiff struct {
+	Name    string      `json:"name"`              // Window name.
+	Status  DiffStatus  `json:"status"`            // Window status.
+	Changes []FieldDiff `json:"changes,omitempty"` // Changed window fields.
+	Panes   []PaneDiff  `json:"panes,omitempty"`   // Pane differences.
+}
+
+// HasChanges returns true if the window or any of its panes differ from the
+// configuration.
+func (d WindowDiff) HasChanges() bool {
+	if d.Status != DiffEqual {
+		return true
+	}
+
+	for _, p := range d.Panes {
+		if p.Status != DiffEqual {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PaneDiff describes the differences between a pane in a running tmux window
+// and its configuration.
+//
+// Panes are identified by the position of their configuration when the pane
+// configurations of the window are walked depth-first, where position 1 is the
+// first pane split from the window's initial pane. Running panes that are not
+// configured are numbered after the configured ones.
+type PaneDiff struct {
+	Position int         `json:"position"`          // Pane position.
+	Status   DiffStatus  `json:"status"`            // Pane status.
+	Changes  []FieldDiff `json:"changes,omitempty"` // Changed pane fields.
+}
+
+// FieldDiff describes a configuration field with a value different from the
+// running tmux session.
+type FieldDiff struct {
+	Field string `json:"field"` // Configuration field name.
+	Want  string `json:"want"`  // Configured value.
+	Got   string `json:"got"`   // Value in the running session.
+}
+
+// Diff compares the provided configuration with the running tmux session of
+// the same name, and returns a description of the differences.
+//
+// Windows are matched by name. A configured window without a match is paired
+// with an unmatched running window at the same position, if any, and reported
+// as changed. Panes are matched by their position in the split tree of the
+// window, in the same way as with [Sync].
+//
+// Paths are compared with the directories windows and panes were started in,
+// as their current directories change when commands are run in them, and are
+// not compared if tmux does not report them. Custom window layouts are
+// compared with the layout reported by tmux, and preset layouts with the
+// arrangement of the panes, as their sizes change when the window is resized.
+//
+// If no session with the configured name is running, all configured windows
+// are reported as missing.
+//
+// Returns [ErrMultipleSessions] if the configuration has more than one session
+// (see [Up]).
+func Diff(ctx context.Context, cfg *Config, runner tmux.Runner) (*SessionDiff, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cfg, err := singleSessionCfg(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SessionDiff{Name: cfg.Session.Name, Windows: []WindowDiff{}}
+
+	session, err := findSession(ctx, runner, cfg.Session.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		for _, wCfg := range cfg.Session.Windows {
+			res.Windows = append(res.Windows, missingWindowDiff(wCfg))
+		}
+
+		return res, nil
+	}
+
+	res.Exists = true
+
+	live, err := tmux.GetWindows(ctx, runner, session)
+	if err != nil {
+		return nil, fmt.Errorf("getting windows for %s: %w", session, err)
+	}
+
+	pairs := pairWindows(cfg.Session.Windows, live)
+	matched := make(map[*tmux.Window]bool, len(live))
+
+	for i, wCfg := range cfg.Session.Windows {
+		win := pairs[i]
+		if win == nil {
+			res.Windows = append(res.Windows, missingWindowDiff(wCfg))
+			continue
+		}
+
+		matched[win] = true
+
+		wDiff, err := diffWindow(ctx, runner, wCfg, win)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Windows = append(res.Windows, wDiff)
+	}
+
+	for _, win := range live {
+		if !matched[win] {
+			res.Windows = append(res.Windows, WindowDiff{Name: win.ShortName(), Status: DiffExtra})
+		}
+	}
+
+	return res, nil
+}
+
+// pairWindows pairs window configurations with running windows.
+//
+// The returned slice has an element for each window configuration, which is
+// nil if no running window could be paired with it.
+func pairWindows(cfgs []WindowConfig, live []*tmux.Window) []*tmux.Window {
+	pairs := make([]*tmux.Window, len(cfgs))
+	taken := make(map[*tmux.Window]bool, len(live))
+
+	for i, wCfg := range cfgs {
+		if wCfg.Name == "" {
+			continue
+		}
+
+		for _, w := range live {
+			if !taken[w] && w.ShortName() == wCfg.Name {
+				pairs[i] = w
+				taken[w] = true
+
+				break
+			}
+		}
+	}
+
+	for i := range cfgs {
+		if pairs[i] != nil || i >= len(live) || taken[live[i]] {
+			continue
+		}
+
+		pairs[i] = live[i]
+		taken[live[i]] = true
+	}
+
+	return pairs
+}
+
+// diffWindow compares a window configuration with a running window and its
+// panes.
+func diffWindow(ctx context.Context, r tmux.Runner, cfg WindowConfig, win *tmux.Window) (WindowDiff, error) {
+	res := WindowDiff{Name: cfg.Name, Status: DiffEqual}
+
+	if res.Name == "" {
+		res.Name = win.ShortName()
+	}
+
+	panes, err := tmux.GetPanes(ctx, r, win)
+	if err != nil {
+		return res, fmt.Errorf("getting panes for %s: %w", win, err)
+	}
+
+	if cfg.Name != "" && cfg.Name != win.ShortName() {
+		res.Changes = append(res.Changes, FieldDiff{Field: "name", Want: cfg.Name, Got: win.ShortName()})
+	}
+
+	root := tmux.InitialPane(panes)
+
+	if root != nil && pathChanged(cfg.Path, root) {
+		res.Changes = append(res.Changes, FieldDiff{Field: "path", Want: cfg.Path, Got: root.StartPath()})
+	}
+
+	if layoutChanged(cfg.Layout, win, len(panes)) {
+		res.Changes = append(res.Changes, FieldDiff{Field: "layout", Want: cfg.Layout, Got: win.Layout()})
+	}
+
+	if len(res.Changes) != 0 {
+		res.Status = DiffChanged
+	}
+
+	pd := &paneDiffer{tree: splitTree(panes), matched: make(map[*tmux.Pane]bool, len(panes))}
+	pd.diff(root, cfg.Panes)
+
+	res.Panes = pd.res
+
+	for _, p := range creationOrder(panes) {
+		if p != root && !pd.matched[p] {
+			res.Panes = append(res.Panes, PaneDiff{Position: len(res.Panes) + 1, Status: DiffExtra})
+		}
+	}
+
+	return res, nil
+}
+
+// paneDiffer matches pane configurations for a window with its running panes
+// in the same way as [paneSyncer], and compares them.
+type paneDiffer struct {
+	tree    map[*tmux.Pane][]*tmux.Pane
+	matched map[*tmux.Pane]bool
+	res     []PaneDiff
+}
+
+// diff compares the provided pane configurations with the running panes split
+// from the parent pane. If parent is nil, the panes are reported as missing.
+func (d *paneDiffer) diff(parent *tmux.Pane, cfgs []PaneConfig) {
+	var live []*tmux.Pane
+	if parent != nil {
+		live = d.tree[parent]
+	}
+
+	for i, pCfg := range cfgs {
+		pos := len(d.res) + 1
+
+		if i >= len(live) {
+			d.res = append(d.res, PaneDiff{Position: pos, Status: DiffMissing})
+			d.diff(nil, pCfg.Panes)
+
+			continue
+		}
+
+		pane := live[i]
+		d.matched[pane] = true
+		d.res = append(d.res, diffPane(pos, pCfg, pane))
+		d.diff(pane, pCfg.Panes)
+	}
+}
+
+// diffPane compares a pane configuration with a running pane.
+func diffPane(pos int, cfg PaneConfig, pane *tmux.Pane) PaneDiff {
+	res := PaneDiff{Position: pos, Status: DiffEqual}
+
+	if pathChanged(cfg.Path, pane) {
+		res.Changes = append(res.Changes, FieldDiff{Field: "path", Want: cfg.Path, Got: pane.StartPath()})
+		res.Status = DiffChanged
+	}
+
+	return res
+}
+
+// pathChanged returns true if the configured path differs from the directory
+// the pane was started in. Paths are not compared if either is unknown.
+func pathChanged(path string, pane *tmux.Pane) bool {
+	return path != "" && pane.StartPath() != "" && path != pane.StartPath()
+}
+
+// layoutChanged returns true if the configured layout differs from the layout
+// of the running window with the provided number of panes.
+//
+// Custom layouts are compared with the layout string reported by tmux. Preset
+// layouts are compared with the arrangement of the panes in it, ignoring their
+// sizes (see [layoutShape]).
+func layoutChanged(layout string, win *tmux.Window, numPanes int) bool {
+	if layout == "" || win.Layout() == "" {
+		return false
+	}
+
+	if !presetLayouts[layout] {
+		return layout != win.Layout()
+	}
+
+	return presetShape(layout, numPanes) != layoutShape(win.Layout())
+}
+
+var (
+	// layoutLeafRE matches the size, offsets and pane ID of a pane cell in a
+	// layout string.
+	layoutLeafRE = regexp.MustCompile(`\d+x\d+,\d+,\d+,\d+`)
+
+	// layoutContainerRE matches the size and offsets of a container cell in a
+	// layout string.
+	layoutContainerRE = regexp.MustCompile(`\d+x\d+,\d+,\d+`)
+)
+
+// layoutShape returns the arrangement of the panes in a layout string reported
+// by tmux, with pane cells written as p, and containers written as {} for
+// panes side by side and [] for panes stacked, e.g. {p,[p,p]} for a pane with
+// two stacked panes next to it.
+func layoutShape(layout string) string {
+	_, body, _ := strings.Cut(layout, ",")
+	body = layoutLeafRE.ReplaceAllString(body, "p")
+
+	return layoutContainerRE.ReplaceAllString(body, "")
+}
+
+// presetShape returns the arrangement of the provided number of panes in the
+// preset layout as written by [layoutShape], which is the arrangement tmux
+// gives the panes when the layout is selected.
+func presetShape(layout string, numPanes int) string {
+	if numPanes <= 1 {
+		return "p"
+	}
+
+	switch layout {
+	case "even-horizontal":
+		return spreadShape("{", "}", numPanes)
+	case "even-vertical":
+		return spreadShape("[", "]", numPanes)
+	case "main-horizontal":
+		return "[p," + spreadShape("{", "}", numPanes-1) + "]"
+	case "main-vertical":
+		return "{p," + spreadShape("[", "]", numPanes-1) + "}"
+	}
+
+	// Tiled layouts have as many rows and columns as needed for the panes,
+	// with more rows than columns if they differ. The last row can hold fewer
+	// panes than the others.
+	rows, cols := 1, 1
+
+	for rows*cols < numPanes {
+		rows++
+
+		if rows*cols < numPanes {
+			cols++
+		}
+	}
+
+	shapes := make([]string, 0, rows)
+
+	for n := numPanes; n > 0; n -= cols {
+		shapes = append(shapes, spreadShape("{", "}", min(n, cols)))
+	}
+
+	return "[" + strings.Join(shapes, ",") + "]"
+}
+
+// spreadShape returns the arrangement of the provided number of panes in a
+// container with the provided brackets, or a single pane cell.
+func spreadShape(open, closing string, numPanes int) string {
+	if numPanes == 1 {
+		return "p"
+	}
+
+	return open + strings.Repeat("p,", numPanes-1) + "p" + closing
+}
+
+// missingWindowDiff returns a window diff for a configured window that does
+// not exist, with all of its panes reported as missing.
+func missingWindowDiff(cfg WindowConfig) WindowDiff {
+	res := WindowDiff{Name: cfg.Name, Status: DiffMissing}
+
+	for i := range flattenPaneCfgs(cfg.Panes) {
+		res.Panes = append(res.Panes, PaneDiff{Position: i + 1, Status: DiffMissing})
+	}
+
+	return res
+}
+
+// flattenPaneCfgs returns the provided pane configurations and their nested
+// pane configurations in depth-first order.
+func flattenPaneCfgs(cfgs []PaneConfig) []PaneConfig {
+	var res []PaneConfig
+
+	for _, pCfg := range cfgs {
+		res = append(res, pCfg)
+		res = append(res, flattenPaneCfgs(pCfg.Panes)...)
+	}
+
+	return res
+}
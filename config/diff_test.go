@@ -0,0 +1,134 @@
+package config_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/internal/faketmux"
+	"github.com/michenriksen/tmpl/internal/testutils"
+)
+
+func TestDiff_NestedPanes(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	srv, err := faketmux.NewServer(faketmux.WithWorkingDir(dir))
+	require.NoError(t, err)
+
+	// Pane b is split from pane a, and pane c from the initial pane, so tmux
+	// gives pane c a lower index than panes a and b.
+	_, err = config.Apply(context.Background(), writeDiffCfg(t, "", `
+        - command: a
+          panes:
+            - command: b
+        - command: c
+`), srv)
+	require.NoError(t, err)
+
+	diff, err := config.Diff(context.Background(), writeDiffCfg(t, "", `
+        - command: a
+          panes:
+            - command: b
+            - command: d
+        - command: c
+`), srv)
+	require.NoError(t, err)
+
+	require.Len(t, diff.Windows, 1)
+	require.Equal(t, []config.PaneDiff{
+		{Position: 1, Status: config.DiffEqual},
+		{Position: 2, Status: config.DiffEqual},
+		{Position: 3, Status: config.DiffMissing},
+		{Position: 4, Status: config.DiffEqual},
+	}, diff.Windows[0].Panes)
+
+	diff, err = config.Diff(context.Background(), writeDiffCfg(t, "", `
+        - command: a
+        - command: c
+`), srv)
+	require.NoError(t, err)
+
+	require.Equal(t, []config.PaneDiff{
+		{Position: 1, Status: config.DiffEqual},
+		{Position: 2, Status: config.DiffEqual},
+		{Position: 3, Status: config.DiffExtra},
+	}, diff.Windows[0].Panes)
+}
+
+func TestDiff_PresetLayout(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	presets := []string{"even-horizontal", "even-vertical", "main-horizontal", "main-vertical", "tiled"}
+
+	for _, layout := range presets {
+		for numPanes := 1; numPanes <= 5; numPanes++ {
+			layout, numPanes := layout, numPanes
+
+			t.Run(fmt.Sprintf("%s %d panes", layout, numPanes), func(t *testing.T) {
+				srv, err := faketmux.NewServer(faketmux.WithWorkingDir(dir))
+				require.NoError(t, err)
+
+				panes := strings.Repeat("        - command: pane\n", numPanes-1)
+
+				_, err = config.Apply(context.Background(), writeDiffCfg(t, layout, panes), srv)
+				require.NoError(t, err)
+
+				diff, err := config.Diff(context.Background(), writeDiffCfg(t, layout, panes), srv)
+				require.NoError(t, err)
+				require.False(t, diff.HasChanges(), "expected no changes for layout %s", layout)
+
+				// Selecting another preset changes the arrangement of two or
+				// more panes, except for main-vertical and even-horizontal
+				// with two panes, which are arranged in the same way.
+				other := "even-horizontal"
+				if layout == other {
+					other = "even-vertical"
+				}
+
+				_, err = srv.Run(context.Background(), "select-layout", "-t", "diff:code", other)
+				require.NoError(t, err)
+
+				diff, err = config.Diff(context.Background(), writeDiffCfg(t, layout, panes), srv)
+				require.NoError(t, err)
+
+				same := numPanes == 1 || (numPanes == 2 && layout == "main-vertical")
+
+				require.Equal(t, !same, diff.HasChanges(), "layout %s after selecting %s", layout, other)
+			})
+		}
+	}
+}
+
+// writeDiffCfg writes a configuration with a single code window with the
+// provided layout and pane configurations, and loads it.
+func writeDiffCfg(t *testing.T, layout, panes string) *config.Config {
+	t.Helper()
+
+	data := "session:\n  name: diff\n  windows:\n    - name: code\n"
+	if layout != "" {
+		data += "      layout: " + layout + "\n"
+	}
+
+	if panes != "" {
+		data += "      panes:\n" + panes
+	}
+
+	cfgPath := filepath.Join(t.TempDir(), "tmpl.yaml")
+	testutils.WriteFile(t, []byte(data), cfgPath)
+
+	cfg, err := config.FromFile(cfgPath)
+	require.NoError(t, err)
+
+	return cfg
+}
@@ -0,0 +1,386 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// DefaultEnvFromTimeout is the default maximum run time of a command loading
+// the value of an environment variable configured with env_from.
+const DefaultEnvFromTimeout = 30 * time.Second
+
+// EnvFromConfig represents a shell command that loads the value of an
+// environment variable configured with env_from.
+//
+// If the command does not finish within the timeout, it is killed. The timeout
+// defaults to [DefaultEnvFromTimeout].
+//
+// In YAML, a command without a timeout can be written as a string, and a
+// command with a timeout as a mapping:
+//
+//	API_TOKEN: pass show project/api-token
+//	API_TOKEN: {command: vault read -field=token secret/api, timeout: 2m}
+type EnvFromConfig struct {
+	Command string        `yaml:"command"`           // Shell command to run.
+	Timeout time.Duration `yaml:"timeout,omitempty"` // Maximum run time of the command.
+}
+
+// UnmarshalYAML implements the [yaml.Unmarshaler] interface.
+func (e *EnvFromConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&e.Command) //nolint:wrapcheck // Error is reported by the decoder.
+	}
+
+	if value.Kind != yaml.MappingNode {
+		return errors.New("env_from value must be a command or a mapping with a command key")
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, val := value.Content[i], value.Content[i+1]
+
+		var err error
+
+		switch key.Value {
+		case "command":
+			err = val.Decode(&e.Command)
+		case "timeout":
+			err = val.Decode(&e.Timeout)
+		default:
+			return fmt.Errorf("line %d: field %s not found in env_from value", key.Line, key.Value)
+		}
+
+		if err != nil {
+			return err //nolint:wrapcheck // Error is reported by the decoder.
+		}
+	}
+
+	return nil
+}
+
+// MarshalYAML implements the [yaml.Marshaler] interface.
+func (e EnvFromConfig) MarshalYAML() (any, error) {
+	if e.Timeout == 0 {
+		return e.Command, nil
+	}
+
+	return map[string]string{"command": e.Command, "timeout": e.Timeout.String()}, nil
+}
+
+// StringList is a list of strings that can be written as a single string in
+// YAML, e.g. env_file: .env instead of env_file: [.env].
+type StringList []string
+
+// UnmarshalYAML implements the [yaml.Unmarshaler] interface.
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var s string
+		if err := value.Decode(&s); err != nil {
+			return err //nolint:wrapcheck // Error is reported by the decoder.
+		}
+
+		*l = StringList{s}
+
+		return nil
+	}
+
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err //nolint:wrapcheck // Error is reported by the decoder.
+	}
+
+	*l = list
+
+	return nil
+}
+
+// loadEnv returns a copy of the session configuration with the environment
+// variables of the session, window and pane configurations loaded from their
+// env files and env_from commands.
+//
+// Within each configuration, variables from env files are loaded in the order
+// the files are listed, followed by variables from commands, with later
+// variables taking precedence.
+//
+// Env file paths are resolved against the session, window or pane path when
+// the configuration is loaded, and commands are run with the shell in the
+// session, window or pane path. If the runner is in dry-run or replay mode,
+// the commands are logged but not run, and their variables are left out (see
+// [skipHostCommands]).
+func loadEnv(ctx context.Context, r tmux.Runner, sCfg SessionConfig) (SessionConfig, error) {
+	var err error
+
+	if sCfg.loadedEnv, err = loadEnvVars(ctx, r, sCfg.EnvFile, sCfg.EnvFrom, sCfg.Path); err != nil {
+		return sCfg, fmt.Errorf("loading environment for session %q: %w", sCfg.Name, err)
+	}
+
+	if len(sCfg.Windows) == 0 {
+		return sCfg, nil
+	}
+
+	windows := make([]WindowConfig, len(sCfg.Windows))
+
+	for i, wCfg := range sCfg.Windows {
+		wCfg = wCfg.clone()
+
+		if wCfg.loadedEnv, err = loadEnvVars(ctx, r, wCfg.EnvFile, wCfg.EnvFrom, wCfg.Path); err != nil {
+			return sCfg, fmt.Errorf("loading environment for window %q: %w", wCfg.Name, err)
+		}
+
+		if err := loadPaneEnv(ctx, r, wCfg.Panes); err != nil {
+			return sCfg, fmt.Errorf("loading environment for pane in window %q: %w", wCfg.Name, err)
+		}
+
+		windows[i] = wCfg
+	}
+
+	sCfg.Windows = windows
+
+	return sCfg, nil
+}
+
+// loadPaneEnv loads the environment variables of the provided pane
+// configurations and their nested pane configurations in place.
+func loadPaneEnv(ctx context.Context, r tmux.Runner, panes []PaneConfig) error {
+	var err error
+
+	for i := range panes {
+		p := &panes[i]
+
+		if p.loadedEnv, err = loadEnvVars(ctx, r, p.EnvFile, p.EnvFrom, p.Path); err != nil {
+			return err
+		}
+
+		if err := loadPaneEnv(ctx, r, p.Panes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadEnvVars loads environment variables from the provided env files and
+// env_from commands. Returns nil if no variables are configured.
+func loadEnvVars(ctx context.Context, r tmux.Runner, files []string, from map[string]EnvFromConfig, dir string) (map[string]string, error) { //nolint:revive // more readable in one line.
+	if len(files) == 0 && len(from) == 0 {
+		return nil, nil
+	}
+
+	res := make(map[string]string)
+
+	for _, name := range files {
+		r.Log("loading environment file", "file", name)
+
+		vars, err := readEnvFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range vars {
+			res[k] = v
+		}
+	}
+
+	keys := make([]string, 0, len(from))
+	for k := range from {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		val, err := runEnvCommand(ctx, r, key, from[key], dir)
+		if err != nil {
+			return nil, fmt.Errorf("loading environment variable %s from command %q: %w", key, from[key].Command, err)
+		}
+
+		if !skipHostCommands(r) {
+			res[key] = val
+		}
+	}
+
+	return res, nil
+}
+
+// runEnvCommand runs an env_from command with the shell in dir and returns
+// its output with trailing newlines removed.
+//
+// Output on stderr is included in the returned error if the command fails.
+func runEnvCommand(ctx context.Context, r tmux.Runner, key string, e EnvFromConfig, dir string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	r.Log("loading environment variable", "var", key, "cmd", e.Command, "path", dir)
+
+	if skipHostCommands(r) {
+		return "", nil
+	}
+
+	timeout := e.Timeout
+	if timeout == 0 {
+		timeout = DefaultEnvFromTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, hookShell, "-c", e.Command)
+	cmd.Dir = dir
+	cmd.Stderr = stderr
+	cmd.WaitDelay = hookWaitDelay
+
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("timed out after %s", timeout)
+		}
+
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+
+		return "", err //nolint:wrapcheck // Wrapping is done by caller.
+	}
+
+	r.Debug("environment variable loaded", "var", key, "cmd", e.Command, "dur", time.Since(start))
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// readEnvFile reads environment variables from a dotenv file.
+func readEnvFile(name string) (map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("opening env file: %w", err)
+	}
+	defer f.Close()
+
+	vars, err := parseEnvFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing env file %s: %w", name, err)
+	}
+
+	return vars, nil
+}
+
+// parseEnvFile parses environment variables in the dotenv format from r.
+//
+// Each line holds a variable as KEY=VALUE, optionally prefixed with "export".
+// Blank lines and lines starting with # are ignored. Values can be enclosed in
+// single quotes, which are taken literally, or double quotes, which support
+// the escape sequences \n, \t, \" and \\. A # preceded by whitespace starts a
+// comment in unquoted values. Variable references are not expanded.
+func parseEnvFile(r io.Reader) (map[string]string, error) {
+	res := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNum)
+		}
+
+		key = strings.TrimSpace(key)
+		if !varNameRE.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNum, key)
+		}
+
+		val, err := parseEnvValue(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		res[key] = val
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading lines: %w", err)
+	}
+
+	return res, nil
+}
+
+func parseEnvValue(val string) (string, error) {
+	if val == "" {
+		return "", nil
+	}
+
+	switch val[0] {
+	case '\'':
+		end := strings.IndexByte(val[1:], '\'')
+		if end == -1 {
+			return "", errors.New("unterminated single-quoted value")
+		}
+
+		return val[1 : end+1], nil
+	case '"':
+		var b strings.Builder
+
+		for i := 1; i < len(val); i++ {
+			c := val[i]
+
+			switch {
+			case c == '"':
+				return b.String(), nil
+			case c == '\\' && i+1 < len(val):
+				i++
+
+				switch val[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case '"', '\\':
+					b.WriteByte(val[i])
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(val[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+
+		return "", errors.New("unterminated double-quoted value")
+	}
+
+	if i := strings.Index(val, " #"); i != -1 {
+		val = val[:i]
+	}
+
+	if i := strings.Index(val, "\t#"); i != -1 {
+		val = val[:i]
+	}
+
+	return strings.TrimSpace(val), nil
+}
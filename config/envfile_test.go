@@ -0,0 +1,168 @@
+package config_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/tmux"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply_EnvFile(t *testing.T) {
+	dir := t.TempDir()
+
+	envFile, err := filepath.Abs(filepath.Join("testdata", "app.env"))
+	require.NoError(t, err)
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name:    "tmpl_test_session",
+		Path:    dir,
+		EnvFile: config.StringList{envFile},
+		Env:     map[string]string{"APP_ENV": "session"},
+		BeforeStart: []config.HookConfig{
+			{Command: `echo "$DB_PASSWORD"`, Path: dir},
+		},
+		Windows: []config.WindowConfig{
+			{
+				Name:    "code",
+				Path:    dir,
+				EnvFrom: map[string]config.EnvFromConfig{"API_TOKEN": {Command: `printf 'tok-%s\n' "$(basename "$PWD")"`}},
+				Panes: []config.PaneConfig{
+					{Path: dir, Env: map[string]string{"DB_HOST": "db"}},
+				},
+			},
+		},
+	}}
+
+	token := "tok-" + filepath.Base(dir)
+	expectedCmds := map[string]*stubCmd{
+		listSessionsArgs: {Output: "session_id:$0,session_name:main,session_path:" + dir},
+		"new-session -d -P -F session_id:#{session_id},session_name:#{s/([,\\\\])/\\\\\\1/:session_name},session_path:#{s/([,\\\\])/\\\\\\1/:session_path} -s tmpl_test_session": {
+			Output: "session_id:$1,session_name:tmpl_test_session,session_path:" + dir,
+		},
+		"new-window -P -F window_id:#{window_id},window_name:#{s/([,\\\\])/\\\\\\1/:window_name},window_path:#{s/([,\\\\])/\\\\\\1/:window_path},window_index:#{window_index},window_width:#{window_width},window_height:#{window_height},window_layout:#{s/([,\\\\])/\\\\\\1/:window_layout} -k -t tmpl_test_session:^ " +
+			"-e API_TOKEN=" + token + ` -e APP_ENV=session -e DB_HOST=localhost -e DB_NAME=app#1 -e DB_PASSWORD=s3cr3t-"pa55" -e DB_USER=tmpl -n code -c ` + dir: {
+			Output: "window_id:@2,window_name:code,window_path:" + dir + ",window_index:1,window_width:80,window_height:24",
+		},
+		"split-window -d -P -F pane_id:#{pane_id},pane_path:#{s/([,\\\\])/\\\\\\1/:pane_path},pane_index:#{pane_index},pane_width:#{pane_width},pane_height:#{pane_height},pane_current_path:#{s/([,\\\\])/\\\\\\1/:pane_current_path} -t tmpl_test_session:code " +
+			"-e API_TOKEN=" + token + ` -e APP_ENV=session -e DB_HOST=db -e DB_NAME=app#1 -e DB_PASSWORD=s3cr3t-"pa55" -e DB_USER=tmpl -c ` + dir: {
+			Output: "pane_id:%3,pane_path:" + dir + ",pane_index:1,pane_width:80,pane_height:12",
+		},
+		"show-option -gqv pane-base-index":        {Output: "0"},
+		"select-pane -t tmpl_test_session:code.0": {},
+	}
+
+	logs := new(bytes.Buffer)
+
+	cmd, err := tmux.NewRunner(
+		tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)),
+		tmux.WithLogger(slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	require.NoError(t, err)
+
+	_, err = config.Apply(context.Background(), cfg, cmd)
+	require.NoError(t, err)
+
+	requireStubCmdsSeen(t, expectedCmds)
+
+	require.Contains(t, logs.String(), `msg="hook output" hook=before_start output=[REDACTED]`)
+	require.Contains(t, logs.String(), "API_TOKEN=[REDACTED]")
+	require.NotContains(t, logs.String(), "pa55")
+	require.NotContains(t, logs.String(), token)
+}
+
+func TestApply_EnvFrom_Fails(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name:    "tmpl_test_session",
+		Path:    dir,
+		EnvFrom: map[string]config.EnvFromConfig{"API_TOKEN": {Command: "echo vault is locked >&2; exit 3"}},
+	}}
+
+	// Only the session lookup is expected as the session must not be created.
+	expectedCmds := map[string]*stubCmd{listSessionsArgs: {Output: "session_id:$0,session_name:main,session_path:" + dir}}
+
+	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)))
+	require.NoError(t, err)
+
+	session, err := config.Apply(context.Background(), cfg, cmd)
+	require.ErrorContains(t, err, `loading environment variable API_TOKEN from command "echo vault is locked >&2; exit 3": exit status 3: vault is locked`)
+	require.Nil(t, session)
+}
+
+func TestApply_EnvFrom_Timeout(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name: "tmpl_test_session",
+		Path: dir,
+		EnvFrom: map[string]config.EnvFromConfig{
+			"API_TOKEN": {Command: "sleep 5", Timeout: 100 * time.Millisecond},
+		},
+	}}
+
+	expectedCmds := map[string]*stubCmd{listSessionsArgs: {Output: "session_id:$0,session_name:main,session_path:" + dir}}
+
+	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)))
+	require.NoError(t, err)
+
+	start := time.Now()
+
+	_, err = config.Apply(context.Background(), cfg, cmd)
+	require.ErrorContains(t, err, `loading environment variable API_TOKEN from command "sleep 5": timed out after 100ms`)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestApply_EnvFile_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, ".env")
+
+	require.NoError(t, os.WriteFile(envFile, []byte("APP_ENV=dev\nAPI_TOKEN=\"unterminated\n"), 0o600))
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name:    "tmpl_test_session",
+		Path:    dir,
+		EnvFile: config.StringList{envFile},
+	}}
+
+	expectedCmds := map[string]*stubCmd{listSessionsArgs: {Output: "session_id:$0,session_name:main,session_path:" + dir}}
+
+	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)))
+	require.NoError(t, err)
+
+	_, err = config.Apply(context.Background(), cfg, cmd)
+	require.ErrorContains(t, err, "line 2: unterminated double-quoted value")
+}
+
+func TestApply_EnvFrom_DryRun(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name:    "tmpl_test_session",
+		Path:    dir,
+		EnvFrom: map[string]config.EnvFromConfig{"API_TOKEN": {Command: "touch env_from.txt"}},
+		Windows: []config.WindowConfig{{Name: "code", Path: dir}},
+	}}
+
+	logs := new(bytes.Buffer)
+
+	cmd, err := tmux.NewRunner(
+		tmux.WithDryRunMode(true),
+		tmux.WithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+	)
+	require.NoError(t, err)
+
+	_, err = config.Apply(context.Background(), cfg, cmd)
+	require.NoError(t, err)
+
+	require.NoFileExists(t, filepath.Join(dir, "env_from.txt"))
+	require.Contains(t, logs.String(), `msg="loading environment variable" var=API_TOKEN cmd="touch env_from.txt"`)
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"errors"
 	"fmt"
+
+	"github.com/michenriksen/tmpl/tmux"
 )
 
 var (
@@ -13,6 +15,24 @@ var (
 	// ErrInvalidConfig is returned when a configuration file contains invalid
 	// and unparsable YAML.
 	ErrInvalidConfig = errors.New("configuration file is not parsable")
+	// ErrExtendsCycle is returned when configuration files extend each other
+	// in a cycle.
+	ErrExtendsCycle = errors.New("configuration files extend each other in a cycle")
+	// ErrLocalExtends is returned when a local override file extends another
+	// configuration file.
+	ErrLocalExtends = errors.New("local override file cannot extend other configuration files")
+	// ErrMultipleSessions is returned when a configuration with multiple
+	// sessions is used where a single session is expected.
+	ErrMultipleSessions = errors.New("configuration has multiple sessions")
+	// ErrUnknownSession is returned when a session name is not found in a
+	// configuration.
+	ErrUnknownSession = errors.New("session not found in configuration")
+	// ErrUnknownProfile is returned when a profile name is not found in a
+	// configuration.
+	ErrUnknownProfile = errors.New("profile not found in configuration")
+	// ErrSessionNotFound is returned when a tmux session does not exist. It is
+	// the same error as [tmux.ErrSessionNotFound].
+	ErrSessionNotFound = tmux.ErrSessionNotFound
 )
 
 // DecodeError is returned when a configuration file cannot be decoded.
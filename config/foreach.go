@@ -0,0 +1,175 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// itemVar is the name of the template variable holding the current item when
+// window and pane configurations are generated with for_each.
+const itemVar = "Item"
+
+// ForEachConfig represents a list of items to generate window or pane
+// configurations for. A copy of the configuration is made for each item, with
+// the item available as the {{ .Item }} template variable.
+//
+// The items are either given as a list, or as a file pattern in the syntax of
+// [filepath.Match], in which case the items are the paths of the matching
+// files. Relative patterns are resolved against the session path for windows,
+// and the window or parent pane path for panes, and the items are absolute
+// paths, so they can be used as window and pane paths.
+//
+// In YAML, a list of items is written as a sequence, and a file pattern as a
+// mapping with a glob key:
+//
+//	for_each: [api, worker, web]
+//	for_each: {glob: "services/*"}
+type ForEachConfig struct {
+	Items []string `yaml:"items,omitempty"` // Items to generate configurations for.
+	Glob  string   `yaml:"glob,omitempty"`  // File pattern to generate configurations for matching paths.
+}
+
+// UnmarshalYAML implements the [yaml.Unmarshaler] interface.
+func (f *ForEachConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		return value.Decode(&f.Items) //nolint:wrapcheck // Error is reported by the decoder.
+	}
+
+	if value.Kind != yaml.MappingNode {
+		return errors.New("for_each must be a list of items or a mapping with a glob key")
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, val := value.Content[i], value.Content[i+1]
+
+		if key.Value != "glob" {
+			return fmt.Errorf("line %d: field %s not found in for_each", key.Line, key.Value)
+		}
+
+		if err := val.Decode(&f.Glob); err != nil {
+			return err //nolint:wrapcheck // Error is reported by the decoder.
+		}
+	}
+
+	return nil
+}
+
+// MarshalYAML implements the [yaml.Marshaler] interface.
+func (f ForEachConfig) MarshalYAML() (any, error) {
+	if f.Glob != "" {
+		return map[string]string{"glob": f.Glob}, nil
+	}
+
+	return f.Items, nil
+}
+
+// items returns the items to generate configurations for, with templates in
+// the items and file pattern rendered with the provided variables. Like the
+// generated configurations, the items are rendered even if no variables are
+// defined. Relative file patterns are resolved against dir.
+func (f *ForEachConfig) items(dir string, data templateData) ([]string, error) {
+	data.render = true
+
+	if f.Glob == "" {
+		items := make([]string, 0, len(f.Items))
+
+		for _, item := range f.Items {
+			item, err := renderTemplate(item, data)
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, item)
+		}
+
+		return items, nil
+	}
+
+	pattern, err := renderTemplate(f.Glob, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if pattern, err = resolvePath(pattern, dir); err != nil {
+		return nil, fmt.Errorf("expanding glob pattern: %w", err)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("matching glob pattern: %w", err)
+	}
+
+	return matches, nil
+}
+
+// itemData returns a copy of the template data with the provided item added as
+// the {{ .Item }} variable. Generated configurations are always rendered, so
+// that the item can be referenced without defining any variables.
+func itemData(data templateData, item string) templateData {
+	vars := make(map[string]string, len(data.vars)+1)
+	maps.Copy(vars, data.vars)
+	vars[itemVar] = item
+
+	data.vars = vars
+	data.render = true
+
+	return data
+}
+
+// clone returns a deep copy of the window configuration.
+func (w WindowConfig) clone() WindowConfig {
+	w.Commands = cloneStrings(w.Commands)
+	w.Env = maps.Clone(w.Env)
+	w.EnvFile = cloneStrings(w.EnvFile)
+	w.EnvFrom = maps.Clone(w.EnvFrom)
+	w.Panes = clonePanes(w.Panes)
+
+	if w.When != nil {
+		when := *w.When
+		w.When = &when
+	}
+
+	return w
+}
+
+// clone returns a deep copy of the pane configuration.
+func (p PaneConfig) clone() PaneConfig {
+	p.Commands = cloneStrings(p.Commands)
+	p.Env = maps.Clone(p.Env)
+	p.EnvFile = cloneStrings(p.EnvFile)
+	p.EnvFrom = maps.Clone(p.EnvFrom)
+	p.Panes = clonePanes(p.Panes)
+
+	if p.When != nil {
+		when := *p.When
+		p.When = &when
+	}
+
+	return p
+}
+
+func clonePanes(panes []PaneConfig) []PaneConfig {
+	if panes == nil {
+		return nil
+	}
+
+	res := make([]PaneConfig, len(panes))
+
+	for i, p := range panes {
+		res[i] = p.clone()
+	}
+
+	return res
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	return append([]string(nil), s...)
+}
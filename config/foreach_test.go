@@ -0,0 +1,124 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/internal/testutils"
+)
+
+func TestFromFile_ForEach(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "services", "billing"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "services", "search"), 0o755))
+	testutils.WriteFile(t, []byte("app\n"), dir, "log", "app.log")
+	testutils.WriteFile(t, []byte("db\n"), dir, "log", "db.log")
+
+	testutils.WriteFile(t, []byte(`---
+vars:
+  make: make -j4
+session:
+  windows:
+    - name: "{{ .Item }}"
+      for_each: [api, worker]
+      command: "{{ .make }} run-{{ .Item }}"
+      env:
+        SERVICE: "{{ .Item }}"
+    - name: "svc-{{ base .Item }}"
+      for_each:
+        glob: services/*
+      path: "{{ .Item }}"
+    - name: logs
+      panes:
+        - for_each: {glob: "log/*.log"}
+          commands:
+            - "tail -f {{ .Item }}"
+`), dir, config.DefaultConfigFile)
+
+	cfg, err := config.FromFile(filepath.Join(dir, config.DefaultConfigFile))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	windows := cfg.Session.Windows
+	require.Len(t, windows, 5)
+
+	require.Equal(t, "api", windows[0].Name)
+	require.Equal(t, "make -j4 run-api", windows[0].Command)
+	require.Equal(t, map[string]string{"SERVICE": "api"}, windows[0].Env)
+	require.Nil(t, windows[0].ForEach)
+
+	require.Equal(t, "worker", windows[1].Name)
+	require.Equal(t, "make -j4 run-worker", windows[1].Command)
+	require.Equal(t, map[string]string{"SERVICE": "worker"}, windows[1].Env)
+
+	require.Equal(t, "svc-billing", windows[2].Name)
+	require.Equal(t, filepath.Join(dir, "services", "billing"), windows[2].Path)
+	require.Equal(t, "svc-search", windows[3].Name)
+	require.Equal(t, filepath.Join(dir, "services", "search"), windows[3].Path)
+
+	panes := windows[4].Panes
+	require.Len(t, panes, 2)
+	require.Equal(t, []string{"tail -f " + filepath.Join(dir, "log", "app.log")}, panes[0].Commands)
+	require.Equal(t, []string{"tail -f " + filepath.Join(dir, "log", "db.log")}, panes[1].Commands)
+}
+
+func TestFromFile_ForEach_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	tt := []struct {
+		name      string
+		content   string
+		assertErr testutils.ErrorAssertion
+	}{
+		{
+			"duplicate generated names",
+			"session:\n  windows:\n    - name: api\n    - name: \"{{ .Item }}\"\n      for_each: [web, api]\n",
+			testutils.RequireErrorContains(`window name "api" is used more than once`),
+		},
+		{
+			"invalid generated name",
+			"session:\n  windows:\n    - name: \"{{ .Item }}\"\n      for_each: [web app]\n",
+			testutils.RequireErrorContains("must only contain alphanumeric characters, underscores, dots, and dashes"),
+		},
+		{
+			"undefined variable in item",
+			"session:\n  windows:\n    - name: \"{{ .Item }}\"\n      for_each: [\"{{ .service }}\"]\n",
+			testutils.RequireErrorContains(`session: (windows: (0: (for_each: map has no entry for key "service".).).)`),
+		},
+		{
+			"unknown for_each field",
+			"session:\n  windows:\n    - name: \"{{ .Item }}\"\n      for_each: {pattern: \"*\"}\n",
+			testutils.RequireErrorContains("field pattern not found in for_each"),
+		},
+		{
+			"invalid for_each value",
+			"session:\n  windows:\n    - name: \"{{ .Item }}\"\n      for_each: api\n",
+			testutils.RequireErrorContains("for_each must be a list of items or a mapping with a glob key"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			testutils.WriteFile(t, []byte("---\n"+tc.content), dir, config.DefaultConfigFile)
+
+			cfg, err := config.FromFile(filepath.Join(dir, config.DefaultConfigFile))
+			if err == nil {
+				err = cfg.Validate()
+			}
+
+			require.Error(t, err)
+			tc.assertErr(t, err)
+		})
+	}
+}
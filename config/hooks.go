@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// DefaultHookTimeout is the default maximum run time of a hook command.
+const DefaultHookTimeout = time.Minute
+
+// Names of the hooks run at different points of the session lifecycle.
+const (
+	HookBeforeStart = "before_start"
+	HookAfterStart  = "after_start"
+	HookOnStop      = "on_stop"
+)
+
+// hookShell is the shell used to run hook commands.
+const hookShell = "/bin/sh"
+
+// hookWaitDelay is the maximum time to wait for the output of a hook command
+// after it has exited. Commands that start background processes, such as
+// daemons, may leave their output open after exiting.
+const hookWaitDelay = time.Second
+
+// runHooks runs the provided hook configurations one by one on the host.
+//
+// Output from the hook commands is written line by line to the runner's
+// logger. If the runner is in dry-run or replay mode, the hooks are logged but
+// not run (see [skipHostCommands]).
+//
+// Running stops at the first hook that fails or times out, and its error is
+// returned.
+func runHooks(ctx context.Context, r tmux.Runner, name string, env map[string]string, hooks []HookConfig) error {
+	for _, h := range hooks {
+		if err := runHook(ctx, r, name, env, h); err != nil {
+			return fmt.Errorf("running %s hook %q: %w", name, h.Command, err)
+		}
+	}
+
+	return nil
+}
+
+// skipHostCommands returns true if commands must not be run on the host, which
+// is the case when the runner is in dry-run mode, or replays tmux commands from
+// a transcript (see [tmux.IsReplay]).
+func skipHostCommands(r tmux.Runner) bool {
+	return r.IsDryRun() || tmux.IsReplay(r)
+}
+
+func runHook(ctx context.Context, r tmux.Runner, name string, env map[string]string, h HookConfig) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	redactHookSecrets(r, h)
+
+	r.Log("running hook", "hook", name, "cmd", h.Command, "path", h.Path)
+
+	if skipHostCommands(r) {
+		return nil
+	}
+
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = DefaultHookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	out := &hookLogWriter{log: func(line string) {
+		r.Log("hook output", "hook", name, "output", line)
+	}}
+
+	cmd := exec.CommandContext(ctx, hookShell, "-c", h.Command)
+	cmd.Dir = h.Path
+	cmd.Env = hookEnv(env, h.Env)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.WaitDelay = hookWaitDelay
+
+	err := cmd.Run()
+	out.flush()
+
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+
+		return err //nolint:wrapcheck // Wrapping is done by caller.
+	}
+
+	r.Debug("hook finished", "hook", name, "cmd", h.Command, "dur", time.Since(start))
+
+	return nil
+}
+
+// hookEnv returns the environment for a hook command, which is the current
+// process environment extended with the provided environment variable maps.
+//
+// Variables in later maps take precedence over variables in earlier maps.
+func hookEnv(envs ...map[string]string) []string {
+	res := os.Environ()
+
+	for _, env := range envs {
+		for k, v := range env {
+			res = append(res, k+"="+v)
+		}
+	}
+
+	return res
+}
+
+// hookLogWriter is an [io.Writer] that calls a log function for each line
+// written to it.
+type hookLogWriter struct {
+	log func(line string)
+	buf []byte
+}
+
+func (w *hookLogWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i == -1 {
+			break
+		}
+
+		w.logLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// flush logs any remaining output not terminated by a newline.
+func (w *hookLogWriter) flush() {
+	w.logLine(w.buf)
+	w.buf = nil
+}
+
+func (w *hookLogWriter) logLine(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+
+	w.log(string(line))
+}
@@ -0,0 +1,224 @@
+package config_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/internal/testutils"
+	"github.com/michenriksen/tmpl/tmux"
+
+	"github.com/stretchr/testify/require"
+)
+
+const listSessionsArgs = "list-sessions -F session_id:#{session_id},session_name:#{s/([,\\\\])/\\\\\\1/:session_name},session_path:#{s/([,\\\\])/\\\\\\1/:session_path}"
+
+func TestApply_Hooks(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "project", "cmd"), 0755))
+
+	// Stub HOME and current working directory for consistent test results.
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	cfg, err := config.FromFile(filepath.Join("testdata", "apply.yaml"))
+	require.NoError(t, err)
+
+	t.Setenv("TMPL_HOOK_VAR", "process")
+	t.Setenv("TMPL_HOOK_OTHER", "process")
+
+	cfg.Session.BeforeStart = []config.HookConfig{
+		{
+			Command: `echo "before $TMPL_HOOK_VAR $TMPL_HOOK_OTHER" > before_start.txt; echo hello from before_start`,
+			Path:    dir,
+			Env:     map[string]string{"TMPL_HOOK_VAR": "hook"},
+		},
+	}
+	cfg.Session.AfterStart = []config.HookConfig{
+		{Command: "pwd > after_start.txt", Path: filepath.Join(dir, "project")},
+	}
+
+	expectedCmds := loadStubCmds(t, "apply-stubcmds.json")
+	logs := new(bytes.Buffer)
+
+	cmd, err := tmux.NewRunner(
+		tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)),
+		tmux.WithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+	)
+	require.NoError(t, err)
+
+	_, err = config.Apply(context.Background(), cfg, cmd)
+	require.NoError(t, err)
+
+	requireStubCmdsSeen(t, expectedCmds)
+
+	require.Equal(t, "before hook process\n", string(testutils.ReadFile(t, dir, "before_start.txt")))
+	require.Equal(t, filepath.Join(dir, "project")+"\n", string(testutils.ReadFile(t, dir, "project", "after_start.txt")))
+	require.Contains(t, logs.String(), `msg="hook output" hook=before_start output="hello from before_start"`)
+}
+
+func TestApply_Hooks_BeforeStartFails(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name: "tmpl_test_session",
+		Path: dir,
+		BeforeStart: []config.HookConfig{
+			{Command: "echo checking vpn; exit 3", Path: dir},
+		},
+	}}
+
+	// Only the session lookup is expected as the session must not be created.
+	expectedCmds := map[string]*stubCmd{listSessionsArgs: {Output: "session_id:$0,session_name:main,session_path:" + dir}}
+	logs := new(bytes.Buffer)
+
+	cmd, err := tmux.NewRunner(
+		tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)),
+		tmux.WithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+	)
+	require.NoError(t, err)
+
+	session, err := config.Apply(context.Background(), cfg, cmd)
+	require.ErrorContains(t, err, `running before_start hook "echo checking vpn; exit 3": exit status 3`)
+	require.Nil(t, session)
+
+	require.Contains(t, logs.String(), `output="checking vpn"`)
+}
+
+func TestApply_Hooks_Timeout(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name: "tmpl_test_session",
+		Path: dir,
+		BeforeStart: []config.HookConfig{
+			{Command: "sleep 5", Path: dir, Timeout: 100 * time.Millisecond},
+		},
+	}}
+
+	expectedCmds := map[string]*stubCmd{listSessionsArgs: {Output: "session_id:$0,session_name:main,session_path:" + dir}}
+
+	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)))
+	require.NoError(t, err)
+
+	start := time.Now()
+
+	_, err = config.Apply(context.Background(), cfg, cmd)
+	require.ErrorContains(t, err, `running before_start hook "sleep 5": timed out after 100ms`)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestApply_Hooks_DryRun(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name: "tmpl_test_session",
+		Path: dir,
+		BeforeStart: []config.HookConfig{
+			{Command: "touch before_start.txt", Path: dir},
+		},
+		AfterStart: []config.HookConfig{
+			{Command: "touch after_start.txt", Path: dir},
+		},
+		Windows: []config.WindowConfig{{Name: "code", Path: dir}},
+	}}
+
+	logs := new(bytes.Buffer)
+
+	cmd, err := tmux.NewRunner(
+		tmux.WithDryRunMode(true),
+		tmux.WithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+	)
+	require.NoError(t, err)
+
+	_, err = config.Apply(context.Background(), cfg, cmd)
+	require.NoError(t, err)
+
+	require.NoFileExists(t, filepath.Join(dir, "before_start.txt"))
+	require.NoFileExists(t, filepath.Join(dir, "after_start.txt"))
+	require.Contains(t, logs.String(), `msg="running hook" hook=before_start cmd="touch before_start.txt"`)
+	require.Contains(t, logs.String(), `msg="running hook" hook=after_start cmd="touch after_start.txt"`)
+}
+
+func TestStop(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name: "tmpl_test_session",
+		Path: dir,
+		OnStop: []config.HookConfig{
+			{Command: "touch on_stop.txt", Path: dir},
+		},
+	}}
+
+	expectedCmds := map[string]*stubCmd{
+		listSessionsArgs:                    {Output: "session_id:$1,session_name:tmpl_test_session,session_path:" + dir},
+		"kill-session -t tmpl_test_session": {},
+	}
+
+	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)))
+	require.NoError(t, err)
+
+	closed, err := config.Stop(context.Background(), cfg, cmd)
+	require.NoError(t, err)
+	require.True(t, closed)
+
+	requireStubCmdsSeen(t, expectedCmds)
+	require.FileExists(t, filepath.Join(dir, "on_stop.txt"))
+}
+
+func TestStop_Env(t *testing.T) {
+	dir := t.TempDir()
+
+	envFile, err := filepath.Abs(filepath.Join("testdata", "app.env"))
+	require.NoError(t, err)
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name:    "tmpl_test_session",
+		Path:    dir,
+		EnvFile: config.StringList{envFile},
+		EnvFrom: map[string]config.EnvFromConfig{"API_TOKEN": {Command: "echo tok"}},
+		Env:     map[string]string{"DB_USER": "stop"},
+		OnStop: []config.HookConfig{
+			{Command: `echo "$DB_USER $DB_NAME $API_TOKEN" > on_stop.txt`, Path: dir},
+		},
+	}}
+
+	expectedCmds := map[string]*stubCmd{listSessionsArgs: {Output: "session_id:$0,session_name:main,session_path:" + dir}}
+
+	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)))
+	require.NoError(t, err)
+
+	_, err = config.Stop(context.Background(), cfg, cmd)
+	require.NoError(t, err)
+
+	require.Equal(t, "stop app#1 tok\n", string(testutils.ReadFile(t, dir, "on_stop.txt")))
+}
+
+func TestStop_NotRunning(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Session: config.SessionConfig{
+		Name: "tmpl_test_session",
+		Path: dir,
+		OnStop: []config.HookConfig{
+			{Command: "touch on_stop.txt", Path: dir},
+		},
+	}}
+
+	expectedCmds := map[string]*stubCmd{listSessionsArgs: {Output: "session_id:$0,session_name:main,session_path:" + dir}}
+
+	cmd, err := tmux.NewRunner(tmux.WithOSCommandRunner(newStubCmdRunner(t, expectedCmds)))
+	require.NoError(t, err)
+
+	closed, err := config.Stop(context.Background(), cfg, cmd)
+	require.NoError(t, err)
+	require.False(t, closed)
+
+	require.FileExists(t, filepath.Join(dir, "on_stop.txt"))
+}
@@ -0,0 +1,397 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// mergeConfig merges the overlay configuration on top of the base
+// configuration and returns the result. Neither of the provided configurations
+// are modified.
+//
+// The merge follows these rules:
+//
+//   - Scalar fields are taken from the overlay if set, otherwise from the base.
+//     This includes the attach and control_mode options and the active,
+//     disabled and secret flags of sessions and windows, so an overlay can set
+//     them to false.
+//   - Lists of strings, such as tmux options, window commands and env files,
+//     are taken from the overlay if not empty, otherwise from the base.
+//   - Maps, such as vars, env and env_from, are merged key by key with overlay
+//     values taking precedence.
+//   - Hooks from the overlay run after hooks from the base.
+//   - Windows are merged by name (see [mergeWindowCfg]). Overlay windows with
+//     no name or no matching base window are added after the base windows.
+//   - Sessions in the sessions list are merged by name in the same way as
+//     windows.
+//   - Profiles are merged by name. An overlay profile replaces a base profile
+//     with the same name.
+func mergeConfig(base, over *Config) *Config {
+	res := *base
+
+	res.Extends = mergeString(base.Extends, over.Extends)
+	res.Tmux = mergeString(base.Tmux, over.Tmux)
+	res.TmuxOptions = mergeStrings(base.TmuxOptions, over.TmuxOptions)
+	res.ControlMode = mergeBool(base.ControlMode, over.ControlMode)
+	res.Redact = mergeStrings(base.Redact, over.Redact)
+	res.Vars = mergeMap(base.Vars, over.Vars)
+	res.TemplateDelims = mergeStrings(base.TemplateDelims, over.TemplateDelims)
+	res.Profiles = mergeProfiles(base.Profiles, over.Profiles)
+
+	res.Attach = mergeBool(base.Attach, over.Attach)
+	res.Session = mergeSessionCfg(base.Session, over.Session)
+
+	res.Sessions = make([]SessionConfig, 0, len(base.Sessions)+len(over.Sessions))
+	res.Sessions = append(res.Sessions, base.Sessions...)
+
+	for _, oCfg := range over.Sessions {
+		i := sessionCfgIndex(res.Sessions, oCfg.Name)
+		if i == -1 {
+			res.Sessions = append(res.Sessions, oCfg)
+			continue
+		}
+
+		res.Sessions[i] = mergeSessionCfg(res.Sessions[i], oCfg)
+	}
+
+	if len(res.Sessions) == 0 {
+		res.Sessions = nil
+	}
+
+	return &res
+}
+
+// sessionCfgIndex returns the index of the session configuration with the
+// provided name, or -1 if the name is empty or not found.
+func sessionCfgIndex(cfgs []SessionConfig, name string) int {
+	if name == "" {
+		return -1
+	}
+
+	for i, sCfg := range cfgs {
+		if sCfg.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func mergeSessionCfg(base, over SessionConfig) SessionConfig {
+	res := base
+
+	res.Name = mergeString(base.Name, over.Name)
+	res.Path = mergeString(base.Path, over.Path)
+	res.OnWindow = mergeString(base.OnWindow, over.OnWindow)
+	res.OnPane = mergeString(base.OnPane, over.OnPane)
+	res.OnAny = mergeString(base.OnAny, over.OnAny)
+	res.Env = mergeMap(base.Env, over.Env)
+	res.EnvFile = mergeStrings(base.EnvFile, over.EnvFile)
+	res.EnvFrom = mergeMap(base.EnvFrom, over.EnvFrom)
+	res.Secret = mergeFlag(base.Secret, over.Secret, over.set["secret"])
+
+	if over.Width != 0 {
+		res.Width = over.Width
+	}
+
+	if over.Height != 0 {
+		res.Height = over.Height
+	}
+
+	res.BeforeStart = mergeHooks(base.BeforeStart, over.BeforeStart)
+	res.AfterStart = mergeHooks(base.AfterStart, over.AfterStart)
+	res.OnStop = mergeHooks(base.OnStop, over.OnStop)
+
+	res.Windows = make([]WindowConfig, 0, len(base.Windows)+len(over.Windows))
+	res.Windows = append(res.Windows, base.Windows...)
+
+	for _, oCfg := range over.Windows {
+		i := windowCfgIndex(res.Windows, oCfg.Name)
+		if i == -1 {
+			res.Windows = append(res.Windows, oCfg)
+			continue
+		}
+
+		res.Windows[i] = mergeWindowCfg(res.Windows[i], oCfg)
+	}
+
+	if len(res.Windows) == 0 {
+		res.Windows = nil
+	}
+
+	return res
+}
+
+// mergeWindowCfg merges an overlay window configuration on top of a base
+// window configuration with the same name.
+//
+// Panes are taken from the overlay if it has any, as panes cannot be matched
+// reliably by position across files.
+func mergeWindowCfg(base, over WindowConfig) WindowConfig {
+	res := base
+
+	res.Path = mergeString(base.Path, over.Path)
+	res.Command = mergeString(base.Command, over.Command)
+	res.Commands = mergeStrings(base.Commands, over.Commands)
+	res.Env = mergeMap(base.Env, over.Env)
+	res.EnvFile = mergeStrings(base.EnvFile, over.EnvFile)
+	res.EnvFrom = mergeMap(base.EnvFrom, over.EnvFrom)
+	res.Layout = mergeString(base.Layout, over.Layout)
+	res.MainPaneSize = mergeString(base.MainPaneSize, over.MainPaneSize)
+	res.Active = mergeFlag(base.Active, over.Active, over.set["active"])
+	res.Disabled = mergeFlag(base.Disabled, over.Disabled, over.set["disabled"])
+	res.Secret = mergeFlag(base.Secret, over.Secret, over.set["secret"])
+
+	if over.When != nil {
+		res.When = over.When
+	}
+
+	if over.ForEach != nil {
+		res.ForEach = over.ForEach
+	}
+
+	if len(over.Panes) != 0 {
+		res.Panes = over.Panes
+	}
+
+	return res
+}
+
+// windowCfgIndex returns the index of the window configuration with the
+// provided name, or -1 if the name is empty or not found.
+func windowCfgIndex(cfgs []WindowConfig, name string) int {
+	if name == "" {
+		return -1
+	}
+
+	for i, wCfg := range cfgs {
+		if wCfg.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func mergeHooks(base, over []HookConfig) []HookConfig {
+	if len(base) == 0 {
+		return over
+	}
+
+	if len(over) == 0 {
+		return base
+	}
+
+	res := make([]HookConfig, 0, len(base)+len(over))
+
+	return append(append(res, base...), over...)
+}
+
+func mergeProfiles(base, over map[string]ProfileConfig) map[string]ProfileConfig {
+	if len(over) == 0 {
+		return base
+	}
+
+	if len(base) == 0 {
+		return over
+	}
+
+	res := make(map[string]ProfileConfig, len(base)+len(over))
+
+	for k, v := range base {
+		res[k] = v
+	}
+
+	for k, v := range over {
+		res[k] = v
+	}
+
+	return res
+}
+
+func mergeString(base, over string) string {
+	if over != "" {
+		return over
+	}
+
+	return base
+}
+
+func mergeBool(base, over *bool) *bool {
+	if over != nil {
+		return over
+	}
+
+	return base
+}
+
+// mergeFlag returns the overlay value of a boolean flag if it is set in the
+// overlay, which is either recorded when the overlay is decoded, or implied by
+// the value being true. Otherwise, the base value is returned.
+func mergeFlag(base, over, overSet bool) bool {
+	if overSet || over {
+		return over
+	}
+
+	return base
+}
+
+func mergeStrings(base, over []string) []string {
+	if len(over) != 0 {
+		return over
+	}
+
+	return base
+}
+
+func mergeMap[V any](base, over map[string]V) map[string]V {
+	if len(over) == 0 {
+		return base
+	}
+
+	if len(base) == 0 {
+		return over
+	}
+
+	res := make(map[string]V, len(base)+len(over))
+
+	for k, v := range base {
+		res[k] = v
+	}
+
+	for k, v := range over {
+		res[k] = v
+	}
+
+	return res
+}
+
+// Source returns the path to the configuration file that sets the field with
+// the provided dotted path, such as "session.windows.0.path" for the path of
+// the first window, as reported by [Config.Validate].
+//
+// If the configuration was merged from several files, the last file that sets
+// the field is returned. Windows and sessions in the sessions list are looked
+// up by name, as they are merged by name. The path of the configuration is
+// returned if no file sets the field, e.g. because it was set to a default
+// value or generated with for_each.
+func (c *Config) Source(field string) string {
+	if len(c.sources) < 2 {
+		return c.path
+	}
+
+	parts := strings.Split(field, ".")
+
+	for i := len(c.sources) - 1; i >= 0; i-- {
+		if c.sources[i].setsField(c, parts) {
+			return c.sources[i].path
+		}
+	}
+
+	return c.path
+}
+
+// setsField returns true if the decoded configuration file sets the field with
+// the provided path in the merged configuration.
+func (c *Config) setsField(merged *Config, parts []string) bool {
+	switch {
+	case parts[0] == "session" && len(parts) > 1:
+		return c.Session.setsField(merged.Session, parts[1:])
+	case parts[0] == "sessions" && len(parts) > 2:
+		i, err := strconv.Atoi(parts[1])
+		if err != nil || i >= len(merged.Sessions) {
+			return false
+		}
+
+		j := sessionCfgIndex(c.Sessions, merged.Sessions[i].Name)
+		if j == -1 {
+			return false
+		}
+
+		return c.Sessions[j].setsField(merged.Sessions[i], parts[2:])
+	}
+
+	return valueSets(reflect.ValueOf(*c), parts)
+}
+
+func (s SessionConfig) setsField(merged SessionConfig, parts []string) bool {
+	if parts[0] != "windows" || len(parts) < 3 {
+		return (len(parts) == 1 && s.set[parts[0]]) || valueSets(reflect.ValueOf(s), parts)
+	}
+
+	i, err := strconv.Atoi(parts[1])
+	if err != nil || i >= len(merged.Windows) {
+		return false
+	}
+
+	j := windowCfgIndex(s.Windows, merged.Windows[i].Name)
+	if j == -1 {
+		return false
+	}
+
+	// Panes are taken as a whole from the last file that has any, so the
+	// file setting them is the file setting any pane field.
+	if parts[2] == "panes" {
+		return len(s.Windows[j].Panes) != 0
+	}
+
+	if len(parts) == 3 && s.Windows[j].set[parts[2]] {
+		return true
+	}
+
+	return valueSets(reflect.ValueOf(s.Windows[j]), parts[2:])
+}
+
+// valueSets returns true if the value at the provided path of YAML field
+// names, element indexes and map keys in v is set to a non-zero value.
+func valueSets(v reflect.Value, parts []string) bool {
+	for _, part := range parts {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return false
+			}
+
+			v = v.Elem()
+		}
+
+		switch v.Kind() { //nolint:exhaustive // Other kinds have no nested fields.
+		case reflect.Struct:
+			v = yamlField(v, part)
+		case reflect.Slice:
+			i, err := strconv.Atoi(part)
+			if err != nil || i >= v.Len() {
+				return false
+			}
+
+			v = v.Index(i)
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(part))
+		default:
+			return false
+		}
+
+		if !v.IsValid() {
+			return false
+		}
+	}
+
+	return !v.IsZero()
+}
+
+// yamlField returns the field of the struct value v with the provided YAML
+// field name, or the zero value if there is no such field.
+func yamlField(v reflect.Value, name string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if tag, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); tag == name {
+			return v.Field(i)
+		}
+	}
+
+	return reflect.Value{}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ProfileConfig represents a named variation of the session configurations,
+// which is selected when the configuration is loaded (see [WithProfile]).
+//
+// A profile can enable windows that are disabled in the session configurations
+// and disable windows by name. Environment variables defined in the profile
+// are added to the session environment and override session variables with the
+// same name. Hooks defined in the profile replace the session hooks of the
+// same kind.
+//
+// With multiple sessions, the profile applies to all of them.
+type ProfileConfig struct {
+	Enable  []string          `yaml:"enable,omitempty"`  // Names of windows to enable.
+	Disable []string          `yaml:"disable,omitempty"` // Names of windows to disable.
+	Env     map[string]string `yaml:"env,omitempty"`     // Session environment variables.
+
+	BeforeStart []HookConfig `yaml:"before_start,omitempty"` // Hooks to run before the session is created.
+	AfterStart  []HookConfig `yaml:"after_start,omitempty"`  // Hooks to run after the session is created.
+	OnStop      []HookConfig `yaml:"on_stop,omitempty"`      // Hooks to run when the session is stopped.
+}
+
+// applyProfile applies the profile with the provided name to the session
+// configurations and removes disabled windows. If name is empty, only disabled
+// windows are removed.
+//
+// Returns [ErrUnknownProfile] if the profile is not configured.
+func applyProfile(cfg *Config, name string) error {
+	if name != "" {
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+		}
+
+		cfg.profile = name
+
+		if len(cfg.Sessions) != 0 {
+			for i, sCfg := range cfg.Sessions {
+				cfg.Sessions[i] = p.apply(sCfg)
+			}
+		} else {
+			cfg.Session = p.apply(cfg.Session)
+		}
+	}
+
+	if len(cfg.Sessions) != 0 {
+		for i := range cfg.Sessions {
+			cfg.removeDisabledWindows(&cfg.Sessions[i])
+		}
+	} else {
+		cfg.removeDisabledWindows(&cfg.Session)
+	}
+
+	return nil
+}
+
+// apply returns a copy of the session configuration with the profile applied.
+func (p ProfileConfig) apply(sCfg SessionConfig) SessionConfig {
+	res := sCfg
+
+	res.Env = mergeMap(sCfg.Env, p.Env)
+	res.Windows = make([]WindowConfig, len(sCfg.Windows))
+
+	for i, wCfg := range sCfg.Windows {
+		if slices.Contains(p.Enable, wCfg.Name) {
+			wCfg.Disabled = false
+		}
+
+		if slices.Contains(p.Disable, wCfg.Name) {
+			wCfg.Disabled = true
+		}
+
+		res.Windows[i] = wCfg
+	}
+
+	if len(p.BeforeStart) != 0 {
+		res.BeforeStart = append([]HookConfig(nil), p.BeforeStart...)
+	}
+
+	if len(p.AfterStart) != 0 {
+		res.AfterStart = append([]HookConfig(nil), p.AfterStart...)
+	}
+
+	if len(p.OnStop) != 0 {
+		res.OnStop = append([]HookConfig(nil), p.OnStop...)
+	}
+
+	return res
+}
+
+// removeDisabledWindows removes disabled window configurations from the
+// session configuration. The names of removed windows are recorded on the
+// configuration so that profiles referencing them are still valid.
+func (c *Config) removeDisabledWindows(sCfg *SessionConfig) {
+	if len(sCfg.Windows) == 0 {
+		return
+	}
+
+	windows := make([]WindowConfig, 0, len(sCfg.Windows))
+
+	for _, wCfg := range sCfg.Windows {
+		if wCfg.Disabled {
+			c.disabledWindows = append(c.disabledWindows, wCfg.Name)
+			continue
+		}
+
+		windows = append(windows, wCfg)
+	}
+
+	if len(windows) == 0 {
+		windows = nil
+	}
+
+	sCfg.Windows = windows
+}
+
+// windowNames returns the set of names of all window configurations in the
+// session configurations, including windows removed because they are
+// disabled or their conditions do not hold.
+func (c *Config) windowNames() map[string]bool {
+	names := make(map[string]bool)
+
+	for _, sCfg := range c.SessionConfigs() {
+		for _, wCfg := range sCfg.Windows {
+			if wCfg.Name != "" {
+				names[wCfg.Name] = true
+			}
+		}
+	}
+
+	for _, name := range c.disabledWindows {
+		if name != "" {
+			names[name] = true
+		}
+	}
+
+	for _, item := range c.skipped {
+		if item.Pane == "" && item.Window != "" {
+			names[item.Window] = true
+		}
+	}
+
+	return names
+}
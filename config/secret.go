@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"slices"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// redactSecrets registers the commands and environment variable values of the
+// session, window and pane configurations marked as secret with the runner for
+// redaction, if it implements [tmux.Redactor].
+//
+// Values are registered both as configured and with environment variable
+// references expanded, as tmux receives the expanded values.
+//
+// Values loaded from env files and commands are registered if the
+// configuration they are loaded for is marked as secret, or if the name of the
+// variable matches [tmux.DefaultRedactPatterns] or one of the provided
+// patterns. They are registered before any hooks are run, as hook output can
+// contain them.
+func redactSecrets(r tmux.Runner, sCfg SessionConfig, patterns []string) {
+	rd, ok := r.(tmux.Redactor)
+	if !ok {
+		return
+	}
+
+	names := tmux.NewRedaction(append(slices.Clone(tmux.DefaultRedactPatterns), patterns...)...)
+
+	if sCfg.Secret {
+		redactValues(rd, sCfg.Env, sCfg.OnWindow, sCfg.OnPane, sCfg.OnAny)
+	}
+
+	redactLoadedEnv(rd, names, sCfg.loadedEnv, sCfg.Secret)
+
+	for _, wCfg := range sCfg.Windows {
+		secret := sCfg.Secret || wCfg.Secret
+
+		if secret {
+			redactValues(rd, wCfg.Env, append([]string{wCfg.Command}, wCfg.Commands...)...)
+		}
+
+		redactLoadedEnv(rd, names, wCfg.loadedEnv, secret)
+		redactPaneSecrets(rd, names, wCfg.Panes, secret)
+	}
+}
+
+func redactPaneSecrets(rd tmux.Redactor, names *tmux.Redaction, panes []PaneConfig, parentSecret bool) {
+	for _, pCfg := range panes {
+		secret := parentSecret || pCfg.Secret
+
+		if secret {
+			redactValues(rd, pCfg.Env, append([]string{pCfg.Command}, pCfg.Commands...)...)
+		}
+
+		redactLoadedEnv(rd, names, pCfg.loadedEnv, secret)
+		redactPaneSecrets(rd, names, pCfg.Panes, secret)
+	}
+}
+
+// redactLoadedEnv registers the loaded environment variable values that are
+// secret, or have names matching the patterns of names, for redaction. Loaded
+// values are used as they are, so they are not expanded.
+func redactLoadedEnv(rd tmux.Redactor, names *tmux.Redaction, env map[string]string, secret bool) {
+	for k, v := range env {
+		if secret || names.MatchName(k) {
+			rd.Redact(v)
+		}
+	}
+}
+
+// redactHookSecrets registers the command and environment variable values of
+// a hook configuration marked as secret with the runner for redaction.
+func redactHookSecrets(r tmux.Runner, h HookConfig) {
+	if rd, ok := r.(tmux.Redactor); ok && h.Secret {
+		redactValues(rd, h.Env, h.Command)
+	}
+}
+
+func redactValues(rd tmux.Redactor, env map[string]string, cmds ...string) {
+	for _, v := range env {
+		rd.Redact(v, os.ExpandEnv(v))
+	}
+
+	rd.Redact(cmds...)
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// Stop closes the tmux session with the configured name and runs the
+// session's on_stop hooks.
+//
+// The hooks are run even if the session is not running, so that any resources
+// started by before_start or after_start hooks can be cleaned up. Like the
+// before_start and after_start hooks, they are passed the session environment
+// variables along with the variables loaded from the session's env files and
+// commands. Returns true if a running session was closed.
+//
+// If the provided configuration is invalid, an error is returned. Caller can
+// check for validity beforehand by calling [config.Config.Validate] if needed.
+//
+// Returns [ErrMultipleSessions] if the configuration has more than one session
+// (see [Up]).
+func Stop(ctx context.Context, cfg *Config, runner tmux.Runner) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	cfg, err := singleSessionCfg(cfg)
+	if err != nil {
+		return false, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return false, fmt.Errorf("invalid configuration file: %w", err)
+	}
+
+	// Only the session environment is passed to the hooks, so the environment
+	// of windows and panes is not loaded.
+	sCfg := cfg.Session
+	sCfg.Windows = nil
+
+	if sCfg, err = loadEnv(ctx, runner, sCfg); err != nil {
+		return false, err
+	}
+
+	redactSecrets(runner, sCfg, cfg.Redact)
+
+	session, err := findSession(ctx, runner, sCfg.Name)
+	if err != nil {
+		return false, err
+	}
+
+	if session != nil {
+		if err := session.Close(); err != nil {
+			return false, fmt.Errorf("closing %s: %w", session, err)
+		}
+
+		runner.Log("session closed", "session", sCfg.Name)
+	} else {
+		runner.Log("session not running", "session", sCfg.Name)
+	}
+
+	env := mergeMap(sCfg.loadedEnv, sCfg.Env)
+
+	if err := runHooks(ctx, runner, HookOnStop, env, sCfg.OnStop); err != nil {
+		return session != nil, err
+	}
+
+	return session != nil, nil
+}
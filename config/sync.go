@@ -0,0 +1,273 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// SyncResult describes the changes made to a tmux session by [Sync].
+type SyncResult struct {
+	Windows []*tmux.Window // Windows added to the session.
+	Panes   []*tmux.Pane   // Panes added to existing windows.
+	Created bool           // Whether the session was created from scratch.
+}
+
+// Sync reconciles a tmux session with the provided configuration.
+//
+// If no session with the configured name exists, it is created in the same way
+// as with [Apply]. Otherwise, the windows and panes of the running session are
+// matched against the configuration and any missing windows or panes are
+// created. Windows are matched by name, or by position if the window
+// configuration has no name, and panes are matched by their position in the
+// split tree of the window (see [paneSyncer]). Windows and panes that already exist are left untouched, but if
+// panes are added to a window configured with a layout, the layout is selected
+// again to arrange the new panes.
+//
+// Hooks are only run if the session is created, in the same way as with
+// [Apply]. Environment variables from env files and commands are loaded in
+// either case, as windows and panes created in an existing session need them.
+//
+// Unlike [Apply], the session is not closed if an error occurs while adding
+// windows or panes to an existing session.
+//
+// If the provided configuration is invalid, an error is returned. Caller can
+// check for validity beforehand by calling [config.Config.Validate] if needed.
+//
+// Returns [ErrMultipleSessions] if the configuration has more than one session
+// (see [Up]).
+func Sync(ctx context.Context, cfg *Config, runner tmux.Runner) (*tmux.Session, *SyncResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	cfg, err := singleSessionCfg(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration file: %w", err)
+	}
+
+	session, err := findSession(ctx, runner, cfg.Session.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if session == nil {
+		if session, err = applySessionCfg(ctx, runner, cfg.Session, cfg.Redact); err != nil {
+			return nil, nil, err
+		}
+
+		return session, &SyncResult{Created: true}, nil
+	}
+
+	sCfg, err := loadEnv(ctx, runner, cfg.Session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	redactSecrets(runner, sCfg, cfg.Redact)
+
+	if err := session.Configure(makeSessionOpts(sCfg)...); err != nil {
+		return nil, nil, fmt.Errorf("configuring %s: %w", session, err)
+	}
+
+	res := &SyncResult{}
+
+	windows, err := tmux.GetWindows(ctx, runner, session)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting windows for %s: %w", session, err)
+	}
+
+	// Copy the live windows, as the session's window list grows when missing
+	// windows are created.
+	live := append([]*tmux.Window{}, windows...)
+
+	for i, wCfg := range sCfg.Windows {
+		win := matchWindow(live, i, wCfg)
+		if win == nil {
+			if win, err = applyWindowCfg(ctx, runner, session, wCfg); err != nil {
+				return nil, nil, fmt.Errorf("applying window configuration: %w", err)
+			}
+
+			res.Windows = append(res.Windows, win)
+
+			continue
+		}
+
+		panes, err := tmux.GetPanes(ctx, runner, win)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting panes for %s: %w", win, err)
+		}
+
+		ps := &paneSyncer{runner: runner, win: win, res: res, tree: splitTree(panes)}
+
+		root := tmux.InitialPane(panes)
+		numPanes := len(res.Panes)
+
+		if err := ps.sync(ctx, root, wCfg.Panes); err != nil {
+			return nil, nil, err
+		}
+
+		if wCfg.Layout != "" && len(res.Panes) > numPanes {
+			if err := selectWindowLayout(ctx, win, wCfg); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return session, res, nil
+}
+
+// selectWindowLayout arranges the panes of a live window according to the
+// layout in the window configuration.
+func selectWindowLayout(ctx context.Context, win *tmux.Window, cfg WindowConfig) error {
+	opts := []tmux.WindowOption{tmux.WindowWithLayout(cfg.Layout)}
+
+	if cfg.MainPaneSize != "" {
+		opts = append(opts, tmux.WindowWithMainPaneSize(cfg.MainPaneSize))
+	}
+
+	if err := win.Configure(opts...); err != nil {
+		return fmt.Errorf("configuring %s: %w", win, err)
+	}
+
+	if err := win.SelectLayout(ctx); err != nil {
+		return fmt.Errorf("selecting layout for %s: %w", win, err)
+	}
+
+	return nil
+}
+
+// matchWindow returns the live window matching the window configuration at
+// position i, or nil if the window does not exist.
+//
+// Windows are matched by name if the configuration has one, otherwise by
+// position.
+func matchWindow(live []*tmux.Window, i int, cfg WindowConfig) *tmux.Window {
+	if cfg.Name == "" {
+		if i < len(live) {
+			return live[i]
+		}
+
+		return nil
+	}
+
+	for _, w := range live {
+		if w.ShortName() == cfg.Name {
+			return w
+		}
+	}
+
+	return nil
+}
+
+// paneSyncer matches pane configurations for a window with its live panes and
+// creates the ones that are missing.
+//
+// Pane configurations are matched with the live panes by their position in
+// the split tree of the window (see [splitTree]): the panes configured for a
+// window or pane are matched with the live panes split from its pane in the
+// order they were created, which is the order in which [Apply] creates them.
+// Missing panes are split from the live pane of their parent configuration.
+type paneSyncer struct {
+	runner tmux.Runner
+	win    *tmux.Window
+	tree   map[*tmux.Pane][]*tmux.Pane
+	res    *SyncResult
+}
+
+// sync matches the provided pane configurations with the live panes split from
+// the parent pane, and creates the missing ones. If parent is nil, missing
+// panes are split from the window's active pane.
+func (s *paneSyncer) sync(ctx context.Context, parent *tmux.Pane, cfgs []PaneConfig) error {
+	live := s.tree[parent]
+
+	for i, pCfg := range cfgs {
+		var pane *tmux.Pane
+
+		if i < len(live) {
+			pane = live[i]
+		} else {
+			var err error
+
+			if pane, err = tmux.NewPane(s.runner, s.win, parent, makePaneOpts(pCfg)...); err != nil {
+				return fmt.Errorf("creating pane: %w", err)
+			}
+
+			if err := pane.Apply(ctx); err != nil {
+				return fmt.Errorf("applying %s: %w", pane, err)
+			}
+
+			s.res.Panes = append(s.res.Panes, pane)
+		}
+
+		if err := s.sync(ctx, pane, pCfg.Panes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitTree returns the split tree of the provided live panes of a window in
+// index order, mapping each pane to the panes split from it in the order they
+// were created.
+//
+// tmux inserts a new pane right after the pane it is split from in the index
+// order, and gives it a higher ID than the panes that exist at that point.
+// The pane a pane was split from is therefore the closest pane before it in
+// index order with a lower ID. The window's initial pane has no such pane.
+func splitTree(panes []*tmux.Pane) map[*tmux.Pane][]*tmux.Pane {
+	tree := make(map[*tmux.Pane][]*tmux.Pane)
+
+	for _, p := range creationOrder(panes) {
+		var parent *tmux.Pane
+
+		for _, q := range panes {
+			if q == p {
+				break
+			}
+
+			if paneIDNum(q) < paneIDNum(p) {
+				parent = q
+			}
+		}
+
+		if parent != nil {
+			tree[parent] = append(tree[parent], p)
+		}
+	}
+
+	return tree
+}
+
+// creationOrder returns a copy of the provided panes sorted in the order they
+// were created, which is the order of their IDs, as tmux assigns increasing
+// IDs to new panes.
+func creationOrder(panes []*tmux.Pane) []*tmux.Pane {
+	res := append([]*tmux.Pane{}, panes...)
+
+	slices.SortStableFunc(res, func(a, b *tmux.Pane) int {
+		return paneIDNum(a) - paneIDNum(b)
+	})
+
+	return res
+}
+
+// paneIDNum returns the number of the pane's ID, such as 3 for %3, or -1 if the
+// ID is not valid.
+func paneIDNum(p *tmux.Pane) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(p.ID(), "%"))
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// SessionStatus describes the outcome of applying a session configuration with
+// [Up].
+type SessionStatus string
+
+const (
+	SessionCreated SessionStatus = "created" // Session was created.
+	SessionSynced  SessionStatus = "synced"  // Existing session was synchronized.
+	SessionSkipped SessionStatus = "skipped" // Existing session was left untouched.
+	SessionFailed  SessionStatus = "failed"  // Session could not be applied.
+)
+
+// SessionResult describes the outcome of applying a session configuration
+// with [Up].
+type SessionResult struct {
+	Name    string        // Session name.
+	Status  SessionStatus // Outcome of applying the session configuration.
+	Session *tmux.Session // Applied session, or nil if it failed.
+	Sync    *SyncResult   // Changes made to an existing session when synced.
+	Err     error         // Error if the session failed.
+}
+
+// upOpts holds options for [Up].
+type upOpts struct {
+	names []string
+	sync  bool
+}
+
+// UpOption configures [Up].
+type UpOption func(*upOpts) error
+
+// UpWithSessions configures [Up] to only apply the sessions with the provided
+// names.
+func UpWithSessions(names ...string) UpOption {
+	return func(o *upOpts) error {
+		o.names = names
+		return nil
+	}
+}
+
+// UpWithSync configures [Up] to synchronize existing sessions with [Sync]
+// instead of skipping them.
+func UpWithSync(enable bool) UpOption {
+	return func(o *upOpts) error {
+		o.sync = enable
+		return nil
+	}
+}
+
+// Up applies all session configurations in the provided configuration, in the
+// order they are configured, and returns a result for each of them.
+//
+// Each session is applied on its own: if a session fails, it is closed as with
+// [Apply], but sessions applied before it are left running and the remaining
+// sessions are still applied. The error for a failed session is set on its
+// result. Sessions that already exist are skipped, or synchronized if the
+// [UpWithSync] option is enabled.
+//
+// If the provided configuration is invalid, or a session name given with the
+// [UpWithSessions] option is not configured, an error is returned before any
+// session is applied.
+func Up(ctx context.Context, cfg *Config, runner tmux.Runner, opts ...UpOption) ([]SessionResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	o := &upOpts{}
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, fmt.Errorf("applying option: %w", err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration file: %w", err)
+	}
+
+	sCfgs, err := selectSessionCfgs(cfg.SessionConfigs(), o.names)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SessionResult, 0, len(sCfgs))
+
+	for _, sCfg := range sCfgs {
+		results = append(results, upSession(ctx, cfg.withSession(sCfg), runner, o.sync))
+	}
+
+	return results, nil
+}
+
+// selectSessionCfgs returns the session configurations with the provided
+// names in the order they are configured, or all of them if no names are
+// provided.
+func selectSessionCfgs(sCfgs []SessionConfig, names []string) ([]SessionConfig, error) {
+	if len(names) == 0 {
+		return sCfgs, nil
+	}
+
+	want := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		if sessionCfgIndex(sCfgs, name) == -1 {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSession, name)
+		}
+
+		want[name] = true
+	}
+
+	res := make([]SessionConfig, 0, len(names))
+
+	for _, sCfg := range sCfgs {
+		if want[sCfg.Name] {
+			res = append(res, sCfg)
+		}
+	}
+
+	return res, nil
+}
+
+// upSession applies a configuration with a single session and returns the
+// result.
+func upSession(ctx context.Context, cfg *Config, runner tmux.Runner, sync bool) SessionResult {
+	res := SessionResult{Name: cfg.Session.Name}
+
+	if sync {
+		sess, syncRes, err := Sync(ctx, cfg, runner)
+		if err != nil {
+			return failedSession(res, err)
+		}
+
+		res.Session = sess
+		res.Status = SessionCreated
+
+		if !syncRes.Created {
+			res.Status = SessionSynced
+			res.Sync = syncRes
+		}
+
+		return res
+	}
+
+	sess, err := findSession(ctx, runner, cfg.Session.Name)
+	if err != nil {
+		return failedSession(res, err)
+	}
+
+	if sess != nil {
+		res.Session = sess
+		res.Status = SessionSkipped
+
+		return res
+	}
+
+	if sess, err = applySessionCfg(ctx, runner, cfg.Session, cfg.Redact); err != nil {
+		return failedSession(res, err)
+	}
+
+	res.Session = sess
+	res.Status = SessionCreated
+
+	return res
+}
+
+func failedSession(res SessionResult, err error) SessionResult {
+	res.Status = SessionFailed
+	res.Err = err
+
+	return res
+}
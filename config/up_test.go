@@ -0,0 +1,149 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/tmux"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Sessions: []config.SessionConfig{
+		{Name: "api", Path: dir, Windows: []config.WindowConfig{{Name: "code", Path: dir}}},
+		{Name: "web", Path: dir, Windows: []config.WindowConfig{{Name: "code", Path: dir}}},
+		{Name: "broken", Path: dir, Windows: []config.WindowConfig{{Name: "code", Path: dir}}},
+		{Name: "worker", Path: dir, Windows: []config.WindowConfig{{Name: "code", Path: dir}}},
+	}}
+
+	var closed []string
+
+	runner, err := tmux.NewRunner(tmux.WithOSCommandRunner(newUpStubRunner(t, dir, []string{"web"}, "broken", &closed)))
+	require.NoError(t, err)
+
+	results, err := config.Up(context.Background(), cfg, runner)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	require.Equal(t, "api", results[0].Name)
+	require.Equal(t, config.SessionCreated, results[0].Status)
+	require.Equal(t, "api", results[0].Session.Name())
+
+	require.Equal(t, "web", results[1].Name)
+	require.Equal(t, config.SessionSkipped, results[1].Status)
+
+	require.Equal(t, "broken", results[2].Name)
+	require.Equal(t, config.SessionFailed, results[2].Status)
+	require.ErrorContains(t, results[2].Err, "stub window creation failure")
+	require.Nil(t, results[2].Session)
+
+	require.Equal(t, "worker", results[3].Name)
+	require.Equal(t, config.SessionCreated, results[3].Status)
+
+	// Only the failed session is rolled back.
+	require.Equal(t, []string{"broken"}, closed)
+}
+
+func TestUp_WithSessions(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Sessions: []config.SessionConfig{
+		{Name: "api", Path: dir, Windows: []config.WindowConfig{{Name: "code", Path: dir}}},
+		{Name: "web", Path: dir, Windows: []config.WindowConfig{{Name: "code", Path: dir}}},
+	}}
+
+	runner, err := tmux.NewRunner(tmux.WithOSCommandRunner(newUpStubRunner(t, dir, nil, "", nil)))
+	require.NoError(t, err)
+
+	results, err := config.Up(context.Background(), cfg, runner, config.UpWithSessions("web"))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "web", results[0].Name)
+	require.Equal(t, config.SessionCreated, results[0].Status)
+
+	_, err = config.Up(context.Background(), cfg, runner, config.UpWithSessions("unknown"))
+	require.ErrorIs(t, err, config.ErrUnknownSession)
+}
+
+func TestApply_MultipleSessions(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &config.Config{Sessions: []config.SessionConfig{
+		{Name: "api", Path: dir},
+		{Name: "web", Path: dir},
+	}}
+
+	runner, err := tmux.NewRunner(tmux.WithOSCommandRunner(newUpStubRunner(t, dir, nil, "", nil)))
+	require.NoError(t, err)
+
+	_, err = config.Apply(context.Background(), cfg, runner)
+	require.ErrorIs(t, err, config.ErrMultipleSessions)
+}
+
+// newUpStubRunner returns a [tmux.OSCommandRunner] simulating a tmux server
+// with the provided existing sessions. Creating a window in the session with
+// the failing name returns an error, and closed sessions are recorded in the
+// closed slice.
+func newUpStubRunner(t *testing.T, dir string, existing []string, failing string, closed *[]string) tmux.OSCommandRunner {
+	t.Helper()
+
+	nextID := 1
+
+	return func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		t.Logf("received command: tmux %s", strings.Join(args, " "))
+
+		target := argValue(args, "-t")
+		name := argValue(args, "-s")
+
+		switch args[0] {
+		case "list-sessions":
+			records := make([]string, 0, len(existing))
+			for i, s := range existing {
+				records = append(records, fmt.Sprintf("session_id:$%d,session_name:%s,session_path:%s", 100+i, s, dir))
+			}
+
+			return []byte(strings.Join(records, "\n")), nil
+		case "new-session":
+			nextID++
+			return []byte(fmt.Sprintf("session_id:$%d,session_name:%s,session_path:%s", nextID, name, dir)), nil
+		case "new-window":
+			sess, _, _ := strings.Cut(target, ":")
+			if sess == failing {
+				return nil, errors.New("stub window creation failure")
+			}
+
+			nextID++
+
+			return []byte(fmt.Sprintf(
+				"window_id:@%d,window_name:%s,window_path:%s,window_index:1,window_width:80,window_height:24,window_layout:",
+				nextID, argValue(args, "-n"), dir,
+			)), nil
+		case "kill-session":
+			if closed != nil {
+				*closed = append(*closed, target)
+			}
+		case "show-option":
+			return []byte("0"), nil
+		}
+
+		return []byte{}, nil
+	}
+}
+
+// argValue returns the value following the flag in args, or an empty string.
+func argValue(args []string, flag string) string {
+	i := slices.Index(args, flag)
+	if i == -1 || i == len(args)-1 {
+		return ""
+	}
+
+	return args[i+1]
+}
@@ -1,8 +1,15 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/invopop/validation"
 
@@ -13,15 +20,42 @@ const errorTag = "yaml"
 
 var envVarRE = regexp.MustCompile(`^[A-Z_][A-Z0-9_]+$`)
 
+var varNameRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 var nameMatchRule = validation.Match(regexp.MustCompile(`^[\w._-]+$`)).
 	Error("must only contain alphanumeric characters, underscores, dots, and dashes")
 
+// layoutStringRE matches the beginning of a custom tmux layout string, which
+// consists of a checksum followed by the window size and offsets, e.g.
+// "c3e1,80x24,0,0{...}".
+var layoutStringRE = regexp.MustCompile(`^[0-9a-f]{4},\d+x\d+,\d+,\d+`)
+
+var sizeMatchRule = validation.Match(regexp.MustCompile(`^\d+%?$`)).
+	Error("must be a number of cells or a percentage")
+
+// presetLayouts is the set of preset layout names supported by tmux.
+var presetLayouts = map[string]bool{
+	"even-horizontal":          true,
+	"even-vertical":            true,
+	"main-horizontal":          true,
+	"main-horizontal-mirrored": true,
+	"main-vertical":            true,
+	"main-vertical-mirrored":   true,
+	"tiled":                    true,
+}
+
 // Validate validates the configuration.
 //
 // It checks that:
 //
 //   - tmux executable exists
+//   - variable names are valid
+//   - redaction patterns are valid
 //   - session is valid (see [SessionConfig.Validate])
+//   - session is not set together with sessions
+//   - sessions have unique names, and are valid
+//   - profiles are valid, and only reference configured windows (see
+//     [ProfileConfig.Validate])
 //
 // If any of the above checks fail, an error is returned.
 func (c Config) Validate() error {
@@ -29,7 +63,19 @@ func (c Config) Validate() error {
 
 	return validation.ValidateStruct(&c,
 		validation.Field(&c.Tmux, validation.By(rulefuncs.ExecutableExists)),
-		validation.Field(&c.Session, validation.Required),
+		validation.Field(&c.Vars, validation.By(varNameMapRule)),
+		validation.Field(&c.Redact, validation.Each(validation.By(patternRule))),
+		validation.Field(&c.Session,
+			validation.When(len(c.Sessions) == 0, validation.Required),
+			validation.When(len(c.Sessions) != 0, validation.By(zeroRule("cannot be used together with sessions"))),
+		),
+		validation.Field(&c.Sessions,
+			validation.Each(validation.By(sessionNameRule)),
+			validation.By(uniqueSessionNamesRule),
+		),
+		validation.Field(&c.Profiles,
+			validation.Each(validation.By(profileWindowsRule(c.windowNames()))),
+		),
 	)
 }
 
@@ -41,7 +87,12 @@ func (c Config) Validate() error {
 //     and dashes
 //   - session path exists
 //   - session environment variable names are valid
-//   - windows are valid (see [WindowConfig.Validate])
+//   - session env files exist
+//   - session env_from variable names are valid, and commands are valid (see
+//     [EnvFromConfig.Validate])
+//   - session width and height are positive
+//   - windows have unique names, and are valid (see [WindowConfig.Validate])
+//   - hooks are valid (see [HookConfig.Validate])
 //
 // If any of the above checks fail, an error is returned.
 func (s SessionConfig) Validate() error {
@@ -51,7 +102,14 @@ func (s SessionConfig) Validate() error {
 		validation.Field(&s.Name, nameMatchRule),
 		validation.Field(&s.Path, validation.By(rulefuncs.DirExists)),
 		validation.Field(&s.Env, validation.By(envVarMapRule)),
-		validation.Field(&s.Windows),
+		validation.Field(&s.EnvFile, validation.Each(validation.By(rulefuncs.FileExists))),
+		validation.Field(&s.EnvFrom, validation.By(envVarMapRule)),
+		validation.Field(&s.Width, validation.Min(1)),
+		validation.Field(&s.Height, validation.Min(1)),
+		validation.Field(&s.Windows, validation.By(uniqueWindowNamesRule)),
+		validation.Field(&s.BeforeStart),
+		validation.Field(&s.AfterStart),
+		validation.Field(&s.OnStop),
 	)
 }
 
@@ -63,7 +121,15 @@ func (s SessionConfig) Validate() error {
 //     and dashes
 //   - window path exists
 //   - window environment variable names are valid
+//   - window env files exist
+//   - window env_from variable names are valid, and commands are valid (see
+//     [EnvFromConfig.Validate])
+//   - window layout is a preset layout name or a custom layout string
+//   - main pane size is a number of cells or a percentage, and is only set
+//     for main-horizontal and main-vertical layouts
 //   - panes are valid (see [PaneConfig.Validate])
+//   - conditions are valid (see [WhenConfig.Validate])
+//   - for_each is valid (see [ForEachConfig.Validate])
 //
 // If any of the above checks fail, an error is returned.
 func (w WindowConfig) Validate() error {
@@ -73,11 +139,22 @@ func (w WindowConfig) Validate() error {
 		validation.Field(&w.Name, nameMatchRule),
 		validation.Field(&w.Path, validation.By(rulefuncs.DirExists)),
 		validation.Field(&w.Env, validation.By(envVarMapRule)),
+		validation.Field(&w.EnvFile, validation.Each(validation.By(rulefuncs.FileExists))),
+		validation.Field(&w.EnvFrom, validation.By(envVarMapRule)),
+		validation.Field(&w.Layout, validation.By(layoutRule)),
+		validation.Field(&w.MainPaneSize,
+			sizeMatchRule,
+			validation.When(!strings.HasPrefix(w.Layout, "main-"),
+				validation.Empty.Error("requires a main-horizontal or main-vertical layout"),
+			),
+		),
 		validation.Field(&w.Command, validation.Length(1, 0)),
 		validation.Field(&w.Commands,
 			validation.Each(validation.Length(1, 0)),
 		),
 		validation.Field(&w.Panes),
+		validation.Field(&w.When),
+		validation.Field(&w.ForEach),
 	)
 }
 
@@ -87,7 +164,12 @@ func (w WindowConfig) Validate() error {
 //
 //   - pane path exists
 //   - pane environment variable names are valid
+//   - pane env files exist
+//   - pane env_from variable names are valid, and commands are valid (see
+//     [EnvFromConfig.Validate])
 //   - panes are valid
+//   - conditions are valid (see [WhenConfig.Validate])
+//   - for_each is valid (see [ForEachConfig.Validate])
 //
 // If any of the above checks fail, an error is returned.
 func (p PaneConfig) Validate() error {
@@ -96,14 +178,264 @@ func (p PaneConfig) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Path, validation.By(rulefuncs.DirExists)),
 		validation.Field(&p.Env, validation.By(envVarMapRule)),
+		validation.Field(&p.EnvFile, validation.Each(validation.By(rulefuncs.FileExists))),
+		validation.Field(&p.EnvFrom, validation.By(envVarMapRule)),
 		validation.Field(&p.Command, validation.Length(1, 0)),
 		validation.Field(&p.Commands,
 			validation.Each(validation.Length(1, 0)),
 		),
 		validation.Field(&p.Panes),
+		validation.Field(&p.When),
+		validation.Field(&p.ForEach),
 	)
 }
 
+// Validate validates the profile configuration.
+//
+// It checks that:
+//
+//   - windows are not both enabled and disabled
+//   - profile environment variable names are valid
+//   - hook commands are not empty
+//
+// Hooks are otherwise validated as part of the session configurations when the
+// profile is applied.
+//
+// If any of the above checks fail, an error is returned.
+func (p ProfileConfig) Validate() error {
+	validation.ErrorTag = errorTag
+
+	hookRule := validation.Each(validation.By(hookCommandRule))
+
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Disable, validation.Each(validation.By(func(val any) error {
+			if name, ok := val.(string); ok && slices.Contains(p.Enable, name) {
+				return fmt.Errorf("window %q cannot be both enabled and disabled", name)
+			}
+
+			return nil
+		}))),
+		validation.Field(&p.Env, validation.By(envVarMapRule)),
+		validation.Field(&p.BeforeStart, hookRule),
+		validation.Field(&p.AfterStart, hookRule),
+		validation.Field(&p.OnStop, hookRule),
+	)
+}
+
+// Validate validates the conditions.
+//
+// It checks that:
+//
+//   - hostname and git branch patterns are valid
+//
+// If any of the above checks fail, an error is returned.
+func (w WhenConfig) Validate() error {
+	validation.ErrorTag = errorTag
+
+	return validation.ValidateStruct(&w,
+		validation.Field(&w.Hostname, validation.By(patternRule)),
+		validation.Field(&w.GitBranch, validation.By(patternRule)),
+	)
+}
+
+// Validate validates the for_each configuration.
+//
+// It checks that:
+//
+//   - glob is a valid file pattern
+//
+// If any of the above checks fail, an error is returned.
+func (f ForEachConfig) Validate() error {
+	validation.ErrorTag = errorTag
+
+	return validation.ValidateStruct(&f,
+		validation.Field(&f.Glob, validation.By(func(val any) error {
+			if s, ok := val.(string); ok && s != "" {
+				if _, err := filepath.Match(s, ""); err != nil {
+					return errors.New("must be a valid file pattern")
+				}
+			}
+
+			return nil
+		})),
+	)
+}
+
+// Validate validates the hook configuration.
+//
+// It checks that:
+//
+//   - hook command is not empty
+//   - hook path exists
+//   - hook environment variable names are valid
+//   - hook timeout is not negative
+//
+// If any of the above checks fail, an error is returned.
+func (h HookConfig) Validate() error {
+	validation.ErrorTag = errorTag
+
+	return validation.ValidateStruct(&h,
+		validation.Field(&h.Command, validation.Required),
+		validation.Field(&h.Path, validation.By(rulefuncs.DirExists)),
+		validation.Field(&h.Env, validation.By(envVarMapRule)),
+		validation.Field(&h.Timeout, validation.Min(time.Duration(0)).Error("must not be negative")),
+	)
+}
+
+// Validate validates the env_from command configuration.
+//
+// It checks that:
+//
+//   - command is not empty
+//   - timeout is not negative
+//
+// If any of the above checks fail, an error is returned.
+func (e EnvFromConfig) Validate() error {
+	validation.ErrorTag = errorTag
+
+	return validation.ValidateStruct(&e,
+		validation.Field(&e.Command, validation.Required),
+		validation.Field(&e.Timeout, validation.Min(time.Duration(0)).Error("must not be negative")),
+	)
+}
+
+// zeroRule returns a rule that validates that a value is the zero value of its
+// type, failing with the provided message otherwise.
+func zeroRule(msg string) validation.RuleFunc {
+	return func(val any) error {
+		if val == nil || reflect.ValueOf(val).IsZero() {
+			return nil
+		}
+
+		return errors.New(msg)
+	}
+}
+
+// sessionNameRule validates that a session configuration in the sessions list
+// has a name.
+func sessionNameRule(val any) error {
+	s, ok := val.(SessionConfig)
+	if !ok || s.Name != "" {
+		return nil
+	}
+
+	return validation.Errors{"name": errors.New("cannot be blank")}
+}
+
+// uniqueSessionNamesRule validates that session configurations in the sessions
+// list have unique names.
+func uniqueSessionNamesRule(val any) error {
+	sessions, ok := val.([]SessionConfig)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(sessions))
+
+	for _, s := range sessions {
+		if s.Name == "" {
+			continue
+		}
+
+		if seen[s.Name] {
+			return fmt.Errorf("session name %q is used more than once", s.Name)
+		}
+
+		seen[s.Name] = true
+	}
+
+	return nil
+}
+
+// profileWindowsRule returns a rule that validates that the windows enabled
+// and disabled by a profile configuration are in the provided set of window
+// names.
+func profileWindowsRule(names map[string]bool) validation.RuleFunc {
+	return func(val any) error {
+		p, ok := val.(ProfileConfig)
+		if !ok {
+			return nil
+		}
+
+		errs := validation.Errors{}
+
+		for field, refs := range map[string][]string{"enable": p.Enable, "disable": p.Disable} {
+			for _, name := range refs {
+				if !names[name] {
+					errs[field] = fmt.Errorf("window %q is not configured", name)
+					break
+				}
+			}
+		}
+
+		return errsOrNil(errs)
+	}
+}
+
+// hookCommandRule validates that a hook configuration has a command.
+func hookCommandRule(val any) error {
+	h, ok := val.(HookConfig)
+	if !ok || h.Command != "" {
+		return nil
+	}
+
+	return validation.Errors{"command": errors.New("cannot be blank")}
+}
+
+// patternRule validates that a value is a valid [path.Match] pattern.
+func patternRule(val any) error {
+	s, ok := val.(string)
+	if !ok || s == "" {
+		return nil
+	}
+
+	if _, err := path.Match(s, ""); err != nil {
+		return errors.New("must be a valid pattern")
+	}
+
+	return nil
+}
+
+// uniqueWindowNamesRule validates that window configurations have unique
+// names. Windows without a name are ignored.
+func uniqueWindowNamesRule(val any) error {
+	windows, ok := val.([]WindowConfig)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(windows))
+
+	for _, w := range windows {
+		if w.Name == "" {
+			continue
+		}
+
+		if seen[w.Name] {
+			return fmt.Errorf("window name %q is used more than once", w.Name)
+		}
+
+		seen[w.Name] = true
+	}
+
+	return nil
+}
+
+// layoutRule validates that a value is a preset tmux layout name or a custom
+// layout string.
+func layoutRule(val any) error {
+	s, ok := val.(string)
+	if !ok || s == "" {
+		return nil
+	}
+
+	if presetLayouts[s] || layoutStringRE.MatchString(s) {
+		return nil
+	}
+
+	return errors.New("must be a preset layout name or a custom layout string")
+}
+
 // envVarMapRule validates that all keys in a map are valid environment
 // variable names (i.e. uppercase letters, numbers and underscores).
 func envVarMapRule(val any) error {
@@ -111,14 +443,35 @@ func envVarMapRule(val any) error {
 		return nil
 	}
 
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return validation.ErrNotMap
+	}
+
+	for _, k := range v.MapKeys() {
+		if !envVarRE.MatchString(k.String()) {
+			return fmt.Errorf("%q is not a valid environment variable name", k.String())
+		}
+	}
+
+	return nil
+}
+
+// varNameMapRule validates that all keys in a map are valid template variable
+// names (i.e. letters, numbers and underscores, not starting with a number).
+func varNameMapRule(val any) error {
+	if val == nil {
+		return nil
+	}
+
 	m, ok := val.(map[string]string)
 	if !ok {
 		return validation.ErrNotMap
 	}
 
 	for k := range m {
-		if !envVarRE.MatchString(k) {
-			return fmt.Errorf("%q is not a valid environment variable name", k)
+		if !varNameRE.MatchString(k) {
+			return fmt.Errorf("%q is not a valid variable name", k)
 		}
 	}
 
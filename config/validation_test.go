@@ -22,6 +22,11 @@ func TestConfig_Validate(t *testing.T) {
 			"invalid-tmux-not-exist.yaml",
 			testutils.RequireErrorContains("executable file was not found"),
 		},
+		{
+			"invalid redaction pattern",
+			"invalid-redact-bad-pattern.yaml",
+			testutils.RequireErrorContains("redact: (0: must be a valid pattern.)"),
+		},
 		{
 			"session with invalid name",
 			"invalid-session-bad-name.yaml",
@@ -37,6 +42,16 @@ func TestConfig_Validate(t *testing.T) {
 			"invalid-session-bad-env.yaml",
 			testutils.RequireErrorContains("is not a valid environment variable name"),
 		},
+		{
+			"session with non-existent env file",
+			"invalid-session-env-file-not-exist.yaml",
+			testutils.RequireErrorContains("env_file: (0: file does not exist.)"),
+		},
+		{
+			"session with invalid size",
+			"invalid-session-bad-size.yaml",
+			testutils.RequireErrorContains("width: must be no less than 1"),
+		},
 		{
 			"window with invalid name",
 			"invalid-window-bad-name.yaml",
@@ -52,6 +67,26 @@ func TestConfig_Validate(t *testing.T) {
 			"invalid-window-bad-env.yaml",
 			testutils.RequireErrorContains("is not a valid environment variable name"),
 		},
+		{
+			"window with invalid env_from",
+			"invalid-window-bad-env-from.yaml",
+			testutils.RequireErrorContains("is not a valid environment variable name"),
+		},
+		{
+			"window with invalid layout",
+			"invalid-window-bad-layout.yaml",
+			testutils.RequireErrorContains("must be a preset layout name or a custom layout string"),
+		},
+		{
+			"window with main pane size for non-main layout",
+			"invalid-window-main-pane-size.yaml",
+			testutils.RequireErrorContains("requires a main-horizontal or main-vertical layout"),
+		},
+		{
+			"window with custom layout",
+			"window-custom-layout.yaml",
+			nil,
+		},
 		{
 			"pane with non-existent path",
 			"invalid-pane-path-not-exist.yaml",
@@ -62,6 +97,61 @@ func TestConfig_Validate(t *testing.T) {
 			"invalid-pane-bad-env.yaml",
 			testutils.RequireErrorContains("is not a valid environment variable name"),
 		},
+		{
+			"pane with blank env_from command",
+			"invalid-pane-blank-env-from.yaml",
+			testutils.RequireErrorContains("env_from: (API_TOKEN: (command: cannot be blank.).)"),
+		},
+		{
+			"sessions with duplicate names",
+			"invalid-sessions-duplicate-name.yaml",
+			testutils.RequireErrorContains(`session name "api" is used more than once`),
+		},
+		{
+			"sessions without name",
+			"invalid-sessions-no-name.yaml",
+			testutils.RequireErrorContains("sessions: (1: (name: cannot be blank.).)"),
+		},
+		{
+			"session together with sessions",
+			"invalid-session-and-sessions.yaml",
+			testutils.RequireErrorContains("session: cannot be used together with sessions"),
+		},
+		{
+			"hook without command",
+			"invalid-hook-no-command.yaml",
+			testutils.RequireErrorContains("before_start: (0: (command: cannot be blank.).)"),
+		},
+		{
+			"hook with negative timeout",
+			"invalid-hook-bad-timeout.yaml",
+			testutils.RequireErrorContains("timeout: must not be negative"),
+		},
+		{
+			"window with invalid condition pattern",
+			"invalid-window-bad-when.yaml",
+			testutils.RequireErrorContains("when: (git_branch: must be a valid pattern.)"),
+		},
+		{
+			"window with invalid for_each glob",
+			"invalid-window-bad-for-each.yaml",
+			testutils.RequireErrorContains("for_each: (glob: must be a valid file pattern.)"),
+		},
+		{
+			"profile with unknown window",
+			"invalid-profile-unknown-window.yaml",
+			testutils.RequireErrorContains(`profiles: (minimal: (disable: window "logs" is not configured.).)`),
+		},
+		{
+			"profile enabling and disabling window",
+			"invalid-profile-enable-disable.yaml",
+			testutils.RequireErrorContains(`window "code" cannot be both enabled and disabled`),
+		},
+		{
+			"profile hook with no command",
+			"invalid-profile-hook-no-command.yaml",
+			testutils.RequireErrorContains("after_start: (0: (command: cannot be blank.).)"),
+		},
 	}
 
 	for _, tc := range tt {
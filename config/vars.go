@@ -0,0 +1,442 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/invopop/validation"
+
+	"github.com/michenriksen/tmpl/internal/env"
+)
+
+// TemplateError is returned when variables cannot be expanded in one or more
+// fields of a configuration file.
+//
+// The errors are keyed by field path in the same way as validation errors
+// returned by [Config.Validate].
+type TemplateError struct {
+	errs validation.Errors
+	path string
+}
+
+// Error implements the error interface.
+func (e TemplateError) Error() string {
+	return fmt.Sprintf("template error: %s", e.errs)
+}
+
+// Errors returns the template errors keyed by field path.
+func (e TemplateError) Errors() validation.Errors {
+	return e.errs
+}
+
+// Path returns the path to the configuration file with the template errors.
+func (e TemplateError) Path() string {
+	return e.path
+}
+
+// expandVars expands variables in all string fields of the session and profile
+// configurations with Go's text/template package.
+//
+// Variables are taken from the configuration's vars section and the provided
+// overrides, which take precedence. The merged variables are set on the
+// configuration. Referencing a variable that is not defined is an error.
+//
+// Templates are only rendered if variables are defined or overridden, or
+// custom delimiters are set with template_delims, so that configurations
+// without variables can contain template actions meant for commands, such as
+// docker ps --format '{{.Names}}'.
+//
+// Window and pane configurations with for_each are expanded into a
+// configuration for each item (see [ForEachConfig]).
+func expandVars(cfg *Config, overrides map[string]string) error {
+	if len(overrides) != 0 {
+		vars := make(map[string]string, len(cfg.Vars)+len(overrides))
+
+		for k, v := range cfg.Vars {
+			vars[k] = v
+		}
+
+		for k, v := range overrides {
+			vars[k] = v
+		}
+
+		cfg.Vars = vars
+	}
+
+	data, err := newTemplateData(cfg)
+	if err != nil {
+		return TemplateError{errs: validation.Errors{"template_delims": err}, path: cfg.path}
+	}
+
+	wd, err := env.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current working directory: %w", err)
+	}
+
+	errs := validation.Errors{}
+
+	if err := expandSession(&cfg.Session, data, wd); err != nil {
+		errs["session"] = err
+	}
+
+	sessionErrs := validation.Errors{}
+
+	for i := range cfg.Sessions {
+		if err := expandSession(&cfg.Sessions[i], data, wd); err != nil {
+			sessionErrs[strconv.Itoa(i)] = err
+		}
+	}
+
+	if len(sessionErrs) != 0 {
+		errs["sessions"] = sessionErrs
+	}
+
+	profileErrs := validation.Errors{}
+
+	for name, p := range cfg.Profiles {
+		if err := expandValue(reflect.ValueOf(&p).Elem(), data); err != nil {
+			profileErrs[name] = err
+		}
+
+		cfg.Profiles[name] = p
+	}
+
+	if len(profileErrs) != 0 {
+		errs["profiles"] = profileErrs
+	}
+
+	if len(errs) != 0 {
+		return TemplateError{errs: errs, path: cfg.path}
+	}
+
+	return nil
+}
+
+// expandSession expands variables in the session configuration and expands
+// its windows. Relative session paths are resolved against wd.
+func expandSession(sCfg *SessionConfig, data templateData, wd string) error {
+	windows := sCfg.Windows
+	sCfg.Windows = nil
+
+	errs := fieldErrs(expandValue(reflect.ValueOf(sCfg).Elem(), data))
+
+	dir, err := resolvePath(sCfg.Path, wd)
+	if err != nil {
+		return fmt.Errorf("expanding session path: %w", err)
+	}
+
+	if sCfg.Windows, err = expandWindows(windows, data, dir); err != nil {
+		errs["windows"] = err
+	}
+
+	return errsOrNil(errs)
+}
+
+// expandWindows expands variables in the window configurations and returns
+// them with windows configured with for_each replaced by a window for each
+// item. The dir argument is the session path.
+func expandWindows(windows []WindowConfig, data templateData, dir string) ([]WindowConfig, error) {
+	if windows == nil {
+		return nil, nil
+	}
+
+	res := make([]WindowConfig, 0, len(windows))
+	errs := validation.Errors{}
+
+	for i, w := range windows {
+		if w.ForEach == nil {
+			w, err := expandWindow(w, data, dir)
+			if err != nil {
+				errs[strconv.Itoa(i)] = err
+			}
+
+			res = append(res, w)
+
+			continue
+		}
+
+		items, err := w.ForEach.items(dir, data)
+		if err != nil {
+			errs[strconv.Itoa(i)] = validation.Errors{"for_each": err}
+			continue
+		}
+
+		for _, item := range items {
+			gen := w.clone()
+			gen.ForEach = nil
+
+			gen, err := expandWindow(gen, itemData(data, item), dir)
+			if err != nil {
+				errs[strconv.Itoa(i)] = err
+				break
+			}
+
+			res = append(res, gen)
+		}
+	}
+
+	return res, errsOrNil(errs)
+}
+
+func expandWindow(w WindowConfig, data templateData, parentDir string) (WindowConfig, error) {
+	panes := w.Panes
+	w.Panes = nil
+
+	errs := fieldErrs(expandValue(reflect.ValueOf(&w).Elem(), data))
+
+	dir, err := resolvePath(w.Path, parentDir)
+	if err != nil {
+		return w, fmt.Errorf("expanding window path: %w", err)
+	}
+
+	if w.Panes, err = expandPanes(panes, data, dir); err != nil {
+		errs["panes"] = err
+	}
+
+	return w, errsOrNil(errs)
+}
+
+// expandPanes expands variables in the pane configurations and returns them
+// with panes configured with for_each replaced by a pane for each item. The
+// dir argument is the path of the window or parent pane.
+func expandPanes(panes []PaneConfig, data templateData, dir string) ([]PaneConfig, error) {
+	if panes == nil {
+		return nil, nil
+	}
+
+	res := make([]PaneConfig, 0, len(panes))
+	errs := validation.Errors{}
+
+	for i, p := range panes {
+		if p.ForEach == nil {
+			p, err := expandPane(p, data, dir)
+			if err != nil {
+				errs[strconv.Itoa(i)] = err
+			}
+
+			res = append(res, p)
+
+			continue
+		}
+
+		items, err := p.ForEach.items(dir, data)
+		if err != nil {
+			errs[strconv.Itoa(i)] = validation.Errors{"for_each": err}
+			continue
+		}
+
+		for _, item := range items {
+			gen := p.clone()
+			gen.ForEach = nil
+
+			gen, err := expandPane(gen, itemData(data, item), dir)
+			if err != nil {
+				errs[strconv.Itoa(i)] = err
+				break
+			}
+
+			res = append(res, gen)
+		}
+	}
+
+	return res, errsOrNil(errs)
+}
+
+func expandPane(p PaneConfig, data templateData, parentDir string) (PaneConfig, error) {
+	panes := p.Panes
+	p.Panes = nil
+
+	errs := fieldErrs(expandValue(reflect.ValueOf(&p).Elem(), data))
+
+	dir, err := resolvePath(p.Path, parentDir)
+	if err != nil {
+		return p, fmt.Errorf("expanding pane path: %w", err)
+	}
+
+	if p.Panes, err = expandPanes(panes, data, dir); err != nil {
+		errs["panes"] = err
+	}
+
+	return p, errsOrNil(errs)
+}
+
+// expandValue expands variables in the string value v, or recursively in the
+// string fields, elements and map values of v.
+//
+// Pointers are followed to the values they point to.
+//
+// Errors for nested values are returned as [validation.Errors] keyed by YAML
+// field name, element index or map key.
+func expandValue(v reflect.Value, data templateData) error {
+	switch v.Kind() { //nolint:exhaustive // Other kinds have no strings to expand.
+	case reflect.String:
+		s, err := renderTemplate(v.String(), data)
+		if err != nil {
+			return err
+		}
+
+		v.SetString(s)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+
+		return expandValue(v.Elem(), data)
+	case reflect.Struct:
+		errs := validation.Errors{}
+
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+
+			if err := expandValue(v.Field(i), data); err != nil {
+				errs[name] = err
+			}
+		}
+
+		return errsOrNil(errs)
+	case reflect.Slice:
+		errs := validation.Errors{}
+
+		for i := 0; i < v.Len(); i++ {
+			if err := expandValue(v.Index(i), data); err != nil {
+				errs[strconv.Itoa(i)] = err
+			}
+		}
+
+		return errsOrNil(errs)
+	case reflect.Map:
+		errs := validation.Errors{}
+		iter := v.MapRange()
+
+		for iter.Next() {
+			// Map values are not addressable, so they are expanded in a copy
+			// that replaces the value.
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+
+			if err := expandValue(elem, data); err != nil {
+				errs[iter.Key().String()] = err
+				continue
+			}
+
+			v.SetMapIndex(iter.Key(), elem)
+		}
+
+		return errsOrNil(errs)
+	}
+
+	return nil
+}
+
+// templateFuncs are the functions available in templates in addition to the
+// builtin functions of the text/template package.
+var templateFuncs = template.FuncMap{
+	"base": filepath.Base,
+}
+
+// templateData holds the variables that templates in configuration values are
+// rendered with, and the action delimiters of the templates.
+type templateData struct {
+	vars   map[string]string
+	left   string // Left action delimiter.
+	right  string // Right action delimiter.
+	render bool   // Whether values are rendered as templates at all.
+}
+
+// newTemplateData returns the template data for the configuration's
+// variables and template delimiters.
+func newTemplateData(cfg *Config) (templateData, error) {
+	data := templateData{
+		vars:   cfg.Vars,
+		left:   "{{",
+		right:  "}}",
+		render: len(cfg.Vars) != 0 || len(cfg.TemplateDelims) != 0,
+	}
+
+	if data.vars == nil {
+		data.vars = map[string]string{}
+	}
+
+	if len(cfg.TemplateDelims) == 0 {
+		return data, nil
+	}
+
+	if len(cfg.TemplateDelims) != 2 || cfg.TemplateDelims[0] == "" || cfg.TemplateDelims[1] == "" {
+		return data, errors.New("must be a left and a right delimiter")
+	}
+
+	data.left, data.right = cfg.TemplateDelims[0], cfg.TemplateDelims[1]
+
+	return data, nil
+}
+
+// renderTemplate renders s as a template with the provided template data.
+//
+// Strings without template actions are returned unchanged, as are all strings
+// if the template data is not set to be rendered.
+func renderTemplate(s string, data templateData) (string, error) {
+	if !data.render || !strings.Contains(s, data.left) {
+		return s, nil
+	}
+
+	tmpl, err := template.New("").
+		Delims(data.left, data.right).
+		Funcs(templateFuncs).
+		Option("missingkey=error").
+		Parse(s)
+	if err != nil {
+		return "", cleanTemplateErr(err)
+	}
+
+	var b strings.Builder
+
+	if err := tmpl.Execute(&b, data.vars); err != nil {
+		return "", cleanTemplateErr(err)
+	}
+
+	return b.String(), nil
+}
+
+// cleanTemplateErr returns a template error without the template name and
+// position prefix, which is meaningless for templates in single fields.
+func cleanTemplateErr(err error) error {
+	msg := err.Error()
+
+	if i := strings.LastIndex(msg, ": "); i != -1 && strings.HasPrefix(msg, "template: ") {
+		msg = msg[i+2:]
+	}
+
+	return errors.New(msg)
+}
+
+// fieldErrs returns the errors keyed by field name from an error returned by
+// [expandValue] for a struct, or an empty map if err is nil.
+func fieldErrs(err error) validation.Errors {
+	var errs validation.Errors
+	if errors.As(err, &errs) {
+		return errs
+	}
+
+	return validation.Errors{}
+}
+
+func errsOrNil(errs validation.Errors) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michenriksen/tmpl/internal/rulefuncs"
+)
+
+// gitBranchTimeout is the maximum time to wait for git to report the current
+// branch of a git_branch condition.
+const gitBranchTimeout = 5 * time.Second
+
+// WhenConfig represents conditions that must hold for a window or pane to be
+// created. Conditions that are not set are ignored, and a window or pane with
+// no conditions is always created.
+//
+// Relative file paths are resolved against the path of the window or pane,
+// and the git branch is looked up in the git repository containing it.
+//
+// Hostname and git branch conditions are patterns in the syntax of
+// [path.Match], e.g. "feature/*".
+type WhenConfig struct {
+	FileExists string `yaml:"file_exists,omitempty"` // File that must exist.
+	Env        string `yaml:"env,omitempty"`         // Environment variable that must be set.
+	Command    string `yaml:"command,omitempty"`     // Command that must be available in $PATH.
+	Hostname   string `yaml:"hostname,omitempty"`    // Pattern the hostname must match.
+	OS         string `yaml:"os,omitempty"`          // Operating system that must match, e.g. "linux".
+	GitBranch  string `yaml:"git_branch,omitempty"`  // Pattern the current git branch must match.
+}
+
+// SkippedItem describes a window or pane that is not created because the
+// conditions in its when configuration do not hold.
+type SkippedItem struct {
+	Session string // Session name.
+	Window  string // Window name.
+	Pane    string // Index of skipped pane in the window's panes, e.g. "1", or "1.0" for a nested pane.
+	Reason  string // Condition that does not hold.
+}
+
+// evaluateConditions evaluates the conditions of all window and pane
+// configurations and removes the ones with conditions that do not hold. The
+// removed windows and panes are recorded as skipped items on the
+// configuration (see [Config.Skipped]).
+//
+// Must be called after default values are set, as conditions are evaluated
+// against the window and pane paths.
+func evaluateConditions(cfg *Config) error {
+	if len(cfg.Sessions) != 0 {
+		for i := range cfg.Sessions {
+			if err := cfg.evaluateSessionConditions(&cfg.Sessions[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return cfg.evaluateSessionConditions(&cfg.Session)
+}
+
+func (c *Config) evaluateSessionConditions(sCfg *SessionConfig) error {
+	if len(sCfg.Windows) == 0 {
+		return nil
+	}
+
+	windows := make([]WindowConfig, 0, len(sCfg.Windows))
+
+	for _, wCfg := range sCfg.Windows {
+		reason, err := wCfg.When.evaluate(wCfg.Path)
+		if err != nil {
+			return fmt.Errorf("evaluating conditions for window %q: %w", wCfg.Name, err)
+		}
+
+		if reason != "" {
+			c.skipped = append(c.skipped, SkippedItem{Session: sCfg.Name, Window: wCfg.Name, Reason: reason})
+			continue
+		}
+
+		panes, err := c.evaluatePaneConditions(sCfg.Name, wCfg.Name, "", wCfg.Panes)
+		if err != nil {
+			return err
+		}
+
+		wCfg.Panes = panes
+		windows = append(windows, wCfg)
+	}
+
+	if len(windows) == 0 {
+		windows = nil
+	}
+
+	sCfg.Windows = windows
+
+	return nil
+}
+
+func (c *Config) evaluatePaneConditions(session, window, prefix string, panes []PaneConfig) ([]PaneConfig, error) {
+	if len(panes) == 0 {
+		return panes, nil
+	}
+
+	res := make([]PaneConfig, 0, len(panes))
+
+	for i, pCfg := range panes {
+		pos := prefix + strconv.Itoa(i)
+
+		reason, err := pCfg.When.evaluate(pCfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating conditions for pane %s in window %q: %w", pos, window, err)
+		}
+
+		if reason != "" {
+			c.skipped = append(c.skipped, SkippedItem{Session: session, Window: window, Pane: pos, Reason: reason})
+			continue
+		}
+
+		if pCfg.Panes, err = c.evaluatePaneConditions(session, window, pos+".", pCfg.Panes); err != nil {
+			return nil, err
+		}
+
+		res = append(res, pCfg)
+	}
+
+	if len(res) == 0 {
+		res = nil
+	}
+
+	return res, nil
+}
+
+// evaluate evaluates the conditions against the provided window or pane path
+// and returns a description of the first condition that does not hold, or an
+// empty string if all conditions hold.
+//
+// An error is returned if a condition cannot be evaluated.
+func (w *WhenConfig) evaluate(dir string) (string, error) {
+	if w == nil {
+		return "", nil
+	}
+
+	if w.FileExists != "" {
+		name, err := resolvePath(w.FileExists, dir)
+		if err != nil {
+			return "", fmt.Errorf("expanding file path: %w", err)
+		}
+
+		if _, err := os.Stat(name); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fmt.Sprintf("file %s does not exist", w.FileExists), nil
+			}
+
+			return "", fmt.Errorf("getting file info: %w", err)
+		}
+	}
+
+	if w.Env != "" {
+		if _, ok := os.LookupEnv(w.Env); !ok {
+			return fmt.Sprintf("environment variable %s is not set", w.Env), nil
+		}
+	}
+
+	if w.Command != "" {
+		if err := rulefuncs.ExecutableExists(w.Command); err != nil {
+			return fmt.Sprintf("command %s is not in $PATH", w.Command), nil
+		}
+	}
+
+	if w.Hostname != "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", fmt.Errorf("getting hostname: %w", err)
+		}
+
+		ok, err := path.Match(w.Hostname, hostname)
+		if err != nil {
+			return "", fmt.Errorf("matching hostname pattern: %w", err)
+		}
+
+		if !ok {
+			return fmt.Sprintf("hostname %s does not match %s", hostname, w.Hostname), nil
+		}
+	}
+
+	if w.OS != "" && w.OS != runtime.GOOS {
+		return fmt.Sprintf("operating system %s is not %s", runtime.GOOS, w.OS), nil
+	}
+
+	if w.GitBranch != "" {
+		branch := gitBranch(dir)
+		if branch == "" {
+			return fmt.Sprintf("%s is not in a git repository", dir), nil
+		}
+
+		ok, err := path.Match(w.GitBranch, branch)
+		if err != nil {
+			return "", fmt.Errorf("matching git branch pattern: %w", err)
+		}
+
+		if !ok {
+			return fmt.Sprintf("git branch %s does not match %s", branch, w.GitBranch), nil
+		}
+	}
+
+	return "", nil
+}
+
+// gitBranch returns the name of the current branch of the git repository
+// containing dir, or an empty string if dir is not in a git repository, git
+// is not available, or git does not finish within [gitBranchTimeout].
+func gitBranch(dir string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), gitBranchTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
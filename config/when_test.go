@@ -0,0 +1,125 @@
+package config_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/internal/testutils"
+)
+
+func TestFromFile_When(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "project"), 0o755))
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", filepath.Join(dir, "project"))
+	t.Setenv("TMPL_TEST_WHEN_SET", "1")
+
+	testutils.WriteFile(t, []byte("services: {}\n"), dir, "project", "docker-compose.yml")
+	testutils.WriteFile(t, []byte(`---
+session:
+  name: project
+  windows:
+    - name: code
+      when:
+        os: `+runtime.GOOS+`
+        hostname: "*"
+      panes:
+        - when:
+            env: TMPL_TEST_WHEN_SET
+        - when:
+            env: TMPL_TEST_WHEN_UNSET
+          panes:
+            - command: echo never
+        - panes:
+            - when:
+                file_exists: missing.txt
+    - name: db
+      when:
+        file_exists: docker-compose.yml
+        command: sh
+    - name: docker
+      when:
+        command: tmpl-test-no-such-command
+    - name: windows
+      when:
+        os: plan9
+    - name: feature
+      when:
+        git_branch: "feature/*"
+profiles:
+  minimal:
+    disable: [docker]
+`), dir, "project", config.DefaultConfigFile)
+
+	cfg, err := config.FromFile(filepath.Join(dir, "project", config.DefaultConfigFile))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate(), "expected profile references to skipped windows to be valid")
+
+	require.Len(t, cfg.Session.Windows, 2)
+	require.Equal(t, "code", cfg.Session.Windows[0].Name)
+	require.Equal(t, "db", cfg.Session.Windows[1].Name)
+
+	panes := cfg.Session.Windows[0].Panes
+	require.Len(t, panes, 2)
+	require.Equal(t, "TMPL_TEST_WHEN_SET", panes[0].When.Env)
+	require.Empty(t, panes[1].Panes)
+
+	projectDir := filepath.Join(dir, "project")
+
+	require.Equal(t, []config.SkippedItem{
+		{Session: "project", Window: "code", Pane: "1", Reason: "environment variable TMPL_TEST_WHEN_UNSET is not set"},
+		{Session: "project", Window: "code", Pane: "2.0", Reason: "file missing.txt does not exist"},
+		{Session: "project", Window: "docker", Reason: "command tmpl-test-no-such-command is not in $PATH"},
+		{Session: "project", Window: "windows", Reason: "operating system " + runtime.GOOS + " is not plan9"},
+		{Session: "project", Window: "feature", Reason: projectDir + " is not in a git repository"},
+	}, cfg.Skipped())
+}
+
+func TestFromFile_When_GitBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	dir := t.TempDir()
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "feature/when"},
+		{"-c", "user.name=tmpl", "-c", "user.email=tmpl@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	testutils.WriteFile(t, []byte(`---
+session:
+  windows:
+    - name: feature
+      when:
+        git_branch: "feature/*"
+    - name: main
+      when:
+        git_branch: main
+`), dir, config.DefaultConfigFile)
+
+	cfg, err := config.FromFile(filepath.Join(dir, config.DefaultConfigFile))
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Session.Windows, 1)
+	require.Equal(t, "feature", cfg.Session.Windows[0].Name)
+	require.Equal(t, []config.SkippedItem{
+		{Session: cfg.Session.Name, Window: "main", Reason: "git branch feature/when does not match main"},
+	}, cfg.Skipped())
+}
@@ -14,8 +14,13 @@ import (
 )
 
 const (
-	cmdInit  = "init"
-	cmdCheck = "check"
+	cmdInit    = "init"
+	cmdCheck   = "check"
+	cmdDiff    = "diff"
+	cmdExport  = "export"
+	cmdCapture = "capture"
+	cmdStop    = "stop"
+	cmdUp      = "up"
 )
 
 // ErrInvalidConfig is returned when a configuration is invalid.
@@ -33,15 +38,19 @@ type App struct {
 	opts         *options
 	cfg          *config.Config
 	tmux         tmux.Runner
+	control      *tmux.ControlRunner
+	replay       *tmux.ReplayRunner
+	transcript   *os.File
 	sess         *tmux.Session
 	logger       *slog.Logger
+	redaction    *tmux.Redaction
 	attrReplacer func([]string, slog.Attr) slog.Attr
 	out          io.Writer
 }
 
 // NewApp creates a new command-line application.
 func NewApp(opts ...AppOption) (*App, error) {
-	app := &App{out: os.Stdout}
+	app := &App{out: os.Stdout, redaction: tmux.NewRedaction(tmux.DefaultRedactPatterns...)}
 
 	for _, opt := range opts {
 		if err := opt(app); err != nil {
@@ -56,11 +65,14 @@ func NewApp(opts ...AppOption) (*App, error) {
 //
 // Different logic is performed dependening on the sub-command provided as the
 // first command-line argument.
-func (a *App) Run(ctx context.Context, args ...string) error {
-	var (
-		cmd string
-		err error
-	)
+func (a *App) Run(ctx context.Context, args ...string) (err error) {
+	var cmd string
+
+	defer func() {
+		if cerr := a.closeTmux(); cerr != nil && err == nil {
+			err = a.handleErr(cerr)
+		}
+	}()
 
 	if len(args) > 0 {
 		cmd = args[0]
@@ -75,6 +87,46 @@ func (a *App) Run(ctx context.Context, args ...string) error {
 		}
 
 		return a.handleErr(a.runCheck(ctx))
+	case cmdCapture:
+		if a.opts == nil {
+			if a.opts, err = parseCaptureOptions(args[1:], a.out); err != nil {
+				return a.handleErr(err)
+			}
+		}
+
+		return a.handleErr(a.runCapture(ctx))
+	case cmdDiff:
+		if a.opts == nil {
+			if a.opts, err = parseDiffOptions(args[1:], a.out); err != nil {
+				return a.handleErr(err)
+			}
+		}
+
+		return a.handleErr(a.runDiff(ctx))
+	case cmdExport:
+		if a.opts == nil {
+			if a.opts, err = parseExportOptions(args[1:], a.out); err != nil {
+				return a.handleErr(err)
+			}
+		}
+
+		return a.handleErr(a.runExport(ctx))
+	case cmdStop:
+		if a.opts == nil {
+			if a.opts, err = parseStopOptions(args[1:], a.out); err != nil {
+				return a.handleErr(err)
+			}
+		}
+
+		return a.handleErr(a.runStop(ctx))
+	case cmdUp:
+		if a.opts == nil {
+			if a.opts, err = parseUpOptions(args[1:], a.out); err != nil {
+				return a.handleErr(err)
+			}
+		}
+
+		return a.handleErr(a.runUp(ctx))
 	case cmdInit:
 		if a.opts == nil {
 			if a.opts, err = parseInitOptions(args[1:], a.out); err != nil {
@@ -111,12 +163,40 @@ func (a *App) loadConfig() (err error) {
 		}
 	}
 
-	if a.cfg, err = config.FromFile(a.opts.ConfigPath); err != nil {
+	profile := a.opts.Profile
+	if profile == "" {
+		profile = env.Getenv(env.KeyProfile)
+	}
+
+	if a.cfg, err = config.FromFile(a.opts.ConfigPath, config.WithVars(a.opts.Vars), config.WithProfile(profile)); err != nil {
 		return err //nolint:wrapcheck // Wrapping is done by caller.
 	}
 
+	a.redaction.AddPatterns(a.cfg.Redact...)
+
 	a.logger.Info("configuration file loaded", "path", a.opts.ConfigPath)
 
+	for _, src := range a.cfg.Sources() {
+		if src != a.opts.ConfigPath {
+			a.logger.Info("configuration file merged", "path", src)
+		}
+	}
+
+	if profile != "" {
+		a.logger.Info("configuration profile applied", "profile", profile)
+	}
+
+	for _, item := range a.cfg.Skipped() {
+		if item.Pane == "" {
+			a.logger.Info("window skipped", "session", item.Session, "window", item.Window, "reason", item.Reason)
+			continue
+		}
+
+		a.logger.Info("pane skipped",
+			"session", item.Session, "window", item.Window, "pane", item.Pane, "reason", item.Reason,
+		)
+	}
+
 	return nil
 }
 
@@ -1,64 +1,432 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/internal/env"
+	"github.com/michenriksen/tmpl/internal/faketmux"
 	"github.com/michenriksen/tmpl/tmux"
 )
 
 // runApply loads the configuration, applies it to a new tmux session and
 // attaches it.
+//
+// If attaching is disabled with the --no-attach option or in the
+// configuration, the method returns after the configuration is applied. With
+// the --format option, a plan of the session applied in dry-run mode is
+// written to the output writer.
 func (a *App) runApply(ctx context.Context) error {
+	// Logs are written to the output writer along with the plan and the JSON
+	// session details, so only warnings and errors are logged when either is
+	// written.
+	if a.opts.Format != "" || a.opts.Output == formatJSON {
+		a.opts.Quiet = true
+	}
+
 	a.initLogger()
 
 	if err := a.loadConfig(); err != nil {
 		return fmt.Errorf("loading configuration: %w", err)
 	}
 
+	a.setSessionSize()
+
 	runner, err := a.newTmux()
 	if err != nil {
 		return fmt.Errorf("creating tmux runner: %w", err)
 	}
 
-	a.sess, err = config.Apply(ctx, a.cfg, runner)
-	if err != nil {
-		return fmt.Errorf("applying configuration: %w", err)
+	// The tmux commands are recorded for the dry-run plan.
+	var planCalls *bytes.Buffer
+
+	if a.opts.Format != "" {
+		planCalls = new(bytes.Buffer)
+
+		runner, err = tmux.NewRecordingRunner(runner, planCalls, tmux.WithRecordingRedaction(a.redaction))
+		if err != nil {
+			return fmt.Errorf("creating tmux runner: %w", err)
+		}
+	}
+
+	if a.opts.Sync {
+		if err := a.syncSession(ctx, runner); err != nil {
+			return err
+		}
+	} else {
+		a.sess, err = config.Apply(ctx, a.cfg, runner)
+		if err != nil {
+			return fmt.Errorf("applying configuration: %w", err)
+		}
+	}
+
+	if a.opts.Output == formatJSON {
+		if err := a.writeApplyResult(ctx, runner); err != nil {
+			return err
+		}
 	}
 
-	if err := a.sess.Attach(ctx); err != nil {
+	if a.opts.NoAttach || !a.cfg.ShouldAttach() {
+		a.logger.Info("session ready; not attaching client", "session", a.sess.Name())
+	} else if err := a.sess.Attach(ctx); err != nil {
 		return fmt.Errorf("attaching session: %w", err)
 	}
 
+	if planCalls != nil {
+		return a.writePlan(ctx, planCalls)
+	}
+
 	return nil
 }
 
+// applyResult describes a session after a configuration has been applied.
+type applyResult struct {
+	ID      string              `json:"id"`
+	Name    string              `json:"name"`
+	Path    string              `json:"path"`
+	Windows []applyResultWindow `json:"windows"`
+}
+
+// applyResultWindow describes a window in an [applyResult].
+type applyResultWindow struct {
+	ID    string            `json:"id"`
+	Name  string            `json:"name"`
+	Index string            `json:"index"`
+	Path  string            `json:"path,omitempty"`
+	Panes []applyResultPane `json:"panes"`
+}
+
+// applyResultPane describes a pane in an [applyResultWindow].
+type applyResultPane struct {
+	ID    string `json:"id"`
+	Index string `json:"index"`
+	Path  string `json:"path,omitempty"`
+}
+
+// writeApplyResult writes the session's IDs, names and paths to the output
+// writer as JSON.
+//
+// If the session already existed, its windows are loaded from tmux. The panes
+// of each window are always loaded from tmux, so that the initial pane of the
+// window is included along with the panes split from it.
+func (a *App) writeApplyResult(ctx context.Context, runner tmux.Runner) error {
+	if a.sess.NumWindows() == 0 {
+		if _, err := tmux.GetWindows(ctx, runner, a.sess); err != nil {
+			return fmt.Errorf("getting windows for %s: %w", a.sess, err)
+		}
+	}
+
+	res := applyResult{
+		ID:      a.sess.ID(),
+		Name:    a.sess.Name(),
+		Path:    a.sess.Path(),
+		Windows: []applyResultWindow{},
+	}
+
+	for _, w := range a.sess.Windows() {
+		panes, err := tmux.GetPanes(ctx, runner, w)
+		if err != nil {
+			return fmt.Errorf("getting panes for %s: %w", w, err)
+		}
+
+		rw := applyResultWindow{
+			ID:    w.ID(),
+			Name:  w.ShortName(),
+			Index: w.Index(),
+			Path:  w.Path(),
+			Panes: []applyResultPane{},
+		}
+
+		for _, p := range panes {
+			rp := applyResultPane{ID: p.ID(), Index: p.Index(), Path: p.CurrentPath()}
+			if rp.Path == "" {
+				rp.Path = p.Path()
+			}
+
+			rw.Panes = append(rw.Panes, rp)
+		}
+
+		res.Windows = append(res.Windows, rw)
+	}
+
+	enc := json.NewEncoder(a.out)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(res); err != nil {
+		return fmt.Errorf("encoding session details: %w", err)
+	}
+
+	return nil
+}
+
+// setSessionSize sets the size for new sessions in the configuration.
+//
+// Sizes given as command-line options take precedence over the configuration,
+// and the size of the terminal connected to the application's output is used
+// for any dimension that is still unset. If the output is not a terminal, the
+// size is left to tmux.
+func (a *App) setSessionSize() {
+	sCfgs := []*config.SessionConfig{&a.cfg.Session}
+
+	if len(a.cfg.Sessions) != 0 {
+		sCfgs = make([]*config.SessionConfig, len(a.cfg.Sessions))
+
+		for i := range a.cfg.Sessions {
+			sCfgs[i] = &a.cfg.Sessions[i]
+		}
+	}
+
+	var (
+		width, height int
+		termSizeDone  bool
+	)
+
+	for _, sCfg := range sCfgs {
+		if a.opts.Width != 0 {
+			sCfg.Width = a.opts.Width
+		}
+
+		if a.opts.Height != 0 {
+			sCfg.Height = a.opts.Height
+		}
+
+		if sCfg.Width != 0 && sCfg.Height != 0 {
+			continue
+		}
+
+		if !termSizeDone {
+			width, height = a.termSize()
+			termSizeDone = true
+		}
+
+		if sCfg.Width == 0 {
+			sCfg.Width = width
+		}
+
+		if sCfg.Height == 0 {
+			sCfg.Height = height
+		}
+	}
+}
+
+// termSize returns the size of the terminal connected to the application's
+// output, or zero values if the output is not a terminal.
+func (a *App) termSize() (width, height int) {
+	f, ok := a.out.(*os.File)
+	if !ok {
+		return 0, 0
+	}
+
+	width, height, ok = env.TermSize(f)
+	if !ok {
+		a.logger.Debug("terminal size not available; using tmux default size")
+		return 0, 0
+	}
+
+	return width, height
+}
+
+// syncSession synchronizes the configuration with an existing tmux session and
+// logs a report of the windows and panes that were added.
+func (a *App) syncSession(ctx context.Context, runner tmux.Runner) error {
+	sess, res, err := config.Sync(ctx, a.cfg, runner)
+	if err != nil {
+		return fmt.Errorf("synchronizing configuration: %w", err)
+	}
+
+	a.sess = sess
+
+	if res.Created {
+		return nil
+	}
+
+	for _, w := range res.Windows {
+		a.logger.Info("window added", "window", w.Name())
+	}
+
+	for _, p := range res.Panes {
+		a.logger.Info("pane added", "pane", p.Name())
+	}
+
+	a.logger.Info("session synchronized",
+		"session", sess.Name(), "windows_added", len(res.Windows), "panes_added", len(res.Panes),
+	)
+
+	return nil
+}
+
+// newTmux returns the tmux runner for the application.
+//
+// With the --replay option, the runner replays the tmux commands from a
+// transcript file, and with the --record option, the runner records the tmux
+// commands it runs to a transcript file.
 func (a *App) newTmux() (tmux.Runner, error) {
+	if a.opts.Record != "" && a.opts.Replay != "" {
+		return nil, errors.New("--record and --replay options cannot be used together")
+	}
+
+	if a.opts.Replay != "" {
+		return a.newReplayTmux()
+	}
+
+	runner, err := a.newBaseTmux()
+	if err != nil || a.opts.Record == "" {
+		return runner, err
+	}
+
+	return a.newRecordingTmux(runner)
+}
+
+// newBaseTmux returns the tmux runner to run tmux commands with.
+func (a *App) newBaseTmux() (tmux.Runner, error) {
 	if a.tmux != nil {
 		return a.tmux, nil
 	}
 
-	cmdOpts := []tmux.RunnerOption{tmux.WithLogger(a.logger)}
+	if a.opts.DryRun {
+		a.logger.Info("DRY-RUN MODE ENABLED: no tmux commands will be executed and output is simulated")
 
-	if a.cfg.Tmux != "" {
+		return a.newFakeTmux()
+	}
+
+	cmdOpts := []tmux.RunnerOption{tmux.WithLogger(a.logger), tmux.WithRedaction(a.redaction)}
+
+	if a.cfg != nil && a.cfg.Tmux != "" {
 		cmdOpts = append(cmdOpts, tmux.WithTmux(a.cfg.Tmux))
 	}
 
-	if len(a.cfg.TmuxOptions) > 0 {
+	if a.cfg != nil && len(a.cfg.TmuxOptions) > 0 {
 		cmdOpts = append(cmdOpts, tmux.WithTmuxOptions(a.cfg.TmuxOptions...))
 	}
 
-	if a.opts.DryRun {
-		a.logger.Info("DRY-RUN MODE ENABLED: no tmux commands will be executed and output is simulated")
+	cmd, err := tmux.NewRunner(cmdOpts...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Wrapping is done by caller.
+	}
 
-		cmdOpts = append(cmdOpts, tmux.WithDryRunMode(true))
+	if a.cfg == nil || !a.cfg.UseControlMode() {
+		return cmd, nil
 	}
 
-	cmd, err := tmux.NewRunner(cmdOpts...)
+	a.control, err = tmux.NewControlRunner(cmd, tmux.WithControlSession(a.controlSession()))
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Wrapping is done by caller.
+	}
+
+	return a.control, nil
+}
+
+// controlSession returns the name of the session to attach the control mode
+// client to, which is the configured session, or the first session if the
+// configuration has a list of sessions.
+func (a *App) controlSession() string {
+	if len(a.cfg.Sessions) != 0 {
+		return a.cfg.Sessions[0].Name
+	}
+
+	return a.cfg.Session.Name
+}
+
+// newFakeTmux returns an in-memory tmux server to run tmux commands against in
+// dry-run mode, so that sessions, windows and panes get the IDs, indexes and
+// sizes tmux would give them.
+func (a *App) newFakeTmux() (tmux.Runner, error) {
+	srvOpts := []faketmux.ServerOption{
+		faketmux.WithLogger(a.logger),
+		faketmux.WithRedaction(a.redaction),
+		faketmux.WithDryRunMode(true),
+	}
+
+	if a.cfg != nil && a.cfg.Tmux != "" {
+		srvOpts = append(srvOpts, faketmux.WithTmux(a.cfg.Tmux))
+	}
+
+	if a.cfg != nil && len(a.cfg.TmuxOptions) > 0 {
+		srvOpts = append(srvOpts, faketmux.WithTmuxOptions(a.cfg.TmuxOptions...))
+	}
+
+	srv, err := faketmux.NewServer(srvOpts...)
 	if err != nil {
 		return nil, err //nolint:wrapcheck // Wrapping is done by caller.
 	}
 
-	return cmd, nil
+	return srv, nil
+}
+
+// newRecordingTmux returns a runner that records the tmux commands run by
+// runner to the transcript file given with the --record option.
+func (a *App) newRecordingTmux(runner tmux.Runner) (tmux.Runner, error) {
+	f, err := os.OpenFile(a.opts.Record, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("creating transcript file: %w", err)
+	}
+
+	a.transcript = f
+
+	rec, err := tmux.NewRecordingRunner(runner, f, tmux.WithRecordingRedaction(a.redaction))
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Wrapping is done by caller.
+	}
+
+	a.logger.Info("recording tmux commands", "path", a.opts.Record)
+
+	return rec, nil
+}
+
+// newReplayTmux returns a runner that replays the tmux commands from the
+// transcript file given with the --replay option.
+func (a *App) newReplayTmux() (tmux.Runner, error) {
+	base, err := tmux.NewRunner(
+		tmux.WithLogger(a.logger),
+		tmux.WithRedaction(a.redaction),
+		tmux.WithDryRunMode(a.opts.DryRun),
+	)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Wrapping is done by caller.
+	}
+
+	f, err := os.Open(a.opts.Replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening transcript file: %w", err)
+	}
+	defer f.Close()
+
+	a.replay, err = tmux.NewReplayRunner(base, f)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Wrapping is done by caller.
+	}
+
+	a.logger.Info("REPLAY MODE ENABLED: no tmux commands will be executed and output is replayed", "path", a.opts.Replay)
+
+	return a.replay, nil
+}
+
+// closeTmux closes the control mode connection and the transcript file of the
+// tmux runner, if any.
+//
+// Returns an error if the transcript file cannot be closed, or if calls in a
+// replayed transcript were not made.
+func (a *App) closeTmux() error {
+	if a.control != nil {
+		if err := a.control.Close(); err != nil {
+			a.logger.Debug("closing tmux control mode connection failed", "err", err)
+		}
+	}
+
+	if a.transcript != nil {
+		if err := a.transcript.Close(); err != nil {
+			return fmt.Errorf("closing transcript file: %w", err)
+		}
+	}
+
+	if a.replay != nil {
+		return a.replay.Close() //nolint:wrapcheck // Error is descriptive enough.
+	}
+
+	return nil
 }
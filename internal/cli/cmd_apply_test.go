@@ -3,7 +3,9 @@ package cli_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -40,7 +42,11 @@ func TestApp_Run_Apply(t *testing.T) {
 	// included in the golden files.
 	alwaysArgs := []string{"--debug"}
 
-	runner, err := tmux.NewRunner()
+	// Commands mocked with a nil return are run by the wrapped runner, which
+	// fails like tmux does when a session with the same name already exists.
+	runner, err := tmux.NewRunner(tmux.WithOSCommandRunner(func(context.Context, string, ...string) ([]byte, error) {
+		return []byte("duplicate session: my_project\n"), errors.New("exit status 1")
+	}))
 	require.NoError(t, err)
 
 	stubs := loadTmuxStubs(t)
@@ -63,105 +69,47 @@ func TestApp_Run_Apply(t *testing.T) {
 				stub = stubs["NewSession"]
 				newSess := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(listSess)
 
-				// App creates the first window named "code".
-				stub = stubs["NewWindowCode"]
+				// App creates the first window named "code" and runs the on_any and
+				// on_window hook commands followed by Neovim in the same tmux
+				// invocation.
+				stub = batchStubs(stubs, "NewWindowCode", "SendKeysCodeOnAny", "SendKeysCodeOnWindow", "SendKeysCode")
 				newWinCode := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newSess)
 
-				// App runs on_any hook command in the code window.
-				stub = stubs["SendKeysCodeOnAny"]
-				codeOnAny := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinCode)
-
-				// App runs on_window hook command in the code window.
-				stub = stubs["SendKeysCodeOnWindow"]
-				codeOnWindow := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(codeOnAny)
-
-				// App starts Neovim in the "code" window.
-				stub = stubs["SendKeysCode"]
-				sendKeysCode := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(codeOnWindow)
-
 				// App creates a horizontal pane in the "code" window.
 				stub = stubs["NewPaneCode"]
 				newPaneCode := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinCode)
 
-				// App runs on_any hook command in the code pane.
-				stub = stubs["SendKeysCodePaneOnAny"]
-				codePaneOnAny := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newPaneCode)
-
-				// App runs on_pane hook command in the code pane.
-				stub = stubs["SendKeysCodePaneOnPane"]
-				codePaneOnPane := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(codePaneOnAny)
+				// App runs the on_any and on_pane hook commands followed by the
+				// automatic test run script in the code pane.
+				stub = batchStubs(stubs, "SendKeysCodePaneOnAny", "SendKeysCodePaneOnPane", "SendKeysCodePane")
+				r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newPaneCode)
 
-				// App starts automatic test run script in the code pane.
-				stub = stubs["SendKeysCodePane"]
-				r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(codePaneOnPane)
-
-				// App creates the second window named "shell".
-				stub = stubs["NewWindowShell"]
+				// App creates the second window named "shell" and runs the hook
+				// commands followed by `git status`.
+				stub = batchStubs(stubs, "NewWindowShell", "SendKeysShellOnAny", "SendKeysShellOnWindow", "SendKeysShell")
 				newWinShell := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinCode)
 
-				// App runs on_any hook command in the shell window.
-				stub = stubs["SendKeysShellOnAny"]
-				shellOnAny := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinShell)
-
-				// App runs on_window hook command in the shell window.
-				stub = stubs["SendKeysShellOnWindow"]
-				shellOnWindow := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(shellOnAny)
-
-				// App runs `git status` in the shell window.
-				stub = stubs["SendKeysShell"]
-				r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(shellOnWindow)
-
-				// App creates the third window named "server".
-				stub = stubs["NewWindowServer"]
+				// App creates the third window named "server" and runs the hook
+				// commands followed by the development server script.
+				stub = batchStubs(stubs, "NewWindowServer", "SendKeysServerOnAny", "SendKeysServerOnWindow", "SendKeysServer")
 				newWinServer := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinShell)
 
-				// App runs on_any hook command in the server window.
-				stub = stubs["SendKeysServerOnAny"]
-				serverOnAny := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinServer)
-
-				// App runs on_window hook command in the server window.
-				stub = stubs["SendKeysServerOnWindow"]
-				serverOnWindow := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(serverOnAny)
-
-				// App starts development server script in the server window.
-				stub = stubs["SendKeysServer"]
-				r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(serverOnWindow)
-
-				// App creates the fourth window named "prod_logs".
-				stub = stubs["NewWindowProdLogs"]
-				newWinProdLogs := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinServer)
-
-				// App runs on_any hook command in the prod_logs window.
-				stub = stubs["SendKeysProdLogsOnAny"]
-				prodLogsOnAny := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinProdLogs)
+				// App creates the fourth window named "prod_logs" and runs the hook
+				// commands followed by the commands to tail the production logs.
+				stub = batchStubs(stubs,
+					"NewWindowProdLogs", "SendKeysProdLogsOnAny", "SendKeysProdLogsOnWindow",
+					"SendKeysProdLogsSSH", "SendKeysProdLogsCdLogs", "SendKeysProdLogsTail",
+				)
+				r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinServer)
 
-				// App runs on_window hook command in the prod_logs window.
-				stub = stubs["SendKeysProdLogsOnWindow"]
-				prodLogsOnWindow := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(prodLogsOnAny)
-
-				// App connects to production host via SSH in the prod_logs window.
-				stub = stubs["SendKeysProdLogsSSH"]
-				prodLogsSSH := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(prodLogsOnWindow)
-
-				// App navigates to the logs directory in the prod_logs window.
-				stub = stubs["SendKeysProdLogsCdLogs"]
-				prodLogsCdLogs := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(prodLogsSSH)
-
-				// App tails the application log file in the prod_logs window.
-				stub = stubs["SendKeysProdLogsTail"]
-				r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(prodLogsCdLogs)
-
-				// App selects the code window.
-				stub = stubs["SelectWindowCode"]
-				selectWinCode := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(sendKeysCode)
-
-				// App determines the pane base index to select the initial pane.
-				stub = stubs["PaneBaseIndexOpt"]
-				paneBaseIndexOpt := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(selectWinCode)
+				// App selects the code window and determines the pane base index to
+				// select the initial pane.
+				stub = batchStubs(stubs, "SelectWindowCode", "PaneBaseIndexOpt")
+				selectWinCode := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinCode)
 
 				// App selects the initial code pane running Neovim.
 				stub = stubs["SelectPaneCode"]
-				selectPaneCode := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(paneBaseIndexOpt)
+				selectPaneCode := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(selectWinCode)
 
 				// Finally, App attaches the new session.
 				stub = stubs["AttachSession"]
@@ -183,6 +131,78 @@ func TestApp_Run_Apply(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			"session exists without attaching",
+			[]string{"--no-attach", "--output", "json", "-c", filepath.Join(dataDir, "tmpl.yaml")},
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				// App gets the current sessions to check if the session already exists.
+				stub := stubs["ListSessionsExists"]
+				listSess := r.On("Run", stub.Args).Return(stub.Output(), nil).Once()
+
+				// App loads the windows and panes of the existing session for the output.
+				stub = stubs["ListWindowsExists"]
+				listWins := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(listSess)
+
+				for _, name := range []string{"ListPanesCode", "ListPanesShell"} {
+					stub = stubs[name]
+					r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(listWins)
+				}
+
+				// App does not attach the session.
+			},
+			nil,
+		},
+		{
+			"unknown output format",
+			[]string{"--output", "yaml"},
+			nil,
+			testutils.RequireErrorContains("unknown output format: yaml"),
+		},
+		{
+			"sync existing session",
+			[]string{"--sync", "-c", filepath.Join(dataDir, "tmpl.yaml")},
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				// App gets the current sessions to check if the session already exists.
+				stub := stubs["ListSessionsExists"]
+				listSess := r.On("Run", stub.Args).Return(stub.Output(), nil).Once()
+
+				// Since the session already exists, App gets its current windows.
+				stub = stubs["ListWindowsExists"]
+				listWins := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(listSess)
+
+				// App gets the current panes of the existing code window.
+				stub = stubs["ListPanesCode"]
+				listPanesCode := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(listWins)
+
+				// App creates the missing pane in the code window by splitting its
+				// initial pane, and runs its commands.
+				stub = stubs["NewPaneCodeSync"]
+				newPaneCode := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(listPanesCode)
+
+				stub = batchStubs(stubs, "SendKeysCodePaneOnAny", "SendKeysCodePaneOnPane", "SendKeysCodePane")
+				r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newPaneCode)
+
+				// App gets the current panes of the existing shell window.
+				stub = stubs["ListPanesShell"]
+				listPanesShell := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newPaneCode)
+
+				// App creates the missing server window and runs its commands.
+				stub = batchStubs(stubs, "NewWindowServer", "SendKeysServerOnAny", "SendKeysServerOnWindow", "SendKeysServer")
+				newWinServer := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(listPanesShell)
+
+				// App creates the missing prod_logs window and runs its commands.
+				stub = batchStubs(stubs,
+					"NewWindowProdLogs", "SendKeysProdLogsOnAny", "SendKeysProdLogsOnWindow",
+					"SendKeysProdLogsSSH", "SendKeysProdLogsCdLogs", "SendKeysProdLogsTail",
+				)
+				newWinProdLogs := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinServer)
+
+				// Finally, App attaches the synchronized session.
+				stub = stubs["AttachSession"]
+				r.On("Execve", stub.Args).Return(nil).Once().NotBefore(newWinProdLogs)
+			},
+			nil,
+		},
 		{
 			"new session fails",
 			[]string{"-c", filepath.Join(dataDir, "tmpl.yaml")},
@@ -198,6 +218,36 @@ func TestApp_Run_Apply(t *testing.T) {
 			},
 			testutils.RequireErrorContains("running new-session command: exit status 1"),
 		},
+		{
+			"session created by other process",
+			[]string{"-c", filepath.Join(dataDir, "tmpl.yaml")},
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				// App gets the current sessions to check if the session already exists.
+				stub := stubs["ListSessions"]
+				listSess := r.On("Run", stub.Args).Return(stub.Output(), nil).Once()
+
+				// App creates a new session but it has been created by another
+				// process in the meantime.
+				stub = stubs["NewSession"]
+				r.On("Run", stub.Args).Return(nil).Once().NotBefore(listSess)
+			},
+			testutils.RequireErrorIs(tmux.ErrSessionExists),
+		},
+		{
+			"session size from options",
+			[]string{"-c", filepath.Join(dataDir, "tmpl.yaml"), "--width", "200", "-y", "50"},
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				// App gets the current sessions to check if the session already exists.
+				stub := stubs["ListSessions"]
+				listSess := r.On("Run", stub.Args).Return(stub.Output(), nil).Once()
+
+				// App creates a new session with the provided size but it fails.
+				stub = stubs["NewSessionSized"]
+				r.On("Run", stub.Args).
+					Return([]byte("failed to connect to server: Connection refused"), errors.New("exit status 1")).Once().NotBefore(listSess)
+			},
+			testutils.RequireErrorContains("running new-session command: exit status 1"),
+		},
 		{
 			"new window fails",
 			[]string{"-c", filepath.Join(dataDir, "tmpl.yaml")},
@@ -211,7 +261,7 @@ func TestApp_Run_Apply(t *testing.T) {
 				newSess := r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(listSess)
 
 				// App creates the first window named "code" but it fails.
-				stub = stubs["NewWindowCode"]
+				stub = batchStubs(stubs, "NewWindowCode", "SendKeysCodeOnAny", "SendKeysCodeOnWindow", "SendKeysCode")
 				newWinCode := r.On("Run", stub.Args).
 					Return([]byte("failed to connect to server: Connection refused"), errors.New("exit status 1")).Once().NotBefore(newSess)
 
@@ -219,7 +269,7 @@ func TestApp_Run_Apply(t *testing.T) {
 				stub = stubs["CloseSession"]
 				r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newWinCode)
 			},
-			testutils.RequireErrorContains("running new-window command: exit status 1"),
+			testutils.RequireErrorContains("running new-window, send-keys commands: exit status 1"),
 		},
 		{
 			"broken config file",
@@ -283,6 +333,223 @@ func TestApp_Run_Apply(t *testing.T) {
 	}
 }
 
+func TestApp_Run_Apply_DryRun(t *testing.T) {
+	stubHome := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(stubHome, "project", "scripts"), 0o744))
+
+	t.Setenv("NO_COLOR", "1")
+
+	// Stub HOME and current working directory for consistent test results.
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", stubHome)
+
+	// Stub the following environment variables used to determine if the app is
+	// running in a tmux session for consistent test results.
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv("TMUX_TMPDIR", stubHome)
+
+	dataDir, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	out := new(bytes.Buffer)
+
+	// No tmux runner is provided, so the app runs the tmux commands against a
+	// simulated tmux server in dry-run mode.
+	app, err := cli.NewApp(
+		cli.WithOutputWriter(out),
+		cli.WithSlogAttrReplacer(testutils.NewSlogStabilizer(t)),
+	)
+	require.NoError(t, err)
+
+	err = app.Run(context.Background(), "--dry-run", "-x", "120", "-y", "40", "-c", filepath.Join(dataDir, "tmpl.yaml"))
+	require.NoError(t, err)
+
+	testutils.NewGolden(t).RequireMatch(testutils.Stabilize(t, out.Bytes()))
+}
+
+func TestApp_Run_Apply_DryRun_Plan(t *testing.T) {
+	stubHome := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(stubHome, "project", "scripts"), 0o744))
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", stubHome)
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv("TMUX_TMPDIR", stubHome)
+
+	dataDir, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	tt := []struct {
+		name      string
+		args      []string
+		assertErr testutils.ErrorAssertion
+	}{
+		{"tree", []string{"--dry-run", "--format", "tree"}, nil},
+		{"json", []string{"--dry-run", "--format", "json"}, nil},
+		{"script", []string{"--dry-run", "--format", "script"}, nil},
+		{"secrets", []string{"--dry-run", "--format", "tree", "-c", filepath.Join(dataDir, "tmpl-secrets.yaml")}, nil},
+		{"no dry-run", []string{"--format", "tree"}, testutils.RequireErrorContains("--format option requires --dry-run")},
+		{"unknown format", []string{"--dry-run", "--format", "yaml"}, testutils.RequireErrorContains("unknown plan format: yaml")},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+
+			app, err := cli.NewApp(
+				cli.WithOutputWriter(out),
+				cli.WithSlogAttrReplacer(testutils.NewSlogStabilizer(t)),
+			)
+			require.NoError(t, err)
+
+			// Logs are not suppressed with --quiet, as they must be left out of
+			// the plan when a plan format is set.
+			args := append([]string{"-x", "120", "-y", "40", "-c", filepath.Join(dataDir, "tmpl.yaml")}, tc.args...)
+
+			err = app.Run(context.Background(), args...)
+
+			if tc.assertErr != nil {
+				require.Error(t, err)
+				tc.assertErr(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			testutils.NewGolden(t).RequireMatch(testutils.Stabilize(t, out.Bytes()))
+		})
+	}
+}
+
+func TestApp_Run_Apply_DryRun_OutputJSON(t *testing.T) {
+	stubHome := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(stubHome, "project", "scripts"), 0o744))
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", stubHome)
+	t.Setenv("TMUX_TMPDIR", stubHome)
+
+	dataDir, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	out := new(bytes.Buffer)
+
+	app, err := cli.NewApp(
+		cli.WithOutputWriter(out),
+		cli.WithSlogAttrReplacer(testutils.NewSlogStabilizer(t)),
+	)
+	require.NoError(t, err)
+
+	err = app.Run(context.Background(),
+		"--dry-run", "--no-attach", "--output", "json", "-x", "120", "-y", "40", "-c", filepath.Join(dataDir, "tmpl.yaml"),
+	)
+	require.NoError(t, err)
+
+	require.True(t, json.Valid(out.Bytes()), "expected output to only contain the JSON session details")
+
+	testutils.NewGolden(t).RequireMatch(testutils.Stabilize(t, out.Bytes()))
+}
+
+func TestApp_Run_Apply_RecordReplay(t *testing.T) {
+	stubHome := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(stubHome, "project", "scripts"), 0o744))
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", stubHome)
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv("TMUX_TMPDIR", stubHome)
+
+	dataDir, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	cfgPath := filepath.Join(dataDir, "tmpl.yaml")
+	transcript := filepath.Join(t.TempDir(), "transcript.jsonl")
+
+	run := func(t *testing.T, args ...string) error {
+		t.Helper()
+
+		app, err := cli.NewApp(cli.WithOutputWriter(new(bytes.Buffer)))
+		require.NoError(t, err)
+
+		return app.Run(context.Background(), append([]string{"--quiet", "--dry-run", "-c", cfgPath}, args...)...)
+	}
+
+	require.NoError(t, run(t, "-x", "120", "-y", "40", "--record", transcript))
+
+	data := testutils.ReadFile(t, transcript)
+	require.Contains(t, string(data), `{"method":"run","args":["new-session",`)
+	require.Contains(t, string(data), `{"method":"execve","args":["attach-session",`)
+
+	t.Run("replay", func(t *testing.T) {
+		require.NoError(t, run(t, "-x", "120", "-y", "40", "--replay", transcript))
+	})
+
+	t.Run("replay diverged", func(t *testing.T) {
+		err := run(t, "-x", "100", "-y", "40", "--replay", transcript)
+		require.ErrorIs(t, err, tmux.ErrReplayDiverged)
+	})
+
+	t.Run("replay incomplete", func(t *testing.T) {
+		err := run(t, "-x", "120", "-y", "40", "--no-attach", "--replay", transcript)
+		require.ErrorIs(t, err, tmux.ErrReplayDiverged)
+	})
+
+	t.Run("record and replay", func(t *testing.T) {
+		err := run(t, "--record", transcript, "--replay", transcript)
+		require.ErrorContains(t, err, "--record and --replay options cannot be used together")
+	})
+}
+
+func TestApp_Run_Apply_Replay_Hooks(t *testing.T) {
+	stubHome := t.TempDir()
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", stubHome)
+	t.Setenv("TMUX_TMPDIR", stubHome)
+
+	marker := filepath.Join(stubHome, "hook-ran")
+	cfgPath := filepath.Join(stubHome, ".tmpl.yaml")
+	testutils.WriteFile(t, []byte(fmt.Sprintf(`---
+session:
+  name: my_project
+  env_from:
+    APP_TOKEN: "touch %[1]s && echo secret"
+  before_start:
+    - command: "touch %[1]s"
+  after_start:
+    - command: "touch %[1]s"
+  windows:
+    - name: code
+`, marker)), cfgPath)
+
+	transcript := filepath.Join(t.TempDir(), "transcript.jsonl")
+
+	run := func(t *testing.T, args ...string) error {
+		t.Helper()
+
+		app, err := cli.NewApp(cli.WithOutputWriter(new(bytes.Buffer)))
+		require.NoError(t, err)
+
+		return app.Run(context.Background(), append([]string{"--quiet", "--no-attach", "-c", cfgPath}, args...)...)
+	}
+
+	require.NoError(t, run(t, "--dry-run", "--record", transcript))
+	require.NoError(t, run(t, "--replay", transcript))
+
+	require.NoFileExists(t, marker, "expected hooks and env_from commands not to run when replaying")
+}
+
 // loadTmuxStubs loads the expected tmux command arguments and stub output from
 // the tmux-stubs.yaml file in the testdata directory.
 func loadTmuxStubs(t *testing.T) map[string]tmuxStub {
@@ -308,3 +575,34 @@ type tmuxStub struct {
 func (s tmuxStub) Output() []byte {
 	return []byte(s.OutputString)
 }
+
+// batchStubs combines the named stubs into a stub for a single tmux invocation
+// running the stubbed commands in order, as done by the tmux package when
+// commands are batched.
+//
+// Like the tmux package, a display-message command printing a marker line is
+// expected after each stubbed command with output that is followed by other
+// commands, and the marker line is included in the combined output.
+func batchStubs(stubs map[string]tmuxStub, names ...string) tmuxStub {
+	var res tmuxStub
+
+	for i, name := range names {
+		stub := stubs[name]
+
+		if i > 0 {
+			res.Args = append(res.Args, ";")
+		}
+
+		res.Args = append(res.Args, stub.Args...)
+
+		if stub.OutputString == "" || i == len(names)-1 {
+			res.OutputString += stub.OutputString
+			continue
+		}
+
+		res.Args = append(res.Args, ";", "display-message", "-p", "tmpl:batch:next")
+		res.OutputString += stub.OutputString + "\ntmpl:batch:next\n"
+	}
+
+	return res
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/internal/env"
+)
+
+// runCapture creates a configuration from a running tmux session and writes it
+// to a new configuration file.
+func (a *App) runCapture(ctx context.Context) error {
+	a.initLogger()
+
+	dst := ""
+	if len(a.opts.args) != 0 {
+		dst = a.opts.args[0]
+	}
+
+	dst, err := a.configDestination(dst)
+	if err != nil {
+		return err
+	}
+
+	if dst == "" {
+		return nil
+	}
+
+	name := a.opts.SessionName
+	if name == "" {
+		wd, err := env.Getwd()
+		if err != nil {
+			return fmt.Errorf("getting current working directory: %w", err)
+		}
+
+		name = cleanSessionName(filepath.Base(wd))
+	}
+
+	runner, err := a.newTmux()
+	if err != nil {
+		return fmt.Errorf("creating tmux runner: %w", err)
+	}
+
+	cfg, err := config.FromSession(ctx, runner, name)
+	if err != nil {
+		return fmt.Errorf("capturing session: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("validating captured configuration: %w", err)
+	}
+
+	cfgFile, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("creating configuration file: %w", err)
+	}
+	defer cfgFile.Close()
+
+	if err := cfg.Encode(cfgFile); err != nil {
+		return fmt.Errorf("writing configuration file: %w", err)
+	}
+
+	a.logger.Info("session captured",
+		"session", name, "path", cfgFile.Name(), "windows", cfg.NumWindows(), "panes", cfg.NumPanes(),
+	)
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/internal/cli"
+	"github.com/michenriksen/tmpl/internal/mock"
+	"github.com/michenriksen/tmpl/internal/testutils"
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+func TestApp_Run_Capture(t *testing.T) {
+	stubHome := t.TempDir()
+	projectDir := filepath.Join(stubHome, "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(projectDir, "scripts"), 0o744))
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", projectDir)
+
+	runner, err := tmux.NewRunner()
+	require.NoError(t, err)
+
+	stubs := loadTmuxStubs(t)
+
+	existsDir := filepath.Join(stubHome, "exists")
+	testutils.WriteFile(t, []byte("---\nsession:\n  name: test\n"), existsDir, config.ConfigFileName())
+
+	firstActiveDir := filepath.Join(stubHome, "first-active")
+	require.NoError(t, os.MkdirAll(firstActiveDir, 0o744))
+
+	tt := []struct {
+		name        string
+		args        []string
+		wantCfgPath string
+		setupMocks  func(*testing.T, *mock.TmuxRunner)
+		assertErr   testutils.ErrorAssertion
+	}{
+		{
+			"capture session",
+			[]string{"capture", "-s", "my_project"},
+			filepath.Join(projectDir, config.ConfigFileName()),
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				r.On("Run", stubs["ListSessionsExists"].Args).Return([]byte(fmt.Sprintf(
+					"session_id:$1,session_name:my_project,session_path:%s", projectDir,
+				)), nil).Once()
+
+				r.On("Run", stubs["ListWindowsExists"].Args).Return([]byte(
+					"window_id:@5,window_name:code,window_index:1,window_layout:c3e1\\,80x24\\,0\\,0{40x24\\,0\\,0\\,1\\,39x24\\,41\\,0\\,2},"+
+						"window_active:0\n"+
+						"window_id:@6,window_name:shell,window_index:2,window_layout:b25f\\,80x24\\,0\\,0\\,3,window_active:1",
+				), nil).Once()
+
+				r.On("Run", stubs["ListPanesCode"].Args).Return([]byte(fmt.Sprintf(
+					"pane_id:%%1,pane_index:0,pane_current_path:%[1]s,pane_active:0\n"+
+						"pane_id:%%2,pane_index:1,pane_current_path:%[1]s/scripts,pane_active:1",
+					projectDir,
+				)), nil).Once()
+
+				r.On("Run", stubs["ListPanesShell"].Args).Return([]byte(fmt.Sprintf(
+					"pane_id:%%3,pane_index:0,pane_current_path:%s,pane_active:1", stubHome,
+				)), nil).Once()
+			},
+			nil,
+		},
+		{
+			"first window active",
+			[]string{"capture", "-s", "my_project", firstActiveDir},
+			filepath.Join(firstActiveDir, config.ConfigFileName()),
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				r.On("Run", stubs["ListSessionsExists"].Args).Return([]byte(fmt.Sprintf(
+					"session_id:$1,session_name:my_project,session_path:%s", projectDir,
+				)), nil).Once()
+
+				r.On("Run", stubs["ListWindowsExists"].Args).Return([]byte(
+					"window_id:@5,window_name:code,window_index:1,window_active:1\n"+
+						"window_id:@6,window_name:shell,window_index:2,window_active:0",
+				), nil).Once()
+
+				r.On("Run", stubs["ListPanesCode"].Args).Return([]byte(fmt.Sprintf(
+					"pane_id:%%1,pane_index:0,pane_current_path:%s,pane_active:1", projectDir,
+				)), nil).Once()
+
+				r.On("Run", stubs["ListPanesShell"].Args).Return([]byte(fmt.Sprintf(
+					"pane_id:%%2,pane_index:0,pane_current_path:%s,pane_active:1", projectDir,
+				)), nil).Once()
+			},
+			nil,
+		},
+		{
+			"session not found",
+			[]string{"capture", "-s", "my_project", filepath.Join(stubHome, "other.yaml")},
+			"",
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				stub := stubs["ListSessions"]
+				r.On("Run", stub.Args).Return(stub.Output(), nil).Once()
+			},
+			testutils.RequireErrorIs(config.ErrSessionNotFound),
+		},
+		{
+			"file exists",
+			[]string{"capture", "-s", "my_project", existsDir},
+			"",
+			nil,
+			nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			mockRunner := mock.NewTmuxRunner(t, runner)
+
+			if tc.setupMocks != nil {
+				tc.setupMocks(t, mockRunner)
+			}
+
+			app, err := cli.NewApp(
+				cli.WithOutputWriter(out),
+				cli.WithTmux(mockRunner),
+				cli.WithSlogAttrReplacer(testutils.NewSlogStabilizer(t)),
+			)
+			require.NoError(t, err)
+
+			err = app.Run(context.Background(), tc.args...)
+
+			if tc.assertErr != nil {
+				require.Error(t, err)
+				tc.assertErr(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if !mockRunner.AssertExpectations(t) {
+				t.FailNow()
+			}
+
+			if tc.wantCfgPath != "" {
+				data := testutils.ReadFile(t, tc.wantCfgPath)
+
+				cfg, err := config.FromFile(tc.wantCfgPath)
+				require.NoError(t, err, "expected captured configuration to be loadable")
+				require.NoError(t, cfg.Validate(), "expected captured configuration to be valid")
+
+				out.WriteString("\n")
+				out.Write(data)
+			}
+
+			testutils.NewGolden(t).RequireMatch(testutils.Stabilize(t, out.Bytes()))
+		})
+	}
+}
@@ -43,6 +43,41 @@ func TestApp_Run_Check(t *testing.T) {
 		stubHome, ".tmpl.invalid.yaml",
 	)
 
+	testutils.WriteFile(t,
+		testutils.ReadFile(t, "testdata", "tmpl-vars.yaml"),
+		stubHome, ".tmpl.vars.yaml",
+	)
+
+	testutils.WriteFile(t,
+		testutils.ReadFile(t, "testdata", "tmpl-profiles.yaml"),
+		stubHome, ".tmpl.profiles.yaml",
+	)
+
+	testutils.WriteFile(t,
+		[]byte("---\nsession:\n  windows:\n    - name: code\n      panes:\n        - when:\n            env: TMPL_TEST_UNSET\n    - name: db\n      when:\n        file_exists: docker-compose.yml\n"),
+		stubHome, ".tmpl.when.yaml",
+	)
+
+	testutils.WriteFile(t,
+		[]byte("---\nextends: ../.tmpl.yaml\nsession:\n  name: extended\n"),
+		stubHome, "extended", config.ConfigFileName(),
+	)
+
+	testutils.WriteFile(t,
+		[]byte("---\nsession:\n  env:\n    APP_ENV: local\n"),
+		stubHome, "extended", config.LocalConfigPath(config.ConfigFileName()),
+	)
+
+	testutils.WriteFile(t,
+		[]byte("---\nsession:\n  windows:\n    - name: code\n      path: ~/does-not-exist\n"),
+		stubHome, "invalid-extended", "base.yaml",
+	)
+
+	testutils.WriteFile(t,
+		[]byte("---\nextends: base.yaml\nsession:\n  name: invalid_extended\n  windows:\n    - name: code\n      command: nvim .\n"),
+		stubHome, "invalid-extended", config.ConfigFileName(),
+	)
+
 	tt := []struct {
 		name      string
 		args      []string
@@ -68,6 +103,46 @@ func TestApp_Run_Check(t *testing.T) {
 			[]string{"check", "-c", filepath.Join(stubHome, ".tmpl.invalid.yaml")},
 			testutils.RequireErrorIs(cli.ErrInvalidConfig),
 		},
+		{
+			"extended config with local override",
+			[]string{"check", "-c", filepath.Join(stubHome, "extended", config.ConfigFileName())},
+			nil,
+		},
+		{
+			"invalid extended config",
+			[]string{"check", "-c", filepath.Join(stubHome, "invalid-extended", config.ConfigFileName())},
+			testutils.RequireErrorIs(cli.ErrInvalidConfig),
+		},
+		{
+			"undefined variable",
+			[]string{"check", "-c", filepath.Join(stubHome, ".tmpl.vars.yaml")},
+			testutils.RequireErrorIs(cli.ErrInvalidConfig),
+		},
+		{
+			"variables set with flags",
+			[]string{"check", "--set", "port=8080", "--set", "service=payments", "-c", filepath.Join(stubHome, ".tmpl.vars.yaml")},
+			nil,
+		},
+		{
+			"invalid variable flag",
+			[]string{"check", "--set", "port", "-c", filepath.Join(stubHome, ".tmpl.vars.yaml")},
+			testutils.RequireErrorContains(`invalid variable "port": must be in the form name=value`),
+		},
+		{
+			"skipped windows and panes",
+			[]string{"check", "-c", filepath.Join(stubHome, ".tmpl.when.yaml")},
+			nil,
+		},
+		{
+			"profile flag",
+			[]string{"check", "--profile", "full", "-c", filepath.Join(stubHome, ".tmpl.profiles.yaml")},
+			nil,
+		},
+		{
+			"unknown profile",
+			[]string{"check", "-p", "minimal", "-c", filepath.Join(stubHome, ".tmpl.profiles.yaml")},
+			testutils.RequireErrorIs(config.ErrUnknownProfile),
+		},
 	}
 
 	for _, tc := range tt {
@@ -93,3 +168,28 @@ func TestApp_Run_Check(t *testing.T) {
 		})
 	}
 }
+
+func TestApp_Run_Check_ProfileEnv(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("TMPL_PROFILE", "full")
+
+	stubHome := t.TempDir()
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", stubHome)
+
+	testutils.WriteFile(t,
+		testutils.ReadFile(t, "testdata", "tmpl-profiles.yaml"),
+		stubHome, config.ConfigFileName(),
+	)
+
+	out := new(bytes.Buffer)
+
+	app, err := cli.NewApp(
+		cli.WithOutputWriter(out),
+		cli.WithSlogAttrReplacer(testutils.NewSlogStabilizer(t)),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, app.Run(context.Background(), "check"))
+	require.Contains(t, out.String(), "configuration profile applied profile=full")
+}
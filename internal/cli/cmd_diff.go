@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/michenriksen/tmpl/config"
+)
+
+// runDiff loads the configuration, compares it with the running tmux session
+// and writes the differences to the output writer in the configured format.
+func (a *App) runDiff(ctx context.Context) error {
+	a.initLogger()
+
+	if err := a.loadConfig(); err != nil {
+		return fmt.Errorf("loading configuration: %w", err)
+	}
+
+	runner, err := a.newTmux()
+	if err != nil {
+		return fmt.Errorf("creating tmux runner: %w", err)
+	}
+
+	diff, err := config.Diff(ctx, a.cfg, runner)
+	if err != nil {
+		return fmt.Errorf("comparing configuration with session: %w", err)
+	}
+
+	if a.opts.Format == formatJSON {
+		enc := json.NewEncoder(a.out)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(diff); err != nil {
+			return fmt.Errorf("encoding differences: %w", err)
+		}
+
+		return nil
+	}
+
+	writeDiffTree(a.out, diff)
+
+	return nil
+}
+
+// writeDiffTree writes a human-readable tree representation of the provided
+// session diff to w.
+func writeDiffTree(w io.Writer, diff *config.SessionDiff) {
+	header := "session " + diff.Name
+	if !diff.Exists {
+		header += " (not running)"
+	}
+
+	fmt.Fprintln(w, header)
+
+	for i, wDiff := range diff.Windows {
+		last := i == len(diff.Windows)-1
+		fmt.Fprintln(w, treeBranch(last)+diffLabel("window "+wDiff.Name, wDiff.Status))
+
+		indent := treeIndent(last)
+		items := len(wDiff.Changes) + len(wDiff.Panes)
+		n := 0
+
+		for _, c := range wDiff.Changes {
+			n++
+			fmt.Fprintln(w, indent+treeBranch(n == items)+fieldDiffLabel(c))
+		}
+
+		for _, pDiff := range wDiff.Panes {
+			n++
+			fmt.Fprintln(w, indent+treeBranch(n == items)+diffLabel(fmt.Sprintf("pane %d", pDiff.Position), pDiff.Status))
+
+			for j, c := range pDiff.Changes {
+				fmt.Fprintln(w, indent+treeIndent(n == items)+treeBranch(j == len(pDiff.Changes)-1)+fieldDiffLabel(c))
+			}
+		}
+	}
+
+	if !diff.HasChanges() {
+		fmt.Fprintln(w, "\nsession matches configuration")
+	}
+}
+
+func diffLabel(label string, status config.DiffStatus) string {
+	if status == config.DiffEqual {
+		return label
+	}
+
+	return fmt.Sprintf("%s (%s)", label, status)
+}
+
+func fieldDiffLabel(c config.FieldDiff) string {
+	return fmt.Sprintf("%s: want %q, got %q", c.Field, c.Want, c.Got)
+}
+
+func treeBranch(last bool) string {
+	if last {
+		return "└── "
+	}
+
+	return "├── "
+}
+
+func treeIndent(last bool) string {
+	if last {
+		return strings.Repeat(" ", 4)
+	}
+
+	return "│   "
+}
@@ -0,0 +1,108 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/internal/cli"
+	"github.com/michenriksen/tmpl/internal/mock"
+	"github.com/michenriksen/tmpl/internal/testutils"
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+func TestApp_Run_Diff(t *testing.T) {
+	stubHome := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(stubHome, "project", "scripts"), 0o744))
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", stubHome)
+
+	dataDir, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	runner, err := tmux.NewRunner()
+	require.NoError(t, err)
+
+	stubs := loadTmuxStubs(t)
+
+	existingSession := func(_ *testing.T, r *mock.TmuxRunner) {
+		for _, name := range []string{"ListSessionsExists", "ListWindowsExists", "ListPanesCode", "ListPanesShell"} {
+			stub := stubs[name]
+			r.On("Run", stub.Args).Return(stub.Output(), nil).Once()
+		}
+	}
+
+	tt := []struct {
+		name       string
+		args       []string
+		setupMocks func(*testing.T, *mock.TmuxRunner)
+		assertErr  testutils.ErrorAssertion
+	}{
+		{
+			"session not running",
+			[]string{"diff", "-c", filepath.Join(dataDir, "tmpl.yaml")},
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				stub := stubs["ListSessions"]
+				r.On("Run", stub.Args).Return(stub.Output(), nil).Once()
+			},
+			nil,
+		},
+		{
+			"session drift tree",
+			[]string{"diff", "-c", filepath.Join(dataDir, "tmpl.yaml")},
+			existingSession,
+			nil,
+		},
+		{
+			"session drift json",
+			[]string{"diff", "--format", "json", "-c", filepath.Join(dataDir, "tmpl.yaml")},
+			existingSession,
+			nil,
+		},
+		{
+			"unknown format",
+			[]string{"diff", "--format", "yaml"},
+			nil,
+			testutils.RequireErrorContains("unknown output format: yaml"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			mockRunner := mock.NewTmuxRunner(t, runner)
+
+			if tc.setupMocks != nil {
+				tc.setupMocks(t, mockRunner)
+			}
+
+			app, err := cli.NewApp(
+				cli.WithOutputWriter(out),
+				cli.WithTmux(mockRunner),
+				cli.WithSlogAttrReplacer(testutils.NewSlogStabilizer(t)),
+			)
+			require.NoError(t, err)
+
+			err = app.Run(context.Background(), tc.args...)
+
+			if tc.assertErr != nil {
+				require.Error(t, err)
+				tc.assertErr(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if !mockRunner.AssertExpectations(t) {
+				t.FailNow()
+			}
+
+			testutils.NewGolden(t).RequireMatch(testutils.Stabilize(t, out.Bytes()))
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// runExport loads the configuration and writes a shell script that creates
+// the session with tmux alone to a file, or to the output writer if no path is
+// given.
+//
+// The session is applied in dry-run mode against the same in-memory tmux server
+// as apply --dry-run (see [App.newFakeTmux]) to resolve its windows and panes,
+// so no tmux commands are run and no hooks or env_from commands are run. The
+// script starts with warnings about the hooks and env_from variables it leaves
+// out, and about secrets written to it, which are also logged when the script
+// is written to a file.
+func (a *App) runExport(ctx context.Context) error {
+	dst := ""
+	if len(a.opts.args) != 0 {
+		dst = a.opts.args[0]
+	}
+
+	// Logs are written to the output writer, so only warnings and errors are
+	// logged when the script is written to it.
+	if dst == "" {
+		a.opts.Quiet = true
+	}
+
+	a.initLogger()
+
+	if err := a.loadConfig(); err != nil {
+		return fmt.Errorf("loading configuration: %w", err)
+	}
+
+	runner, err := a.newFakeTmux()
+	if err != nil {
+		return fmt.Errorf("creating tmux runner: %w", err)
+	}
+
+	sess, err := config.Apply(ctx, a.cfg, runner)
+	if err != nil {
+		return fmt.Errorf("resolving configuration: %w", err)
+	}
+
+	opts := []tmux.ScriptOption{tmux.ScriptWithTmux(a.tmuxCommand()...)}
+
+	if a.cfg.ShouldAttach() {
+		opts = append(opts, tmux.ScriptWithAttach())
+	}
+
+	warnings, err := exportWarnings(sess, a.cfg.Session, a.redaction, opts)
+	if err != nil {
+		return err
+	}
+
+	opts = append(opts, tmux.ScriptWithWarnings(warnings...))
+
+	var w io.Writer = a.out
+
+	if dst != "" {
+		// The script can hold values of secret environment variables, so it
+		// is only readable by the owner.
+		f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o700)
+		if err != nil {
+			return fmt.Errorf("creating script file: %w", err)
+		}
+		defer f.Close()
+
+		w = f
+
+		for _, warning := range warnings {
+			a.logger.Warn(warning, "path", dst)
+		}
+	}
+
+	if err := tmux.WriteScript(w, sess, opts...); err != nil {
+		return fmt.Errorf("writing script: %w", err)
+	}
+
+	if dst != "" {
+		a.logger.Info("configuration exported",
+			"session", sess.Name(), "path", dst, "windows", sess.NumWindows(), "panes", sess.NumPanes(),
+		)
+	}
+
+	return nil
+}
+
+// exportWarnings returns warnings about the hooks and env_from variables of
+// the session configuration that are left out of the exported script, and
+// about secrets that are written to it.
+//
+// Secrets are found by writing the script with the provided options, with and
+// without redaction.
+func exportWarnings(sess *tmux.Session, sCfg config.SessionConfig, rd *tmux.Redaction, opts []tmux.ScriptOption) ([]string, error) { //nolint:revive // more readable in one line.
+	var warnings []string
+
+	if len(sCfg.BeforeStart) != 0 || len(sCfg.AfterStart) != 0 {
+		warnings = append(warnings, "before_start and after_start hooks are not run by the script")
+	}
+
+	if names := envFromNames(sCfg); len(names) != 0 {
+		warnings = append(warnings, "env_from commands are not run by the script; variables not set: "+strings.Join(names, ", "))
+	}
+
+	var plain, redacted bytes.Buffer
+
+	if err := tmux.WriteScript(&plain, sess, opts...); err != nil {
+		return nil, fmt.Errorf("writing script: %w", err)
+	}
+
+	if err := tmux.WriteScript(&redacted, sess, append(opts, tmux.ScriptWithRedaction(rd))...); err != nil {
+		return nil, fmt.Errorf("writing script: %w", err)
+	}
+
+	if !bytes.Equal(plain.Bytes(), redacted.Bytes()) {
+		warnings = append(warnings, "the script contains secret values; keep it private")
+	}
+
+	return warnings, nil
+}
+
+// envFromNames returns the sorted names of the environment variables loaded
+// from env_from commands in the session configuration and its windows and
+// panes.
+func envFromNames(sCfg config.SessionConfig) []string {
+	var names []string
+
+	addNames := func(envFrom map[string]config.EnvFromConfig) {
+		for name := range envFrom {
+			names = append(names, name)
+		}
+	}
+
+	var addPanes func(panes []config.PaneConfig)
+
+	addPanes = func(panes []config.PaneConfig) {
+		for _, pCfg := range panes {
+			addNames(pCfg.EnvFrom)
+			addPanes(pCfg.Panes)
+		}
+	}
+
+	addNames(sCfg.EnvFrom)
+
+	for _, wCfg := range sCfg.Windows {
+		addNames(wCfg.EnvFrom)
+		addPanes(wCfg.Panes)
+	}
+
+	slices.Sort(names)
+
+	return slices.Compact(names)
+}
@@ -0,0 +1,112 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/internal/cli"
+	"github.com/michenriksen/tmpl/internal/testutils"
+)
+
+func TestApp_Run_Export(t *testing.T) {
+	stubHome := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(stubHome, "project", "scripts"), 0o744))
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", stubHome)
+
+	dataDir, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	cfgPath := filepath.Join(dataDir, "tmpl.yaml")
+
+	tt := []struct {
+		name      string
+		args      []string
+		assertErr testutils.ErrorAssertion
+	}{
+		{"stdout", []string{"export", "-c", cfgPath}, nil},
+		{"format", []string{"export", "--format", "sh", "-c", cfgPath}, nil},
+		{"warnings", []string{"export", "-c", filepath.Join(dataDir, "tmpl-export.yaml")}, nil},
+		{
+			"unknown format",
+			[]string{"export", "--format", "zsh", "-c", cfgPath},
+			testutils.RequireErrorContains("unknown script format: zsh"),
+		},
+		{
+			"too many arguments",
+			[]string{"export", "-c", cfgPath, "a.sh", "b.sh"},
+			testutils.RequireErrorContains("unexpected argument: b.sh"),
+		},
+		{
+			"multiple sessions",
+			[]string{"export", "-c", filepath.Join(dataDir, "tmpl-sessions.yaml")},
+			testutils.RequireErrorContains("configuration has multiple sessions"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+
+			app, err := cli.NewApp(
+				cli.WithOutputWriter(out),
+				cli.WithSlogAttrReplacer(testutils.NewSlogStabilizer(t)),
+			)
+			require.NoError(t, err)
+
+			err = app.Run(context.Background(), tc.args...)
+
+			if tc.assertErr != nil {
+				require.Error(t, err)
+				tc.assertErr(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			testutils.NewGolden(t).RequireMatch(testutils.Stabilize(t, out.Bytes()))
+		})
+	}
+}
+
+func TestApp_Run_Export_File(t *testing.T) {
+	stubHome := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(stubHome, "project", "scripts"), 0o744))
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", stubHome)
+
+	dataDir, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	dst := filepath.Join(t.TempDir(), "session.sh")
+	out := new(bytes.Buffer)
+
+	app, err := cli.NewApp(
+		cli.WithOutputWriter(out),
+		cli.WithSlogAttrReplacer(testutils.NewSlogStabilizer(t)),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, app.Run(context.Background(), "export", "-c", filepath.Join(dataDir, "tmpl.yaml"), dst))
+
+	info, err := os.Stat(dst)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	script := string(testutils.ReadFile(t, dst))
+	require.Contains(t, script, "#!/usr/bin/env bash\n")
+	require.Contains(t, script, "tmux attach-session -t my_project\n")
+
+	require.Contains(t, out.String(), "configuration exported")
+	require.NotContains(t, out.String(), "#!/usr/bin/env bash")
+}
@@ -27,32 +27,13 @@ func (a *App) runInit(_ context.Context) error {
 		dst = a.opts.args[0]
 	}
 
-	if dst == "" {
-		wd, err := env.Getwd()
-		if err != nil {
-			return fmt.Errorf("getting current working directory: %w", err)
-		}
-
-		dst = filepath.Join(wd, config.ConfigFileName())
-	}
-
-	info, err := os.Stat(dst)
+	dst, err := a.configDestination(dst)
 	if err != nil {
-		if !errors.Is(err, fs.ErrNotExist) {
-			return fmt.Errorf("getting file info for destination path: %w", err)
-		}
+		return err
 	}
 
-	if info != nil {
-		if !info.IsDir() {
-			a.logger.Info("file already exists, skipping",
-				"path", info.Name(), "size", info.Size(), "modified", info.ModTime(),
-			)
-
-			return nil
-		}
-
-		dst = filepath.Join(dst, config.ConfigFileName())
+	if dst == "" {
+		return nil
 	}
 
 	text := static.ConfigTemplate
@@ -88,6 +69,59 @@ func (a *App) runInit(_ context.Context) error {
 	return nil
 }
 
+// configDestination returns the destination path for a new configuration file
+// from the provided path.
+//
+// If path is empty, the destination is a configuration file in the current
+// working directory. If path is a directory, the destination is a configuration
+// file in that directory.
+//
+// If a file already exists at the destination, it is logged and an empty
+// string is returned to signal that nothing should be written.
+func (a *App) configDestination(dst string) (string, error) {
+	if dst == "" {
+		wd, err := env.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("getting current working directory: %w", err)
+		}
+
+		dst = filepath.Join(wd, config.ConfigFileName())
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("getting file info for destination path: %w", err)
+		}
+	}
+
+	if info == nil {
+		return dst, nil
+	}
+
+	if !info.IsDir() {
+		a.logger.Info("file already exists, skipping",
+			"path", info.Name(), "size", info.Size(), "modified", info.ModTime(),
+		)
+
+		return "", nil
+	}
+
+	dst = filepath.Join(dst, config.ConfigFileName())
+
+	// Check the destination inside the directory as well, so that an existing
+	// configuration file is never overwritten.
+	if info, err = os.Stat(dst); err == nil {
+		a.logger.Info("file already exists, skipping",
+			"path", info.Name(), "size", info.Size(), "modified", info.ModTime(),
+		)
+
+		return "", nil
+	}
+
+	return dst, nil
+}
+
 func cleanSessionName(name string) string {
 	name = cleanSessNameRE.ReplaceAllString(strings.TrimSpace(name), "_")
 	return strings.Trim(name, "._-")
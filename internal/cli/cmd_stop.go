@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/michenriksen/tmpl/config"
+)
+
+// runStop loads the configuration, closes the tmux session and runs the
+// configured on_stop hooks.
+func (a *App) runStop(ctx context.Context) error {
+	a.initLogger()
+
+	if err := a.loadConfig(); err != nil {
+		return fmt.Errorf("loading configuration: %w", err)
+	}
+
+	runner, err := a.newTmux()
+	if err != nil {
+		return fmt.Errorf("creating tmux runner: %w", err)
+	}
+
+	if _, err := config.Stop(ctx, a.cfg, runner); err != nil {
+		return fmt.Errorf("stopping session: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/michenriksen/tmpl/config"
+)
+
+// runUp loads the configuration, applies all or the named sessions in it and
+// writes a summary of the results to the output writer.
+//
+// Returns an error if any of the sessions failed, after all sessions have been
+// applied.
+func (a *App) runUp(ctx context.Context) error {
+	a.initLogger()
+
+	if err := a.loadConfig(); err != nil {
+		return fmt.Errorf("loading configuration: %w", err)
+	}
+
+	a.setSessionSize()
+
+	runner, err := a.newTmux()
+	if err != nil {
+		return fmt.Errorf("creating tmux runner: %w", err)
+	}
+
+	results, err := config.Up(ctx, a.cfg, runner,
+		config.UpWithSessions(a.opts.args...),
+		config.UpWithSync(a.opts.Sync),
+	)
+	if err != nil {
+		return fmt.Errorf("applying configuration: %w", err)
+	}
+
+	failed := 0
+
+	for _, res := range results {
+		if res.Status == config.SessionFailed {
+			failed++
+			a.logger.Error("session failed", "session", res.Name, "error", res.Err)
+		}
+	}
+
+	writeUpSummary(a.out, results)
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d sessions failed", failed, len(results))
+	}
+
+	return nil
+}
+
+// writeUpSummary writes a table with the outcome of applying each session to
+// w.
+func writeUpSummary(w io.Writer, results []config.SessionResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "SESSION\tSTATUS\tDETAILS")
+
+	for _, res := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", res.Name, res.Status, upDetails(res))
+	}
+
+	tw.Flush()
+}
+
+func upDetails(res config.SessionResult) string {
+	switch res.Status {
+	case config.SessionCreated:
+		return plural(res.Session.NumWindows(), "window")
+	case config.SessionSynced:
+		return plural(len(res.Sync.Windows), "window") + " and " + plural(len(res.Sync.Panes), "pane") + " added"
+	case config.SessionSkipped:
+		return "already running"
+	case config.SessionFailed:
+		return res.Err.Error()
+	default:
+		return ""
+	}
+}
+
+// plural returns n followed by noun, with an s appended to noun unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
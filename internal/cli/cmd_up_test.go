@@ -0,0 +1,132 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/internal/cli"
+	"github.com/michenriksen/tmpl/internal/mock"
+	"github.com/michenriksen/tmpl/internal/testutils"
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+func TestApp_Run_Up(t *testing.T) {
+	stubHome := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(stubHome, "project", "scripts"), 0o744))
+
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("HOME", stubHome)
+	t.Setenv("TMPL_PWD", stubHome)
+
+	dataDir, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+
+	runner, err := tmux.NewRunner()
+	require.NoError(t, err)
+
+	stubs := loadTmuxStubs(t)
+	cfgPath := filepath.Join(dataDir, "tmpl-sessions.yaml")
+
+	// createAPISession sets up mocks for creating the api session, which fails
+	// to be created if failErr is not nil.
+	createAPISession := func(r *mock.TmuxRunner, failErr error) {
+		stub := stubs["NewSessionAPI"]
+		if failErr != nil {
+			r.On("Run", stub.Args).Return([]byte{}, failErr).Once()
+			return
+		}
+
+		newSess := r.On("Run", stub.Args).Return(stub.Output(), nil).Once()
+
+		stub = stubs["NewWindowAPICode"]
+		r.On("Run", stub.Args).Return(stub.Output(), nil).Once().NotBefore(newSess)
+	}
+
+	tt := []struct {
+		name       string
+		args       []string
+		setupMocks func(*testing.T, *mock.TmuxRunner)
+		assertErr  testutils.ErrorAssertion
+	}{
+		{
+			"all sessions",
+			[]string{"up", "-c", cfgPath},
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				// App checks if each session already exists.
+				stub := stubs["ListSessionsExists"]
+				r.On("Run", stub.Args).Return(stub.Output(), nil).Twice()
+
+				// Session my_project exists and is skipped.
+				createAPISession(r, nil)
+			},
+			nil,
+		},
+		{
+			"named session",
+			[]string{"up", "-c", cfgPath, "api"},
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				stub := stubs["ListSessionsExists"]
+				r.On("Run", stub.Args).Return(stub.Output(), nil).Once()
+
+				createAPISession(r, nil)
+			},
+			nil,
+		},
+		{
+			"session fails",
+			[]string{"up", "-c", cfgPath},
+			func(_ *testing.T, r *mock.TmuxRunner) {
+				stub := stubs["ListSessionsExists"]
+				r.On("Run", stub.Args).Return(stub.Output(), nil).Twice()
+
+				createAPISession(r, errors.New("server exited unexpectedly"))
+			},
+			testutils.RequireErrorContains("1 of 2 sessions failed"),
+		},
+		{
+			"unknown session",
+			[]string{"up", "-c", cfgPath, "web"},
+			nil,
+			testutils.RequireErrorContains("session not found in configuration: web"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			mockRunner := mock.NewTmuxRunner(t, runner)
+
+			if tc.setupMocks != nil {
+				tc.setupMocks(t, mockRunner)
+			}
+
+			app, err := cli.NewApp(
+				cli.WithOutputWriter(out),
+				cli.WithTmux(mockRunner),
+				cli.WithSlogAttrReplacer(testutils.NewSlogStabilizer(t)),
+			)
+			require.NoError(t, err)
+
+			err = app.Run(context.Background(), tc.args...)
+
+			if tc.assertErr != nil {
+				require.Error(t, err)
+				tc.assertErr(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if !mockRunner.AssertExpectations(t) {
+				t.FailNow()
+			}
+
+			testutils.NewGolden(t).RequireMatch(testutils.Stabilize(t, out.Bytes()))
+		})
+	}
+}
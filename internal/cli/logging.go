@@ -11,11 +11,24 @@ import (
 
 	"github.com/michenriksen/tmpl/config"
 	"github.com/michenriksen/tmpl/internal/env"
+	"github.com/michenriksen/tmpl/tmux"
 )
 
 // skipLogErrors contains errors that should not be logged.
 var skipLogErrors = []error{ErrVersion, ErrHelp}
 
+// tmuxErrHints contains hints to log for known tmux errors.
+var tmuxErrHints = []struct {
+	err  error
+	hint string
+}{
+	{tmux.ErrNoServer, "make sure the tmux server is running and that tmux_options point to its socket"},
+	{tmux.ErrSessionExists, "the session was created by another process; run the command again to attach it"},
+	{tmux.ErrTargetNotFound, "the session, window or pane was closed or renamed while the command was running"},
+	{tmux.ErrUnknownOption, "the option is not supported by the installed tmux version; check the configuration and the tmux version"},
+	{tmux.ErrSizeTooSmall, "there is not enough space for the configured panes; use fewer panes or a larger session size with --width and --height"},
+}
+
 func (a *App) initLogger() {
 	a.logger = a.newLogger()
 
@@ -29,7 +42,7 @@ func (a *App) initLogger() {
 func (a *App) newLogger() *slog.Logger {
 	hOpts := &slog.HandlerOptions{
 		Level:       slog.LevelInfo,
-		ReplaceAttr: a.attrReplacer,
+		ReplaceAttr: a.replaceAttr,
 	}
 
 	if a.opts != nil {
@@ -53,6 +66,18 @@ func (a *App) newLogger() *slog.Logger {
 	return slog.New(handler)
 }
 
+// replaceAttr redacts secrets in log attributes before passing them on to the
+// attribute replacer configured with [WithSlogAttrReplacer], if any.
+func (a *App) replaceAttr(groups []string, attr slog.Attr) slog.Attr {
+	attr = a.redaction.ReplaceAttr(groups, attr)
+
+	if a.attrReplacer != nil {
+		return a.attrReplacer(groups, attr)
+	}
+
+	return attr
+}
+
 func (a *App) newTextHandler(hOpts *slog.HandlerOptions) slog.Handler {
 	tOpts := &tint.Options{
 		Level:       hOpts.Level,
@@ -101,30 +126,54 @@ func (a *App) handleErr(err error) error {
 		return ErrInvalidConfig
 	}
 
+	var tmplErr config.TemplateError
+	if errors.As(err, &tmplErr) {
+		logger.Error("configuration file cannot be rendered", "path", tmplErr.Path())
+		logValidationErrs(logger, tmplErr.Errors(), "", nil)
+
+		return ErrInvalidConfig
+	}
+
 	var verrs validation.Errors
 	if errors.As(err, &verrs) {
 		logger.Error("configuration file is invalid", "errors", len(verrs))
-		logValidationErrs(logger, verrs, "")
+		logValidationErrs(logger, verrs, "", a.cfg)
 
 		return ErrInvalidConfig
 	}
 
 	logger.Error(err.Error())
 
+	for _, h := range tmuxErrHints {
+		if errors.Is(err, h.err) {
+			logger.Warn(h.hint)
+			break
+		}
+	}
+
 	return err
 }
 
 // logValidationErrs logs validation errors recursively.
-func logValidationErrs(logger *slog.Logger, verrs validation.Errors, fieldPrfx string) {
+//
+// If cfg is merged from several configuration files, the path to the file
+// setting each invalid field is logged along with the error.
+func logValidationErrs(logger *slog.Logger, verrs validation.Errors, fieldPrfx string, cfg *config.Config) {
 	for field, err := range verrs {
 		field = fieldPrfx + field
 
 		if verr, ok := err.(validation.Errors); ok {
-			logValidationErrs(logger, verr, field+".")
+			logValidationErrs(logger, verr, field+".", cfg)
 			continue
 		}
 
-		logger.Warn(fmt.Sprintf("%s %v", field, err.Error()), "field", field)
+		attrs := []any{"field", field}
+
+		if cfg != nil && len(cfg.Sources()) > 1 {
+			attrs = append(attrs, "path", cfg.Source(field))
+		}
+
+		logger.Warn(fmt.Sprintf("%s %v", field, err.Error()), attrs...)
 	}
 }
 
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"text/template"
 )
@@ -20,8 +21,13 @@ const globalOpts = `Global options:
 const subCmds = `Available commands:
 
     apply (default)            apply configuration and attach session
+    capture                    generate a configuration file from a session
     check                      validate configuration file
-    init                       generate a new configuration file`
+    diff                       show differences between session and configuration
+    export                     write configuration as a standalone shell script
+    init                       generate a new configuration file
+    stop                       close session and run on_stop hooks
+    up                         apply all sessions in configuration file`
 
 const usageTmpl = `Usage: {{ .AppName }} [command] [options] [args]
 
@@ -48,13 +54,31 @@ const applyUsageTmpl = `Usage: {{ .AppName }} apply [options]
 Creates a new tmux session from a {{ .AppName }} configuration file and then
 connects to it.
 
-If the session already exists, the configuration process is skipped.
+If the session already exists, the configuration process is skipped unless
+the --sync option is given, in which case any windows and panes missing from
+the session are created.
+
+New sessions are created with the size of the current terminal, unless a size
+is given with the --width and --height options or in the configuration file.
+
+With the --no-attach option, or attach set to false in the configuration file,
+the session is set up in the background and the client is not attached to it.
 
 
 Options:
 
     -c, --config PATH          configuration file path (default: find nearest)
     -n, --dry-run              enable dry-run mode
+    -f, --format FORMAT        print dry-run plan: tree, json or script
+    -N, --no-attach            do not attach client to session
+    -o, --output FORMAT        print session details: text or json (default: text)
+    -p, --profile NAME         apply configuration profile (default: $TMPL_PROFILE)
+    --record FILE              record tmux commands to a transcript file
+    --replay FILE              replay tmux commands from a transcript file
+    --set NAME=VALUE           set template variable (can be repeated)
+    -s, --sync                 create missing windows and panes in existing session
+    -x, --width COLUMNS        session width (default: terminal width)
+    -y, --height LINES         session height (default: terminal height)
 
 {{ .GlobalOptions }}
 
@@ -68,6 +92,21 @@ Examples:
 
     # simulate applying configuration file. No tmux commands are executed:
     $ {{ .AppName }} apply --dry-run
+
+    # show the windows, panes and tmux commands of the session as a tree:
+    $ {{ .AppName }} apply --dry-run --format tree
+
+    # add windows and panes added to the configuration to a running session:
+    $ {{ .AppName }} apply --sync
+
+    # apply configuration with a template variable overridden:
+    $ {{ .AppName }} apply --set service=billing --set port=8081
+
+    # apply configuration with the minimal profile:
+    $ {{ .AppName }} apply --profile minimal
+
+    # set up session in the background and print its details as JSON:
+    $ {{ .AppName }} apply --no-attach --output json
 `
 
 const initUsageTmpl = `Usage: {{ .AppName }} init [options] [path]
@@ -89,6 +128,33 @@ Examples:
     $ {{ .AppName }} init /path/to/config.yaml
 `
 
+const captureUsageTmpl = `Usage: {{ .AppName }} capture [options] [path]
+
+Generates a {{ .AppName }} configuration file from the windows and panes of a
+running tmux session.
+
+Paths below the session path are written as relative paths. An existing file is
+never overwritten.
+
+
+Options:
+
+    --record FILE              record tmux commands to a transcript file
+    --replay FILE              replay tmux commands from a transcript file
+    -s, --session NAME         session name (default: current directory name)
+
+{{ .GlobalOptions }}
+
+Examples:
+
+    # capture session named after the current directory to a new
+    # configuration file in the current working directory:
+    $ {{ .AppName }} capture
+
+    # capture a specific session to a specific location:
+    $ {{ .AppName }} capture -s my_session /path/to/config.yaml
+`
+
 const checkUsageTmpl = `Usage: {{ .AppName }} check [options] [path]
 
 Performs validation of a {{ .AppName }} configuration file and reports whether
@@ -98,6 +164,8 @@ it is valid or not.
 Options:
 
     -c, --config PATH          configuration file path (default: find nearest)
+    -p, --profile NAME         apply configuration profile (default: $TMPL_PROFILE)
+    --set NAME=VALUE           set template variable (can be repeated)
 
 {{ .GlobalOptions }}
 
@@ -110,6 +178,124 @@ Examples:
     $ {{ .AppName }} check -c /path/to/config.yaml
 `
 
+const diffUsageTmpl = `Usage: {{ .AppName }} diff [options]
+
+Compares a running tmux session with its {{ .AppName }} configuration file and
+shows windows and panes that are missing or extra, as well as differences in
+names and paths.
+
+
+Options:
+
+    -c, --config PATH          configuration file path (default: find nearest)
+    -f, --format FORMAT        output format: tree or json (default: tree)
+    -p, --profile NAME         apply configuration profile (default: $TMPL_PROFILE)
+    --record FILE              record tmux commands to a transcript file
+    --replay FILE              replay tmux commands from a transcript file
+
+{{ .GlobalOptions }}
+
+Examples:
+
+    # show differences for the nearest configuration file:
+    $ {{ .AppName }} diff
+
+    # show differences as JSON for use in other tools:
+    $ {{ .AppName }} diff --format json
+`
+
+const exportUsageTmpl = `Usage: {{ .AppName }} export [options] [path]
+
+Writes a shell script that creates the tmux session of a {{ .AppName }}
+configuration file with tmux alone, for use where {{ .AppName }} is not
+installed. The script is written to standard output if no path is given.
+
+Like the apply command, the script only creates the session if it does not
+already exist, and then attaches the client to it unless attach is set to false
+in the configuration file. Hooks and env_from commands are not included.
+
+NOTE: values of environment variables, including secrets loaded from env
+files, are written to the script as they are.
+
+
+Options:
+
+    -c, --config PATH          configuration file path (default: find nearest)
+    -f, --format FORMAT        script format: sh (default: sh)
+    -p, --profile NAME         apply configuration profile (default: $TMPL_PROFILE)
+    --set NAME=VALUE           set template variable (can be repeated)
+
+{{ .GlobalOptions }}
+
+Examples:
+
+    # export nearest configuration file to a script:
+    $ {{ .AppName }} export session.sh
+
+    # print script for a specific configuration file:
+    $ {{ .AppName }} export -c /path/to/config.yaml
+`
+
+const stopUsageTmpl = `Usage: {{ .AppName }} stop [options]
+
+Closes the tmux session of a {{ .AppName }} configuration file and runs the
+on_stop hooks of the configuration.
+
+The hooks are run even if the session is not running, so that resources
+started by before_start and after_start hooks can be cleaned up.
+
+
+Options:
+
+    -c, --config PATH          configuration file path (default: find nearest)
+    -n, --dry-run              enable dry-run mode
+    -p, --profile NAME         apply configuration profile (default: $TMPL_PROFILE)
+    --record FILE              record tmux commands to a transcript file
+    --replay FILE              replay tmux commands from a transcript file
+
+{{ .GlobalOptions }}
+
+Examples:
+
+    # close session of the nearest configuration file:
+    $ {{ .AppName }} stop
+
+    # show which hooks would be run without closing the session:
+    $ {{ .AppName }} stop --dry-run
+`
+
+const upUsageTmpl = `Usage: {{ .AppName }} up [options] [session...]
+
+Creates tmux sessions for all sessions in a {{ .AppName }} configuration file,
+or only the named sessions, and prints a summary of the results. The client is
+not attached to any of the sessions.
+
+Each session is applied on its own, so a session that fails does not affect
+the others. Sessions that already exist are skipped unless the --sync option is
+given, in which case any windows and panes missing from them are created.
+
+
+Options:
+
+    -c, --config PATH          configuration file path (default: find nearest)
+    -n, --dry-run              enable dry-run mode
+    -p, --profile NAME         apply configuration profile (default: $TMPL_PROFILE)
+    --record FILE              record tmux commands to a transcript file
+    --replay FILE              replay tmux commands from a transcript file
+    --set NAME=VALUE           set template variable (can be repeated)
+    -s, --sync                 create missing windows and panes in existing sessions
+
+{{ .GlobalOptions }}
+
+Examples:
+
+    # apply all sessions in the nearest configuration file:
+    $ {{ .AppName }} up
+
+    # apply a single session:
+    $ {{ .AppName }} up api
+`
+
 const versionTmpl = `{{ .AppName }}:
   Version:    {{ .Version }}
   Go version: {{ .GoVersion }}
@@ -117,6 +303,15 @@ const versionTmpl = `{{ .AppName }}:
   Released:   {{ .BuildTime }}
 `
 
+// Output formats for sub-commands with structured output.
+const (
+	formatText   = "text"
+	formatTree   = "tree"
+	formatJSON   = "json"
+	formatScript = "script"
+	formatSh     = "sh"
+)
+
 var (
 	ErrHelp    = errors.New("help requested")
 	ErrVersion = errors.New("version requested")
@@ -133,9 +328,30 @@ type options struct {
 	Quiet bool
 	JSON  bool
 
-	// Options for apply sub-command.
+	// Options for apply and stop sub-commands.
 	ConfigPath string
 	DryRun     bool
+	Sync       bool
+	Width      int
+	Height     int
+	NoAttach   bool
+	Output     string
+
+	// Options for apply and check sub-commands.
+	Vars varsFlag
+
+	// Options for sub-commands running tmux commands.
+	Record string
+	Replay string
+
+	// Options for sub-commands loading a configuration file.
+	Profile string
+
+	// Options for apply, diff and export sub-commands.
+	Format string
+
+	// Options for capture sub-command.
+	SessionName string
 
 	// Options for init sub-command.
 	Plain bool
@@ -171,8 +387,25 @@ func parseApplyOptions(args []string, output io.Writer) (*options, error) {
 
 	flagSet.StringVar(&opts.ConfigPath, "config", "", "path to the configuration file")
 	flagSet.StringVar(&opts.ConfigPath, "c", "", "path to the configuration file")
+	flagSet.StringVar(&opts.Profile, "profile", "", "configuration profile to apply")
+	flagSet.StringVar(&opts.Profile, "p", "", "configuration profile to apply")
 	flagSet.BoolVar(&opts.DryRun, "dry-run", false, "enable dry-run mode")
 	flagSet.BoolVar(&opts.DryRun, "n", false, "enable dry-run mode")
+	flagSet.BoolVar(&opts.Sync, "sync", false, "create missing windows and panes in existing session")
+	flagSet.BoolVar(&opts.Sync, "s", false, "create missing windows and panes in existing session")
+	flagSet.IntVar(&opts.Width, "width", 0, "session width")
+	flagSet.IntVar(&opts.Width, "x", 0, "session width")
+	flagSet.IntVar(&opts.Height, "height", 0, "session height")
+	flagSet.IntVar(&opts.Height, "y", 0, "session height")
+	flagSet.BoolVar(&opts.NoAttach, "no-attach", false, "do not attach client to session")
+	flagSet.BoolVar(&opts.NoAttach, "N", false, "do not attach client to session")
+	flagSet.StringVar(&opts.Output, "output", formatText, "session details output format")
+	flagSet.StringVar(&opts.Output, "o", formatText, "session details output format")
+	flagSet.StringVar(&opts.Format, "format", "", "dry-run plan output format")
+	flagSet.StringVar(&opts.Format, "f", "", "dry-run plan output format")
+	flagSet.Var(&opts.Vars, "set", "set template variable")
+	flagSet.StringVar(&opts.Record, "record", "", "record tmux commands to a transcript file")
+	flagSet.StringVar(&opts.Replay, "replay", "", "replay tmux commands from a transcript file")
 
 	if isSubCmd {
 		args = args[1:]
@@ -187,6 +420,20 @@ func parseApplyOptions(args []string, output io.Writer) (*options, error) {
 		return nil, fmt.Errorf("unknown command: %s", opts.args[0])
 	}
 
+	if opts.Output != formatText && opts.Output != formatJSON {
+		return nil, fmt.Errorf("unknown output format: %s", opts.Output)
+	}
+
+	switch opts.Format {
+	case "":
+	case formatTree, formatJSON, formatScript:
+		if !opts.DryRun {
+			return nil, fmt.Errorf("--format option requires --dry-run")
+		}
+	default:
+		return nil, fmt.Errorf("unknown plan format: %s", opts.Format)
+	}
+
 	return opts, nil
 }
 
@@ -213,6 +460,32 @@ func parseInitOptions(args []string, output io.Writer) (*options, error) {
 	return parseFlagSet(args, flagSet, opts)
 }
 
+// parseCaptureOptions parses the command-line options for the capture
+// sub-command.
+func parseCaptureOptions(args []string, output io.Writer) (*options, error) {
+	flagSet := flag.NewFlagSet("capture", flag.ContinueOnError)
+
+	flagSet.SetOutput(output)
+	flagSet.Usage = func() {
+		usage, err := renderOptsTemplate(captureUsageTmpl)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Fprint(output, usage)
+	}
+
+	opts := &options{}
+	initGlobalOpts(flagSet, opts)
+
+	flagSet.StringVar(&opts.SessionName, "session", "", "session name")
+	flagSet.StringVar(&opts.SessionName, "s", "", "session name")
+	flagSet.StringVar(&opts.Record, "record", "", "record tmux commands to a transcript file")
+	flagSet.StringVar(&opts.Replay, "replay", "", "replay tmux commands from a transcript file")
+
+	return parseFlagSet(args, flagSet, opts)
+}
+
 func parseCheckOptions(args []string, output io.Writer) (*options, error) {
 	flagSet := flag.NewFlagSet("check", flag.ContinueOnError)
 
@@ -231,6 +504,183 @@ func parseCheckOptions(args []string, output io.Writer) (*options, error) {
 
 	flagSet.StringVar(&opts.ConfigPath, "config", "", "path to the configuration file")
 	flagSet.StringVar(&opts.ConfigPath, "c", "", "path to the configuration file")
+	flagSet.StringVar(&opts.Profile, "profile", "", "configuration profile to apply")
+	flagSet.StringVar(&opts.Profile, "p", "", "configuration profile to apply")
+	flagSet.Var(&opts.Vars, "set", "set template variable")
+
+	return parseFlagSet(args, flagSet, opts)
+}
+
+// parseDiffOptions parses the command-line options for the diff sub-command.
+func parseDiffOptions(args []string, output io.Writer) (*options, error) {
+	flagSet := flag.NewFlagSet("diff", flag.ContinueOnError)
+
+	flagSet.SetOutput(output)
+	flagSet.Usage = func() {
+		usage, err := renderOptsTemplate(diffUsageTmpl)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Fprint(output, usage)
+	}
+
+	opts := &options{}
+	initGlobalOpts(flagSet, opts)
+
+	flagSet.StringVar(&opts.ConfigPath, "config", "", "path to the configuration file")
+	flagSet.StringVar(&opts.ConfigPath, "c", "", "path to the configuration file")
+	flagSet.StringVar(&opts.Profile, "profile", "", "configuration profile to apply")
+	flagSet.StringVar(&opts.Profile, "p", "", "configuration profile to apply")
+	flagSet.StringVar(&opts.Format, "format", formatTree, "output format")
+	flagSet.StringVar(&opts.Format, "f", formatTree, "output format")
+	flagSet.StringVar(&opts.Record, "record", "", "record tmux commands to a transcript file")
+	flagSet.StringVar(&opts.Replay, "replay", "", "replay tmux commands from a transcript file")
+
+	opts, err := parseFlagSet(args, flagSet, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Format != formatTree && opts.Format != formatJSON {
+		return nil, fmt.Errorf("unknown output format: %s", opts.Format)
+	}
+
+	return opts, nil
+}
+
+// parseExportOptions parses the command-line options for the export
+// sub-command.
+func parseExportOptions(args []string, output io.Writer) (*options, error) {
+	flagSet := flag.NewFlagSet("export", flag.ContinueOnError)
+
+	flagSet.SetOutput(output)
+	flagSet.Usage = func() {
+		usage, err := renderOptsTemplate(exportUsageTmpl)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Fprint(output, usage)
+	}
+
+	opts := &options{}
+	initGlobalOpts(flagSet, opts)
+
+	flagSet.StringVar(&opts.ConfigPath, "config", "", "path to the configuration file")
+	flagSet.StringVar(&opts.ConfigPath, "c", "", "path to the configuration file")
+	flagSet.StringVar(&opts.Profile, "profile", "", "configuration profile to apply")
+	flagSet.StringVar(&opts.Profile, "p", "", "configuration profile to apply")
+	flagSet.StringVar(&opts.Format, "format", formatSh, "script format")
+	flagSet.StringVar(&opts.Format, "f", formatSh, "script format")
+	flagSet.Var(&opts.Vars, "set", "set template variable")
+
+	opts, err := parseFlagSet(args, flagSet, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Format != formatSh {
+		return nil, fmt.Errorf("unknown script format: %s", opts.Format)
+	}
+
+	if len(opts.args) > 1 {
+		return nil, fmt.Errorf("unexpected argument: %s", opts.args[1])
+	}
+
+	return opts, nil
+}
+
+// parseStopOptions parses the command-line options for the stop sub-command.
+func parseStopOptions(args []string, output io.Writer) (*options, error) {
+	flagSet := flag.NewFlagSet("stop", flag.ContinueOnError)
+
+	flagSet.SetOutput(output)
+	flagSet.Usage = func() {
+		usage, err := renderOptsTemplate(stopUsageTmpl)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Fprint(output, usage)
+	}
+
+	opts := &options{}
+	initGlobalOpts(flagSet, opts)
+
+	flagSet.StringVar(&opts.ConfigPath, "config", "", "path to the configuration file")
+	flagSet.StringVar(&opts.ConfigPath, "c", "", "path to the configuration file")
+	flagSet.StringVar(&opts.Profile, "profile", "", "configuration profile to apply")
+	flagSet.StringVar(&opts.Profile, "p", "", "configuration profile to apply")
+	flagSet.BoolVar(&opts.DryRun, "dry-run", false, "enable dry-run mode")
+	flagSet.BoolVar(&opts.DryRun, "n", false, "enable dry-run mode")
+	flagSet.StringVar(&opts.Record, "record", "", "record tmux commands to a transcript file")
+	flagSet.StringVar(&opts.Replay, "replay", "", "replay tmux commands from a transcript file")
+
+	return parseFlagSet(args, flagSet, opts)
+}
+
+// varsFlag is a repeatable flag value holding template variables given as
+// name=value pairs.
+type varsFlag map[string]string
+
+// String implements the [flag.Value] interface.
+func (v *varsFlag) String() string {
+	pairs := make([]string, 0, len(*v))
+
+	for name, val := range *v {
+		pairs = append(pairs, name+"="+val)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// Set implements the [flag.Value] interface.
+func (v *varsFlag) Set(s string) error {
+	name, val, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid variable %q: must be in the form name=value", s)
+	}
+
+	if *v == nil {
+		*v = varsFlag{}
+	}
+
+	(*v)[name] = val
+
+	return nil
+}
+
+// parseUpOptions parses the command-line options for the up sub-command.
+func parseUpOptions(args []string, output io.Writer) (*options, error) {
+	flagSet := flag.NewFlagSet("up", flag.ContinueOnError)
+
+	flagSet.SetOutput(output)
+	flagSet.Usage = func() {
+		usage, err := renderOptsTemplate(upUsageTmpl)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Fprint(output, usage)
+	}
+
+	opts := &options{}
+	initGlobalOpts(flagSet, opts)
+
+	flagSet.StringVar(&opts.ConfigPath, "config", "", "path to the configuration file")
+	flagSet.StringVar(&opts.ConfigPath, "c", "", "path to the configuration file")
+	flagSet.StringVar(&opts.Profile, "profile", "", "configuration profile to apply")
+	flagSet.StringVar(&opts.Profile, "p", "", "configuration profile to apply")
+	flagSet.BoolVar(&opts.DryRun, "dry-run", false, "enable dry-run mode")
+	flagSet.BoolVar(&opts.DryRun, "n", false, "enable dry-run mode")
+	flagSet.BoolVar(&opts.Sync, "sync", false, "create missing windows and panes in existing sessions")
+	flagSet.BoolVar(&opts.Sync, "s", false, "create missing windows and panes in existing sessions")
+	flagSet.Var(&opts.Vars, "set", "set template variable")
+	flagSet.StringVar(&opts.Record, "record", "", "record tmux commands to a transcript file")
+	flagSet.StringVar(&opts.Replay, "replay", "", "replay tmux commands from a transcript file")
 
 	return parseFlagSet(args, flagSet, opts)
 }
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// writePlan writes a plan of the applied session to the output writer in the
+// configured format, with the tmux commands recorded in the provided
+// transcript.
+func (a *App) writePlan(ctx context.Context, transcript io.Reader) error {
+	calls, err := tmux.ReadTranscript(transcript)
+	if err != nil {
+		return fmt.Errorf("reading tmux commands: %w", err)
+	}
+
+	plan, err := tmux.NewPlan(ctx, a.sess, tmux.PlanWithTranscript(calls), tmux.PlanWithRedaction(a.redaction))
+	if err != nil {
+		return fmt.Errorf("making plan for %s: %w", a.sess, err)
+	}
+
+	switch a.opts.Format {
+	case formatJSON:
+		enc := json.NewEncoder(a.out)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(plan); err != nil {
+			return fmt.Errorf("encoding plan: %w", err)
+		}
+	case formatScript:
+		opts := []tmux.ScriptOption{
+			tmux.ScriptWithTmux(a.tmuxCommand()...),
+			tmux.ScriptWithRedaction(a.redaction),
+		}
+
+		if !a.opts.NoAttach && a.cfg.ShouldAttach() {
+			opts = append(opts, tmux.ScriptWithAttach())
+		}
+
+		if err := tmux.WriteScript(a.out, a.sess, opts...); err != nil {
+			return fmt.Errorf("writing plan script: %w", err)
+		}
+	default:
+		writePlanTree(a.out, plan)
+	}
+
+	return nil
+}
+
+// tmuxCommand returns the tmux executable and options to run tmux commands
+// with.
+func (a *App) tmuxCommand() []string {
+	cmd := []string{"tmux"}
+
+	if a.cfg.Tmux != "" {
+		cmd[0] = a.cfg.Tmux
+	}
+
+	return append(cmd, a.cfg.TmuxOptions...)
+}
+
+// planNode is a node in the tree representation of a plan.
+type planNode struct {
+	label    string
+	children []planNode
+}
+
+// writePlanTree writes a human-readable tree representation of the provided
+// plan to w.
+func writePlanTree(w io.Writer, plan *tmux.Plan) {
+	sess := plan.Session
+	root := planNode{label: "session " + sess.Name}
+
+	root.children = append(root.children, planNode{label: "path: " + sess.Path})
+	root.children = append(root.children, envNodes(sess.Env)...)
+
+	for _, wp := range sess.Windows {
+		label := fmt.Sprintf("window %s %s", wp.Index, wp.Name)
+		if wp.Active {
+			label += " (active)"
+		}
+
+		node := planNode{label: label}
+		node.children = append(node.children, planNode{label: "path: " + wp.Path})
+
+		if wp.Width != 0 && wp.Height != 0 {
+			node.children = append(node.children, planNode{label: fmt.Sprintf("size: %dx%d", wp.Width, wp.Height)})
+		}
+
+		if wp.Layout != "" {
+			node.children = append(node.children, planNode{label: "layout: " + wp.Layout})
+		}
+
+		node.children = append(node.children, envNodes(wp.Env)...)
+		node.children = append(node.children, cmdNodes(wp.Commands)...)
+
+		for _, pp := range wp.Panes {
+			node.children = append(node.children, panePlanNode(pp))
+		}
+
+		root.children = append(root.children, node)
+	}
+
+	fmt.Fprintln(w, root.label)
+	writePlanNodes(w, root.children, "")
+
+	fmt.Fprintf(w, "\n%d tmux commands\n", len(plan.Commands))
+}
+
+func panePlanNode(pp tmux.PanePlan) planNode {
+	label := fmt.Sprintf("pane %s", pp.Index)
+	if pp.Active {
+		label += " (active)"
+	}
+
+	node := planNode{label: label}
+	node.children = append(node.children, planNode{label: "path: " + pp.Path})
+
+	if pp.Width != 0 && pp.Height != 0 {
+		node.children = append(node.children, planNode{label: fmt.Sprintf("size: %dx%d", pp.Width, pp.Height)})
+	}
+
+	node.children = append(node.children, envNodes(pp.Env)...)
+	node.children = append(node.children, cmdNodes(pp.Commands)...)
+
+	for _, child := range pp.Panes {
+		node.children = append(node.children, panePlanNode(child))
+	}
+
+	return node
+}
+
+// envNodes returns a node for each of the provided environment variables,
+// sorted by name.
+func envNodes(env map[string]string) []planNode {
+	names := make([]string, 0, len(env))
+
+	for name := range env {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	nodes := make([]planNode, len(names))
+
+	for i, name := range names {
+		nodes[i] = planNode{label: fmt.Sprintf("env: %s=%s", name, env[name])}
+	}
+
+	return nodes
+}
+
+func cmdNodes(cmds []string) []planNode {
+	nodes := make([]planNode, len(cmds))
+
+	for i, cmd := range cmds {
+		nodes[i] = planNode{label: "cmd: " + cmd}
+	}
+
+	return nodes
+}
+
+func writePlanNodes(w io.Writer, nodes []planNode, indent string) {
+	for i, node := range nodes {
+		last := i == len(nodes)-1
+
+		fmt.Fprintln(w, indent+treeBranch(last)+node.label)
+		writePlanNodes(w, node.children, indent+treeIndent(last))
+	}
+}
@@ -10,6 +10,9 @@ const (
 	// KeyConfigName is the environment variable key for specifying a different
 	// configuration file name instead of the default.
 	KeyConfigName = "CONFIG_NAME"
+	// KeyProfile is the environment variable key for selecting a configuration
+	// profile when none is given with the --profile option.
+	KeyProfile = "PROFILE"
 	// KeyPwd is the environment variable key for a stubbed working directory
 	// used by tests.
 	KeyPwd = "PWD"
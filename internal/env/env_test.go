@@ -1,6 +1,7 @@
 package env_test
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -23,3 +24,18 @@ func TestLookupEnv(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, "good", val)
 }
+
+func TestTermSize_NotTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "not-a-terminal")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { f.Close() })
+
+	width, height, ok := env.TermSize(f)
+	require.False(t, ok)
+	require.Zero(t, width)
+	require.Zero(t, height)
+
+	_, _, ok = env.TermSize(nil)
+	require.False(t, ok)
+}
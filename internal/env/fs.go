@@ -29,7 +29,8 @@ func Getwd() (string, error) {
 // AbsPath returns the absolute path for the given path.
 //
 // Works like [filepath.Abs] except that it will expand the home directory if
-// the path starts with "~".
+// the path starts with "~", and resolve relative paths against the working
+// directory returned by [Getwd].
 //
 // If the path is already absolute, it will be returned as-is.
 func AbsPath(path string) (string, error) {
@@ -46,10 +47,10 @@ func AbsPath(path string) (string, error) {
 		return filepath.Join(home, filepath.Clean(path[1:])), nil
 	}
 
-	abs, err := filepath.Abs(path)
+	wd, err := Getwd()
 	if err != nil {
 		return "", fmt.Errorf("getting absolute path: %w", err)
 	}
 
-	return abs, nil
+	return filepath.Join(wd, path), nil
 }
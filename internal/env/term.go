@@ -0,0 +1,21 @@
+package env
+
+import "os"
+
+// TermSize returns the width and height of the terminal connected to the
+// provided file.
+//
+// Returns false if the file is not connected to a terminal, or if the size
+// cannot be determined on the current platform.
+func TermSize(f *os.File) (width, height int, ok bool) {
+	if f == nil {
+		return 0, 0, false
+	}
+
+	width, height, ok = termSize(f.Fd())
+	if !ok || width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+
+	return width, height, true
+}
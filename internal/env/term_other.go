@@ -0,0 +1,7 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package env
+
+func termSize(uintptr) (width, height int, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,29 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package env
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// winsize is the structure filled by the TIOCGWINSZ ioctl.
+type winsize struct {
+	Row    uint16
+	Col    uint16
+	Xpixel uint16
+	Ypixel uint16
+}
+
+func termSize(fd uintptr) (width, height int, ok bool) {
+	var ws winsize
+
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL, fd, uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)),
+	)
+	if errno != 0 {
+		return 0, 0, false
+	}
+
+	return int(ws.Col), int(ws.Row), true
+}
@@ -0,0 +1,190 @@
+package faketmux
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// cmdArgs holds the parsed flags and positional arguments of a command.
+type cmdArgs struct {
+	name  string
+	flags map[byte][]string
+	args  []string
+}
+
+// has returns true if the flag was given.
+func (a *cmdArgs) has(flag byte) bool {
+	_, ok := a.flags[flag]
+	return ok
+}
+
+// get returns the value of the last occurrence of the flag, or an empty
+// string if the flag was not given.
+func (a *cmdArgs) get(flag byte) string {
+	vals := a.flags[flag]
+	if len(vals) == 0 {
+		return ""
+	}
+
+	return vals[len(vals)-1]
+}
+
+// all returns the values of all occurrences of the flag.
+func (a *cmdArgs) all(flag byte) []string {
+	return a.flags[flag]
+}
+
+// parseArgs parses the arguments of the named command with a getopt-style
+// template, like tmux does. Letters followed by a colon in the template take
+// a value.
+//
+// minArgs and maxArgs restrict the number of positional arguments. A maxArgs
+// of -1 means no limit.
+func parseArgs(name, template string, minArgs, maxArgs int, args []string) (*cmdArgs, error) {
+	res := &cmdArgs{name: name, flags: make(map[byte][]string)}
+
+	i := 0
+
+	for ; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			i++
+			break
+		}
+
+		if len(arg) < 2 || arg[0] != '-' {
+			break
+		}
+
+		for j := 1; j < len(arg); j++ {
+			flag := arg[j]
+
+			pos := strings.IndexByte(template, flag)
+			if pos == -1 || flag == ':' {
+				return nil, fmt.Errorf("command %s: unknown flag -%c", name, flag)
+			}
+
+			if pos+1 == len(template) || template[pos+1] != ':' {
+				res.flags[flag] = append(res.flags[flag], "")
+				continue
+			}
+
+			val := arg[j+1:]
+
+			if val == "" {
+				if i+1 == len(args) {
+					return nil, fmt.Errorf("command %s: -%c expects an argument", name, flag)
+				}
+
+				i++
+				val = args[i]
+			}
+
+			res.flags[flag] = append(res.flags[flag], val)
+
+			break
+		}
+	}
+
+	res.args = args[i:]
+
+	if len(res.args) < minArgs {
+		return nil, fmt.Errorf("command %s: too few arguments (need at least %d)", name, minArgs)
+	}
+
+	if maxArgs != -1 && len(res.args) > maxArgs {
+		return nil, fmt.Errorf("command %s: too many arguments (need at most %d)", name, maxArgs)
+	}
+
+	return res, nil
+}
+
+// splitCommands splits a tmux command line into commands, like tmux does for
+// command lines with commands separated by semicolons.
+//
+// An argument ending with a semicolon ends the command, unless the semicolon
+// is escaped with a backslash, in which case it is kept as a literal
+// semicolon.
+func splitCommands(args []string) [][]string {
+	var (
+		cmds [][]string
+		cur  []string
+	)
+
+	for _, arg := range args {
+		switch {
+		case strings.HasSuffix(arg, `\;`):
+			cur = append(cur, arg[:len(arg)-2]+";")
+		case strings.HasSuffix(arg, ";"):
+			if arg != ";" {
+				cur = append(cur, arg[:len(arg)-1])
+			}
+
+			if len(cur) != 0 {
+				cmds = append(cmds, cur)
+			}
+
+			cur = nil
+		default:
+			cur = append(cur, arg)
+		}
+	}
+
+	if len(cur) != 0 {
+		cmds = append(cmds, cur)
+	}
+
+	return cmds
+}
+
+// parseGlobalFlags removes the tmux global flags preceding the command from
+// args and returns the remaining arguments along with the socket path the
+// flags point to. Socket names given with -L are relative to socketDir.
+func parseGlobalFlags(args []string, socketDir string) ([]string, string, error) {
+	socket := filepath.Join(socketDir, "default")
+
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+		arg := args[0]
+		args = args[1:]
+
+		if arg == "--" {
+			break
+		}
+
+		for j := 1; j < len(arg); j++ {
+			flag := arg[j]
+
+			if !strings.ContainsRune("LSfcT", rune(flag)) {
+				if strings.ContainsRune("2CDNluvV", rune(flag)) {
+					continue
+				}
+
+				return nil, "", fmt.Errorf("unknown option -- %c", flag)
+			}
+
+			val := arg[j+1:]
+
+			if val == "" {
+				if len(args) == 0 {
+					return nil, "", fmt.Errorf("option requires an argument -- %c", flag)
+				}
+
+				val = args[0]
+				args = args[1:]
+			}
+
+			switch flag {
+			case 'L':
+				socket = filepath.Join(socketDir, val)
+			case 'S':
+				socket = val
+			}
+
+			break
+		}
+	}
+
+	return args, socket, nil
+}
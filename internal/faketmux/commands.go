@@ -0,0 +1,1091 @@
+package faketmux
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// command describes a tmux command supported by the server.
+//
+// The template lists the supported flags like tmux does, with letters followed
+// by a colon taking a value. Flags that are not in the template are rejected
+// as unknown flags.
+type command struct {
+	name        string
+	alias       string
+	template    string
+	minArgs     int
+	maxArgs     int
+	startServer bool
+	exec        func(s *Server, args *cmdArgs, out *strings.Builder) error
+}
+
+// commands are the tmux commands supported by the server.
+var commands = []command{
+	{"attach-session", "attach", "dt:", 0, 0, false, (*Server).attachSession},
+	{"display-message", "display", "pt:", 0, 1, false, (*Server).displayMessage},
+	{"has-session", "has", "t:", 0, 0, false, (*Server).hasSession},
+	{"kill-pane", "killp", "t:", 0, 0, false, (*Server).killPane},
+	{"kill-server", "", "", 0, 0, false, (*Server).killServer},
+	{"kill-session", "", "t:", 0, 0, false, (*Server).killSession},
+	{"kill-window", "killw", "t:", 0, 0, false, (*Server).killWindow},
+	{"list-panes", "lsp", "asF:t:", 0, 0, false, (*Server).listPanes},
+	{"list-sessions", "ls", "F:", 0, 0, false, (*Server).listSessions},
+	{"list-windows", "lsw", "aF:t:", 0, 0, false, (*Server).listWindows},
+	{"move-window", "movew", "dks:t:", 0, 0, false, (*Server).moveWindow},
+	{"new-session", "new", "c:de:F:n:Ps:x:y:", 0, -1, true, (*Server).newSession},
+	{"new-window", "neww", "abc:de:F:kn:Pt:", 0, -1, false, (*Server).newWindow},
+	{"rename-session", "rename", "t:", 1, 1, false, (*Server).renameSession},
+	{"rename-window", "renamew", "t:", 1, 1, false, (*Server).renameWindow},
+	{"resize-pane", "resizep", "DLRt:Ux:y:", 0, 1, false, (*Server).resizePane},
+	{"respawn-pane", "respawnp", "c:e:kt:", 0, -1, false, (*Server).respawnPane},
+	{"select-layout", "selectl", "t:", 0, 1, false, (*Server).selectLayout},
+	{"select-pane", "selectp", "t:", 0, 0, false, (*Server).selectPane},
+	{"select-window", "selectw", "npt:", 0, 0, false, (*Server).selectWindow},
+	{"send-keys", "send", "lt:", 0, -1, false, (*Server).sendKeys},
+	{"set-option", "set", "agoqst:uw", 1, 2, false, (*Server).setOption},
+	{"show-options", "show", "gqst:vw", 0, 1, false, (*Server).showOptions},
+	{"split-window", "splitw", "bc:de:F:hl:p:Pt:v", 0, -1, false, (*Server).splitWindow},
+	{"start-server", "start", "", 0, 0, true, (*Server).startServer},
+	{"swap-pane", "swapp", "dDs:t:U", 0, 0, false, (*Server).swapPane},
+	{"switch-client", "switchc", "t:", 0, 0, false, (*Server).switchClient},
+}
+
+// lookupCommand returns the command with the provided name or alias, or the
+// command with a name starting with name if it is unambiguous.
+func lookupCommand(name string) (*command, error) {
+	var matches []*command
+
+	for i := range commands {
+		cmd := &commands[i]
+
+		if cmd.name == name || cmd.alias == name {
+			return cmd, nil
+		}
+
+		if strings.HasPrefix(cmd.name, name) {
+			matches = append(matches, cmd)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("unknown command: %s", name)
+	case 1:
+		return matches[0], nil
+	}
+
+	names := make([]string, 0, len(matches))
+	for _, cmd := range matches {
+		names = append(names, cmd.name)
+	}
+
+	return nil, fmt.Errorf("ambiguous command: %s, could be: %s", name, strings.Join(names, ", "))
+}
+
+// parseCommand parses the arguments of a single tmux command.
+func parseCommand(args []string) (*command, *cmdArgs, error) {
+	cmd, err := lookupCommand(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parsed, err := parseArgs(cmd.name, cmd.template, cmd.minArgs, cmd.maxArgs, args[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cmd, parsed, nil
+}
+
+func (s *Server) newSession(a *cmdArgs, out *strings.Builder) error {
+	if !a.has('d') {
+		return errors.New("open terminal failed: not a terminal")
+	}
+
+	// Like tmux, colons and periods in session names are replaced, as they are
+	// used as separators in targets.
+	name := strings.NewReplacer(":", "_", ".", "_").Replace(a.get('s'))
+
+	for _, sess := range s.sessions {
+		if name != "" && sess.name == name {
+			return fmt.Errorf("duplicate session: %s", name)
+		}
+	}
+
+	sx, sy := 80, 24
+	fmt.Sscanf(s.options["default-size"], "%dx%d", &sx, &sy) //nolint:errcheck // Defaults are kept on error.
+
+	if a.has('x') {
+		n, err := strconv.Atoi(a.get('x'))
+		if err != nil {
+			return errors.New("width invalid")
+		}
+
+		if n < 1 {
+			return errors.New("width too small")
+		}
+
+		sx = n
+	}
+
+	if a.has('y') {
+		n, err := strconv.Atoi(a.get('y'))
+		if err != nil {
+			return errors.New("height invalid")
+		}
+
+		if n < 1 {
+			return errors.New("height too small")
+		}
+
+		sy = n
+	}
+
+	env := make(map[string]string)
+	if err := parseEnv(env, a.all('e')); err != nil {
+		return err
+	}
+
+	sess := &session{
+		id:   s.nextSession,
+		name: name,
+		path: s.path(a.get('c')),
+		sx:   sx,
+		sy:   sy,
+		env:  env,
+		opts: make(map[string]string),
+	}
+
+	s.nextSession++
+
+	if a.has('x') || a.has('y') {
+		sess.opts["default-size"] = fmt.Sprintf("%dx%d", sx, sy)
+	}
+
+	if sess.name == "" {
+		sess.name = strconv.Itoa(sess.id)
+	}
+
+	base, _ := strconv.Atoi(sess.option(s.options, "base-index"))
+	sess.curw = s.createWindow(sess, base, a.get('n'), sess.path, nil, strings.Join(a.args, " "))
+
+	s.sessions = append(s.sessions, sess)
+	s.touch(sess)
+
+	if a.has('P') {
+		s.printFormat(out, a.get('F'), "#{session_name}:", sess, sess.curw, sess.curw.active)
+	}
+
+	return nil
+}
+
+func (s *Server) newWindow(a *cmdArgs, out *strings.Builder) error {
+	sess, idx, err := s.findIndex(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	base, _ := strconv.Atoi(sess.option(s.options, "base-index"))
+
+	switch {
+	case a.has('a') || a.has('b'):
+		if idx == -1 {
+			idx = sess.curw.index
+		}
+
+		if a.has('a') {
+			idx++
+		}
+
+		shiftWindows(sess, idx)
+	case idx == -1:
+		idx = sess.nextIndex(base)
+	default:
+		if existing := sess.window(idx); existing != nil {
+			if !a.has('k') {
+				return fmt.Errorf("create window failed: index %d in use", idx)
+			}
+
+			sess.removeWindow(existing)
+		}
+	}
+
+	env := make(map[string]string)
+	if err := parseEnv(env, a.all('e')); err != nil {
+		return err
+	}
+
+	w := s.createWindow(sess, idx, a.get('n'), s.path(a.get('c')), env, strings.Join(a.args, " "))
+
+	if !a.has('d') || sess.curw == nil {
+		sess.curw = w
+	}
+
+	if a.has('P') {
+		s.printFormat(out, a.get('F'), "#{session_name}:#{window_index}.#{pane_index}", sess, w, w.active)
+	}
+
+	return nil
+}
+
+func (s *Server) splitWindow(a *cmdArgs, out *strings.Builder) error {
+	target, err := s.findPane(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	w := target.win
+	typ := cellTopBottom
+
+	if a.has('h') {
+		typ = cellLeftRight
+	}
+
+	size := -1
+
+	switch {
+	case a.has('l'):
+		if size, err = parseSize(a.get('l'), target.cell.size(typ)); err != nil {
+			return errors.New("lines invalid")
+		}
+	case a.has('p'):
+		if size, err = parseSize(a.get('p')+"%", target.cell.size(typ)); err != nil {
+			return errors.New("percentage invalid")
+		}
+	}
+
+	env := make(map[string]string)
+	if err := parseEnv(env, a.all('e')); err != nil {
+		return err
+	}
+
+	p := s.createPane(w.sess, s.path(a.get('c')), env, strings.Join(a.args, " "))
+
+	if _, err := target.cell.split(p, typ, size, a.has('b')); err != nil {
+		s.nextPane--
+		return err
+	}
+
+	if w.root.parent != nil {
+		w.root = w.root.parent
+	}
+
+	w.insertPane(p, target, a.has('b'))
+
+	if !a.has('d') {
+		w.setActive(p)
+	}
+
+	if a.has('P') {
+		s.printFormat(out, a.get('F'), "#{session_name}:#{window_index}.#{pane_index}", w.sess, w, p)
+	}
+
+	return nil
+}
+
+func (s *Server) sendKeys(a *cmdArgs, _ *strings.Builder) error {
+	p, err := s.findPane(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	for _, key := range a.args {
+		p.sendKey(key, a.has('l'))
+	}
+
+	return nil
+}
+
+func (s *Server) selectWindow(a *cmdArgs, _ *strings.Builder) error {
+	w, err := s.findWindow(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	sess := w.sess
+
+	if a.has('n') || a.has('p') {
+		pos := 0
+
+		for i, other := range sess.windows {
+			if other == sess.curw {
+				pos = i
+			}
+		}
+
+		if a.has('n') {
+			pos = (pos + 1) % len(sess.windows)
+		} else {
+			pos = (pos + len(sess.windows) - 1) % len(sess.windows)
+		}
+
+		w = sess.windows[pos]
+	}
+
+	sess.curw = w
+
+	return nil
+}
+
+func (s *Server) selectPane(a *cmdArgs, _ *strings.Builder) error {
+	p, err := s.findPane(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	p.win.setActive(p)
+
+	return nil
+}
+
+func (s *Server) selectLayout(a *cmdArgs, _ *strings.Builder) error {
+	w, err := s.findWindow(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	name := w.lastPreset
+
+	if len(a.args) > 0 {
+		name = a.args[0]
+	}
+
+	if name == "" {
+		return nil
+	}
+
+	if preset, ok := lookupPreset(name); ok {
+		mainOpt, mainAvail := "main-pane-width", w.root.sx
+
+		if preset == "main-horizontal" {
+			mainOpt, mainAvail = "main-pane-height", w.root.sy
+		}
+
+		mainSize, err := parseSize(w.option(s.options, mainOpt), mainAvail)
+		if err != nil {
+			mainSize = mainAvail
+		}
+
+		w.setLayout(presetLayout(preset, w.panes, w.root.sx, w.root.sy, mainSize))
+		w.lastPreset = preset
+
+		return nil
+	}
+
+	root, err := parseLayout(name)
+	if err != nil || checkLayout(root) != nil {
+		return fmt.Errorf("invalid layout: %s", name)
+	}
+
+	if n := len(root.leaves()); n != len(w.panes) {
+		return fmt.Errorf("have %d panes but need %d: %s", len(w.panes), n, name)
+	}
+
+	// Like tmux, the window is resized to the size of the layout.
+	w.setLayout(root)
+
+	return nil
+}
+
+func (s *Server) setOption(a *cmdArgs, _ *strings.Builder) error {
+	name := a.args[0]
+
+	if !validOption(name) {
+		if a.has('q') {
+			return nil
+		}
+
+		return fmt.Errorf("invalid option: %s", name)
+	}
+
+	opts, global, err := s.optionMap(a)
+	if err != nil {
+		return err
+	}
+
+	if a.has('u') {
+		delete(opts, name)
+
+		// Global options are reset to their default values.
+		if def, ok := optionDefaults[name]; ok && global {
+			opts[name] = def.value
+		}
+
+		return nil
+	}
+
+	cur, exists := opts[name]
+
+	if len(a.args) == 1 {
+		switch cur {
+		case "on":
+			opts[name] = "off"
+		case "off":
+			opts[name] = "on"
+		default:
+			return errors.New("empty value")
+		}
+
+		return nil
+	}
+
+	if a.has('o') && exists {
+		return fmt.Errorf("already set: %s", name)
+	}
+
+	if a.has('a') {
+		opts[name] = cur + a.args[1]
+		return nil
+	}
+
+	opts[name] = a.args[1]
+
+	return nil
+}
+
+func (s *Server) showOptions(a *cmdArgs, out *strings.Builder) error {
+	opts, _, err := s.optionMap(a)
+	if err != nil {
+		return err
+	}
+
+	names := a.args
+
+	if len(names) == 0 {
+		for name := range opts {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		val, ok := opts[name]
+
+		if !ok {
+			if validOption(name) && !strings.HasPrefix(name, "@") || a.has('q') {
+				continue
+			}
+
+			return fmt.Errorf("invalid option: %s", name)
+		}
+
+		if a.has('v') {
+			fmt.Fprintln(out, val)
+			continue
+		}
+
+		if val == "" || strings.ContainsAny(val, " #';${}%\"") {
+			val = strconv.Quote(val)
+		}
+
+		fmt.Fprintf(out, "%s %s\n", name, val)
+	}
+
+	return nil
+}
+
+// optionMap returns the options a set-option or show-options command applies
+// to, which are the global options, or the options of the target session or
+// window. The returned bool is true for the global options.
+func (s *Server) optionMap(a *cmdArgs) (map[string]string, bool, error) {
+	scope := scopeSession
+
+	if len(a.args) > 0 {
+		if opt, ok := optionDefaults[a.args[0]]; ok {
+			scope = opt.scope
+		}
+	}
+
+	switch {
+	case a.has('w'):
+		scope = scopeWindow
+	case a.has('s'):
+		scope = scopeServer
+	}
+
+	if a.has('g') || scope == scopeServer {
+		return s.options, true, nil
+	}
+
+	if scope == scopeWindow {
+		w, err := s.findWindow(a.get('t'))
+		if err != nil {
+			return nil, false, err
+		}
+
+		return w.opts, false, nil
+	}
+
+	sess, err := s.findSession(a.get('t'))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return sess.opts, false, nil
+}
+
+func (s *Server) displayMessage(a *cmdArgs, out *strings.Builder) error {
+	p, err := s.findPane(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	if !a.has('p') {
+		return nil
+	}
+
+	format := "[#{session_name}] #{window_index}:#{window_name}, current pane #{pane_index}"
+
+	if len(a.args) > 0 {
+		format = a.args[0]
+	}
+
+	s.printFormat(out, format, "", p.win.sess, p.win, p)
+
+	return nil
+}
+
+func (s *Server) listSessions(a *cmdArgs, out *strings.Builder) error {
+	sessions := append([]*session{}, s.sessions...)
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].name < sessions[j].name
+	})
+
+	for _, sess := range sessions {
+		s.printFormat(out, a.get('F'), "#{session_name}: #{session_windows} windows", sess, sess.curw, sess.curw.active)
+	}
+
+	return nil
+}
+
+func (s *Server) listWindows(a *cmdArgs, out *strings.Builder) error {
+	const defaultFormat = "#{window_index}: #{window_name} (#{window_panes} panes) " +
+		"[#{window_width}x#{window_height}] [layout #{window_layout}] #{window_id}"
+
+	sessions := s.sessions
+
+	if !a.has('a') {
+		sess, err := s.findSession(a.get('t'))
+		if err != nil {
+			return err
+		}
+
+		sessions = []*session{sess}
+	}
+
+	for _, sess := range sessions {
+		for _, w := range sess.windows {
+			s.printFormat(out, a.get('F'), defaultFormat, sess, w, w.active)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) listPanes(a *cmdArgs, out *strings.Builder) error {
+	const defaultFormat = "#{pane_index}: [#{pane_width}x#{pane_height}] #{pane_id}"
+
+	var windows []*window
+
+	switch {
+	case a.has('a'):
+		for _, sess := range s.sessions {
+			windows = append(windows, sess.windows...)
+		}
+	case a.has('s'):
+		sess, err := s.findSession(a.get('t'))
+		if err != nil {
+			return err
+		}
+
+		windows = sess.windows
+	default:
+		w, err := s.findWindow(a.get('t'))
+		if err != nil {
+			return err
+		}
+
+		windows = []*window{w}
+	}
+
+	for _, w := range windows {
+		for _, p := range w.panes {
+			s.printFormat(out, a.get('F'), defaultFormat, w.sess, w, p)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) killSession(a *cmdArgs, _ *strings.Builder) error {
+	sess, err := s.findSession(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	s.destroySession(sess)
+
+	return nil
+}
+
+func (s *Server) killWindow(a *cmdArgs, _ *strings.Builder) error {
+	w, err := s.findWindow(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	s.destroyWindow(w)
+
+	return nil
+}
+
+func (s *Server) killPane(a *cmdArgs, _ *strings.Builder) error {
+	p, err := s.findPane(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	if len(p.win.panes) == 1 {
+		s.destroyWindow(p.win)
+		return nil
+	}
+
+	p.win.removePane(p)
+
+	return nil
+}
+
+func (s *Server) killServer(_ *cmdArgs, _ *strings.Builder) error {
+	s.sessions = nil
+	return nil
+}
+
+func (s *Server) renameSession(a *cmdArgs, _ *strings.Builder) error {
+	sess, err := s.findSession(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	name := strings.NewReplacer(":", "_", ".", "_").Replace(a.args[0])
+
+	for _, other := range s.sessions {
+		if other != sess && other.name == name {
+			return fmt.Errorf("duplicate session: %s", name)
+		}
+	}
+
+	if s.attached == sess.name {
+		s.attached = name
+	}
+
+	sess.name = name
+
+	return nil
+}
+
+func (s *Server) renameWindow(a *cmdArgs, _ *strings.Builder) error {
+	w, err := s.findWindow(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	w.name = a.args[0]
+
+	return nil
+}
+
+func (s *Server) moveWindow(a *cmdArgs, _ *strings.Builder) error {
+	w, err := s.findWindow(a.get('s'))
+	if err != nil {
+		return err
+	}
+
+	dst, idx, err := s.findIndex(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	if idx == -1 {
+		base, _ := strconv.Atoi(dst.option(s.options, "base-index"))
+		idx = dst.nextIndex(base)
+	}
+
+	if existing := dst.window(idx); existing != nil {
+		if existing == w {
+			return nil
+		}
+
+		if !a.has('k') {
+			return fmt.Errorf("index in use: %d", idx)
+		}
+
+		dst.removeWindow(existing)
+	}
+
+	src := w.sess
+	src.removeWindow(w)
+
+	w.index = idx
+	dst.addWindow(w)
+
+	if !a.has('d') || dst.curw == nil {
+		dst.curw = w
+	}
+
+	if len(src.windows) == 0 {
+		s.destroySession(src)
+	}
+
+	return nil
+}
+
+func (s *Server) resizePane(a *cmdArgs, _ *strings.Builder) error {
+	p, err := s.findPane(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	if a.has('x') {
+		n, err := parseSize(a.get('x'), p.win.root.sx)
+		if err != nil {
+			return errors.New("width invalid")
+		}
+
+		resizeCellTo(p.cell, cellLeftRight, n)
+	}
+
+	if a.has('y') {
+		n, err := parseSize(a.get('y'), p.win.root.sy)
+		if err != nil {
+			return errors.New("height invalid")
+		}
+
+		resizeCellTo(p.cell, cellTopBottom, n)
+	}
+
+	adjust := 1
+
+	if len(a.args) > 0 {
+		if adjust, err = strconv.Atoi(a.args[0]); err != nil || adjust < 1 {
+			return errors.New("adjustment invalid")
+		}
+	}
+
+	switch {
+	case a.has('L'):
+		resizeCell(p.cell, cellLeftRight, -adjust)
+	case a.has('R'):
+		resizeCell(p.cell, cellLeftRight, adjust)
+	case a.has('U'):
+		resizeCell(p.cell, cellTopBottom, -adjust)
+	case a.has('D'):
+		resizeCell(p.cell, cellTopBottom, adjust)
+	}
+
+	return nil
+}
+
+func (s *Server) swapPane(a *cmdArgs, _ *strings.Builder) error {
+	dst, err := s.findPane(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	var src *pane
+
+	switch {
+	case a.has('D'), a.has('U'):
+		panes := dst.win.panes
+		pos := dst.win.paneIndex(s.options, dst) - dst.win.paneIndex(s.options, panes[0])
+
+		if a.has('D') {
+			src = panes[(pos+1)%len(panes)]
+		} else {
+			src = panes[(pos+len(panes)-1)%len(panes)]
+		}
+	default:
+		if src, err = s.findPane(a.get('s')); err != nil {
+			return err
+		}
+	}
+
+	if src == dst {
+		return nil
+	}
+
+	srcWin, dstWin := src.win, dst.win
+	srcPos := srcWin.paneIndex(s.options, src) - srcWin.paneIndex(s.options, srcWin.panes[0])
+	dstPos := dstWin.paneIndex(s.options, dst) - dstWin.paneIndex(s.options, dstWin.panes[0])
+
+	srcWin.panes[srcPos], dstWin.panes[dstPos] = dst, src
+	src.win, dst.win = dstWin, srcWin
+	src.cell, dst.cell = dst.cell, src.cell
+	src.cell.pane, dst.cell.pane = src, dst
+
+	if srcWin != dstWin {
+		srcWin.lastPanes = removePane(srcWin.lastPanes, src)
+		dstWin.lastPanes = removePane(dstWin.lastPanes, dst)
+
+		if srcWin.active == src {
+			srcWin.active = dst
+		}
+
+		if dstWin.active == dst {
+			dstWin.active = src
+		}
+	}
+
+	// Like tmux, the destination pane becomes active unless -d is set, in
+	// which case the active pane stays in place.
+	if a.has('d') {
+		if srcWin.active == src {
+			srcWin.setActive(dst)
+		}
+
+		if dstWin.active == dst {
+			dstWin.setActive(src)
+		}
+
+		return nil
+	}
+
+	srcWin.setActive(dst)
+
+	if srcWin != dstWin {
+		dstWin.setActive(src)
+	}
+
+	return nil
+}
+
+func (s *Server) respawnPane(a *cmdArgs, _ *strings.Builder) error {
+	p, err := s.findPane(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	if !a.has('k') {
+		return fmt.Errorf("respawn pane failed: pane %s:%d.%d still active",
+			p.win.sess.name, p.win.index, p.win.paneIndex(s.options, p))
+	}
+
+	env := copyEnv(p.win.sess.env)
+	if err := parseEnv(env, a.all('e')); err != nil {
+		return err
+	}
+
+	if a.has('c') {
+		p.path = a.get('c')
+	}
+
+	p.env = env
+	p.command = strings.Join(a.args, " ")
+	p.cmds = nil
+	p.input.Reset()
+
+	return nil
+}
+
+func (s *Server) hasSession(a *cmdArgs, _ *strings.Builder) error {
+	_, err := s.findSession(a.get('t'))
+	return err
+}
+
+func (s *Server) startServer(_ *cmdArgs, _ *strings.Builder) error {
+	return nil
+}
+
+func (s *Server) attachSession(a *cmdArgs, _ *strings.Builder) error {
+	sess, err := s.findSession(a.get('t'))
+	if err != nil {
+		return err
+	}
+
+	s.attached = sess.name
+	s.touch(sess)
+
+	return nil
+}
+
+func (s *Server) switchClient(a *cmdArgs, out *strings.Builder) error {
+	return s.attachSession(a, out)
+}
+
+// createWindow creates a window with a single pane in the session at the
+// provided index.
+func (s *Server) createWindow(sess *session, index int, name, path string, env map[string]string, command string) *window {
+	w := &window{id: s.nextWindow, index: index, name: name, opts: make(map[string]string)}
+	s.nextWindow++
+
+	p := s.createPane(sess, path, env, command)
+	p.win = w
+
+	w.panes = []*pane{p}
+	w.active = p
+	w.root = newLeaf(p, sess.sx, sess.sy)
+
+	if w.name == "" {
+		w.name = windowName(command, sess.option(s.options, "default-shell"))
+	}
+
+	sess.addWindow(w)
+
+	return w
+}
+
+// createPane creates a pane with the environment of the session merged with
+// env.
+func (s *Server) createPane(sess *session, path string, env map[string]string, command string) *pane {
+	p := &pane{id: s.nextPane, path: path, env: copyEnv(sess.env), command: command}
+	s.nextPane++
+
+	for k, v := range env {
+		p.env[k] = v
+	}
+
+	return p
+}
+
+// destroyWindow removes the window from its session and destroys the session
+// if it has no windows left.
+func (s *Server) destroyWindow(w *window) {
+	w.sess.removeWindow(w)
+
+	if len(w.sess.windows) == 0 {
+		s.destroySession(w.sess)
+	}
+}
+
+// destroySession removes the session from the server.
+func (s *Server) destroySession(sess *session) {
+	for i, other := range s.sessions {
+		if other == sess {
+			s.sessions = append(s.sessions[:i], s.sessions[i+1:]...)
+			break
+		}
+	}
+
+	if s.attached == sess.name {
+		s.attached = ""
+	}
+}
+
+// path returns the provided working directory, or the working directory of
+// the server if it is empty, like tmux uses the working directory of the
+// client running the command.
+func (s *Server) path(dir string) string {
+	if dir == "" {
+		return s.dir
+	}
+
+	return dir
+}
+
+// printFormat writes format expanded for the provided objects to out, or
+// defaultFormat if format is empty.
+func (s *Server) printFormat(out *strings.Builder, format, defaultFormat string, sess *session, w *window, p *pane) {
+	if format == "" {
+		format = defaultFormat
+	}
+
+	out.WriteString(formatContext{srv: s, sess: sess, win: w, pane: p}.expand(format))
+	out.WriteString("\n")
+}
+
+// shiftWindows moves the window at index and the windows directly following it
+// up by one index to make room for a new window.
+func shiftWindows(sess *session, index int) {
+	end := index
+
+	for sess.window(end) != nil {
+		end++
+	}
+
+	for idx := end - 1; idx >= index; idx-- {
+		sess.window(idx).index++
+	}
+}
+
+// resizeCell resizes the cell by change in the direction of typ, taking space
+// from or giving space to its next cell. Like tmux, the previous cell is
+// resized instead if the cell is the last in its parent.
+func resizeCell(c *cell, typ cellType, change int) {
+	c = resizeParent(c, typ)
+	if c == nil || change == 0 {
+		return
+	}
+
+	parent := c.parent
+
+	if pos := c.index(); pos == len(parent.children)-1 {
+		c = parent.children[pos-1]
+	}
+
+	other := parent.children[c.index()+1]
+
+	if change > 0 {
+		change = min(change, other.size(typ)-other.minSize(typ))
+	} else {
+		change = max(change, c.minSize(typ)-c.size(typ))
+	}
+
+	c.resize(typ, change)
+	other.resize(typ, -change)
+	parent.fixOffsets()
+}
+
+// resizeCellTo resizes the cell to size in the direction of typ.
+func resizeCellTo(c *cell, typ cellType, size int) {
+	lc := resizeParent(c, typ)
+	if lc == nil {
+		return
+	}
+
+	change := size - lc.size(typ)
+
+	if lc.index() == len(lc.parent.children)-1 {
+		change = -change
+	}
+
+	resizeCell(c, typ, change)
+}
+
+// resizeParent returns the cell or its nearest ancestor whose parent is split
+// in the direction of typ, or nil if there is none.
+func resizeParent(c *cell, typ cellType) *cell {
+	for c.parent != nil && c.parent.typ != typ {
+		c = c.parent
+	}
+
+	if c.parent == nil {
+		return nil
+	}
+
+	return c
+}
+
+// parseSize parses a size as a number of lines or columns, or as a percentage
+// of avail if it ends with a percent sign.
+func parseSize(size string, avail int) (int, error) {
+	if pct, ok := strings.CutSuffix(size, "%"); ok {
+		n, err := strconv.Atoi(pct)
+		if err != nil || n < 0 {
+			return 0, errors.New("invalid size")
+		}
+
+		return avail * n / 100, nil
+	}
+
+	n, err := strconv.Atoi(size)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid size")
+	}
+
+	return n, nil
+}
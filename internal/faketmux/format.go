@@ -0,0 +1,187 @@
+package faketmux
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// formatContext holds the objects a format is expanded for. Any of the fields
+// may be nil.
+type formatContext struct {
+	srv  *Server
+	sess *session
+	win  *window
+	pane *pane
+}
+
+// expand expands the tmux format string for the context.
+//
+// Variables in the form #{name} are replaced with their values and ## is
+// replaced with a single #. Unknown variables expand to an empty string, like
+// they do in tmux. The only supported modifier is the s/pattern/replacement/
+// substitution in the form #{s/pattern/replacement/:name}.
+func (fc formatContext) expand(format string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '#' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+
+		switch format[i+1] {
+		case '#':
+			b.WriteByte('#')
+			i++
+		case '{':
+			end := strings.IndexByte(format[i:], '}')
+			if end == -1 {
+				b.WriteString(format[i:])
+				return b.String()
+			}
+
+			b.WriteString(fc.variable(format[i+2 : i+end]))
+			i += end
+		default:
+			b.WriteByte('#')
+		}
+	}
+
+	return b.String()
+}
+
+// variable returns the value of the format variable expression, which is a
+// variable name optionally prefixed with a substitution modifier.
+func (fc formatContext) variable(expr string) string {
+	if !strings.HasPrefix(expr, "s/") {
+		return fc.lookup(expr)
+	}
+
+	parts := strings.SplitN(expr[2:], "/", 3)
+	if len(parts) != 3 {
+		return ""
+	}
+
+	_, name, ok := strings.Cut(parts[2], ":")
+	if !ok {
+		return ""
+	}
+
+	re, err := regexp.Compile(parts[0])
+	if err != nil {
+		return ""
+	}
+
+	return re.ReplaceAllString(fc.lookup(name), substReplacement(parts[1]))
+}
+
+// substReplacement converts the replacement of a substitution modifier, where
+// \1 to \9 refer to pattern groups and backslashes escape the next character,
+// to the template syntax used by [regexp.Regexp.ReplaceAllString].
+func substReplacement(repl string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(repl); i++ {
+		switch c := repl[i]; {
+		case c == '$':
+			b.WriteString("$$")
+		case c == '\\' && i+1 < len(repl):
+			i++
+
+			if repl[i] >= '1' && repl[i] <= '9' {
+				b.WriteString("${" + string(repl[i]) + "}")
+				continue
+			}
+
+			if repl[i] == '$' {
+				b.WriteString("$$")
+				continue
+			}
+
+			b.WriteByte(repl[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// lookup returns the value of the named format variable.
+func (fc formatContext) lookup(name string) string {
+	s, w, p := fc.sess, fc.win, fc.pane
+
+	switch {
+	case strings.HasPrefix(name, "session_") && s != nil:
+		switch name {
+		case "session_id":
+			return "$" + strconv.Itoa(s.id)
+		case "session_name":
+			return s.name
+		case "session_path":
+			return s.path
+		case "session_windows":
+			return strconv.Itoa(len(s.windows))
+		case "session_attached":
+			return boolFormat(fc.srv.attached == s.name)
+		}
+	case strings.HasPrefix(name, "window_") && w != nil:
+		switch name {
+		case "window_id":
+			return "@" + strconv.Itoa(w.id)
+		case "window_index":
+			return strconv.Itoa(w.index)
+		case "window_name":
+			return w.name
+		case "window_width":
+			return strconv.Itoa(w.root.sx)
+		case "window_height":
+			return strconv.Itoa(w.root.sy)
+		case "window_layout", "window_visible_layout":
+			return dumpLayout(w.root)
+		case "window_active":
+			return boolFormat(w.sess.curw == w)
+		case "window_panes":
+			return strconv.Itoa(len(w.panes))
+		}
+	case strings.HasPrefix(name, "pane_") && p != nil:
+		switch name {
+		case "pane_id":
+			return "%" + strconv.Itoa(p.id)
+		case "pane_index":
+			return strconv.Itoa(p.win.paneIndex(fc.srv.options, p))
+		case "pane_width":
+			return strconv.Itoa(p.cell.sx)
+		case "pane_height":
+			return strconv.Itoa(p.cell.sy)
+		case "pane_left":
+			return strconv.Itoa(p.cell.x)
+		case "pane_top":
+			return strconv.Itoa(p.cell.y)
+		case "pane_active":
+			return boolFormat(p.win.active == p)
+		case "pane_current_path", "pane_start_path":
+			return p.path
+		case "pane_start_command":
+			return p.command
+		case "pane_current_command":
+			return windowName(p.command, p.win.sess.option(fc.srv.options, "default-shell"))
+		case "pane_dead":
+			return "0"
+		}
+	case name == "socket_path":
+		return fc.srv.socket
+	}
+
+	return ""
+}
+
+// boolFormat returns the format value for a boolean.
+func boolFormat(b bool) string {
+	if b {
+		return "1"
+	}
+
+	return "0"
+}
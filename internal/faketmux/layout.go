@@ -0,0 +1,618 @@
+package faketmux
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cellType is the type of a layout cell.
+type cellType int
+
+const (
+	cellPane      cellType = iota // Leaf cell holding a pane.
+	cellLeftRight                 // Container with cells side by side.
+	cellTopBottom                 // Container with cells stacked.
+)
+
+// errInvalidLayout is returned when a custom layout string cannot be parsed.
+var errInvalidLayout = errors.New("invalid layout")
+
+// cell is a node in the layout tree of a window, modeled after the layout
+// cells of tmux.
+//
+// Containers hold two or more child cells, which are separated by a border of
+// one column or row.
+type cell struct {
+	typ      cellType
+	parent   *cell
+	children []*cell
+	pane     *pane
+	sx, sy   int
+	x, y     int
+}
+
+// newLeaf returns a new leaf cell with the provided size holding p.
+func newLeaf(p *pane, sx, sy int) *cell {
+	c := &cell{typ: cellPane, sx: sx, sy: sy, pane: p}
+
+	if p != nil {
+		p.cell = c
+	}
+
+	return c
+}
+
+// size returns the size of the cell in the direction of typ.
+func (c *cell) size(typ cellType) int {
+	if typ == cellLeftRight {
+		return c.sx
+	}
+
+	return c.sy
+}
+
+// setSize sets the size of the cell in the direction of typ.
+func (c *cell) setSize(typ cellType, n int) {
+	if typ == cellLeftRight {
+		c.sx = n
+	} else {
+		c.sy = n
+	}
+}
+
+// leaves returns the leaf cells in layout order.
+func (c *cell) leaves() []*cell {
+	if c.typ == cellPane {
+		return []*cell{c}
+	}
+
+	var res []*cell
+
+	for _, child := range c.children {
+		res = append(res, child.leaves()...)
+	}
+
+	return res
+}
+
+// replace replaces the cell with other in its parent.
+func (c *cell) replace(other *cell) {
+	other.parent = c.parent
+
+	if c.parent == nil {
+		return
+	}
+
+	for i, child := range c.parent.children {
+		if child == c {
+			c.parent.children[i] = other
+			return
+		}
+	}
+}
+
+// index returns the position of the cell in its parent.
+func (c *cell) index() int {
+	for i, child := range c.parent.children {
+		if child == c {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// fixOffsets recalculates the offsets of all cells below c.
+func (c *cell) fixOffsets() {
+	x, y := c.x, c.y
+
+	for _, child := range c.children {
+		child.x, child.y = x, y
+
+		if c.typ == cellLeftRight {
+			x += child.sx + 1
+		} else {
+			y += child.sy + 1
+		}
+
+		child.fixOffsets()
+	}
+}
+
+// minSize returns the smallest size the cell can be resized to in the
+// direction of typ.
+func (c *cell) minSize(typ cellType) int {
+	if c.typ == cellPane {
+		return 1
+	}
+
+	if c.typ != typ {
+		res := 1
+
+		for _, child := range c.children {
+			res = max(res, child.minSize(typ))
+		}
+
+		return res
+	}
+
+	res := len(c.children) - 1
+
+	for _, child := range c.children {
+		res += child.minSize(typ)
+	}
+
+	return res
+}
+
+// resize changes the size of the cell by change in the direction of typ.
+//
+// Like tmux, space is added to or taken from the last child cells of
+// containers in the same direction, and all children of containers in the
+// other direction are resized.
+func (c *cell) resize(typ cellType, change int) {
+	c.setSize(typ, c.size(typ)+change)
+
+	if c.typ == cellPane {
+		return
+	}
+
+	if c.typ != typ {
+		for _, child := range c.children {
+			child.resize(typ, change)
+		}
+
+		return
+	}
+
+	if change > 0 {
+		c.children[len(c.children)-1].resize(typ, change)
+		return
+	}
+
+	for i := len(c.children) - 1; i >= 0 && change < 0; i-- {
+		child := c.children[i]
+		n := max(change, child.minSize(typ)-child.size(typ))
+
+		child.resize(typ, n)
+		change -= n
+	}
+}
+
+// split splits the leaf cell and returns the new leaf cell for p.
+//
+// The new cell is size columns or rows wide in the direction of typ, or half
+// of the available space if size is -1. The new cell is placed after the cell,
+// or before it if before is true.
+func (c *cell) split(p *pane, typ cellType, size int, before bool) (*cell, error) {
+	avail := c.size(typ)
+	if avail < 3 {
+		return nil, errors.New("no space for new pane")
+	}
+
+	if size == -1 {
+		size = (avail+1)/2 - 1
+	}
+
+	size = min(max(size, 1), avail-2)
+
+	newCell := newLeaf(p, c.sx, c.sy)
+	newCell.setSize(typ, size)
+
+	if c.parent == nil || c.parent.typ != typ {
+		// The cell is replaced by a container holding the cell and the new
+		// cell, as the split is in another direction than the parent.
+		container := &cell{typ: typ, sx: c.sx, sy: c.sy, x: c.x, y: c.y}
+
+		c.replace(container)
+		c.parent = container
+		container.children = []*cell{c}
+	}
+
+	parent := c.parent
+	pos := c.index() + 1
+
+	if before {
+		pos--
+	}
+
+	newCell.parent = parent
+	parent.children = append(parent.children[:pos], append([]*cell{newCell}, parent.children[pos:]...)...)
+
+	c.resize(typ, -(size + 1))
+	parent.fixOffsets()
+
+	return newCell, nil
+}
+
+// remove removes the leaf cell from the layout and returns the new root cell.
+//
+// Like tmux, the space of the cell is given to the previous cell in its
+// parent, or the next cell if it is the first.
+func (c *cell) remove(root *cell) *cell {
+	parent := c.parent
+	if parent == nil {
+		return nil
+	}
+
+	pos := c.index()
+	other := parent.children[max(pos-1, 0)]
+
+	if pos == 0 {
+		other = parent.children[1]
+	}
+
+	other.resize(parent.typ, c.size(parent.typ)+1)
+	parent.children = append(parent.children[:pos], parent.children[pos+1:]...)
+
+	if len(parent.children) == 1 {
+		child := parent.children[0]
+		child.x, child.y = parent.x, parent.y
+		parent.replace(child)
+
+		if parent == root {
+			root = child
+		}
+	}
+
+	root.fixOffsets()
+
+	return root
+}
+
+// String returns the layout description of the cell as reported by the
+// window_layout format, without the checksum.
+func (c *cell) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%dx%d,%d,%d", c.sx, c.sy, c.x, c.y)
+
+	switch c.typ {
+	case cellPane:
+		fmt.Fprintf(&b, ",%d", c.pane.id)
+	case cellLeftRight, cellTopBottom:
+		open, closing := "{", "}"
+		if c.typ == cellTopBottom {
+			open, closing = "[", "]"
+		}
+
+		b.WriteString(open)
+
+		for i, child := range c.children {
+			if i > 0 {
+				b.WriteString(",")
+			}
+
+			b.WriteString(child.String())
+		}
+
+		b.WriteString(closing)
+	}
+
+	return b.String()
+}
+
+// dumpLayout returns the layout string for the layout tree starting at root,
+// prefixed with its checksum like tmux does.
+func dumpLayout(root *cell) string {
+	body := root.String()
+	return fmt.Sprintf("%04x,%s", layoutChecksum(body), body)
+}
+
+// layoutChecksum returns the checksum of a layout description.
+func layoutChecksum(body string) uint16 {
+	var csum uint16
+
+	for i := 0; i < len(body); i++ {
+		csum = (csum >> 1) + ((csum & 1) << 15)
+		csum += uint16(body[i])
+	}
+
+	return csum
+}
+
+// parseLayout parses a custom layout string as reported by the window_layout
+// format and returns the root cell of the layout.
+func parseLayout(layout string) (*cell, error) {
+	csum, body, ok := strings.Cut(layout, ",")
+	if !ok || len(csum) != 4 {
+		return nil, errInvalidLayout
+	}
+
+	want, err := strconv.ParseUint(csum, 16, 16)
+	if err != nil || uint16(want) != layoutChecksum(body) {
+		return nil, errInvalidLayout
+	}
+
+	root, rest, err := parseCell(body)
+	if err != nil || rest != "" {
+		return nil, errInvalidLayout
+	}
+
+	return root, nil
+}
+
+// parseCell parses a single cell from the beginning of s and returns it with
+// the rest of s.
+func parseCell(s string) (*cell, string, error) {
+	var (
+		c  = &cell{typ: cellPane}
+		ok bool
+	)
+
+	if c.sx, s, ok = parseNum(s, 'x'); !ok {
+		return nil, "", errInvalidLayout
+	}
+
+	if c.sy, s, ok = parseNum(s, ','); !ok {
+		return nil, "", errInvalidLayout
+	}
+
+	if c.x, s, ok = parseNum(s, ','); !ok {
+		return nil, "", errInvalidLayout
+	}
+
+	if c.y, s, ok = parseNum(s, 0); !ok || s == "" || c.sx < 1 || c.sy < 1 {
+		return nil, "", errInvalidLayout
+	}
+
+	if s[0] == ',' {
+		if _, s, ok = parseNum(s[1:], 0); !ok {
+			return nil, "", errInvalidLayout
+		}
+
+		return c, s, nil
+	}
+
+	if s[0] != '{' && s[0] != '[' {
+		return nil, "", errInvalidLayout
+	}
+
+	c.typ = cellLeftRight
+	closing := byte('}')
+
+	if s[0] == '[' {
+		c.typ = cellTopBottom
+		closing = ']'
+	}
+
+	s = s[1:]
+
+	for {
+		child, rest, err := parseCell(s)
+		if err != nil {
+			return nil, "", err
+		}
+
+		child.parent = c
+		c.children = append(c.children, child)
+
+		if rest == "" {
+			return nil, "", errInvalidLayout
+		}
+
+		if rest[0] == closing {
+			return c, rest[1:], nil
+		}
+
+		if rest[0] != ',' {
+			return nil, "", errInvalidLayout
+		}
+
+		s = rest[1:]
+	}
+}
+
+// parseNum parses a number from the beginning of s followed by the separator
+// sep, and returns it with the rest of s. The separator is not required if
+// sep is 0.
+func parseNum(s string, sep byte) (int, string, bool) {
+	i := 0
+
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+
+	n, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return 0, "", false
+	}
+
+	s = s[i:]
+
+	if sep == 0 {
+		return n, s, true
+	}
+
+	if s == "" || s[0] != sep {
+		return 0, "", false
+	}
+
+	return n, s[1:], true
+}
+
+// checkLayout returns an error if the child cells of c do not fill c exactly.
+func checkLayout(c *cell) error {
+	if c.typ == cellPane {
+		return nil
+	}
+
+	total := -1
+
+	for _, child := range c.children {
+		if c.typ == cellLeftRight && child.sy != c.sy || c.typ == cellTopBottom && child.sx != c.sx {
+			return errInvalidLayout
+		}
+
+		total += child.size(c.typ) + 1
+
+		if err := checkLayout(child); err != nil {
+			return err
+		}
+	}
+
+	if total != c.size(c.typ) {
+		return errInvalidLayout
+	}
+
+	return nil
+}
+
+// layoutPresets are the names of the preset layouts supported by the
+// select-layout command.
+var layoutPresets = []string{"even-horizontal", "even-vertical", "main-horizontal", "main-vertical", "tiled"}
+
+// lookupPreset returns the preset layout name matching name or an
+// unambiguous prefix of a name.
+func lookupPreset(name string) (string, bool) {
+	match := ""
+
+	for _, preset := range layoutPresets {
+		if preset == name {
+			return preset, true
+		}
+
+		if strings.HasPrefix(preset, name) {
+			if match != "" {
+				return "", false
+			}
+
+			match = preset
+		}
+	}
+
+	return match, match != ""
+}
+
+// presetLayout returns the root cell of the named preset layout for panes in
+// a window of the provided size.
+//
+// mainSize is the size of the main pane for the main-horizontal and
+// main-vertical layouts.
+func presetLayout(name string, panes []*pane, sx, sy, mainSize int) *cell {
+	if len(panes) == 1 {
+		return newLeaf(panes[0], sx, sy)
+	}
+
+	var root *cell
+
+	switch name {
+	case "even-horizontal":
+		root = spreadCells(cellLeftRight, panes, sx, sy)
+	case "even-vertical":
+		root = spreadCells(cellTopBottom, panes, sx, sy)
+	case "main-horizontal":
+		mainSize = min(mainSize, sy-2)
+		root = &cell{typ: cellTopBottom, sx: sx, sy: sy}
+		root.children = []*cell{
+			newLeaf(panes[0], sx, mainSize),
+			spreadCells(cellLeftRight, panes[1:], sx, sy-mainSize-1),
+		}
+	case "main-vertical":
+		mainSize = min(mainSize, sx-2)
+		root = &cell{typ: cellLeftRight, sx: sx, sy: sy}
+		root.children = []*cell{
+			newLeaf(panes[0], mainSize, sy),
+			spreadCells(cellTopBottom, panes[1:], sx-mainSize-1, sy),
+		}
+	default:
+		root = tiledLayout(panes, sx, sy)
+	}
+
+	for _, child := range root.children {
+		child.parent = root
+	}
+
+	root.fixOffsets()
+
+	return root
+}
+
+// spreadCells returns a container of type typ and the provided size, holding a
+// leaf cell for each pane. The space is spread evenly and the remainder is
+// given to the last cell.
+//
+// A single leaf cell is returned if there is only one pane.
+func spreadCells(typ cellType, panes []*pane, sx, sy int) *cell {
+	if len(panes) == 1 {
+		return newLeaf(panes[0], sx, sy)
+	}
+
+	c := &cell{typ: typ, sx: sx, sy: sy}
+	avail := c.size(typ) - (len(panes) - 1)
+	each := avail / len(panes)
+
+	for i, p := range panes {
+		leaf := newLeaf(p, sx, sy)
+		leaf.parent = c
+
+		if i == len(panes)-1 {
+			leaf.setSize(typ, avail-each*(len(panes)-1))
+		} else {
+			leaf.setSize(typ, each)
+		}
+
+		c.children = append(c.children, leaf)
+	}
+
+	return c
+}
+
+// tiledLayout returns the root cell of the tiled layout for panes.
+func tiledLayout(panes []*pane, sx, sy int) *cell {
+	rows, cols := 1, 1
+
+	for rows*cols < len(panes) {
+		rows++
+
+		if rows*cols < len(panes) {
+			cols++
+		}
+	}
+
+	height := (sy - (rows - 1)) / rows
+	root := &cell{typ: cellTopBottom, sx: sx, sy: sy}
+
+	for r := 0; r < rows; r++ {
+		rowPanes := panes[r*cols : min((r+1)*cols, len(panes))]
+		rowHeight := height
+
+		if r == rows-1 {
+			rowHeight = sy - (rows-1)*(height+1)
+		}
+
+		if len(rowPanes) < cols {
+			root.children = append(root.children, spreadCells(cellLeftRight, rowPanes, sx, rowHeight))
+			continue
+		}
+
+		row := &cell{typ: cellLeftRight, sx: sx, sy: rowHeight}
+		width := (sx - (cols - 1)) / cols
+
+		for i, p := range rowPanes {
+			leaf := newLeaf(p, width, rowHeight)
+
+			if i == cols-1 {
+				leaf.sx = sx - (cols-1)*(width+1)
+			}
+
+			leaf.parent = row
+			row.children = append(row.children, leaf)
+		}
+
+		if len(row.children) == 1 {
+			row = row.children[0]
+		}
+
+		root.children = append(root.children, row)
+	}
+
+	if len(root.children) == 1 {
+		root = root.children[0]
+		root.parent = nil
+	}
+
+	return root
+}
@@ -0,0 +1,98 @@
+package faketmux
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// optionScope is the scope of a tmux option.
+type optionScope int
+
+const (
+	scopeServer optionScope = iota
+	scopeSession
+	scopeWindow
+)
+
+// optionDefaults are the tmux options known to the server and their default
+// values. Options not in this table are rejected as invalid, except for user
+// options starting with @.
+var optionDefaults = map[string]struct {
+	scope optionScope
+	value string
+}{
+	"buffer-limit":        {scopeServer, "50"},
+	"default-terminal":    {scopeServer, "tmux-256color"},
+	"escape-time":         {scopeServer, "500"},
+	"exit-empty":          {scopeServer, "on"},
+	"extended-keys":       {scopeServer, "off"},
+	"focus-events":        {scopeServer, "off"},
+	"history-file":        {scopeServer, ""},
+	"set-clipboard":       {scopeServer, "external"},
+	"base-index":          {scopeSession, "0"},
+	"default-command":     {scopeSession, ""},
+	"default-shell":       {scopeSession, "/bin/sh"},
+	"default-size":        {scopeSession, "80x24"},
+	"destroy-unattached":  {scopeSession, "off"},
+	"detach-on-destroy":   {scopeSession, "on"},
+	"display-time":        {scopeSession, "750"},
+	"history-limit":       {scopeSession, "2000"},
+	"mouse":               {scopeSession, "off"},
+	"prefix":              {scopeSession, "C-b"},
+	"renumber-windows":    {scopeSession, "off"},
+	"repeat-time":         {scopeSession, "500"},
+	"set-titles":          {scopeSession, "off"},
+	"status":              {scopeSession, "on"},
+	"status-keys":         {scopeSession, "emacs"},
+	"status-left":         {scopeSession, "[#{session_name}] "},
+	"status-position":     {scopeSession, "bottom"},
+	"status-style":        {scopeSession, "bg=green,fg=black"},
+	"visual-activity":     {scopeSession, "off"},
+	"aggressive-resize":   {scopeWindow, "off"},
+	"allow-rename":        {scopeWindow, "off"},
+	"automatic-rename":    {scopeWindow, "on"},
+	"clock-mode-style":    {scopeWindow, "24"},
+	"main-pane-height":    {scopeWindow, "24"},
+	"main-pane-width":     {scopeWindow, "80"},
+	"mode-keys":           {scopeWindow, "emacs"},
+	"monitor-activity":    {scopeWindow, "off"},
+	"other-pane-height":   {scopeWindow, "0"},
+	"other-pane-width":    {scopeWindow, "0"},
+	"pane-base-index":     {scopeWindow, "0"},
+	"pane-border-status":  {scopeWindow, "off"},
+	"remain-on-exit":      {scopeWindow, "off"},
+	"synchronize-panes":   {scopeWindow, "off"},
+	"window-size":         {scopeWindow, "latest"},
+	"window-status-style": {scopeWindow, "default"},
+	"wrap-search":         {scopeWindow, "on"},
+}
+
+// defaultOptions returns the global options of a new server.
+//
+// Like tmux, the default-shell option defaults to the SHELL environment
+// variable if it is set to an absolute path.
+func defaultOptions() map[string]string {
+	res := make(map[string]string, len(optionDefaults))
+
+	for name, opt := range optionDefaults {
+		res[name] = opt.value
+	}
+
+	if shell := os.Getenv("SHELL"); filepath.IsAbs(shell) {
+		res["default-shell"] = shell
+	}
+
+	return res
+}
+
+// validOption returns true if name is a known option or a user option.
+func validOption(name string) bool {
+	if strings.HasPrefix(name, "@") && len(name) > 1 {
+		return true
+	}
+
+	_, ok := optionDefaults[name]
+
+	return ok
+}
@@ -0,0 +1,515 @@
+// Package faketmux provides an in-memory simulation of a tmux server.
+//
+// The [Server] implements [tmux.Runner] by running tmux commands against
+// sessions, windows and panes kept in memory instead of starting tmux
+// processes. It understands the subset of tmux commands used by tmpl, assigns
+// IDs and indexes, calculates pane sizes and layouts, expands formats given
+// with -F, and fails with the same error messages as tmux. Commands and flags
+// that are not supported fail like unknown commands and flags do in tmux.
+//
+// The server is used for dry-runs and tests.
+package faketmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// errExitStatus is returned by the command runner of the server when a command
+// fails, like the error returned when a tmux process exits with status 1.
+var errExitStatus = errors.New("exit status 1")
+
+// Server is an in-memory tmux server implementing [tmux.Runner].
+//
+// Like a real tmux server, the server starts when a session is created and
+// exits when the last session is killed, after which all IDs, indexes and
+// global options are reset.
+type Server struct {
+	mu        sync.Mutex
+	runner    *tmux.DefaultRunner
+	logger    *slog.Logger
+	redaction *tmux.Redaction
+	tmux      string
+	tmuxOpts  []string
+	dryRun    bool
+	dir       string
+	initOpts  map[string]string
+	socketDir string
+
+	socket      string
+	running     bool
+	options     map[string]string
+	sessions    []*session
+	nextSession int
+	nextWindow  int
+	nextPane    int
+	used        int
+	attached    string
+}
+
+// NewServer creates a new [Server] with the provided options.
+func NewServer(opts ...ServerOption) (*Server, error) {
+	s := &Server{
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		redaction: tmux.NewRedaction(tmux.DefaultRedactPatterns...),
+		tmux:      tmux.DefaultTmux,
+		initOpts:  make(map[string]string),
+	}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, fmt.Errorf("applying option: %w", err)
+		}
+	}
+
+	if s.dir == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("getting working directory: %w", err)
+		}
+
+		s.dir = dir
+	}
+
+	if s.socketDir == "" {
+		tmpDir := os.Getenv("TMUX_TMPDIR")
+		if tmpDir == "" {
+			tmpDir = "/tmp"
+		}
+
+		s.socketDir = filepath.Join(tmpDir, fmt.Sprintf("tmux-%d", os.Getuid()))
+	}
+
+	s.reset()
+
+	runner, err := tmux.NewRunner(
+		tmux.WithTmux(s.tmux),
+		tmux.WithTmuxOptions(s.tmuxOpts...),
+		tmux.WithOSCommandRunner(s.exec),
+		tmux.WithLogger(s.wrapLogger(s.logger)),
+		tmux.WithRedaction(s.redaction),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating runner: %w", err)
+	}
+
+	s.runner = runner
+
+	return s, nil
+}
+
+// Run runs the tmux command with the provided arguments against the server
+// and returns the output.
+//
+// Commands can be separated with semicolon arguments like with tmux. If a
+// command fails, the remaining commands are not run and the output ends with
+// the error message. The returned error is a [tmux.CommandError] if the error
+// message is a known tmux error message.
+func (s *Server) Run(ctx context.Context, args ...string) ([]byte, error) {
+	return s.runner.Run(ctx, args...) //nolint:wrapcheck // Wrapping is done by caller.
+}
+
+// Execve runs the tmux command with the provided arguments against the server.
+//
+// Unlike [tmux.DefaultRunner.Execve], the current process is not replaced, and
+// the method returns after the command has run. Attaching or switching the
+// client to a session marks it as attached, which is reported by
+// [Server.Attached].
+func (s *Server) Execve(args ...string) error {
+	start := time.Now()
+	args = append(append([]string{}, s.tmuxOpts...), args...)
+	msg := "execve successful"
+
+	output, err := s.exec(context.Background(), s.tmux, args...)
+	if err != nil {
+		msg = "execve failed"
+		err = errors.New(strings.TrimSpace(string(output)))
+	}
+
+	s.runner.Debug(msg, "path", s.tmux, "args", args, "dur", time.Since(start))
+
+	return err
+}
+
+// IsDryRun returns true if the server is in dry-run mode.
+func (s *Server) IsDryRun() bool {
+	return s.dryRun
+}
+
+// Debug logs a debug message using a [slog.Logger].
+func (s *Server) Debug(msg string, args ...any) {
+	s.runner.Debug(msg, args...)
+}
+
+// Log logs an info message using a [slog.Logger].
+func (s *Server) Log(msg string, args ...any) {
+	s.runner.Log(msg, args...)
+}
+
+// SetLogger sets the logger used by the server.
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.runner.SetLogger(s.wrapLogger(logger))
+}
+
+// Redact registers values that must not be written to logs.
+func (s *Server) Redact(values ...string) {
+	s.runner.Redact(values...)
+}
+
+// Attached returns the name of the session the client was attached or
+// switched to with [Server.Execve], or an empty string.
+func (s *Server) Attached() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attached
+}
+
+// Session is a snapshot of a session on a [Server].
+type Session struct {
+	ID      string
+	Name    string
+	Path    string
+	Width   int
+	Height  int
+	Env     map[string]string
+	Windows []Window
+}
+
+// Window is a snapshot of a window on a [Server].
+type Window struct {
+	ID      string
+	Name    string
+	Index   int
+	Layout  string
+	Active  bool
+	Options map[string]string
+	Panes   []Pane
+}
+
+// Pane is a snapshot of a pane on a [Server].
+//
+// Commands holds the lines of input entered in the pane with the send-keys
+// command, and Input holds the input typed after the last entered line.
+type Pane struct {
+	ID       string
+	Index    int
+	Path     string
+	Width    int
+	Height   int
+	Active   bool
+	Env      map[string]string
+	Command  string
+	Commands []string
+	Input    string
+}
+
+// Sessions returns snapshots of the sessions on the server, sorted by name
+// like the list-sessions command.
+func (s *Server) Sessions() []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]Session, 0, len(s.sessions))
+
+	for _, sess := range s.sessions {
+		snap := Session{
+			ID:     "$" + strconv.Itoa(sess.id),
+			Name:   sess.name,
+			Path:   sess.path,
+			Width:  sess.sx,
+			Height: sess.sy,
+			Env:    copyEnv(sess.env),
+		}
+
+		for _, w := range sess.windows {
+			snap.Windows = append(snap.Windows, s.windowSnapshot(w))
+		}
+
+		res = append(res, snap)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
+
+// Session returns a snapshot of the named session and true, or false if the
+// session does not exist.
+func (s *Server) Session(name string) (Session, bool) {
+	for _, sess := range s.Sessions() {
+		if sess.Name == name {
+			return sess, true
+		}
+	}
+
+	return Session{}, false
+}
+
+func (s *Server) windowSnapshot(w *window) Window {
+	snap := Window{
+		ID:      "@" + strconv.Itoa(w.id),
+		Name:    w.name,
+		Index:   w.index,
+		Layout:  dumpLayout(w.root),
+		Active:  w.sess.curw == w,
+		Options: copyEnv(w.opts),
+	}
+
+	for _, p := range w.panes {
+		snap.Panes = append(snap.Panes, Pane{
+			ID:       "%" + strconv.Itoa(p.id),
+			Index:    w.paneIndex(s.options, p),
+			Path:     p.path,
+			Width:    p.cell.sx,
+			Height:   p.cell.sy,
+			Active:   w.active == p,
+			Env:      copyEnv(p.env),
+			Command:  p.command,
+			Commands: slices.Clone(p.cmds),
+			Input:    p.input.String(),
+		})
+	}
+
+	return snap
+}
+
+// exec runs a tmux command line against the server. It is used as the
+// [tmux.OSCommandRunner] of the [tmux.DefaultRunner] the server delegates to,
+// which takes care of logging and error parsing.
+func (s *Server) exec(ctx context.Context, _ string, args ...string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	args, socket, err := parseGlobalFlags(args, s.socketDir)
+	if err != nil {
+		return []byte(err.Error() + "\n"), errExitStatus
+	}
+
+	if len(args) == 0 {
+		args = []string{"new-session"}
+	}
+
+	type parsedCommand struct {
+		cmd  *command
+		args *cmdArgs
+	}
+
+	var (
+		parsed      []parsedCommand
+		startServer bool
+	)
+
+	// Like tmux, the whole command line is parsed before any command is run.
+	for _, cmdArgs := range splitCommands(args) {
+		cmd, a, err := parseCommand(cmdArgs)
+		if err != nil {
+			return []byte(err.Error() + "\n"), errExitStatus
+		}
+
+		parsed = append(parsed, parsedCommand{cmd, a})
+		startServer = startServer || cmd.startServer
+	}
+
+	if !s.running && !startServer || s.running && s.socket != socket {
+		return []byte(fmt.Sprintf("error connecting to %s (No such file or directory)\n", socket)), errExitStatus
+	}
+
+	if !s.running {
+		s.running = true
+		s.socket = socket
+	}
+
+	var out strings.Builder
+
+	for _, pc := range parsed {
+		if err := pc.cmd.exec(s, pc.args, &out); err != nil {
+			out.WriteString(err.Error() + "\n")
+			s.exitIfEmpty()
+
+			return []byte(out.String()), errExitStatus
+		}
+	}
+
+	s.exitIfEmpty()
+
+	return []byte(out.String()), nil
+}
+
+// exitIfEmpty resets the server if it has no sessions left, like a tmux server
+// exits when its last session is killed.
+func (s *Server) exitIfEmpty() {
+	if len(s.sessions) == 0 {
+		s.reset()
+	}
+}
+
+// reset resets the server to the state of a server that is not running.
+func (s *Server) reset() {
+	s.running = false
+	s.socket = ""
+	s.sessions = nil
+	s.nextSession, s.nextWindow, s.nextPane = 0, 0, 0
+	s.used = 0
+	s.attached = ""
+	s.options = defaultOptions()
+
+	for name, val := range s.initOpts {
+		s.options[name] = val
+	}
+}
+
+// wrapLogger returns logger with a dry_run attribute added to all records if
+// the server is in dry-run mode, like [tmux.DefaultRunner] does in dry-run
+// mode.
+func (s *Server) wrapLogger(logger *slog.Logger) *slog.Logger {
+	if !s.dryRun || logger == nil {
+		return logger
+	}
+
+	return slog.New(dryRunHandler{logger.Handler()})
+}
+
+// dryRunHandler is a [slog.Handler] adding a dry_run attribute to records.
+type dryRunHandler struct {
+	slog.Handler
+}
+
+// Handle adds the dry_run attribute to the record and handles it with the
+// wrapped handler.
+func (h dryRunHandler) Handle(ctx context.Context, r slog.Record) error {
+	r = r.Clone()
+	r.AddAttrs(slog.Bool("dry_run", true))
+
+	return h.Handler.Handle(ctx, r) //nolint:wrapcheck // Wrapping is not needed.
+}
+
+// WithAttrs returns a new handler with the provided attributes.
+func (h dryRunHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return dryRunHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a new handler with the provided group.
+func (h dryRunHandler) WithGroup(name string) slog.Handler {
+	return dryRunHandler{h.Handler.WithGroup(name)}
+}
+
+// ServerOption configures a [Server].
+type ServerOption func(*Server) error
+
+// WithLogger configures the server to use the provided [slog.Logger] for
+// logging.
+//
+// The default is a no-op logger writing to [os.Discard].
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(s *Server) error {
+		s.logger = logger
+		return nil
+	}
+}
+
+// WithRedaction configures the server to use the provided [tmux.Redaction] to
+// keep secrets out of its log output.
+//
+// The default is a redaction with [tmux.DefaultRedactPatterns]. Redaction is
+// disabled if r is nil.
+func WithRedaction(r *tmux.Redaction) ServerOption {
+	return func(s *Server) error {
+		s.redaction = r
+		return nil
+	}
+}
+
+// WithTmux configures the name of the tmux executable reported in log
+// messages.
+//
+// The default is "tmux".
+func WithTmux(name string) ServerOption {
+	return func(s *Server) error {
+		s.tmux = name
+		return nil
+	}
+}
+
+// WithTmuxOptions configures the server with additional tmux options to be
+// added to all tmux command invocations, like [tmux.WithTmuxOptions].
+//
+// The -L and -S options select the socket of the server. Commands for other
+// sockets fail as if no server is running.
+func WithTmuxOptions(opts ...string) ServerOption {
+	return func(s *Server) error {
+		s.tmuxOpts = opts
+		return nil
+	}
+}
+
+// WithDryRunMode configures the server to report that it runs in dry-run
+// mode, and to add a dry_run attribute to its log messages.
+//
+// The default is false.
+func WithDryRunMode(enable bool) ServerOption {
+	return func(s *Server) error {
+		s.dryRun = enable
+		return nil
+	}
+}
+
+// WithWorkingDir configures the working directory used for sessions, windows
+// and panes created without a -c flag, like tmux uses the working directory of
+// the client running the command.
+//
+// The default is the current working directory.
+func WithWorkingDir(dir string) ServerOption {
+	return func(s *Server) error {
+		s.dir = dir
+		return nil
+	}
+}
+
+// WithSocketDir configures the directory of the server's socket, and of
+// sockets named with the -L flag.
+//
+// The default is the same as for tmux: a tmux-UID directory in $TMUX_TMPDIR,
+// or in /tmp if it is not set.
+func WithSocketDir(dir string) ServerOption {
+	return func(s *Server) error {
+		s.socketDir = dir
+		return nil
+	}
+}
+
+// WithOption configures the server with a global tmux option, like setting it
+// in a tmux configuration file.
+//
+// The option is restored when the server is reset after the last session is
+// killed.
+func WithOption(name, value string) ServerOption {
+	return func(s *Server) error {
+		if !validOption(name) {
+			return fmt.Errorf("invalid option: %s", name)
+		}
+
+		s.initOpts[name] = value
+
+		return nil
+	}
+}
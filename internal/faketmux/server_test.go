@@ -0,0 +1,594 @@
+package faketmux_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/internal/faketmux"
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// The expected outputs in these tests were recorded with tmux 3.3a.
+
+func TestServer_Run(t *testing.T) {
+	tt := []struct {
+		name    string
+		setup   []string
+		args    string
+		want    string
+		wantErr string
+	}{
+		{
+			"new-session",
+			nil,
+			"new-session -d -s a -x 80 -y 24 -P -F #{session_id}:#{session_name}:#{session_path}",
+			"$0:a:/home/user/project\n",
+			"",
+		},
+		{
+			"new-session without name",
+			[]string{"new-session -d -s a"},
+			"new-session -d -P",
+			"1:\n",
+			"",
+		},
+		{
+			"new-session replaces separators in name",
+			nil,
+			"new-session -d -s a.b:c -P -F #{session_name}",
+			"a_b_c\n",
+			"",
+		},
+		{
+			"new-session duplicate",
+			[]string{"new-session -d -s a"},
+			"new-session -d -s a",
+			"duplicate session: a\n",
+			"duplicate session: a",
+		},
+		{
+			"new-session invalid width",
+			nil,
+			"new-session -d -s a -x abc",
+			"width invalid\n",
+			"exit status 1",
+		},
+		{
+			"new-window",
+			[]string{"new-session -d -s a"},
+			"new-window -P -F #{window_id},#{window_index},#{window_name},#{window_width},#{window_height},#{window_layout} -t a: -n code -c /tmp",
+			"@1,1,code,80,24,b25e,80x24,0,0,1\n",
+			"",
+		},
+		{
+			"new-window default format",
+			[]string{"new-session -d -s a"},
+			"new-window -t a: -P",
+			"a:1.0\n",
+			"",
+		},
+		{
+			"new-window index in use",
+			[]string{"new-session -d -s a"},
+			"new-window -t a:0",
+			"create window failed: index 0 in use\n",
+			"exit status 1",
+		},
+		{
+			"new-window index of session current window",
+			[]string{"new-session -d -s a"},
+			"new-window -d -t a",
+			"create window failed: index 0 in use\n",
+			"exit status 1",
+		},
+		{
+			"new-window kill existing",
+			[]string{"new-session -d -s a"},
+			"new-window -k -t a:^ -P -F #{window_id},#{window_index}",
+			"@1,0\n",
+			"",
+		},
+		{
+			"new-window after target",
+			[]string{"new-session -d -s a", "new-window -t a:1", "new-window -t a:2"},
+			"new-window -a -t a:0 ; list-windows -t a -F #{window_id},#{window_index},#{window_active}",
+			"@0,0,0\n@3,1,1\n@1,2,0\n@2,3,0\n",
+			"",
+		},
+		{
+			"new-window with base-index",
+			[]string{"new-session -d -s a", "set-option -g base-index 1"},
+			"new-window -t a: -P -F #{window_index}",
+			"1\n",
+			"",
+		},
+		{
+			"split-window",
+			[]string{"new-session -d -s a"},
+			"split-window -d -P -F #{pane_id},#{pane_index},#{pane_width},#{pane_height},#{pane_current_path},#{pane_path} -t a:0 -h -c /tmp",
+			"%1,1,39,24,/tmp,\n",
+			"",
+		},
+		{
+			"split-window size",
+			[]string{"new-session -d -s a", "split-window -d -t a:0 -h"},
+			"split-window -P -F #{pane_id},#{pane_index},#{pane_width},#{pane_height} -t a:0.1 -l 30%",
+			"%2,2,39,7\n",
+			"",
+		},
+		{
+			"split-window size clamped",
+			[]string{"new-session -d -s a"},
+			"split-window -P -F #{pane_height},#{pane_width} -t a:0 -l 200%",
+			"22,80\n",
+			"",
+		},
+		{
+			"split-window invalid size",
+			[]string{"new-session -d -s a"},
+			"split-window -t a:0 -h -l abc",
+			"lines invalid\n",
+			"exit status 1",
+		},
+		{
+			"split-window no space",
+			[]string{"new-session -d -s a -x 80 -y 2"},
+			"split-window -t a:0",
+			"no space for new pane\n",
+			"no space for new pane",
+		},
+		{
+			"split-window with pane-base-index",
+			[]string{"new-session -d -s a", "set-option -g pane-base-index 1"},
+			"split-window -t a:0 -P",
+			"a:0.2\n",
+			"",
+		},
+		{
+			"list-panes after kill-pane",
+			[]string{
+				"new-session -d -s a",
+				"split-window -d -t a:0 -h",
+				"split-window -t a:0.1 -l 30%",
+				"split-window -t a:0.0 -l 5",
+				"kill-pane -t a:0.1",
+				"kill-pane -t a:0.0",
+			},
+			"list-panes -t a:0 -F #{pane_id},#{pane_index},#{pane_width},#{pane_height},#{pane_active},#{pane_left},#{pane_top}",
+			"%1,0,80,16,0,0,0\n%2,1,80,7,1,0,17\n",
+			"",
+		},
+		{
+			"resize-pane",
+			[]string{
+				"new-session -d -s a -x 120 -y 40",
+				"split-window -t a:0 -h",
+				"split-window -t a:0",
+				"resize-pane -t a:0.0 -x 30",
+				"resize-pane -t a:0.2 -L 5",
+				"resize-pane -t a:0.1 -D 3",
+			},
+			"display-message -p -t a:0 #{window_layout}",
+			"6854,120x40,0,0{25x40,0,0,0,94x40,26,0[94x23,26,0,1,94x16,26,24,2]}\n",
+			"",
+		},
+		{
+			"select-layout custom",
+			[]string{"new-session -d -s a -x 120 -y 40", "split-window -t a:0 -h", "split-window -t a:0 -h"},
+			"select-layout -t a:0 ee22,100x30,0,0{30x30,0,0,0,69x30,31,0[69x15,31,0,1,69x14,31,16,2]} ; " +
+				"display-message -p -t a:0 #{window_width}x#{window_height},#{window_layout}",
+			"100x30,ee22,100x30,0,0{30x30,0,0,0,69x30,31,0[69x15,31,0,1,69x14,31,16,2]}\n",
+			"",
+		},
+		{
+			"select-layout custom wrong pane count",
+			[]string{"new-session -d -s a", "split-window -t a:0"},
+			"select-layout -t a:0 b25e,80x24,0,0,1",
+			"have 2 panes but need 1: b25e,80x24,0,0,1\n",
+			"exit status 1",
+		},
+		{
+			"display-message substitution modifier",
+			[]string{"new-session -d -s a -x 80 -y 24"},
+			`display-message -p -t a:0 #{s/([,\\])/\\\1/:window_layout}`,
+			"b25d\\,80x24\\,0\\,0\\,0\n",
+			"",
+		},
+		{
+			"select-layout invalid",
+			[]string{"new-session -d -s a"},
+			"select-layout -t a:0 bogus",
+			"invalid layout: bogus\n",
+			"exit status 1",
+		},
+		{
+			"select-window unknown",
+			[]string{"new-session -d -s a"},
+			"select-window -t a:9",
+			"can't find window: 9\n",
+			"can't find window: 9",
+		},
+		{
+			"select-pane unknown",
+			[]string{"new-session -d -s a"},
+			"select-pane -t a:0.7",
+			"can't find pane: 7\n",
+			"can't find pane: 7",
+		},
+		{
+			"has-session unknown",
+			[]string{"new-session -d -s a"},
+			"has-session -t nope",
+			"can't find session: nope\n",
+			"can't find session: nope",
+		},
+		{
+			"display-message default target",
+			[]string{"new-session -d -s a", "new-session -d -s b", "new-window -t a:"},
+			"display-message -p #{session_name}:#{window_index}.#{pane_index}",
+			"b:0.0\n",
+			"",
+		},
+		{
+			"show-option",
+			[]string{"new-session -d -s a"},
+			"show-option -gqv pane-base-index",
+			"0\n",
+			"",
+		},
+		{
+			"show-option unknown quiet",
+			[]string{"new-session -d -s a"},
+			"show-option -gqv bogus",
+			"",
+			"",
+		},
+		{
+			"show-options quotes values",
+			[]string{"new-session -d -s a", "set-option -w -t a:0 main-pane-height 30%", "set-option -w -t a:0 mode-keys vi"},
+			"show-options -w -t a:0",
+			"main-pane-height \"30%\"\nmode-keys vi\n",
+			"",
+		},
+		{
+			"set-option unknown",
+			[]string{"new-session -d -s a"},
+			"set-option -g bogus 1",
+			"invalid option: bogus\n",
+			"invalid option: bogus",
+		},
+		{
+			"set-option empty value",
+			[]string{"new-session -d -s a"},
+			"set-option -g main-pane-width",
+			"empty value\n",
+			"exit status 1",
+		},
+		{
+			"move-window index in use",
+			[]string{"new-session -d -s a", "new-window -t a:"},
+			"move-window -s a:1 -t a:0",
+			"index in use: 0\n",
+			"exit status 1",
+		},
+		{
+			"move-window kill existing",
+			[]string{"new-session -d -s a", "new-window -d -t a:", "new-window -d -t a:"},
+			"move-window -k -s a:0 -t a:1 ; list-windows -t a -F #{window_id},#{window_index},#{window_active}",
+			"@0,1,1\n@2,2,0\n",
+			"",
+		},
+		{
+			"respawn-pane active",
+			[]string{"new-session -d -s a"},
+			"respawn-pane -t a:0.0",
+			"respawn pane failed: pane a:0.0 still active\n",
+			"exit status 1",
+		},
+		{
+			"swap-pane",
+			[]string{"new-session -d -s a", "split-window -t a:0 -h", "split-window -t a:0 -h"},
+			"swap-pane -s a:0.0 -t a:0.2 ; list-panes -t a:0 -F #{pane_id},#{pane_index},#{pane_active},#{pane_width}",
+			"%2,0,1,40\n%1,1,0,19\n%0,2,0,19\n",
+			"",
+		},
+		{
+			"rename-session duplicate",
+			[]string{"new-session -d -s a", "new-session -d -s b"},
+			"rename-session -t a b",
+			"duplicate session: b\n",
+			"duplicate session: b",
+		},
+		{
+			"commands stop at first error",
+			[]string{"new-session -d -s a"},
+			"display-message -p first ; select-window -t a:9 ; display-message -p last",
+			"first\ncan't find window: 9\n",
+			"can't find window: 9",
+		},
+		{
+			"escaped semicolon",
+			[]string{"new-session -d -s a"},
+			"display-message -p a\\; ; display-message -p b",
+			"a;\nb\n",
+			"",
+		},
+		{
+			"unknown command",
+			[]string{"new-session -d -s a"},
+			"display-message -p first ; bogus-cmd",
+			"unknown command: bogus-cmd\n",
+			"exit status 1",
+		},
+		{
+			"unknown flag",
+			[]string{"new-session -d -s a"},
+			"new-window -Z",
+			"command new-window: unknown flag -Z\n",
+			"exit status 1",
+		},
+		{
+			"no server",
+			nil,
+			"list-sessions",
+			"error connecting to /tmp/tt/tmux-0/default (No such file or directory)\n",
+			"error connecting to /tmp/tt/tmux-0/default (No such file or directory)",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newServer(t)
+
+			for _, args := range tc.setup {
+				_, err := srv.Run(context.Background(), strings.Fields(args)...)
+				require.NoError(t, err, "running setup command: %s", args)
+			}
+
+			output, err := srv.Run(context.Background(), strings.Fields(tc.args)...)
+
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tc.want, string(output))
+		})
+	}
+}
+
+func TestServer_Run_Layouts(t *testing.T) {
+	tt := []struct {
+		layout string
+		panes  int
+		opts   []string
+		want   string
+	}{
+		{"even-horizontal", 2, nil, "89f5,80x24,0,0{39x24,0,0,0,40x24,40,0,1}"},
+		{"even-horizontal", 6, nil, "de52,80x24,0,0{12x24,0,0,0,12x24,13,0,1,12x24,26,0,2,12x24,39,0,3,12x24,52,0,4,15x24,65,0,5}"},
+		{"even-vertical", 4, nil, "a0c0,80x24,0,0[80x5,0,0,0,80x5,0,6,1,80x5,0,12,2,80x6,0,18,3]"},
+		{"main-horizontal", 2, nil, "d89e,80x24,0,0[80x22,0,0,0,80x1,0,23,1]"},
+		{"main-horizontal", 5, []string{"main-pane-height", "10"}, "6f66,80x24,0,0[80x10,0,0,0,80x13,0,11{19x13,0,11,1,19x13,20,11,2,19x13,40,11,3,20x13,60,11,4}]"},
+		{"main-vertical", 4, nil, "5624,80x24,0,0{78x24,0,0,0,1x24,79,0[1x7,79,0,1,1x7,79,8,2,1x8,79,16,3]}"},
+		{"main-vertical", 3, []string{"main-pane-width", "40"}, "c4be,80x24,0,0{40x24,0,0,0,39x24,41,0[39x11,41,0,1,39x12,41,12,2]}"},
+		{"tiled", 2, nil, "9295,80x24,0,0[80x11,0,0,0,80x12,0,12,1]"},
+		{"tiled", 3, nil, "a7cf,80x24,0,0[80x11,0,0{39x11,0,0,0,40x11,40,0,1},80x12,0,12,2]"},
+		{"tiled", 6, nil, "5297,80x24,0,0[80x7,0,0{39x7,0,0,0,40x7,40,0,1},80x7,0,8{39x7,0,8,2,40x7,40,8,3},80x8,0,16{39x8,0,16,4,40x8,40,16,5}]"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.layout, func(t *testing.T) {
+			srv := newServer(t)
+			ctx := context.Background()
+
+			_, err := srv.Run(ctx, "new-session", "-d", "-s", "a", "-x", "80", "-y", "24")
+			require.NoError(t, err)
+
+			for i := 1; i < tc.panes; i++ {
+				_, err := srv.Run(ctx, "split-window", "-t", "a:0", ";", "select-layout", "-t", "a:0", "tiled")
+				require.NoError(t, err)
+			}
+
+			if tc.opts != nil {
+				_, err := srv.Run(ctx, append([]string{"set-option", "-w", "-t", "a:0"}, tc.opts...)...)
+				require.NoError(t, err)
+			}
+
+			output, err := srv.Run(ctx, "select-layout", "-t", "a:0", tc.layout, ";", "display-message", "-p", "-t", "a:0", "#{window_layout}")
+			require.NoError(t, err)
+
+			require.Equal(t, tc.want+"\n", string(output))
+		})
+	}
+}
+
+func TestServer_Run_CommandError(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+
+	_, err := srv.Run(ctx, "list-sessions")
+	require.ErrorIs(t, err, tmux.ErrNoServer)
+
+	_, err = srv.Run(ctx, "new-session", "-d", "-s", "a")
+	require.NoError(t, err)
+
+	_, err = srv.Run(ctx, "new-session", "-d", "-s", "a")
+	require.ErrorIs(t, err, tmux.ErrSessionExists)
+
+	_, err = srv.Run(ctx, "kill-window", "-t", "a:5")
+	require.ErrorIs(t, err, tmux.ErrTargetNotFound)
+
+	_, err = srv.Run(ctx, "set-option", "-g", "bogus", "on")
+	require.ErrorIs(t, err, tmux.ErrUnknownOption)
+
+	var cmdErr tmux.CommandError
+
+	require.ErrorAs(t, err, &cmdErr)
+	require.Equal(t, []string{"set-option", "-g", "bogus", "on"}, cmdErr.Args())
+}
+
+func TestServer_Sessions(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+
+	for _, args := range [][]string{
+		{"new-session", "-d", "-s", "a", "-e", "APP_ENV=dev"},
+		{"new-window", "-k", "-t", "a:^", "-n", "code", "-c", "/src", ";", "send-keys", "-t", "a:code", "nvim .", "C-m"},
+		{"split-window", "-d", "-t", "a:code", "-e", "PORT=8080", "-h"},
+		{"send-keys", "-t", "a:code.1", "make test", "C-m", "git status"},
+		{"send-keys", "-t", "a:code.1", "-l", "echo C-m"},
+		{"new-window", "-d", "-t", "a:", "-n", "shell", "htop"},
+	} {
+		_, err := srv.Run(ctx, args...)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []faketmux.Session{
+		{
+			ID:     "$0",
+			Name:   "a",
+			Path:   "/home/user/project",
+			Width:  80,
+			Height: 24,
+			Env:    map[string]string{"APP_ENV": "dev"},
+			Windows: []faketmux.Window{
+				{
+					ID:      "@1",
+					Name:    "code",
+					Index:   0,
+					Layout:  "020a,80x24,0,0{40x24,0,0,1,39x24,41,0,2}",
+					Active:  true,
+					Options: map[string]string{},
+					Panes: []faketmux.Pane{
+						{
+							ID:       "%1",
+							Index:    0,
+							Path:     "/src",
+							Width:    40,
+							Height:   24,
+							Active:   true,
+							Env:      map[string]string{"APP_ENV": "dev"},
+							Commands: []string{"nvim ."},
+						},
+						{
+							ID:       "%2",
+							Index:    1,
+							Path:     "/home/user/project",
+							Width:    39,
+							Height:   24,
+							Env:      map[string]string{"APP_ENV": "dev", "PORT": "8080"},
+							Commands: []string{"make test"},
+							Input:    "git statusecho C-m",
+						},
+					},
+				},
+				{
+					ID:      "@2",
+					Name:    "shell",
+					Index:   1,
+					Layout:  "b260,80x24,0,0,3",
+					Options: map[string]string{},
+					Panes: []faketmux.Pane{
+						{
+							ID:      "%3",
+							Index:   0,
+							Path:    "/home/user/project",
+							Width:   80,
+							Height:  24,
+							Active:  true,
+							Env:     map[string]string{"APP_ENV": "dev"},
+							Command: "htop",
+						},
+					},
+				},
+			},
+		},
+	}, srv.Sessions())
+}
+
+func TestServer_Reset(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+
+	_, err := srv.Run(ctx, "new-session", "-d", "-s", "a", ";", "new-window", "-t", "a:", ";", "kill-session", "-t", "a")
+	require.NoError(t, err)
+
+	_, err = srv.Run(ctx, "list-sessions")
+	require.ErrorIs(t, err, tmux.ErrNoServer)
+
+	output, err := srv.Run(ctx, "new-session", "-d", "-s", "b", "-P", "-F", "#{session_id},#{window_id},#{pane_id}")
+	require.NoError(t, err)
+	require.Equal(t, "$0,@0,%0\n", string(output))
+}
+
+func TestServer_Execve(t *testing.T) {
+	srv := newServer(t)
+
+	_, err := srv.Run(context.Background(), "new-session", "-d", "-s", "a")
+	require.NoError(t, err)
+
+	require.EqualError(t, srv.Execve("attach-session", "-t", "nope"), "can't find session: nope")
+	require.Empty(t, srv.Attached())
+
+	require.NoError(t, srv.Execve("attach-session", "-t", "a"))
+	require.Equal(t, "a", srv.Attached())
+}
+
+func TestServer_TmuxOptions(t *testing.T) {
+	srv := newServer(t, faketmux.WithTmuxOptions("-L", "tmpl"))
+	ctx := context.Background()
+
+	_, err := srv.Run(ctx, "new-session", "-d", "-s", "a")
+	require.NoError(t, err)
+
+	output, err := srv.Run(ctx, "display-message", "-p", "#{socket_path}")
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/tt/tmux-0/tmpl\n", string(output))
+}
+
+func TestServer_DryRun(t *testing.T) {
+	logs := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "dur" {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	}))
+
+	srv := newServer(t, faketmux.WithLogger(logger), faketmux.WithDryRunMode(true))
+	require.True(t, srv.IsDryRun())
+
+	output, err := srv.Run(context.Background(), "new-session", "-d", "-s", "a", "-P", "-F", "#{session_id}")
+	require.NoError(t, err)
+	require.Equal(t, "$0\n", string(output))
+
+	srv.Log("session created", "session", "a")
+
+	require.Equal(t,
+		"level=DEBUG msg=\"command successful\" name=tmux args=\"[new-session -d -s a -P -F #{session_id}]\" output=$0 dry_run=true\n"+
+			"level=INFO msg=\"session created\" session=a dry_run=true\n",
+		logs.String(),
+	)
+}
+
+func newServer(t *testing.T, opts ...faketmux.ServerOption) *faketmux.Server {
+	t.Helper()
+
+	t.Setenv("TMUX_TMPDIR", "/tmp/tt")
+	t.Setenv("SHELL", "/bin/bash")
+
+	srv, err := faketmux.NewServer(append([]faketmux.ServerOption{faketmux.WithWorkingDir("/home/user/project")}, opts...)...)
+	require.NoError(t, err)
+
+	return srv
+}
@@ -0,0 +1,313 @@
+package faketmux
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// session is the server state of a tmux session.
+type session struct {
+	id      int
+	name    string
+	path    string
+	sx, sy  int
+	env     map[string]string
+	opts    map[string]string
+	windows []*window // Sorted by index.
+	curw    *window
+	used    int // Order of last use, for finding the current session.
+}
+
+// window is the server state of a tmux window.
+type window struct {
+	id         int
+	sess       *session
+	index      int
+	name       string
+	opts       map[string]string
+	root       *cell
+	panes      []*pane // In pane index order.
+	active     *pane
+	lastPanes  []*pane // Previously active panes, most recent first.
+	lastPreset string
+}
+
+// pane is the server state of a tmux window pane.
+type pane struct {
+	id      int
+	win     *window
+	path    string
+	env     map[string]string
+	command string
+	cmds    []string
+	input   strings.Builder
+	cell    *cell
+}
+
+// option returns the value of a session option, falling back to the global
+// value.
+func (s *session) option(global map[string]string, name string) string {
+	if val, ok := s.opts[name]; ok {
+		return val
+	}
+
+	return global[name]
+}
+
+// window returns the window with the provided index, or nil.
+func (s *session) window(index int) *window {
+	for _, w := range s.windows {
+		if w.index == index {
+			return w
+		}
+	}
+
+	return nil
+}
+
+// addWindow adds the window to the session, keeping the windows sorted by
+// index.
+func (s *session) addWindow(w *window) {
+	w.sess = s
+	s.windows = append(s.windows, w)
+
+	sort.Slice(s.windows, func(i, j int) bool {
+		return s.windows[i].index < s.windows[j].index
+	})
+}
+
+// removeWindow removes the window from the session and selects another
+// window as the current window if needed.
+func (s *session) removeWindow(w *window) {
+	pos := -1
+
+	for i, other := range s.windows {
+		if other == w {
+			pos = i
+			break
+		}
+	}
+
+	if pos == -1 {
+		return
+	}
+
+	s.windows = append(s.windows[:pos], s.windows[pos+1:]...)
+
+	if s.curw != w {
+		return
+	}
+
+	s.curw = nil
+
+	if len(s.windows) > 0 {
+		s.curw = s.windows[max(pos-1, 0)]
+	}
+}
+
+// nextIndex returns the first free window index starting at base.
+func (s *session) nextIndex(base int) int {
+	for idx := base; ; idx++ {
+		if s.window(idx) == nil {
+			return idx
+		}
+	}
+}
+
+// option returns the value of a window option, falling back to the global
+// value.
+func (w *window) option(global map[string]string, name string) string {
+	if val, ok := w.opts[name]; ok {
+		return val
+	}
+
+	return global[name]
+}
+
+// paneIndex returns the index of the pane in the window, taking the
+// pane-base-index option into account.
+func (w *window) paneIndex(global map[string]string, p *pane) int {
+	base, _ := strconv.Atoi(w.option(global, "pane-base-index"))
+
+	for i, other := range w.panes {
+		if other == p {
+			return base + i
+		}
+	}
+
+	return -1
+}
+
+// removePane removes the pane from the window and its layout, and selects
+// another pane as the active pane if needed.
+func (w *window) removePane(p *pane) {
+	pos := -1
+
+	for i, other := range w.panes {
+		if other == p {
+			pos = i
+			break
+		}
+	}
+
+	if pos == -1 {
+		return
+	}
+
+	w.panes = append(w.panes[:pos], w.panes[pos+1:]...)
+	w.lastPanes = removePane(w.lastPanes, p)
+	w.root = p.cell.remove(w.root)
+	p.cell = nil
+
+	if w.active != p || len(w.panes) == 0 {
+		return
+	}
+
+	// Like tmux, the previously active pane becomes active, or the pane before
+	// the removed pane if there is none.
+	if len(w.lastPanes) > 0 {
+		w.active = w.lastPanes[0]
+		w.lastPanes = w.lastPanes[1:]
+
+		return
+	}
+
+	w.active = w.panes[max(pos-1, 0)]
+}
+
+// setActive makes p the active pane of the window.
+func (w *window) setActive(p *pane) {
+	if w.active == p {
+		return
+	}
+
+	w.lastPanes = removePane(w.lastPanes, p)
+
+	if w.active != nil {
+		w.lastPanes = append([]*pane{w.active}, w.lastPanes...)
+	}
+
+	w.active = p
+}
+
+// removePane returns panes without p.
+func removePane(panes []*pane, p *pane) []*pane {
+	res := panes[:0]
+
+	for _, other := range panes {
+		if other != p {
+			res = append(res, other)
+		}
+	}
+
+	return res
+}
+
+// insertPane inserts the pane into the window after the pane other, or before
+// it if before is true.
+func (w *window) insertPane(p, other *pane, before bool) {
+	pos := len(w.panes)
+
+	for i, wp := range w.panes {
+		if wp == other {
+			pos = i + 1
+
+			if before {
+				pos = i
+			}
+
+			break
+		}
+	}
+
+	p.win = w
+	w.panes = append(w.panes[:pos], append([]*pane{p}, w.panes[pos:]...)...)
+}
+
+// setLayout assigns the leaf cells of the layout tree starting at root to the
+// panes of the window in order.
+func (w *window) setLayout(root *cell) {
+	root.x, root.y = 0, 0
+	root.fixOffsets()
+
+	for i, leaf := range root.leaves() {
+		leaf.pane = w.panes[i]
+		w.panes[i].cell = leaf
+	}
+
+	w.root = root
+}
+
+// sendKey sends a key to the pane. Lines of input are entered as commands when
+// the Enter key is sent.
+func (p *pane) sendKey(key string, literal bool) {
+	if literal {
+		p.input.WriteString(key)
+		return
+	}
+
+	switch key {
+	case "Enter", "C-m", "KPEnter":
+		p.cmds = append(p.cmds, p.input.String())
+		p.input.Reset()
+	case "C-c", "C-u":
+		p.input.Reset()
+	case "BSpace":
+		if s := p.input.String(); s != "" {
+			p.input.Reset()
+			p.input.WriteString(s[:len(s)-1])
+		}
+	case "Space":
+		p.input.WriteString(" ")
+	case "Tab":
+		p.input.WriteString("\t")
+	case "Escape":
+	default:
+		if len(key) > 2 && (key[:2] == "C-" || key[:2] == "M-") {
+			return
+		}
+
+		p.input.WriteString(key)
+	}
+}
+
+// windowName returns the automatic name of a window running the provided shell
+// command, or the default shell if the command is empty.
+func windowName(command, shell string) string {
+	if command == "" {
+		command = shell
+	}
+
+	name, _, _ := strings.Cut(command, " ")
+
+	return filepath.Base(name)
+}
+
+// parseEnv parses environment variable arguments in the form KEY=VALUE into
+// env.
+func parseEnv(env map[string]string, vals []string) error {
+	for _, val := range vals {
+		k, v, ok := strings.Cut(val, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("invalid environment: %s", val)
+		}
+
+		env[k] = v
+	}
+
+	return nil
+}
+
+// copyEnv returns a copy of env.
+func copyEnv(env map[string]string) map[string]string {
+	res := make(map[string]string, len(env))
+
+	for k, v := range env {
+		res[k] = v
+	}
+
+	return res
+}
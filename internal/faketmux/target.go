@@ -0,0 +1,306 @@
+package faketmux
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// currentSession returns the most recently used session, or nil if there are
+// no sessions.
+func (s *Server) currentSession() *session {
+	var res *session
+
+	for _, sess := range s.sessions {
+		if res == nil || sess.used > res.used {
+			res = sess
+		}
+	}
+
+	return res
+}
+
+// touch marks the session as the most recently used session.
+func (s *Server) touch(sess *session) {
+	s.used++
+	sess.used = s.used
+}
+
+// findSession resolves a session target, which is either a session ID, an
+// exact session name or an unambiguous prefix of a session name. Anything
+// following a colon in the target is ignored.
+func (s *Server) findSession(target string) (*session, error) {
+	name, _, _ := strings.Cut(target, ":")
+
+	if name == "" {
+		if sess := s.currentSession(); sess != nil {
+			return sess, nil
+		}
+
+		return nil, errors.New("no current session")
+	}
+
+	if strings.HasPrefix(name, "$") {
+		for _, sess := range s.sessions {
+			if "$"+strconv.Itoa(sess.id) == name {
+				return sess, nil
+			}
+		}
+
+		return nil, fmt.Errorf("can't find session: %s", name)
+	}
+
+	var match *session
+
+	for _, sess := range s.sessions {
+		if sess.name == name {
+			return sess, nil
+		}
+
+		if strings.HasPrefix(sess.name, name) {
+			if match != nil {
+				return nil, fmt.Errorf("can't find session: %s", name)
+			}
+
+			match = sess
+		}
+	}
+
+	if match == nil {
+		return nil, fmt.Errorf("can't find session: %s", name)
+	}
+
+	return match, nil
+}
+
+// findWindow resolves a window target in the form session:window, where
+// window is a window ID, index, exact name or unambiguous prefix of a name.
+//
+// A target without a colon is looked up as a window in the current session
+// first, and then as a session.
+func (s *Server) findWindow(target string) (*window, error) {
+	switch {
+	case strings.HasPrefix(target, "@"):
+		for _, sess := range s.sessions {
+			for _, w := range sess.windows {
+				if "@"+strconv.Itoa(w.id) == target {
+					return w, nil
+				}
+			}
+		}
+
+		return nil, fmt.Errorf("can't find window: %s", target)
+	case strings.HasPrefix(target, "%"):
+		p, err := s.findPane(target)
+		if err != nil {
+			return nil, err
+		}
+
+		return p.win, nil
+	}
+
+	sessName, winName, ok := strings.Cut(target, ":")
+	if ok {
+		sess, err := s.findSession(sessName)
+		if err != nil {
+			return nil, err
+		}
+
+		winName, _, _ = strings.Cut(winName, ".")
+
+		return lookupWindow(sess, winName)
+	}
+
+	cur := s.currentSession()
+	if cur == nil {
+		return nil, errors.New("no current session")
+	}
+
+	if target == "" {
+		return cur.curw, nil
+	}
+
+	if w, err := lookupWindow(cur, target); err == nil {
+		return w, nil
+	}
+
+	if sess, err := s.findSession(target); err == nil {
+		return sess.curw, nil
+	}
+
+	return nil, fmt.Errorf("can't find window: %s", target)
+}
+
+// lookupWindow looks up a window in the session by ID, index, the special
+// tokens ^ and $ for the first and last window, exact name, or unambiguous
+// prefix of a name. The current window is returned if name is empty.
+func lookupWindow(sess *session, name string) (*window, error) {
+	switch name {
+	case "":
+		return sess.curw, nil
+	case "^":
+		return sess.windows[0], nil
+	case "$":
+		return sess.windows[len(sess.windows)-1], nil
+	}
+
+	if idx, err := strconv.Atoi(name); err == nil {
+		if w := sess.window(idx); w != nil {
+			return w, nil
+		}
+
+		return nil, fmt.Errorf("can't find window: %s", name)
+	}
+
+	var exact, prefix []*window
+
+	for _, w := range sess.windows {
+		switch {
+		case "@"+strconv.Itoa(w.id) == name, w.name == name:
+			exact = append(exact, w)
+		case strings.HasPrefix(w.name, name):
+			prefix = append(prefix, w)
+		}
+	}
+
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+
+	if len(exact) == 0 && len(prefix) == 1 {
+		return prefix[0], nil
+	}
+
+	return nil, fmt.Errorf("can't find window: %s", name)
+}
+
+// findPane resolves a pane target in the form session:window.pane, where pane
+// is a pane ID or index. The active pane of the window is used if the pane is
+// omitted.
+//
+// A target without a colon or period is looked up as a pane in the current
+// window first, then as a window in the current session, and then as a
+// session.
+func (s *Server) findPane(target string) (*pane, error) {
+	if strings.HasPrefix(target, "%") {
+		for _, sess := range s.sessions {
+			for _, w := range sess.windows {
+				for _, p := range w.panes {
+					if "%"+strconv.Itoa(p.id) == target {
+						return p, nil
+					}
+				}
+			}
+		}
+
+		return nil, fmt.Errorf("can't find pane: %s", target)
+	}
+
+	if strings.HasPrefix(target, "@") {
+		w, err := s.findWindow(target)
+		if err != nil {
+			return nil, err
+		}
+
+		return w.active, nil
+	}
+
+	if sessName, rest, ok := strings.Cut(target, ":"); ok {
+		sess, err := s.findSession(sessName)
+		if err != nil {
+			return nil, err
+		}
+
+		winName, paneName, _ := strings.Cut(rest, ".")
+
+		w, err := lookupWindow(sess, winName)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.lookupPane(w, paneName)
+	}
+
+	cur := s.currentSession()
+	if cur == nil {
+		return nil, errors.New("no current session")
+	}
+
+	if winName, paneName, ok := strings.Cut(target, "."); ok {
+		w, err := lookupWindow(cur, winName)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.lookupPane(w, paneName)
+	}
+
+	if p, err := s.lookupPane(cur.curw, target); err == nil {
+		return p, nil
+	}
+
+	if w, err := s.findWindow(target); err == nil {
+		return w.active, nil
+	}
+
+	return nil, fmt.Errorf("can't find pane: %s", target)
+}
+
+// lookupPane looks up a pane in the window by ID or index. The active pane is
+// returned if name is empty.
+func (s *Server) lookupPane(w *window, name string) (*pane, error) {
+	if name == "" {
+		return w.active, nil
+	}
+
+	for _, p := range w.panes {
+		if "%"+strconv.Itoa(p.id) == name || strconv.Itoa(w.paneIndex(s.options, p)) == name {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("can't find pane: %s", name)
+}
+
+// findIndex resolves a window index target, which is like a window target
+// except that the window index does not have to exist. An index of -1 is
+// returned if the target does not include a window.
+func (s *Server) findIndex(target string) (*session, int, error) {
+	sessName, winName, ok := strings.Cut(target, ":")
+
+	if !ok && target != "" {
+		if _, err := strconv.Atoi(target); err != nil {
+			// Like tmux, a target without a colon that is not an index refers
+			// to an existing window, or the current window of a session.
+			w, err := s.findWindow(target)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			return w.sess, w.index, nil
+		}
+
+		sessName, winName = "", target
+	}
+
+	sess, err := s.findSession(sessName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if winName == "" {
+		return sess, -1, nil
+	}
+
+	if idx, err := strconv.Atoi(winName); err == nil {
+		return sess, idx, nil
+	}
+
+	w, err := lookupWindow(sess, winName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return sess, w.index, nil
+}
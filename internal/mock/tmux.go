@@ -70,6 +70,14 @@ func (r *TmuxRunner) SetLogger(logger *slog.Logger) {
 	r.wrapped.SetLogger(logger)
 }
 
+func (r *TmuxRunner) Redact(values ...string) {
+	r.tb.Helper()
+
+	if rd, ok := r.wrapped.(tmux.Redactor); ok {
+		rd.Redact(values...)
+	}
+}
+
 func cleanArgs(args []string) []string {
 	tmpDir := os.TempDir()
 	res := make([]string, 0, len(args))
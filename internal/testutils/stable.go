@@ -32,12 +32,12 @@ var replacers = []struct {
 	},
 	{
 		// Temporary directory paths.
-		regexp.MustCompile(fmt.Sprintf(`\b\/%s\/[\w\/_-]+\b`, regexp.QuoteMeta(tempDir))),
+		regexp.MustCompile(fmt.Sprintf(`%s\/[\w\/_-]+\b`, regexp.QuoteMeta(tempDir))),
 		[]byte("/tmp/path"),
 	},
 	{
 		// Home directory paths.
-		regexp.MustCompile(fmt.Sprintf(`\b\/%s\/[\w\/_-]+\b`, regexp.QuoteMeta(homeDir))),
+		regexp.MustCompile(fmt.Sprintf(`%s\/[\w\/_-]+\b`, regexp.QuoteMeta(homeDir))),
 		[]byte("/home/user"),
 	},
 }
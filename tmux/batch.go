@@ -0,0 +1,198 @@
+package tmux
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// commandSeparator is the tmux command line argument separating commands run
+// with a single tmux invocation, written as \; in a shell.
+const commandSeparator = ";"
+
+// batchMarker is printed after commands with output in a batch to split the
+// batch output into the output of each command.
+const batchMarker = "tmpl:batch:next"
+
+// batch queues tmux commands to run them with a single tmux invocation, such as
+// tmux cmd1 \; cmd2 \; cmd3, instead of starting a tmux process for each
+// command.
+//
+// tmux runs the commands in order and stops at the first command that fails.
+// A display-message command printing [batchMarker] is queued after each
+// command with output that is followed by other commands, so the output can
+// be passed to the right handler, and so that commands known to have
+// succeeded can be told apart from the rest if a command fails.
+type batch struct {
+	tmux Runner
+	cmds []batchCmd
+}
+
+// batchCmd is a command queued in a [batch].
+type batchCmd struct {
+	args    []string
+	done    func()
+	handler func(output []byte) error
+}
+
+// newBatch creates a new empty [batch] using the provided runner.
+func newBatch(runner Runner) *batch {
+	return &batch{tmux: runner}
+}
+
+// add queues a tmux command with the provided arguments and no output. The
+// done function is called when the batch is run if the command succeeded, and
+// can be nil.
+func (b *batch) add(done func(), args ...string) {
+	b.cmds = append(b.cmds, batchCmd{args: args, done: done})
+}
+
+// addWithOutput queues a tmux command with the provided arguments. The handler
+// is called with the output of the command when the batch is run if the
+// command succeeded.
+func (b *batch) addWithOutput(handler func(output []byte) error, args ...string) {
+	b.cmds = append(b.cmds, batchCmd{args: args, handler: handler})
+}
+
+// run runs the queued commands with a single tmux invocation and calls the
+// done functions and output handlers of the commands. The queue is emptied.
+//
+// In dry-run mode, the output of commands is simulated without markers, so
+// the commands are split into several invocations that each end with a
+// command with output.
+//
+// If a command fails, the functions and handlers of the commands known to
+// have succeeded are called before an error is returned.
+func (b *batch) run(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cmds := b.cmds
+	b.cmds = nil
+
+	if !b.tmux.IsDryRun() {
+		return b.runCmds(ctx, cmds)
+	}
+
+	for len(cmds) != 0 {
+		n := slices.IndexFunc(cmds, func(cmd batchCmd) bool { return cmd.handler != nil }) + 1
+		if n == 0 {
+			n = len(cmds)
+		}
+
+		if err := b.runCmds(ctx, cmds[:n]); err != nil {
+			return err
+		}
+
+		cmds = cmds[n:]
+	}
+
+	return nil
+}
+
+// runCmds runs the provided commands with a single tmux invocation and calls
+// their done functions and output handlers.
+func (b *batch) runCmds(ctx context.Context, cmds []batchCmd) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	output, runErr := b.tmux.Run(ctx, batchArgs(cmds)...)
+	outputs := bytes.Split(output, []byte(batchMarker+"\n"))
+
+	// If a command failed, the commands up to and including the last command
+	// with output followed by a printed marker are known to have succeeded.
+	n := len(cmds)
+
+	if runErr != nil {
+		n = 0
+
+		for seen := 1; n < len(cmds) && seen < len(outputs); n++ {
+			if cmds[n].handler != nil {
+				seen++
+			}
+		}
+	}
+
+	var seen int
+
+	for _, cmd := range cmds[:n] {
+		if cmd.done != nil {
+			cmd.done()
+		}
+
+		if cmd.handler == nil {
+			continue
+		}
+
+		var out []byte
+
+		// The output of stubbed runners has no markers, in which case only
+		// the first command with output gets the output.
+		if seen < len(outputs) {
+			out = outputs[seen]
+		}
+
+		seen++
+
+		if err := cmd.handler(out); err != nil {
+			return err
+		}
+	}
+
+	if runErr != nil {
+		return fmt.Errorf("running %s: %w", commandNames(cmds[n:]), runErr)
+	}
+
+	return nil
+}
+
+// batchArgs returns the tmux command line arguments for running the provided
+// commands with a single tmux invocation.
+//
+// Arguments ending with a semicolon are escaped, as tmux would otherwise take
+// the semicolon as the end of the command.
+func batchArgs(cmds []batchCmd) []string {
+	var args []string
+
+	for i, cmd := range cmds {
+		if i > 0 {
+			args = append(args, commandSeparator)
+		}
+
+		for _, arg := range cmd.args {
+			if strings.HasSuffix(arg, commandSeparator) {
+				arg = arg[:len(arg)-1] + `\;`
+			}
+
+			args = append(args, arg)
+		}
+
+		if cmd.handler != nil && i < len(cmds)-1 {
+			args = append(args, commandSeparator, "display-message", "-p", batchMarker)
+		}
+	}
+
+	return args
+}
+
+// commandNames describes the provided commands by name for error messages,
+// e.g. "send-keys command" or "select-window, select-pane commands".
+func commandNames(cmds []batchCmd) string {
+	var names []string
+
+	for _, cmd := range cmds {
+		if len(cmd.args) != 0 && !slices.Contains(names, cmd.args[0]) {
+			names = append(names, cmd.args[0])
+		}
+	}
+
+	if len(names) == 1 {
+		return names[0] + " command"
+	}
+
+	return strings.Join(names, ", ") + " commands"
+}
@@ -0,0 +1,260 @@
+package tmux_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+func TestWindow_Apply_Batch(t *testing.T) {
+	const newWindowArgs = "new-window -P -F window_id:#{window_id},window_name:#{s/([,\\\\])/\\\\\\1/:window_name},window_path:#{s/([,\\\\])/\\\\\\1/:window_path}," +
+		"window_index:#{window_index},window_width:#{window_width},window_height:#{window_height},window_layout:#{s/([,\\\\])/\\\\\\1/:window_layout} " +
+		"-k -t test:^ -n code"
+
+	tt := []struct {
+		name        string
+		output      string
+		err         error
+		wantArgs    string
+		wantApplied bool
+		wantErr     string
+	}{
+		{
+			"success",
+			"window_id:@3,window_name:code,window_index:1,window_width:80,window_height:24\ntmpl:batch:next\n",
+			nil,
+			newWindowArgs + " ; display-message -p tmpl:batch:next ; send-keys -t test:code git status C-m ; send-keys -t test:code echo a\\; C-m",
+			true,
+			"",
+		},
+		{
+			"send-keys fails",
+			"window_id:@3,window_name:code,window_index:1,window_width:80,window_height:24\ntmpl:batch:next\n",
+			errors.New("exit status 1"),
+			newWindowArgs + " ; display-message -p tmpl:batch:next ; send-keys -t test:code git status C-m ; send-keys -t test:code echo a\\; C-m",
+			true,
+			"running send-keys command: exit status 1",
+		},
+		{
+			"new-window fails",
+			"create window failed: index 1 in use\n",
+			errors.New("exit status 1"),
+			newWindowArgs + " ; display-message -p tmpl:batch:next ; send-keys -t test:code git status C-m ; send-keys -t test:code echo a\\; C-m",
+			false,
+			"running new-window, send-keys commands: exit status 1",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cmds []string
+
+			runner, err := tmux.NewRunner(tmux.WithOSCommandRunner(func(_ context.Context, _ string, args ...string) ([]byte, error) {
+				if args[0] == "new-session" {
+					return []byte("session_id:$1,session_name:test,session_path:/home/user/project"), nil
+				}
+
+				cmds = append(cmds, strings.Join(args, " "))
+
+				return []byte(tc.output), tc.err
+			}))
+			require.NoError(t, err)
+
+			sess, err := tmux.NewSession(runner, tmux.SessionWithName("test"))
+			require.NoError(t, err)
+			require.NoError(t, sess.Apply(context.Background()))
+
+			win, err := tmux.NewWindow(runner, sess, tmux.WindowWithName("code"), tmux.WindowWithCommands("git status", "echo a;"))
+			require.NoError(t, err)
+
+			err = win.Apply(context.Background())
+
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, []string{tc.wantArgs}, cmds)
+			require.Equal(t, tc.wantApplied, win.IsApplied())
+
+			if tc.wantApplied {
+				require.Equal(t, "@3", win.ID())
+				require.Equal(t, []*tmux.Window{win}, sess.Windows())
+			} else {
+				require.Empty(t, sess.Windows())
+			}
+		})
+	}
+}
+
+func TestWindow_Apply_Batch_DryRun(t *testing.T) {
+	base, err := tmux.NewRunner(tmux.WithDryRunMode(true))
+	require.NoError(t, err)
+
+	runner := &dryRunRecorder{DefaultRunner: base}
+
+	sess, err := tmux.NewSession(runner, tmux.SessionWithName("test"))
+	require.NoError(t, err)
+	require.NoError(t, sess.Apply(context.Background()))
+
+	win, err := tmux.NewWindow(runner, sess, tmux.WindowWithName("code"), tmux.WindowWithCommands("git status"))
+	require.NoError(t, err)
+	require.NoError(t, win.Apply(context.Background()))
+
+	require.Len(t, runner.cmds, 3)
+	require.True(t, strings.HasPrefix(runner.cmds[1], "new-window "), "expected new-window command; got %q", runner.cmds[1])
+	require.NotContains(t, runner.cmds[1], "tmpl:batch:next")
+	require.Equal(t, "send-keys -t test:code git status C-m", runner.cmds[2])
+	require.True(t, win.IsApplied())
+}
+
+func TestPane_RunCommands_Batch(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"list-panes":    "pane_id:%1,pane_index:0,pane_width:80,pane_height:24",
+		"send-keys":     "",
+	})
+
+	pane := getPanes(t, runner, getWindows(t, runner, "test")[0])[0]
+
+	*cmds = nil
+
+	require.NoError(t, pane.RunCommands(context.Background(), "cd src", "ls"))
+	require.Equal(t, []string{"send-keys -t test:code.0 cd src C-m ; send-keys -t test:code.0 ls C-m"}, *cmds)
+}
+
+func TestWindow_Select_Batch(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"split-window":  "pane_id:%2,pane_index:1,pane_width:40,pane_height:24",
+		"select-window": "1",
+		"select-pane":   "",
+	})
+
+	win := getWindows(t, runner, "test")[0]
+
+	pane, err := tmux.NewPane(runner, win, nil)
+	require.NoError(t, err)
+	require.NoError(t, pane.Apply(context.Background()))
+
+	*cmds = nil
+
+	// The recording runner returns the output of the first command, which is
+	// passed to the show-option command as it is the only command with output.
+	require.NoError(t, win.Select(context.Background()))
+	require.Equal(t, []string{
+		"select-window -t test:code ; show-option -gqv pane-base-index",
+		"select-pane -t test:code.1",
+	}, *cmds)
+}
+
+// BenchmarkSession_Apply applies a session with 8 windows and 20 panes, and
+// reports the number of tmux processes started per operation as spawns/op,
+// and the number of tmux commands run as commands/op, which is the number of
+// processes that would be started without batching.
+func BenchmarkSession_Apply(b *testing.B) {
+	var spawns, commands int
+
+	runner, err := tmux.NewRunner(tmux.WithOSCommandRunner(func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		spawns++
+
+		var output strings.Builder
+
+		for _, cmd := range splitCommands(args) {
+			if cmd[0] == "display-message" {
+				output.WriteString(cmd[len(cmd)-1] + "\n")
+				continue
+			}
+
+			commands++
+
+			switch cmd[0] {
+			case "new-session":
+				output.WriteString("session_id:$1,session_name:bench,session_path:/tmp\n")
+			case "new-window":
+				fmt.Fprintf(&output, "window_id:@%d,window_name:%s,window_index:%d,window_width:80,window_height:24\n",
+					commands, cmd[len(cmd)-1], commands)
+			case "split-window":
+				fmt.Fprintf(&output, "pane_id:%%%d,pane_index:%d,pane_width:40,pane_height:24\n", commands, commands)
+			case "show-option":
+				output.WriteString("0\n")
+			}
+		}
+
+		return []byte(output.String()), nil
+	}))
+	require.NoError(b, err)
+
+	ctx := context.Background()
+	cmds := []string{"source .env", "clear", "git status"}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		sess, err := tmux.NewSession(runner, tmux.SessionWithName("bench"), tmux.SessionWithOnAnyCommand("cd ~/project"))
+		require.NoError(b, err)
+		require.NoError(b, sess.Apply(ctx))
+
+		for w := 0; w < 8; w++ {
+			win, err := tmux.NewWindow(runner, sess, tmux.WindowWithName(fmt.Sprintf("win%d", w)), tmux.WindowWithCommands(cmds...))
+			require.NoError(b, err)
+			require.NoError(b, win.Apply(ctx))
+
+			// The first 4 windows get 3 panes and the rest get 2 panes.
+			numPanes := 2
+			if w < 4 {
+				numPanes = 3
+			}
+
+			for p := 0; p < numPanes; p++ {
+				pane, err := tmux.NewPane(runner, win, nil, tmux.PaneWithCommands(cmds...))
+				require.NoError(b, err)
+				require.NoError(b, pane.Apply(ctx))
+			}
+		}
+
+		require.Equal(b, 20, sess.NumPanes())
+		require.NoError(b, sess.SelectActive(ctx))
+	}
+
+	b.ReportMetric(float64(spawns)/float64(b.N), "spawns/op")
+	b.ReportMetric(float64(commands)/float64(b.N), "commands/op")
+}
+
+// splitCommands splits tmux command line arguments into the commands separated
+// by semicolon arguments.
+func splitCommands(args []string) [][]string {
+	var cmds [][]string
+
+	start := 0
+
+	for i, arg := range args {
+		if arg == ";" {
+			cmds = append(cmds, args[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(cmds, args[start:])
+}
+
+// dryRunRecorder is a dry-run runner that records the commands it is asked to
+// run.
+type dryRunRecorder struct {
+	*tmux.DefaultRunner
+	cmds []string
+}
+
+func (r *dryRunRecorder) Run(ctx context.Context, args ...string) ([]byte, error) {
+	r.cmds = append(r.cmds, strings.Join(args, " "))
+	return r.DefaultRunner.Run(ctx, args...)
+}
@@ -0,0 +1,588 @@
+package tmux
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// controlConnectTimeout is the maximum time to wait for a control mode client
+// to attach to a session.
+const controlConnectTimeout = 5 * time.Second
+
+// controlCloseTimeout is the maximum time to wait for a control mode client
+// process to exit after its input is closed, before it is killed.
+const controlCloseTimeout = 2 * time.Second
+
+// ErrCommandFailed is returned when tmux reports that a command sent over a
+// control mode connection failed. The error message from tmux is included in
+// the returned error and output.
+var ErrCommandFailed = errors.New("tmux command failed")
+
+// errControlExited is returned when a control mode client exits while a
+// command is waiting for its reply.
+var errControlExited = errors.New("control mode client exited")
+
+// ControlClientStarter starts a tmux control mode client process with the
+// provided name and arguments, and returns a connection to it.
+//
+// Commands are written to the connection one per line, and replies and
+// notifications are read from it. Closing the connection ends the client
+// process.
+type ControlClientStarter func(name string, args ...string) (io.ReadWriteCloser, error)
+
+// ControlRunner is a [Runner] that sends tmux commands over a single
+// persistent control mode client connection (tmux -C) instead of running a new
+// tmux process for every command.
+//
+// A control mode client must be attached to a session, so the connection is
+// opened when the first command is run, by attaching to the session configured
+// with [WithControlSession]. If no session is configured, the client attaches
+// to the most recently used session, like tmux attach-session does without a
+// target. Until a connection can be opened, for instance because the session
+// does not exist yet, commands are run with the fallback [DefaultRunner]. A new
+// connection is attempted after a session is created with the new-session
+// command, and after a connection is lost.
+//
+// Commands sent over the connection run in the context of the attached
+// session, so commands should always specify their targets.
+//
+// Logging, dry-run mode and [Runner.Execve] are handled by the fallback
+// runner. In dry-run mode, no connection is opened.
+//
+// The connection must be closed with [ControlRunner.Close] when the runner is
+// no longer needed.
+//
+// https://github.com/tmux/tmux/wiki/Control-Mode
+type ControlRunner struct {
+	*DefaultRunner
+	start     ControlClientStarter
+	session   string
+	conn      *controlConn
+	reconnect bool
+	mu        sync.Mutex
+}
+
+// NewControlRunner creates a new [ControlRunner] with the provided fallback
+// runner and options.
+//
+// The tmux executable and tmux options of the fallback runner are used to
+// start the control mode client.
+func NewControlRunner(fallback *DefaultRunner, opts ...ControlRunnerOption) (*ControlRunner, error) {
+	if fallback == nil {
+		return nil, ErrNilRunner
+	}
+
+	c := &ControlRunner{
+		DefaultRunner: fallback,
+		start:         startControlClient,
+		reconnect:     true,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("applying option: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// Run sends the tmux command with the provided arguments over the control
+// mode connection and returns the output.
+//
+// If no connection can be opened, or the control mode client exits before
+// replying, the command is run with the fallback runner. If the context is
+// canceled before tmux replies, the context error is returned and the
+// connection is closed, as the reply can still arrive.
+//
+// Commands can be separated by ";" arguments, like on the tmux command line,
+// in which case the output of the commands is concatenated.
+//
+// Returns an error wrapping [ErrCommandFailed] if tmux reports that the
+// command failed. The error is a [CommandError] if tmux reports a known error.
+func (c *ControlRunner) Run(ctx context.Context, args ...string) ([]byte, error) {
+	if c.dryRun || len(args) == 0 {
+		return c.DefaultRunner.Run(ctx, args...)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if conn := c.connection(ctx); conn != nil {
+		output, err := c.runControl(ctx, conn, args)
+		if !errors.Is(err, errControlExited) {
+			return output, err
+		}
+
+		// The client exited before replying, which means that tmux did not
+		// run the command, e.g. because the tmux server exited.
+		c.Debug("control mode connection lost; running command with fallback runner", "err", err)
+		_ = c.closeConn()
+		c.reconnect = true
+	}
+
+	output, err := c.DefaultRunner.Run(ctx, args...)
+	if err == nil && args[0] == "new-session" {
+		c.reconnect = true
+	}
+
+	return output, err
+}
+
+// runControl sends a command over the control mode connection and logs the
+// result.
+func (c *ControlRunner) runControl(ctx context.Context, conn *controlConn, args []string) ([]byte, error) {
+	start := time.Now()
+	msg := "command successful"
+
+	output, err := conn.run(ctx, args)
+	if errors.Is(err, errControlExited) {
+		return nil, err
+	}
+
+	if ctx.Err() != nil {
+		// The replies to the command may still arrive, so the connection
+		// cannot be used for other commands.
+		_ = c.closeConn()
+		c.reconnect = true
+	}
+
+	if err != nil {
+		msg = "command failed"
+	}
+
+	c.Debug(msg, "name", c.tmux, "args", args, "output", strings.TrimSpace(string(output)), "dur", time.Since(start), "control_mode", true)
+
+	return output, commandError(args, output, err)
+}
+
+// Execve closes the control mode connection and runs the tmux command with
+// the provided arguments using the execve syscall ([syscall.Exec]) to replace
+// the current process with the tmux process.
+func (c *ControlRunner) Execve(args ...string) error {
+	if err := c.Close(); err != nil {
+		c.Debug("closing control mode connection failed", "err", err)
+	}
+
+	return c.DefaultRunner.Execve(args...)
+}
+
+// Close closes the control mode connection, if open, and waits for the client
+// process to exit.
+//
+// The runner can still be used after it is closed, in which case a new
+// connection is opened when needed.
+func (c *ControlRunner) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reconnect = true
+
+	return c.closeConn()
+}
+
+// connection returns the open control mode connection, or opens a new one if
+// a connection should be attempted. Returns nil if no connection is available.
+func (c *ControlRunner) connection(ctx context.Context) *controlConn {
+	if c.conn != nil || !c.reconnect {
+		return c.conn
+	}
+
+	c.reconnect = false
+
+	start := time.Now()
+	args := append(append([]string{}, c.tmuxOpts...), "-C", "attach-session")
+
+	if c.session != "" {
+		args = append(args, "-t", "="+c.session)
+	}
+
+	conn, err := c.connect(ctx, args)
+	if err != nil {
+		c.Debug("control mode connection failed; running commands with fallback runner", "name", c.tmux, "args", args, "err", err, "dur", time.Since(start))
+		return nil
+	}
+
+	c.Debug("control mode connection opened", "name", c.tmux, "args", args, "dur", time.Since(start))
+	c.conn = conn
+
+	return conn
+}
+
+// connect starts a control mode client and waits for it to reply to the
+// attach-session command it is started with.
+func (c *ControlRunner) connect(ctx context.Context, args []string) (*controlConn, error) {
+	client, err := c.start(c.tmux, args...)
+	if err != nil {
+		return nil, fmt.Errorf("starting control mode client: %w", err)
+	}
+
+	conn := newControlConn(client)
+
+	ctx, cancel := context.WithTimeout(ctx, controlConnectTimeout)
+	defer cancel()
+
+	reply, err := conn.wait(ctx)
+	if err == nil && reply.failed {
+		err = fmt.Errorf("%w: %s", ErrCommandFailed, strings.TrimSpace(string(reply.output)))
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", controlConnectTimeout)
+		}
+
+		if cErr := conn.close(); cErr != nil && errors.Is(err, errControlExited) {
+			err = fmt.Errorf("%w: %w", err, cErr)
+		}
+
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (c *ControlRunner) closeConn() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.close()
+	c.conn = nil
+
+	return err
+}
+
+// ControlRunnerOption configures a [ControlRunner].
+type ControlRunnerOption func(*ControlRunner) error
+
+// WithControlSession configures the runner to attach the control mode client
+// to the session with the provided name.
+//
+// The session is matched by its exact name, so the client never attaches to
+// another session with a name that starts with the provided name.
+func WithControlSession(name string) ControlRunnerOption {
+	return func(c *ControlRunner) error {
+		c.session = name
+		return nil
+	}
+}
+
+// WithControlClientStarter configures the runner to use the provided
+// [ControlClientStarter] for starting control mode clients.
+//
+// This option is intended for testing purposes only.
+func WithControlClientStarter(starter ControlClientStarter) ControlRunnerOption {
+	return func(c *ControlRunner) error {
+		c.start = starter
+		return nil
+	}
+}
+
+// controlReply is the reply to a command sent over a control mode connection.
+type controlReply struct {
+	output []byte
+	failed bool
+}
+
+// controlConn is a connection to a control mode client.
+//
+// Replies are read by a separate goroutine and delivered in the order the
+// commands were sent. Notifications outside of replies are ignored.
+type controlConn struct {
+	client  io.ReadWriteCloser
+	replies chan controlReply
+	done    chan struct{}
+	readErr error
+}
+
+func newControlConn(client io.ReadWriteCloser) *controlConn {
+	conn := &controlConn{
+		client:  client,
+		replies: make(chan controlReply),
+		done:    make(chan struct{}),
+	}
+
+	go conn.read()
+
+	return conn
+}
+
+// run sends a command line and waits for the replies to its commands.
+//
+// tmux replies to each command in the line separately, and stops at the first
+// command that fails. The output of the commands is concatenated.
+func (c *controlConn) run(ctx context.Context, args []string) ([]byte, error) {
+	line, n := controlCommand(args)
+
+	if _, err := io.WriteString(c.client, line+"\n"); err != nil {
+		return nil, fmt.Errorf("%w: writing command: %w", errControlExited, err)
+	}
+
+	var output []byte
+
+	for i := 0; i < n; i++ {
+		reply, err := c.wait(ctx)
+		if err != nil {
+			if i > 0 && errors.Is(err, errControlExited) {
+				// Some of the commands were run, so the command line must
+				// not be run again.
+				return output, fmt.Errorf("control mode client exited after %d of %d commands", i, n)
+			}
+
+			return output, err
+		}
+
+		output = append(output, reply.output...)
+
+		if reply.failed {
+			return output, fmt.Errorf("%w: %s", ErrCommandFailed, strings.TrimSpace(string(reply.output)))
+		}
+	}
+
+	return output, nil
+}
+
+// wait waits for the next reply.
+func (c *controlConn) wait(ctx context.Context) (controlReply, error) {
+	select {
+	case <-ctx.Done():
+		return controlReply{}, ctx.Err()
+	case reply, ok := <-c.replies:
+		if !ok {
+			if c.readErr != nil {
+				return controlReply{}, fmt.Errorf("%w: %w", errControlExited, c.readErr)
+			}
+
+			return controlReply{}, errControlExited
+		}
+
+		return reply, nil
+	}
+}
+
+// close closes the client connection and stops the reader goroutine.
+func (c *controlConn) close() error {
+	close(c.done)
+
+	return c.client.Close() //nolint:wrapcheck // Wrapping is done by caller.
+}
+
+// read reads replies from the client until it exits or the connection is
+// closed.
+//
+// A reply starts with a %begin line and ends with an %end or %error line with
+// the same timestamp, command number and flags. Lines in between are the
+// output of the command, or the error message if the command failed.
+func (c *controlConn) read() {
+	defer close(c.replies)
+
+	r := bufio.NewReader(c.client)
+
+	var (
+		guard  string
+		output *bytes.Buffer
+	)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				c.readErr = err
+			}
+
+			return
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+
+		if output == nil {
+			if rest, ok := strings.CutPrefix(line, "%begin "); ok {
+				guard = rest
+				output = &bytes.Buffer{}
+			}
+
+			continue
+		}
+
+		var reply controlReply
+
+		switch line {
+		case "%end " + guard:
+			reply = controlReply{output: output.Bytes()}
+		case "%error " + guard:
+			reply = controlReply{output: output.Bytes(), failed: true}
+		default:
+			output.WriteString(line + "\n")
+			continue
+		}
+
+		output = nil
+
+		select {
+		case c.replies <- reply:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// controlCommand returns a tmux command line for the provided arguments and
+// the number of commands in it, with each argument quoted so it is taken
+// literally by the tmux command parser.
+//
+// The arguments are interpreted the way tmux interprets its command line
+// arguments: an argument ending with a semicolon ends a command, unless the
+// semicolon is escaped with a backslash, in which case it is taken literally.
+func controlCommand(args []string) (string, int) {
+	var (
+		cmds [][]string
+		cur  []string
+	)
+
+	for _, arg := range args {
+		switch {
+		case strings.HasSuffix(arg, `\;`):
+			cur = append(cur, quoteControlArg(arg[:len(arg)-2]+";"))
+		case strings.HasSuffix(arg, ";"):
+			if arg != ";" {
+				cur = append(cur, quoteControlArg(arg[:len(arg)-1]))
+			}
+
+			if len(cur) != 0 {
+				cmds = append(cmds, cur)
+			}
+
+			cur = nil
+		default:
+			cur = append(cur, quoteControlArg(arg))
+		}
+	}
+
+	if len(cur) != 0 {
+		cmds = append(cmds, cur)
+	}
+
+	lines := make([]string, len(cmds))
+
+	for i, cmd := range cmds {
+		lines[i] = strings.Join(cmd, " ")
+	}
+
+	return strings.Join(lines, " ; "), len(cmds)
+}
+
+// quoteControlArg encloses arg in double quotes with characters that are
+// special to the tmux command parser escaped. Newlines and other control
+// characters are escaped so the command stays on a single line.
+func quoteControlArg(arg string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+
+	for i := 0; i < len(arg); i++ {
+		switch c := arg[i]; c {
+		case '"', '\\', '$':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&b, `\%03o`, c)
+				continue
+			}
+
+			b.WriteByte(c)
+		}
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// osControlClient is a control mode client running as a tmux process.
+type osControlClient struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.Reader
+	stderr *bytes.Buffer
+}
+
+// startControlClient is the default [ControlClientStarter] implementation.
+func startControlClient(name string, args ...string) (io.ReadWriteCloser, error) {
+	cmd := exec.Command(name, args...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating stdin pipe: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating stdout pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err //nolint:wrapcheck // Wrapping is done by caller.
+	}
+
+	return &osControlClient{cmd: cmd, stdin: stdin, stdout: stdout, stderr: stderr}, nil
+}
+
+func (c *osControlClient) Read(p []byte) (int, error) {
+	return c.stdout.Read(p) //nolint:wrapcheck // Wrapping is done by caller.
+}
+
+func (c *osControlClient) Write(p []byte) (int, error) {
+	return c.stdin.Write(p) //nolint:wrapcheck // Wrapping is done by caller.
+}
+
+// Close closes the input of the client process, which makes tmux detach the
+// client, and waits for the process to exit. The process is killed if it does
+// not exit within [controlCloseTimeout].
+func (c *osControlClient) Close() error {
+	_ = c.stdin.Close()
+
+	done := make(chan error, 1)
+
+	go func() { done <- c.cmd.Wait() }()
+
+	var err error
+
+	select {
+	case err = <-done:
+	case <-time.After(controlCloseTimeout):
+		_ = c.cmd.Process.Kill()
+		err = <-done
+	}
+
+	if err != nil {
+		if msg := strings.TrimSpace(c.stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+
+		return err //nolint:wrapcheck // Wrapping is done by caller.
+	}
+
+	return nil
+}
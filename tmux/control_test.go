@@ -0,0 +1,358 @@
+package tmux_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// controlReplyFunc returns the reply of a fake control mode client to a
+// command line, and whether the command failed.
+type controlReplyFunc func(line string) (output string, failed bool)
+
+// fakeControlClient is a control mode client that replies to commands with a
+// [controlReplyFunc] instead of running tmux.
+type fakeControlClient struct {
+	io.Writer
+	inR   *io.PipeReader
+	outR  *io.PipeReader
+	outW  *io.PipeWriter
+	lines []string
+	mu    sync.Mutex
+}
+
+// newFakeControlStarter returns a [tmux.ControlClientStarter] that starts
+// fake control mode clients replying with reply. The attach-session command
+// the client is started with fails with attachErr if it is not empty.
+//
+// The clients started by the returned starter are sent on the returned
+// channel.
+func newFakeControlStarter(t *testing.T, attachErr string, reply controlReplyFunc) (tmux.ControlClientStarter, chan *fakeControlClient) {
+	t.Helper()
+
+	clients := make(chan *fakeControlClient, 10)
+
+	return func(name string, args ...string) (io.ReadWriteCloser, error) {
+		require.Equal(t, "tmux", name)
+		require.Equal(t, []string{"-C", "attach-session"}, args)
+
+		inR, inW := io.Pipe()
+		outR, outW := io.Pipe()
+		c := &fakeControlClient{Writer: inW, inR: inR, outR: outR, outW: outW}
+
+		go c.serve(attachErr, reply)
+
+		clients <- c
+
+		return c, nil
+	}, clients
+}
+
+func (c *fakeControlClient) serve(attachErr string, reply controlReplyFunc) {
+	defer c.outW.Close()
+
+	num := 100
+
+	writeReply := func(output string, failed bool) bool {
+		guard := fmt.Sprintf("1700000000 %d 1", num)
+		end := "%end"
+
+		if failed {
+			end = "%error"
+		}
+
+		if output != "" && !strings.HasSuffix(output, "\n") {
+			output += "\n"
+		}
+
+		num++
+
+		_, err := fmt.Fprintf(c.outW, "%%begin %s\n%s%s %s\n%%output %%1 noise\n", guard, output, end, guard)
+
+		return err == nil
+	}
+
+	if !writeReply(attachErr, attachErr != "") || attachErr != "" {
+		io.WriteString(c.outW, "%exit\n") //nolint:errcheck // Best effort.
+		return
+	}
+
+	io.WriteString(c.outW, "%session-changed $0 main\n") //nolint:errcheck // Best effort.
+
+	scanner := bufio.NewScanner(c.inR)
+	for scanner.Scan() {
+		c.mu.Lock()
+		c.lines = append(c.lines, scanner.Text())
+		c.mu.Unlock()
+
+		for _, cmd := range strings.Split(scanner.Text(), " ; ") {
+			output, failed := reply(cmd)
+			if !writeReply(output, failed) {
+				return
+			}
+
+			if failed {
+				break
+			}
+		}
+	}
+}
+
+func (c *fakeControlClient) Read(p []byte) (int, error) {
+	return c.outR.Read(p)
+}
+
+func (c *fakeControlClient) Close() error {
+	c.inR.Close()
+	c.outR.Close()
+
+	return nil
+}
+
+func (c *fakeControlClient) sent() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.lines...)
+}
+
+func TestControlRunner_ImplementsRunnerIface(t *testing.T) {
+	_, ok := any(&tmux.ControlRunner{}).(tmux.Runner)
+
+	require.True(t, ok, "expected ControlRunner to implement Runner interface")
+}
+
+func TestControlRunner_Run(t *testing.T) {
+	replies := map[string]string{
+		`"list-sessions" "-F" "#{session_id}"`:                     "$0\n$1",
+		`"display-message" "-p" "%end 1700000000 101 0"`:           "%end 1700000000 101 0",
+		`"send-keys" "-t" "main:1" "echo \"\$HOME\" \\ ~" "Enter"`: "",
+		`"send-keys" "-t" "main:1" "a\nb\tc" "Enter"`:              "",
+	}
+
+	starter, clients := newFakeControlStarter(t, "", func(line string) (string, bool) {
+		if strings.HasPrefix(line, `"select-window"`) {
+			return "can't find window: 9", true
+		}
+
+		out, ok := replies[line]
+		if !ok {
+			return "unknown command: " + line, true
+		}
+
+		return out, false
+	})
+
+	fallback, err := tmux.NewRunner(tmux.WithOSCommandRunner(func(context.Context, string, ...string) ([]byte, error) {
+		t.Fatal("expected no commands to be run with fallback runner")
+		return nil, nil
+	}))
+	require.NoError(t, err)
+
+	runner, err := tmux.NewControlRunner(fallback, tmux.WithControlClientStarter(starter))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, runner.Close()) })
+
+	tt := []struct {
+		name       string
+		args       []string
+		wantOutput string
+		wantErr    string
+	}{
+		{"output", []string{"list-sessions", "-F", "#{session_id}"}, "$0\n$1\n", ""},
+		{"output with end line", []string{"display-message", "-p", "%end 1700000000 101 0"}, "%end 1700000000 101 0\n", ""},
+		{"special characters", []string{"send-keys", "-t", "main:1", `echo "$HOME" \ ~`, "Enter"}, "", ""},
+		{"control characters", []string{"send-keys", "-t", "main:1", "a\nb\tc", "Enter"}, "", ""},
+		{"error", []string{"bogus"}, "unknown command: \"bogus\"\n", "tmux command failed: unknown command: \"bogus\""},
+		{"known error", []string{"select-window", "-t", "main:9"}, "can't find window: 9\n", "can't find window: 9"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := runner.Run(context.Background(), tc.args...)
+
+			if tc.wantErr != "" {
+				require.ErrorIs(t, err, tmux.ErrCommandFailed)
+				require.Equal(t, strings.HasPrefix(tc.wantErr, "can't find"), errors.Is(err, tmux.ErrTargetNotFound))
+				require.EqualError(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tc.wantOutput, string(output))
+		})
+	}
+
+	require.Len(t, clients, 1, "expected a single control mode client to be started")
+}
+
+func TestControlRunner_Run_Session(t *testing.T) {
+	starter, _ := newFakeControlStarter(t, "", func(string) (string, bool) { return "$0", false })
+
+	var startArgs []string
+
+	fallback, err := tmux.NewRunner(tmux.WithTmuxOptions("-L", "test"))
+	require.NoError(t, err)
+
+	runner, err := tmux.NewControlRunner(fallback,
+		tmux.WithControlSession("main"),
+		tmux.WithControlClientStarter(func(name string, args ...string) (io.ReadWriteCloser, error) {
+			startArgs = args
+			return starter(name, "-C", "attach-session")
+		}),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, runner.Close()) })
+
+	output, err := runner.Run(context.Background(), "list-sessions", "-F", "#{session_id}")
+	require.NoError(t, err)
+	require.Equal(t, "$0\n", string(output))
+	require.Equal(t, []string{"-L", "test", "-C", "attach-session", "-t", "=main"}, startArgs)
+}
+
+func TestControlRunner_Run_Fallback(t *testing.T) {
+	// Attaching fails until a session is created with the fallback runner.
+	failStarter, _ := newFakeControlStarter(t, "no sessions", nil)
+	okStarter, clients := newFakeControlStarter(t, "", func(string) (string, bool) { return "@1", false })
+
+	var fallbackArgs [][]string
+
+	fallback, err := tmux.NewRunner(tmux.WithOSCommandRunner(func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		fallbackArgs = append(fallbackArgs, args)
+		return []byte("$0\n"), nil
+	}))
+	require.NoError(t, err)
+
+	runner, err := tmux.NewControlRunner(fallback, tmux.WithControlClientStarter(func(name string, args ...string) (io.ReadWriteCloser, error) {
+		if len(fallbackArgs) == 0 {
+			return failStarter(name, args...)
+		}
+
+		return okStarter(name, args...)
+	}))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, runner.Close()) })
+
+	ctx := context.Background()
+
+	output, err := runner.Run(ctx, "new-session", "-d", "-P", "-F", "#{session_id}")
+	require.NoError(t, err)
+	require.Equal(t, "$0\n", string(output))
+	require.Equal(t, [][]string{{"new-session", "-d", "-P", "-F", "#{session_id}"}}, fallbackArgs)
+	require.Empty(t, clients)
+
+	output, err = runner.Run(ctx, "new-window", "-P", "-F", "#{window_id}")
+	require.NoError(t, err)
+	require.Equal(t, "@1\n", string(output))
+	require.Len(t, fallbackArgs, 1, "expected command to be sent over control mode connection")
+	require.Len(t, clients, 1)
+}
+
+func TestControlRunner_Run_ContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+
+	starter, clients := newFakeControlStarter(t, "", func(line string) (string, bool) {
+		if strings.Contains(line, "slow") {
+			<-release
+		}
+
+		return "ok", false
+	})
+
+	fallback, err := tmux.NewRunner()
+	require.NoError(t, err)
+
+	runner, err := tmux.NewControlRunner(fallback, tmux.WithControlClientStarter(starter))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, runner.Close()) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = runner.Run(ctx, "display-message", "-p", "slow")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+
+	output, err := runner.Run(context.Background(), "display-message", "-p", "fast")
+	require.NoError(t, err)
+	require.Equal(t, "ok\n", string(output))
+
+	require.Len(t, clients, 2, "expected a new control mode client to be started after cancellation")
+	require.Equal(t, []string{`"display-message" "-p" "slow"`}, (<-clients).sent())
+	require.Equal(t, []string{`"display-message" "-p" "fast"`}, (<-clients).sent())
+}
+
+func TestControlRunner_Run_MultipleCommands(t *testing.T) {
+	starter, clients := newFakeControlStarter(t, "", func(cmd string) (string, bool) {
+		if strings.HasPrefix(cmd, `"bogus"`) {
+			return "unknown command: bogus", true
+		}
+
+		return strings.Fields(cmd)[len(strings.Fields(cmd))-1], false
+	})
+
+	fallback, err := tmux.NewRunner()
+	require.NoError(t, err)
+
+	runner, err := tmux.NewControlRunner(fallback, tmux.WithControlClientStarter(starter))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, runner.Close()) })
+
+	ctx := context.Background()
+
+	output, err := runner.Run(ctx, "display-message", "-p", "one", ";", "display-message", "-p", `two\;`, ";", "display-message", "-p", "three;")
+	require.NoError(t, err)
+	require.Equal(t, "\"one\"\n\"two;\"\n\"three\"\n", string(output))
+
+	output, err = runner.Run(ctx, "display-message", "-p", "one", ";", "bogus", ";", "display-message", "-p", "three")
+	require.ErrorIs(t, err, tmux.ErrCommandFailed)
+	require.Equal(t, "\"one\"\nunknown command: bogus\n", string(output))
+
+	output, err = runner.Run(ctx, "display-message", "-p", "after")
+	require.NoError(t, err)
+	require.Equal(t, "\"after\"\n", string(output), "expected no replies to commands after failed command")
+
+	require.Equal(t, []string{
+		`"display-message" "-p" "one" ; "display-message" "-p" "two;" ; "display-message" "-p" "three"`,
+		`"display-message" "-p" "one" ; "bogus" ; "display-message" "-p" "three"`,
+		`"display-message" "-p" "after"`,
+	}, (<-clients).sent())
+}
+
+func TestControlRunner_Run_DryRun(t *testing.T) {
+	fallback, err := tmux.NewRunner(tmux.WithDryRunMode(true))
+	require.NoError(t, err)
+
+	runner, err := tmux.NewControlRunner(fallback, tmux.WithControlClientStarter(func(string, ...string) (io.ReadWriteCloser, error) {
+		t.Fatal("expected no control mode client to be started in dry-run mode")
+		return nil, nil
+	}))
+	require.NoError(t, err)
+
+	output, err := runner.Run(context.Background(), "new-session", "-d")
+	require.NoError(t, err)
+	require.Empty(t, output)
+	require.NoError(t, runner.Close())
+}
+
+func TestNewControlRunner_NilFallback(t *testing.T) {
+	_, err := tmux.NewControlRunner(nil)
+
+	require.ErrorIs(t, err, tmux.ErrNilRunner)
+}
@@ -1,6 +1,9 @@
 package tmux
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 var (
 	// ErrNilRunner is returned when a nil [Runner] argument is passed.
@@ -10,11 +13,108 @@ var (
 	// ErrNilWindow is returned when a nil [Window] argument is passed.
 	ErrNilWindow = errors.New("window is nil")
 	// ErrNilPane is returned when a nil [Pane] argument is passed.
+	ErrNilPane = errors.New("pane is nil")
+	// ErrSessionClosed is returned when a closed [Session] is used.
 	ErrSessionClosed = errors.New("session is closed")
+	// ErrWindowClosed is returned when a closed [Window] is used.
+	ErrWindowClosed = errors.New("window is closed")
+	// ErrPaneClosed is returned when a closed [Pane] is used.
+	ErrPaneClosed = errors.New("pane is closed")
 	// ErrSessionNotApplied is returned when an unapplied [Session] is used.
 	ErrSessionNotApplied = errors.New("session is not applied")
 	// ErrWindowNotApplied is returned when an unapplied [Window] is used.
 	ErrWindowNotApplied = errors.New("window is not applied")
 	// ErrPaneNotApplied is returned when an unapplied [Pane] is used.
 	ErrPaneNotApplied = errors.New("pane is not applied")
+	// ErrSessionNotFound is returned when no session matches a target.
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrWindowNotFound is returned when no window matches a target.
+	ErrWindowNotFound = errors.New("window not found")
+	// ErrNoServer is returned when a command fails because no tmux server is
+	// running.
+	ErrNoServer = errors.New("no tmux server running")
+	// ErrSessionExists is returned when a command fails because a session with
+	// the same name already exists.
+	ErrSessionExists = errors.New("session already exists")
+	// ErrTargetNotFound is returned when a command fails because its target
+	// session, window or pane does not exist.
+	ErrTargetNotFound = errors.New("target not found")
+	// ErrUnknownOption is returned when a command fails because of an unknown
+	// tmux option.
+	ErrUnknownOption = errors.New("unknown option")
+	// ErrSizeTooSmall is returned when a command fails because there is not
+	// enough space for a new pane.
+	ErrSizeTooSmall = errors.New("size too small")
 )
+
+// knownCommandErrors maps prefixes of tmux error messages to the errors they
+// are reported as.
+var knownCommandErrors = []struct {
+	prefix string
+	err    error
+}{
+	{"no server running on ", ErrNoServer},
+	{"error connecting to ", ErrNoServer},
+	{"failed to connect to server", ErrNoServer},
+	{"duplicate session: ", ErrSessionExists},
+	{"can't find session: ", ErrTargetNotFound},
+	{"can't find window: ", ErrTargetNotFound},
+	{"can't find pane: ", ErrTargetNotFound},
+	{"invalid option: ", ErrUnknownOption},
+	{"unknown option: ", ErrUnknownOption},
+	{"no space for new pane", ErrSizeTooSmall},
+}
+
+// CommandError is returned by runners when a tmux command fails with a known
+// tmux error message.
+//
+// The error wraps one of [ErrNoServer], [ErrSessionExists],
+// [ErrTargetNotFound], [ErrUnknownOption] or [ErrSizeTooSmall], as well as
+// the error returned by running the command.
+type CommandError struct {
+	err    error
+	kind   error
+	args   []string
+	stderr string
+}
+
+// commandError returns a [CommandError] if the output of a failed tmux command
+// with the provided arguments contains a known tmux error message, or err
+// as-is otherwise.
+func commandError(args []string, output []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+
+		for _, known := range knownCommandErrors {
+			if strings.HasPrefix(line, known.prefix) {
+				return CommandError{err: err, kind: known.err, args: args, stderr: line}
+			}
+		}
+	}
+
+	return err
+}
+
+// Error implements the error interface.
+func (e CommandError) Error() string {
+	return e.stderr
+}
+
+// Unwrap returns the error kind and the error returned by running the command.
+func (e CommandError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// Args returns the arguments of the failed tmux command.
+func (e CommandError) Args() []string {
+	return e.args
+}
+
+// Stderr returns the error message printed by tmux.
+func (e CommandError) Stderr() string {
+	return e.stderr
+}
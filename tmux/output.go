@@ -2,16 +2,37 @@ package tmux
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
 var (
-	newline = []byte("\n")
-	comma   = []byte(",")
-	colon   = []byte(":")
+	newline   = []byte("\n")
+	colon     = []byte(":")
+	backslash = []byte("\\")
 )
 
+var outputKeyRegexp = regexp.MustCompile(`^[a-z_]+$`)
+
+// plainOutputVars are tmux variables with values that never contain commas or
+// backslashes, such as IDs, indexes, sizes and flags, and therefore do not need
+// to be escaped in command output.
+var plainOutputVars = map[string]bool{
+	"session_id":    true,
+	"window_id":     true,
+	"window_index":  true,
+	"window_width":  true,
+	"window_height": true,
+	"window_active": true,
+	"pane_id":       true,
+	"pane_index":    true,
+	"pane_width":    true,
+	"pane_height":   true,
+	"pane_active":   true,
+}
+
 // outputRecord represents a line of output from a tmux command, that follows
 // a specific format for parsing it as key-value pairs.
 type outputRecord map[string]string
@@ -35,9 +56,10 @@ func (r outputRecord) String() string {
 // The vars are expected to be valid tmux variable names (e.g. session_id).
 //
 // The output format is a comma-separated list of key-value pairs, where the
-// key is the variable name and the value is the variable placeholder:
+// key is the variable name and the value is the variable placeholder (see
+// [outputFormatVar]):
 //
-//	"session_id:#{session_id},session_name:#{session_name}"
+//	"session_id:#{session_id},session_name:#{s/([,\\])/\\\1/:session_name}"
 func outputFormat(vars ...string) string {
 	res := make([]string, 0, len(vars))
 
@@ -51,7 +73,8 @@ func outputFormat(vars ...string) string {
 // parseOutput parses the tmux command output into a slice of output records.
 //
 // The output is expected to follow the format created by the [outputFormat]
-// function.
+// function, where commas and backslashes in values are escaped with a
+// backslash.
 func parseOutput(output []byte) ([]outputRecord, error) {
 	output = bytes.TrimSpace(output)
 	if len(output) == 0 {
@@ -64,16 +87,15 @@ func parseOutput(output []byte) ([]outputRecord, error) {
 	for _, line := range lines {
 		line = bytes.TrimSpace(line)
 
-		if len(lines) == 0 {
+		if len(line) == 0 {
 			continue
 		}
 
 		record := make(outputRecord)
-		keyvals := bytes.Split(line, comma)
 
-		for _, kv := range keyvals {
+		for _, kv := range splitOutputLine(line) {
 			key, val, ok := bytes.Cut(kv, colon)
-			if !ok {
+			if !ok || !outputKeyRegexp.Match(key) {
 				return nil, fmt.Errorf("invalid key-value pair in command output: %s", kv)
 			}
 
@@ -81,7 +103,7 @@ func parseOutput(output []byte) ([]outputRecord, error) {
 				return nil, fmt.Errorf("duplicate key in command output: %s", key)
 			}
 
-			record[string(key)] = string(val)
+			record[string(key)] = unescapeOutputValue(val)
 		}
 
 		res = append(res, record)
@@ -90,6 +112,75 @@ func parseOutput(output []byte) ([]outputRecord, error) {
 	return res, nil
 }
 
+// parseOutputRecord parses the output of a tmux command that is expected to
+// print a single record, such as a command that creates a session, window or
+// pane.
+//
+// Returns an error if the output is empty.
+func parseOutputRecord(output []byte) (outputRecord, error) {
+	records, err := parseOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("command output is empty")
+	}
+
+	return records[0], nil
+}
+
+// splitOutputLine splits a line of command output on commas that are not
+// escaped with a backslash.
+func splitOutputLine(line []byte) [][]byte {
+	var res [][]byte
+
+	start := 0
+
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case ',':
+			res = append(res, line[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(res, line[start:])
+}
+
+// unescapeOutputValue returns the value with the backslash escapes added by
+// the [outputFormatVar] placeholder removed.
+func unescapeOutputValue(val []byte) string {
+	if !bytes.Contains(val, backslash) {
+		return string(val)
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(val); i++ {
+		if val[i] == '\\' && i+1 < len(val) {
+			i++
+		}
+
+		b.WriteByte(val[i])
+	}
+
+	return b.String()
+}
+
+// outputFormatVar returns a tmux format placeholder for the variable with the
+// provided name.
+//
+// Unless the variable is one of [plainOutputVars], the placeholder uses the
+// s/// format modifier to escape commas and backslashes in the value with a
+// backslash, so that values such as window layouts and paths containing commas
+// can be parsed unambiguously.
 func outputFormatVar(name string) string {
-	return fmt.Sprintf("#{%s}", name)
+	if plainOutputVars[name] {
+		return fmt.Sprintf("#{%s}", name)
+	}
+
+	return `#{s/([,\\])/\\\1/:` + name + "}"
 }
@@ -1,11 +1,22 @@
 package tmux
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
-var paneOutputFormat = outputFormat("pane_id", "pane_path", "pane_index", "pane_width", "pane_height")
+var paneOutputFormat = outputFormat(
+	"pane_id", "pane_path", "pane_index", "pane_width", "pane_height",
+	"pane_current_path",
+)
+
+// paneListOutputFormat is used for loading existing panes, and includes the
+// flag for whether the pane is the active pane of its window, and the
+// directory the pane was started in.
+var paneListOutputFormat = paneOutputFormat + "," + outputFormat("pane_active", "pane_start_path")
 
 // Pane represents a tmux window pane.
 type Pane struct {
@@ -16,6 +27,8 @@ type Pane struct {
 	env        map[string]string
 	id         string
 	path       string
+	curPath    string
+	startPath  string
 	cmds       []string
 	size       string
 	width      string
@@ -94,47 +107,33 @@ func (p *Pane) Apply(ctx context.Context) error {
 		return nil
 	}
 
+	// The parent pane is targeted by its ID, as its index changes when panes
+	// are split from the panes before it.
 	target := p.win.Name()
 	if p.pane != nil {
-		target = p.pane.Name()
-	}
-
-	args := []string{"split-window", "-d", "-P", "-F", paneOutputFormat, "-t", target}
-
-	args = append(args, p.envArgs()...)
-
-	if p.path != "" {
-		args = append(args, "-c", p.path)
-	}
-
-	if p.size != "" {
-		args = append(args, "-l", p.size)
-	}
-
-	if p.horizontal {
-		args = append(args, "-h")
+		target = p.pane.target()
 	}
 
-	output, err := p.tmux.Run(ctx, args...)
+	output, err := p.tmux.Run(ctx, p.splitWindowArgs(target)...)
 	if err != nil {
 		return fmt.Errorf("running split-window command: %w", err)
 	}
 
-	if p.tmux.IsDryRun() {
+	if p.tmux.IsDryRun() && len(bytes.TrimSpace(output)) == 0 {
 		output = []byte(p.dryRunRecord())
 	}
 
-	records, err := parseOutput(output)
+	record, err := parseOutputRecord(output)
 	if err != nil {
 		return fmt.Errorf("parsing split-window command output: %w", err)
 	}
 
-	if err := p.update(records[0]); err != nil {
+	if err := p.update(record); err != nil {
 		return fmt.Errorf("updating pane data: %w", err)
 	}
 
 	if p.pane != nil {
-		p.addPane(p)
+		p.pane.addPane(p)
 	} else {
 		p.win.addPane(p)
 	}
@@ -149,7 +148,8 @@ func (p *Pane) Apply(ctx context.Context) error {
 // RunCommands runs the provided commands inside the pane by invoking the
 // send-keys tmux command using its internal [Runner] instance.
 //
-// The commands are automatically followed by a carriage return.
+// The commands are automatically followed by a carriage return, and are all
+// sent with a single tmux invocation.
 //
 // If the pane is not applied, the method returns [ErrPaneNotApplied].
 //
@@ -169,16 +169,17 @@ func (p *Pane) RunCommands(ctx context.Context, cmds ...string) error {
 		return fmt.Errorf("checking pane state: %w", err)
 	}
 
+	b := newBatch(p.tmux)
+
 	for _, cmd := range cmds {
-		args := []string{"send-keys", "-t", p.Name(), cmd, "C-m"}
-		if _, err := p.tmux.Run(ctx, args...); err != nil {
-			return fmt.Errorf("running send-keys command: %w", err)
-		}
+		cmd := cmd
 
-		p.log("pane send-keys", "cmd", cmd+"<cr>")
+		b.add(func() {
+			p.log("pane send-keys", "cmd", cmd+"<cr>")
+		}, sendKeysArgs(p.Name(), cmd)...)
 	}
 
-	return nil
+	return b.run(ctx)
 }
 
 // Select selects the pane by invoking the select-pane command using its
@@ -196,11 +197,212 @@ func (p *Pane) Select(ctx context.Context) error {
 		return fmt.Errorf("checking pane state: %w", err)
 	}
 
-	if _, err := p.tmux.Run(ctx, "select-pane", "-t", p.Name()); err != nil {
-		return fmt.Errorf("running select-pane command: %w", err)
+	b := newBatch(p.tmux)
+	p.queueSelect(b)
+
+	return b.run(ctx)
+}
+
+// queueSelect queues a select-pane command for selecting the pane in the
+// provided batch.
+func (p *Pane) queueSelect(b *batch) {
+	b.add(func() {
+		p.log("pane selected")
+	}, "select-pane", "-t", p.Name())
+}
+
+// Resize resizes the pane by invoking the resize-pane command using its
+// internal [Runner] instance.
+//
+// The width and height can be specified as a number of cells or as a
+// percentage of the window size (e.g. 50%). An empty width or height leaves
+// the dimension unchanged.
+//
+// If the pane is not applied, the method returns [ErrPaneNotApplied].
+//
+// https://man.archlinux.org/man/tmux.1#resize-pane
+func (p *Pane) Resize(ctx context.Context, width, height string) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	p.log("pane selected")
+	if err := p.checkState(); err != nil {
+		return fmt.Errorf("checking pane state: %w", err)
+	}
+
+	if width == "" && height == "" {
+		return nil
+	}
+
+	args := []string{"resize-pane", "-t", p.target()}
+
+	if width != "" {
+		args = append(args, "-x", width)
+	}
+
+	if height != "" {
+		args = append(args, "-y", height)
+	}
+
+	if _, err := p.tmux.Run(ctx, args...); err != nil {
+		return fmt.Errorf("running resize-pane command: %w", err)
+	}
+
+	if err := p.refresh(ctx); err != nil {
+		return err
+	}
+
+	p.log("pane resized")
+
+	return nil
+}
+
+// Swap swaps the pane with the provided pane by invoking the swap-pane command
+// using its internal [Runner] instance.
+//
+// The panes trade places, including their indexes, windows and parent panes,
+// so that both panes keep describing the same tmux pane after the swap.
+//
+// If either pane is not applied, the method returns [ErrPaneNotApplied].
+//
+// https://man.archlinux.org/man/tmux.1#swap-pane
+func (p *Pane) Swap(ctx context.Context, other *Pane) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if other == nil {
+		return ErrNilPane
+	}
+
+	if err := p.checkState(); err != nil {
+		return fmt.Errorf("checking pane state: %w", err)
+	}
+
+	if err := other.checkState(); err != nil {
+		return fmt.Errorf("checking other pane state: %w", err)
+	}
+
+	if p == other {
+		return nil
+	}
+
+	if _, err := p.tmux.Run(ctx, "swap-pane", "-d", "-s", p.target(), "-t", other.target()); err != nil {
+		return fmt.Errorf("running swap-pane command: %w", err)
+	}
+
+	swapPaneRefs(p.win.panes, other.win.panes, p, other)
+
+	if p.pane != nil || other.pane != nil {
+		var pPanes, oPanes []*Pane
+
+		if p.pane != nil {
+			pPanes = p.pane.panes
+		}
+
+		if other.pane != nil {
+			oPanes = other.pane.panes
+		}
+
+		swapPaneRefs(pPanes, oPanes, p, other)
+	}
+
+	p.sess, other.sess = other.sess, p.sess
+	p.win, other.win = other.win, p.win
+	p.pane, other.pane = other.pane, p.pane
+	p.index, other.index = other.index, p.index
+	p.width, other.width = other.width, p.width
+	p.height, other.height = other.height, p.height
+
+	p.log("pane swapped", "other_pane", other.Name())
+
+	return nil
+}
+
+// Respawn restarts the pane by invoking the respawn-pane command using its
+// internal [Runner] instance.
+//
+// Any process running in the pane is killed and replaced with a new shell in
+// the pane's working directory. The pane's commands are run again afterwards,
+// in the same way as when the pane was created with [Pane.Apply].
+//
+// If the pane is not applied, the method returns [ErrPaneNotApplied].
+//
+// https://man.archlinux.org/man/tmux.1#respawn-pane
+func (p *Pane) Respawn(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := p.checkState(); err != nil {
+		return fmt.Errorf("checking pane state: %w", err)
+	}
+
+	args := []string{"respawn-pane", "-k", "-t", p.target()}
+
+	args = append(args, p.envArgs()...)
+
+	if p.path != "" {
+		args = append(args, "-c", p.path)
+	}
+
+	if _, err := p.tmux.Run(ctx, args...); err != nil {
+		return fmt.Errorf("running respawn-pane command: %w", err)
+	}
+
+	p.log("pane respawned")
+
+	cmds := append(p.sess.onPaneCommands(), p.cmds...)
+
+	return p.RunCommands(ctx, cmds...)
+}
+
+// Close closes the pane by invoking the kill-pane command using its internal
+// [Runner] instance.
+//
+// The pane is removed from its window and parent pane, and panes split from it
+// are moved to its parent pane. Any subsequent calls to command-invoking
+// methods on the pane will return an [ErrPaneClosed] error.
+//
+// If the pane is already closed, this method is a no-op. If the pane is not
+// applied, the method returns [ErrPaneNotApplied].
+//
+// https://man.archlinux.org/man/tmux.1#kill-pane
+func (p *Pane) Close(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if p.IsClosed() {
+		return nil
+	}
+
+	if err := p.checkState(); err != nil {
+		return fmt.Errorf("checking pane state: %w", err)
+	}
+
+	if _, err := p.tmux.Run(ctx, "kill-pane", "-t", p.target()); err != nil {
+		return fmt.Errorf("running kill-pane command: %w", err)
+	}
+
+	p.log("pane closed")
+
+	p.state = stateClosed
+	p.win.removePane(p)
+
+	if p.pane != nil {
+		p.pane.panes = removePane(p.pane.panes, p)
+	}
+
+	for _, child := range p.panes {
+		child.pane = p.pane
+
+		if p.pane != nil {
+			p.pane.panes = append(p.pane.panes, child)
+		}
+	}
+
+	p.panes = nil
 
 	return nil
 }
@@ -213,6 +415,71 @@ func (p *Pane) Name() string {
 	return fmt.Sprintf("%s.%s", p.win.Name(), p.index)
 }
 
+// ID returns the pane's unique ID as reported by tmux, e.g. %1.
+//
+// The ID is only known for applied panes.
+func (p *Pane) ID() string {
+	return p.id
+}
+
+// idNum returns the number of the pane's ID, such as 3 for %3, or -1 if the ID
+// is not known.
+func (p *Pane) idNum() int {
+	n, err := strconv.Atoi(strings.TrimPrefix(p.id, "%"))
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
+
+// Width returns the pane's width in cells.
+//
+// The width is only known for applied panes.
+func (p *Pane) Width() string {
+	return p.width
+}
+
+// Height returns the pane's height in cells.
+//
+// The height is only known for applied panes.
+func (p *Pane) Height() string {
+	return p.height
+}
+
+// Window returns the window the pane belongs to.
+func (p *Pane) Window() *Window {
+	return p.win
+}
+
+// Path returns the pane's working directory.
+func (p *Pane) Path() string {
+	return p.path
+}
+
+// Index returns the pane's index within its window.
+//
+// The index is only known for applied panes.
+func (p *Pane) Index() string {
+	return p.index
+}
+
+// CurrentPath returns the current working directory of the pane's active
+// process as reported by tmux.
+//
+// The current path is only known for applied panes.
+func (p *Pane) CurrentPath() string {
+	return p.curPath
+}
+
+// StartPath returns the directory the pane was started in as reported by tmux.
+//
+// The start path is only known for panes loaded with [GetPanes], and is empty
+// with tmux versions that don't report it.
+func (p *Pane) StartPath() string {
+	return p.startPath
+}
+
 // IsApplied returns true if the pane has been applied with [Pane.Apply].
 func (p *Pane) IsApplied() bool {
 	return p.state == stateApplied
@@ -224,7 +491,8 @@ func (p *Pane) IsClosed() bool {
 }
 
 // IsActive returns true if the pane is configured as the active pane of its
-// window.
+// window. For panes loaded with [GetPanes], it returns true if the pane was the
+// active pane when loaded.
 func (p *Pane) IsActive() bool {
 	return p.active
 }
@@ -242,11 +510,13 @@ func (p *Pane) String() string {
 // update updates the pane's internal state from the provided output record.
 func (p *Pane) update(record outputRecord) error {
 	fieldsMap := map[string]*string{
-		"pane_id":     &p.id,
-		"pane_path":   &p.path,
-		"pane_index":  &p.index,
-		"pane_width":  &p.width,
-		"pane_height": &p.height,
+		"pane_id":           &p.id,
+		"pane_path":         &p.path,
+		"pane_index":        &p.index,
+		"pane_width":        &p.width,
+		"pane_height":       &p.height,
+		"pane_current_path": &p.curPath,
+		"pane_start_path":   &p.startPath,
 	}
 
 	for k, v := range record {
@@ -265,8 +535,16 @@ func (p *Pane) update(record outputRecord) error {
 }
 
 func (p *Pane) checkState() error {
-	if p.IsClosed() {
-		return ErrPaneNotApplied
+	if p.win.sess.IsClosed() {
+		return ErrSessionClosed
+	}
+
+	if p.win.state == stateClosed {
+		return ErrWindowClosed
+	}
+
+	if p.state == stateClosed {
+		return ErrPaneClosed
 	}
 
 	if p.IsApplied() {
@@ -278,15 +556,78 @@ func (p *Pane) checkState() error {
 
 func (p *Pane) addPane(pane *Pane) {
 	p.panes = append(p.panes, pane)
-	p.win.addPane(p)
+	p.win.addPane(pane)
+}
+
+// refresh updates the pane's internal state by invoking the display-message
+// command using its internal [Runner] instance.
+//
+// In dry-run mode, the internal state is left unchanged unless the runner
+// simulates the command output.
+func (p *Pane) refresh(ctx context.Context) error {
+	output, err := p.tmux.Run(ctx, "display-message", "-p", "-t", p.target(), paneOutputFormat)
+	if err != nil {
+		return fmt.Errorf("running display-message command: %w", err)
+	}
+
+	if p.tmux.IsDryRun() && len(bytes.TrimSpace(output)) == 0 {
+		return nil
+	}
+
+	record, err := parseOutputRecord(output)
+	if err != nil {
+		return fmt.Errorf("parsing display-message command output: %w", err)
+	}
+
+	return p.update(record)
+}
+
+// target returns the target to use when invoking tmux commands on the pane.
+//
+// The pane ID is preferred as it does not change when other panes in the
+// window are closed or swapped.
+func (p *Pane) target() string {
+	if p.id != "" {
+		return p.id
+	}
+
+	return p.Name()
+}
+
+// splitWindowArgs returns the arguments of the split-window command that
+// creates the pane by splitting the provided target.
+func (p *Pane) splitWindowArgs(target string) []string {
+	args := []string{"split-window", "-d", "-P", "-F", paneOutputFormat, "-t", target}
+
+	args = append(args, p.envArgs()...)
+
+	if p.path != "" {
+		args = append(args, "-c", p.path)
+	}
+
+	if p.size != "" {
+		args = append(args, "-l", p.size)
+	}
+
+	if p.horizontal {
+		args = append(args, "-h")
+	}
+
+	return args
 }
 
 func (p *Pane) envArgs() []string {
+	return envArgs(p.mergedEnv())
+}
+
+// mergedEnv returns the environment variables of the pane merged with the
+// variables inherited from its session, window and parent pane.
+func (p *Pane) mergedEnv() map[string]string {
 	if p.pane != nil {
-		return envArgs(p.sess.env, p.win.env, p.pane.env, p.env)
+		return mergeMaps(p.sess.env, p.win.env, p.pane.env, p.env)
 	}
 
-	return envArgs(p.sess.env, p.win.env, p.env)
+	return mergeMaps(p.sess.env, p.win.env, p.env)
 }
 
 func (p *Pane) log(msg string, args ...any) {
@@ -296,7 +637,7 @@ func (p *Pane) log(msg string, args ...any) {
 }
 
 // dryRunRecord returns an output record string to use when running in dry-run
-// mode.
+// mode with a runner that does not simulate command output.
 func (p *Pane) dryRunRecord() string {
 	return outputRecord{
 		"pane_id":     fmt.Sprintf("%%%d", p.sess.NumPanes()+1),
@@ -364,12 +705,127 @@ func PaneAsActive() PaneOption {
 
 // PaneWithEnv configures the [Pane] with environment variables.
 //
-// Environment variables are inherited from session to window to pane. If a
-// an environment variable is is named the same as an inherited variable, it
-// will take precedence.
+// Environment variables are inherited from session to window to pane. If an
+// environment variable is named the same as an inherited variable, it will
+// take precedence.
+//
+// References to environment variables in the values, such as $HOME, are
+// expanded with the environment of the current process when the option is
+// applied, not when the tmux commands are run. The option can be given more
+// than once: variables are added to variables configured by earlier options,
+// replacing variables with the same name.
 func PaneWithEnv(env map[string]string) PaneOption {
 	return func(p *Pane) error {
-		p.env = env
+		p.env = mergeMaps(p.env, expandEnv(env))
 		return nil
 	}
 }
+
+// PaneWithRawEnv configures the [Pane] with environment variables with values
+// that are used as they are, such as values loaded from files or commands.
+//
+// Unlike [PaneWithEnv], references to environment variables in the values are
+// not expanded. Variables are added to variables configured by earlier
+// options, replacing variables with the same name.
+func PaneWithRawEnv(env map[string]string) PaneOption {
+	return func(p *Pane) error {
+		p.env = mergeMaps(p.env, env)
+		return nil
+	}
+}
+
+// PaneWithSecretEnv configures the [Pane] with environment variables holding
+// secret values.
+//
+// Like with [PaneWithRawEnv], the values are used as they are. They are also
+// registered with the runner for redaction if it implements [Redactor].
+func PaneWithSecretEnv(env map[string]string) PaneOption {
+	return func(p *Pane) error {
+		redactEnv(p.tmux, env)
+		return PaneWithRawEnv(env)(p)
+	}
+}
+
+// GetPanes returns the panes of the provided window by invoking the list-panes
+// command using the provided [Runner] instance.
+//
+// The window must be applied before being passed to this function. The
+// returned panes are applied, marked as active if they are the window's active
+// pane, ordered by their index, and include the window's initial pane (see
+// [InitialPane]).
+//
+// The returned panes replace any panes currently tracked by the window. Since
+// windows created with [Window.Apply] do not track their initial pane, the
+// initial pane is left out of the window's panes.
+//
+// https://man.archlinux.org/man/tmux.1#list-panes
+func GetPanes(ctx context.Context, runner Runner, window *Window) ([]*Pane, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if runner == nil {
+		return nil, ErrNilRunner
+	}
+
+	if window == nil {
+		return nil, ErrNilWindow
+	}
+
+	if err := window.checkState(); err != nil {
+		return nil, fmt.Errorf("checking window state: %w", err)
+	}
+
+	output, err := runner.Run(ctx, "list-panes", "-t", window.target(), "-F", paneListOutputFormat)
+	if err != nil {
+		return nil, fmt.Errorf("running list-panes command: %w", err)
+	}
+
+	records, err := parseOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("parsing list-panes command output: %w", err)
+	}
+
+	panes := make([]*Pane, len(records))
+
+	for i, record := range records {
+		p := &Pane{tmux: runner, sess: window.sess, win: window, state: stateApplied}
+		if err := p.update(record); err != nil {
+			return nil, fmt.Errorf("updating pane data: %w", err)
+		}
+
+		p.active = record["pane_active"] == "1"
+
+		panes[i] = p
+	}
+
+	initial := InitialPane(panes)
+	window.panes = nil
+
+	for _, p := range panes {
+		if p != initial {
+			window.panes = append(window.panes, p)
+		}
+	}
+
+	return panes, nil
+}
+
+// InitialPane returns the initial pane of a window among the provided panes
+// returned by [GetPanes], or nil if there are no panes.
+//
+// The initial pane is the pane that was created first, which is the pane with
+// the lowest ID, as tmux assigns increasing IDs to new panes. It is not always
+// the first pane in index order, as panes can be split before it with
+// split-window -b.
+func InitialPane(panes []*Pane) *Pane {
+	var res *Pane
+
+	for _, p := range panes {
+		if res == nil || p.idNum() < res.idNum() {
+			res = p
+		}
+	}
+
+	return res
+}
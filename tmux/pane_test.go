@@ -0,0 +1,261 @@
+package tmux_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+func TestPane_Apply_NestedPane(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"split-window":  "pane_id:%2,pane_index:1,pane_width:40,pane_height:24",
+	})
+
+	win := getWindows(t, runner, "test")[0]
+
+	parent, err := tmux.NewPane(runner, win, nil)
+	require.NoError(t, err)
+	require.NoError(t, parent.Apply(context.Background()))
+
+	child, err := tmux.NewPane(runner, win, parent, tmux.PaneWithHorizontalDirection())
+	require.NoError(t, err)
+	require.NoError(t, child.Apply(context.Background()))
+
+	require.Equal(t, 1, parent.NumPanes())
+	require.Equal(t, 0, child.NumPanes())
+	require.Equal(t, 2, win.NumPanes())
+
+	lastCmd := (*cmds)[len(*cmds)-1]
+	require.True(t, strings.HasSuffix(lastCmd, " -t %2 -h"), "expected split of parent pane by ID; got %q", lastCmd)
+}
+
+func TestPane_Resize(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions":   "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":    "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"list-panes":      "pane_id:%1,pane_index:0,pane_width:40,pane_height:24\npane_id:%2,pane_index:1,pane_width:39,pane_height:24",
+		"resize-pane":     "",
+		"display-message": "pane_id:%2,pane_index:1,pane_width:20,pane_height:24",
+	})
+
+	panes := getPanes(t, runner, getWindows(t, runner, "test")[0])
+
+	require.NoError(t, panes[1].Resize(context.Background(), "20", ""))
+	require.Contains(t, *cmds, "resize-pane -t %2 -x 20")
+	require.Equal(t, "20", panes[1].Width())
+	require.Equal(t, "24", panes[1].Height())
+}
+
+func TestPane_Resize_EmptyOutput(t *testing.T) {
+	runner := newStubRunner(t, map[string]string{
+		"list-sessions":   "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":    "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"list-panes":      "pane_id:%1,pane_index:0,pane_width:40,pane_height:24\npane_id:%2,pane_index:1,pane_width:39,pane_height:24",
+		"resize-pane":     "",
+		"display-message": "",
+	})
+
+	panes := getPanes(t, runner, getWindows(t, runner, "test")[0])
+
+	err := panes[1].Resize(context.Background(), "20", "")
+	require.ErrorContains(t, err, "command output is empty")
+}
+
+func TestPane_Apply_EmptyOutput(t *testing.T) {
+	runner := newStubRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"split-window":  "",
+	})
+
+	win := getWindows(t, runner, "test")[0]
+
+	pane, err := tmux.NewPane(runner, win, nil)
+	require.NoError(t, err)
+
+	err = pane.Apply(context.Background())
+	require.ErrorContains(t, err, "parsing split-window command output: command output is empty")
+	require.False(t, pane.IsApplied())
+}
+
+func TestPane_Swap(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows": "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24\n" +
+			"window_id:@2,window_name:shell,window_index:1,window_width:80,window_height:24",
+		"list-panes": "pane_id:%1,pane_index:0,pane_width:40,pane_height:24\n" +
+			"pane_id:%2,pane_index:1,pane_width:39,pane_height:24",
+		"swap-pane": "",
+	})
+
+	windows := getWindows(t, runner, "test")
+	codePanes := getPanes(t, runner, windows[0])
+	shellPanes := getPanes(t, runner, windows[1])
+
+	require.NoError(t, codePanes[1].Swap(context.Background(), shellPanes[1]))
+	require.Contains(t, *cmds, "swap-pane -d -s %2 -t %2")
+
+	require.Same(t, windows[1], codePanes[1].Window())
+	require.Same(t, windows[0], shellPanes[1].Window())
+	require.Equal(t, "test:shell.1", codePanes[1].Name())
+	require.Equal(t, "test:code.1", shellPanes[1].Name())
+	require.Equal(t, 1, windows[0].NumPanes())
+	require.Equal(t, 1, windows[1].NumPanes())
+
+	require.ErrorIs(t, codePanes[1].Swap(context.Background(), nil), tmux.ErrNilPane)
+}
+
+func TestPane_Respawn(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"split-window":  "pane_id:%2,pane_index:1,pane_width:40,pane_height:24",
+		"respawn-pane":  "",
+		"send-keys":     "",
+	})
+
+	win := getWindows(t, runner, "test")[0]
+
+	pane, err := tmux.NewPane(runner, win, nil,
+		tmux.PaneWithPath("/home/user/project/cmd"),
+		tmux.PaneWithCommands("make watch"),
+		tmux.PaneWithEnv(map[string]string{"APP_ENV": "test"}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, pane.Apply(context.Background()))
+
+	*cmds = nil
+
+	require.NoError(t, pane.Respawn(context.Background()))
+	require.Equal(t, []string{
+		"respawn-pane -k -t %2 -e APP_ENV=test -c /home/user/project/cmd",
+		"send-keys -t test:code.1 make watch C-m",
+	}, *cmds)
+}
+
+func TestPane_Env(t *testing.T) {
+	t.Setenv("TMPL_TEST_DIR", "/srv")
+
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"split-window":  "pane_id:%2,pane_index:1,pane_width:40,pane_height:24",
+	})
+
+	win := getWindows(t, runner, "test")[0]
+
+	// Variables from repeated options are merged, and references are expanded
+	// when the options are applied, except in secret values.
+	pane, err := tmux.NewPane(runner, win, nil,
+		tmux.PaneWithEnv(map[string]string{"APP_ENV": "dev", "APP_DIR": "$TMPL_TEST_DIR/app"}),
+		tmux.PaneWithEnv(map[string]string{"APP_ENV": "test", "LOG_LEVEL": "debug"}),
+		tmux.PaneWithSecretEnv(map[string]string{"API_TOKEN": "$ecret"}),
+	)
+	require.NoError(t, err)
+
+	t.Setenv("TMPL_TEST_DIR", "/opt")
+	*cmds = nil
+
+	require.NoError(t, pane.Apply(context.Background()))
+	require.Len(t, *cmds, 1)
+	require.Contains(t, (*cmds)[0], "-e API_TOKEN=$ecret -e APP_DIR=/srv/app -e APP_ENV=test -e LOG_LEVEL=debug")
+}
+
+func TestPane_Close(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"split-window":  "pane_id:%2,pane_index:1,pane_width:40,pane_height:24",
+		"kill-pane":     "",
+	})
+
+	win := getWindows(t, runner, "test")[0]
+
+	parent, err := tmux.NewPane(runner, win, nil)
+	require.NoError(t, err)
+	require.NoError(t, parent.Apply(context.Background()))
+
+	child, err := tmux.NewPane(runner, win, parent)
+	require.NoError(t, err)
+	require.NoError(t, child.Apply(context.Background()))
+
+	require.NoError(t, parent.Close(context.Background()))
+	require.Contains(t, *cmds, "kill-pane -t %2")
+
+	require.True(t, parent.IsClosed())
+	require.False(t, child.IsClosed())
+	require.Equal(t, 0, parent.NumPanes())
+	require.Equal(t, 1, win.NumPanes())
+
+	require.NoError(t, parent.Close(context.Background()), "expected closing a closed pane to be a no-op")
+	require.ErrorIs(t, parent.Select(context.Background()), tmux.ErrPaneClosed)
+}
+
+func TestPane_NotApplied(t *testing.T) {
+	runner, _ := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+	})
+
+	pane, err := tmux.NewPane(runner, getWindows(t, runner, "test")[0], nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	require.ErrorIs(t, pane.Close(ctx), tmux.ErrPaneNotApplied)
+	require.ErrorIs(t, pane.Resize(ctx, "10", "10"), tmux.ErrPaneNotApplied)
+	require.ErrorIs(t, pane.Respawn(ctx), tmux.ErrPaneNotApplied)
+	require.ErrorIs(t, pane.Swap(ctx, pane), tmux.ErrPaneNotApplied)
+}
+
+// newRecordingRunner returns a [tmux.Runner] that returns the stub output
+// mapped to the tmux sub-command of each invocation, and records the arguments
+// of each invocation as a space-separated string.
+//
+// The test fails on unexpected sub-commands.
+func newRecordingRunner(t *testing.T, outputs map[string]string) (tmux.Runner, *[]string) {
+	t.Helper()
+
+	var cmds []string
+
+	runner, err := tmux.NewRunner(tmux.WithOSCommandRunner(func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		output, ok := outputs[args[0]]
+		if !ok {
+			t.Fatalf("unexpected command: %s", strings.Join(args, " "))
+		}
+
+		cmds = append(cmds, strings.Join(args, " "))
+
+		return []byte(output), nil
+	}))
+	require.NoError(t, err)
+
+	return runner, &cmds
+}
+
+// getWindows returns the windows of the current session with the provided
+// name.
+func getWindows(t *testing.T, runner tmux.Runner, session string) []*tmux.Window {
+	t.Helper()
+
+	windows, err := tmux.GetWindows(context.Background(), runner, getSession(t, runner, session))
+	require.NoError(t, err)
+
+	return windows
+}
+
+// getPanes returns the panes of the provided window.
+func getPanes(t *testing.T, runner tmux.Runner, window *tmux.Window) []*tmux.Pane {
+	t.Helper()
+
+	panes, err := tmux.GetPanes(context.Background(), runner, window)
+	require.NoError(t, err)
+
+	return panes
+}
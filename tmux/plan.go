@@ -0,0 +1,294 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Plan describes a tmux session with its windows and panes, and the tmux
+// commands that create it.
+//
+// A plan is made with [NewPlan] from a session applied in dry-run mode, so
+// that it shows what applying the session would do without creating it.
+type Plan struct {
+	Session  SessionPlan   `json:"session"`
+	Commands []PlanCommand `json:"commands"`
+}
+
+// SessionPlan describes a session in a [Plan].
+type SessionPlan struct {
+	Name    string            `json:"name"`
+	Path    string            `json:"path"`
+	Env     map[string]string `json:"env,omitempty"`
+	Windows []WindowPlan      `json:"windows"`
+}
+
+// WindowPlan describes a window in a [Plan].
+//
+// The path is the session's path if the window is not configured with one. The
+// environment variables are merged with the variables inherited from the
+// session, and the commands include the session's on_window and on_any hook
+// commands. Panes are the panes split from the window's initial pane.
+type WindowPlan struct {
+	Name     string            `json:"name"`
+	Target   string            `json:"target"`
+	Index    string            `json:"index"`
+	Path     string            `json:"path"`
+	Width    int               `json:"width,omitempty"`
+	Height   int               `json:"height,omitempty"`
+	Layout   string            `json:"layout,omitempty"`
+	Active   bool              `json:"active,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	Commands []string          `json:"commands,omitempty"`
+	Panes    []PanePlan        `json:"panes,omitempty"`
+}
+
+// PanePlan describes a pane in a [Plan].
+//
+// The path is the current path of the pane as reported by tmux if the pane is
+// not configured with one. The environment variables are merged with the
+// variables inherited from the session, window and parent pane, and the
+// commands include the session's on_pane and on_any hook commands. Panes are
+// the panes split from the pane.
+type PanePlan struct {
+	Target   string            `json:"target"`
+	Index    string            `json:"index"`
+	Path     string            `json:"path"`
+	Width    int               `json:"width,omitempty"`
+	Height   int               `json:"height,omitempty"`
+	Active   bool              `json:"active,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	Commands []string          `json:"commands,omitempty"`
+	Panes    []PanePlan        `json:"panes,omitempty"`
+}
+
+// PlanCommand is a tmux command in a [Plan].
+type PlanCommand struct {
+	Args   []string `json:"args"`             // Arguments to tmux.
+	Execve bool     `json:"execve,omitempty"` // Whether the command replaces the process.
+}
+
+// NewPlan creates a new [Plan] of the provided applied session.
+//
+// The sizes and layouts of the session's windows and panes are refreshed by
+// invoking tmux commands using the session's [Runner] instance, as they change
+// when panes are split and layouts are selected. In dry-run mode, they are
+// only refreshed if the runner simulates the command output.
+//
+// The commands of the plan are provided with [PlanWithTranscript].
+func NewPlan(ctx context.Context, sess *Session, opts ...PlanOption) (*Plan, error) {
+	if sess == nil {
+		return nil, ErrNilSession
+	}
+
+	if err := sess.checkState(); err != nil {
+		return nil, fmt.Errorf("checking session state: %w", err)
+	}
+
+	o := &planOptions{}
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, fmt.Errorf("applying plan option: %w", err)
+		}
+	}
+
+	rd := o.redaction
+
+	plan := &Plan{
+		Session: SessionPlan{
+			Name:    sess.name,
+			Path:    sess.path,
+			Env:     rd.Env(sess.env),
+			Windows: []WindowPlan{},
+		},
+		Commands: []PlanCommand{},
+	}
+
+	for _, w := range sess.windows {
+		wp, err := newWindowPlan(ctx, w, rd)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Session.Windows = append(plan.Session.Windows, wp)
+	}
+
+	targets := planTargets(sess)
+
+	for _, call := range o.calls {
+		// Failed commands, such as looking up sessions when no tmux server is
+		// running, are left out as they do not change anything.
+		if call.Err != "" {
+			continue
+		}
+
+		args := make([]string, len(call.Args))
+
+		for i, arg := range call.Args {
+			// IDs are assigned by the tmux server, so they are replaced with
+			// names to make the commands independent of the server.
+			if name, ok := targets[arg]; ok && i > 0 && (call.Args[i-1] == "-t" || call.Args[i-1] == "-s") {
+				arg = name
+			}
+
+			args[i] = rd.String(arg)
+		}
+
+		plan.Commands = append(plan.Commands, PlanCommand{Args: args, Execve: call.Method == MethodExecve})
+	}
+
+	return plan, nil
+}
+
+func newWindowPlan(ctx context.Context, w *Window, rd *Redaction) (WindowPlan, error) {
+	if err := w.refresh(ctx); err != nil {
+		return WindowPlan{}, fmt.Errorf("refreshing %s: %w", w, err)
+	}
+
+	wp := WindowPlan{
+		Name:     w.name,
+		Target:   windowTarget(w),
+		Index:    w.index,
+		Path:     firstNonEmpty(w.path, w.sess.path),
+		Width:    atoi(w.width),
+		Height:   atoi(w.height),
+		Layout:   w.layout,
+		Active:   w.active,
+		Env:      rd.Env(w.mergedEnv()),
+		Commands: rd.strings(append(w.sess.onWindowCommands(), w.cmds...)),
+	}
+
+	for _, p := range w.panes {
+		if p.pane != nil {
+			continue
+		}
+
+		pp, err := newPanePlan(ctx, p, wp.Path, rd)
+		if err != nil {
+			return WindowPlan{}, err
+		}
+
+		wp.Panes = append(wp.Panes, pp)
+	}
+
+	return wp, nil
+}
+
+func newPanePlan(ctx context.Context, p *Pane, parentPath string, rd *Redaction) (PanePlan, error) {
+	if err := p.refresh(ctx); err != nil {
+		return PanePlan{}, fmt.Errorf("refreshing %s: %w", p, err)
+	}
+
+	pp := PanePlan{
+		Target:   paneTarget(p),
+		Index:    p.index,
+		Path:     firstNonEmpty(p.path, p.curPath, parentPath),
+		Width:    atoi(p.width),
+		Height:   atoi(p.height),
+		Active:   p.active,
+		Env:      rd.Env(p.mergedEnv()),
+		Commands: rd.strings(append(p.sess.onPaneCommands(), p.cmds...)),
+	}
+
+	for _, child := range p.panes {
+		cp, err := newPanePlan(ctx, child, pp.Path, rd)
+		if err != nil {
+			return PanePlan{}, err
+		}
+
+		pp.Panes = append(pp.Panes, cp)
+	}
+
+	return pp, nil
+}
+
+// planTargets returns a map of the IDs of the session and its windows and
+// panes to their names, or their indexes for windows without a name (see
+// [windowTarget]).
+func planTargets(sess *Session) map[string]string {
+	targets := map[string]string{}
+
+	if sess.id != "" {
+		targets[sess.id] = sess.name
+	}
+
+	for _, w := range sess.windows {
+		if w.id != "" {
+			targets[w.id] = windowTarget(w)
+		}
+
+		for _, p := range w.panes {
+			if p.id != "" {
+				targets[p.id] = paneTarget(p)
+			}
+		}
+	}
+
+	return targets
+}
+
+// windowTarget returns the target of the window in a plan, which is its name,
+// or its index if it has no name, qualified with the session name.
+func windowTarget(w *Window) string {
+	if w.name == "" {
+		return fmt.Sprintf("%s:%s", w.sess.Name(), w.index)
+	}
+
+	return w.Name()
+}
+
+// paneTarget returns the target of the pane in a plan, which is its index
+// qualified with the target of its window (see [windowTarget]).
+func paneTarget(p *Pane) string {
+	return fmt.Sprintf("%s.%s", windowTarget(p.win), p.index)
+}
+
+// firstNonEmpty returns the first of the provided strings that is not empty.
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// atoi returns s converted to an integer, or zero if s is not a number.
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// PlanOption configures a [Plan].
+type PlanOption func(*planOptions) error
+
+type planOptions struct {
+	calls     []TranscriptCall
+	redaction *Redaction
+}
+
+// PlanWithTranscript configures the [Plan] with the tmux commands recorded
+// while applying the session, such as with a [RecordingRunner].
+//
+// IDs of the session and its windows and panes used as targets are replaced
+// with their names, and commands that failed are left out.
+func PlanWithTranscript(calls []TranscriptCall) PlanOption {
+	return func(o *planOptions) error {
+		o.calls = calls
+		return nil
+	}
+}
+
+// PlanWithRedaction configures the [Plan] to use the provided [Redaction] to
+// replace secrets in environment variables and commands.
+//
+// The default is to not redact anything.
+func PlanWithRedaction(rd *Redaction) PlanOption {
+	return func(o *planOptions) error {
+		o.redaction = rd
+		return nil
+	}
+}
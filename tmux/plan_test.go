@@ -0,0 +1,139 @@
+package tmux_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/internal/faketmux"
+	"github.com/michenriksen/tmpl/internal/testutils"
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+func TestNewPlan(t *testing.T) {
+	// Stub the environment variables used to determine if the client is
+	// attached with switch-client or attach-session.
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("TERM", "xterm-256color")
+
+	rd := tmux.NewRedaction(tmux.DefaultRedactPatterns...)
+
+	srv, err := faketmux.NewServer(
+		faketmux.WithRedaction(rd),
+		faketmux.WithDryRunMode(true),
+		faketmux.WithWorkingDir("/home/user/project"),
+		faketmux.WithSocketDir("/tmp/tmux-test"),
+	)
+	require.NoError(t, err)
+
+	var transcript bytes.Buffer
+
+	runner, err := tmux.NewRecordingRunner(srv, &transcript, tmux.WithRecordingRedaction(rd))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	_, err = tmux.GetSessions(ctx, runner)
+	require.ErrorIs(t, err, tmux.ErrNoServer)
+
+	sess, err := tmux.NewSession(runner,
+		tmux.SessionWithName("project"),
+		tmux.SessionWithPath("/home/user/project"),
+		tmux.SessionWithSize(120, 40),
+		tmux.SessionWithOnAnyCommand("source .env"),
+		tmux.SessionWithEnv(map[string]string{"APP_ENV": "development"}),
+		tmux.SessionWithSecretEnv(map[string]string{"DB_URL": "postgres://app:hunter2-secret@db"}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, sess.Apply(ctx))
+
+	code, err := tmux.NewWindow(runner, sess,
+		tmux.WindowWithName("code"),
+		tmux.WindowWithCommands("nvim ."),
+		tmux.WindowWithLayout("main-vertical"),
+		tmux.WindowAsActive(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, code.Apply(ctx))
+
+	tests, err := tmux.NewPane(runner, code, nil,
+		tmux.PaneWithPath("/home/user/project/test"),
+		tmux.PaneWithCommands("make test-watch"),
+		tmux.PaneWithEnv(map[string]string{"API_TOKEN": "abc"}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, tests.Apply(ctx))
+
+	logs, err := tmux.NewPane(runner, code, tests, tmux.PaneWithCommands("tail -f log/test.log"))
+	require.NoError(t, err)
+	require.NoError(t, logs.Apply(ctx))
+	require.NoError(t, code.SelectLayout(ctx))
+
+	shell, err := tmux.NewWindow(runner, sess, tmux.WindowWithName("shell"))
+	require.NoError(t, err)
+	require.NoError(t, shell.Apply(ctx))
+
+	require.NoError(t, sess.SelectActive(ctx))
+	require.NoError(t, sess.Attach(ctx))
+
+	calls, err := tmux.ReadTranscript(&transcript)
+	require.NoError(t, err)
+
+	plan, err := tmux.NewPlan(ctx, sess, tmux.PlanWithTranscript(calls), tmux.PlanWithRedaction(rd))
+	require.NoError(t, err)
+
+	testutils.NewGolden(t).RequireMatch(plan)
+}
+
+func TestNewPlan_NotApplied(t *testing.T) {
+	runner, err := tmux.NewRunner(tmux.WithDryRunMode(true))
+	require.NoError(t, err)
+
+	sess, err := tmux.NewSession(runner, tmux.SessionWithName("project"))
+	require.NoError(t, err)
+
+	plan, err := tmux.NewPlan(context.Background(), sess)
+
+	require.ErrorIs(t, err, tmux.ErrSessionNotApplied)
+	require.Nil(t, plan)
+
+	plan, err = tmux.NewPlan(context.Background(), nil)
+
+	require.ErrorIs(t, err, tmux.ErrNilSession)
+	require.Nil(t, plan)
+}
+
+func TestNewPlan_UnnamedWindow(t *testing.T) {
+	runner := newStubRunner(t, map[string]string{
+		"list-sessions":   "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":    "window_id:@1,window_name:,window_index:2,window_width:80,window_height:24",
+		"display-message": "window_id:@1,window_name:,window_index:2,window_width:80,window_height:24",
+	})
+
+	win := getWindows(t, runner, "test")[0]
+	calls := []tmux.TranscriptCall{{Method: tmux.MethodRun, Args: []string{"send-keys", "-t", "@1", "ls", "C-m"}}}
+
+	plan, err := tmux.NewPlan(context.Background(), win.Session(), tmux.PlanWithTranscript(calls))
+	require.NoError(t, err)
+
+	require.Equal(t, "test:2", plan.Session.Windows[0].Target)
+	require.Equal(t, []string{"send-keys", "-t", "test:2", "ls", "C-m"}, plan.Commands[0].Args)
+}
+
+func TestNewPlan_EmptyOutput(t *testing.T) {
+	runner := newStubRunner(t, map[string]string{
+		"list-sessions":   "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":    "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"display-message": "",
+	})
+
+	win := getWindows(t, runner, "test")[0]
+
+	plan, err := tmux.NewPlan(context.Background(), win.Session())
+
+	require.ErrorContains(t, err, "command output is empty")
+	require.Nil(t, plan)
+}
@@ -0,0 +1,307 @@
+package tmux
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Methods of a [Runner] recorded in a transcript.
+const (
+	MethodRun    = "run"
+	MethodExecve = "execve"
+)
+
+// ErrReplayDiverged is returned by a [ReplayRunner] when a call differs from
+// the call recorded in the transcript.
+var ErrReplayDiverged = errors.New("replay diverged from transcript")
+
+// TranscriptCall is a call to a [Runner] recorded in a transcript by a
+// [RecordingRunner].
+//
+// A transcript is a JSON Lines file with a call on each line.
+type TranscriptCall struct {
+	Method string        `json:"method"`           // MethodRun or MethodExecve.
+	Args   []string      `json:"args"`             // Arguments to tmux.
+	Output string        `json:"output,omitempty"` // Output of the command.
+	Err    string        `json:"error,omitempty"`  // Error message if the call failed.
+	Dur    time.Duration `json:"dur_ns"`           // Duration of the call in nanoseconds.
+}
+
+// RecordingRunner is a [Runner] that records every call to [Runner.Run] and
+// [Runner.Execve] of the wrapped runner as a [TranscriptCall] in a transcript,
+// which can be replayed with a [ReplayRunner].
+//
+// Secrets are redacted from the transcript with the [Redaction] configured
+// with [WithRecordingRedaction].
+type RecordingRunner struct {
+	Runner
+	w         io.Writer
+	redaction *Redaction
+	mu        sync.Mutex
+}
+
+// NewRecordingRunner creates a new [RecordingRunner] that wraps runner and
+// writes the transcript to w.
+func NewRecordingRunner(runner Runner, w io.Writer, opts ...RecordingRunnerOption) (*RecordingRunner, error) {
+	if runner == nil {
+		return nil, ErrNilRunner
+	}
+
+	r := &RecordingRunner{
+		Runner:    runner,
+		w:         w,
+		redaction: NewRedaction(DefaultRedactPatterns...),
+	}
+
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, fmt.Errorf("applying option: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// Run runs the tmux command with the wrapped runner and records the call.
+//
+// An error is returned if the call cannot be written to the transcript.
+func (r *RecordingRunner) Run(ctx context.Context, args ...string) ([]byte, error) {
+	start := time.Now()
+	output, err := r.Runner.Run(ctx, args...)
+
+	call := TranscriptCall{Method: MethodRun, Args: args, Output: string(output), Dur: time.Since(start)}
+	if err != nil {
+		call.Err = err.Error()
+	}
+
+	if werr := r.record(call); werr != nil {
+		return output, werr
+	}
+
+	return output, err //nolint:wrapcheck // Errors are returned as-is from the wrapped runner.
+}
+
+// Execve records the call and runs the tmux command with the wrapped runner.
+//
+// As a successful call replaces the current process, the call is recorded
+// before it is made, and without its error.
+func (r *RecordingRunner) Execve(args ...string) error {
+	if err := r.record(TranscriptCall{Method: MethodExecve, Args: args}); err != nil {
+		return err
+	}
+
+	return r.Runner.Execve(args...) //nolint:wrapcheck // Errors are returned as-is from the wrapped runner.
+}
+
+// Redact registers values that must not be written to logs or the transcript.
+func (r *RecordingRunner) Redact(values ...string) {
+	r.redaction.Redact(values...)
+
+	if rd, ok := r.Runner.(Redactor); ok {
+		rd.Redact(values...)
+	}
+}
+
+func (r *RecordingRunner) record(call TranscriptCall) error {
+	call.Args = r.redaction.strings(call.Args)
+	call.Output = r.redaction.String(call.Output)
+	call.Err = r.redaction.String(call.Err)
+
+	data, err := json.Marshal(call)
+	if err != nil {
+		return fmt.Errorf("encoding transcript call: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing transcript: %w", err)
+	}
+
+	return nil
+}
+
+// RecordingRunnerOption configures a [RecordingRunner].
+type RecordingRunnerOption func(*RecordingRunner) error
+
+// WithRecordingRedaction configures the runner to use the provided [Redaction]
+// to keep secrets out of the transcript.
+//
+// The default is a redaction with [DefaultRedactPatterns]. Redaction is
+// disabled if rd is nil.
+func WithRecordingRedaction(rd *Redaction) RecordingRunnerOption {
+	return func(r *RecordingRunner) error {
+		r.redaction = rd
+		return nil
+	}
+}
+
+// ReadTranscript reads the calls of a transcript recorded by a
+// [RecordingRunner] from rd. Empty lines are skipped.
+func ReadTranscript(rd io.Reader) ([]TranscriptCall, error) {
+	var calls []TranscriptCall
+
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var call TranscriptCall
+
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			return nil, fmt.Errorf("decoding transcript line %d: %w", line, err)
+		}
+
+		if call.Method != MethodRun && call.Method != MethodExecve {
+			return nil, fmt.Errorf("decoding transcript line %d: unknown method %q", line, call.Method)
+		}
+
+		calls = append(calls, call)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading transcript: %w", err)
+	}
+
+	return calls, nil
+}
+
+// ReplayRunner is a [Runner] that serves the output and errors of tmux
+// commands from a transcript recorded by a [RecordingRunner] instead of
+// running them.
+//
+// Calls must be made with the same arguments and in the same order as in the
+// transcript. A call that differs fails with an error wrapping
+// [ErrReplayDiverged], as does [ReplayRunner.Close] if calls in the
+// transcript were not made. Arguments are compared after secrets are redacted
+// like in the transcript.
+//
+// Logging, redaction and dry-run mode are handled by the base runner, which
+// does not run any commands. Callers that run commands on the host should
+// check for replay mode with [IsReplay] and skip them, like in dry-run mode.
+type ReplayRunner struct {
+	*DefaultRunner
+	calls []TranscriptCall
+	next  int
+	mu    sync.Mutex
+}
+
+// NewReplayRunner creates a new [ReplayRunner] with the provided base runner
+// that replays the transcript read from rd.
+func NewReplayRunner(base *DefaultRunner, rd io.Reader) (*ReplayRunner, error) {
+	if base == nil {
+		return nil, ErrNilRunner
+	}
+
+	calls, err := ReadTranscript(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReplayRunner{DefaultRunner: base, calls: calls}, nil
+}
+
+// Run returns the output and error of the next call in the transcript.
+//
+// The returned error is a [CommandError] if the recorded error is a known tmux
+// error message.
+func (r *ReplayRunner) Run(ctx context.Context, args ...string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	call, err := r.nextCall(MethodRun, args)
+	if err != nil {
+		return nil, err
+	}
+
+	output := []byte(call.Output)
+
+	if call.Err == "" {
+		r.Debug("command replayed", "args", args, "output", call.Output)
+		return output, nil
+	}
+
+	r.Debug("failed command replayed", "args", args, "output", call.Output, "err", call.Err)
+
+	return output, commandError(args, output, errors.New(call.Err))
+}
+
+// Execve checks the call against the next call in the transcript and returns
+// without replacing the current process.
+func (r *ReplayRunner) Execve(args ...string) error {
+	if _, err := r.nextCall(MethodExecve, args); err != nil {
+		return err
+	}
+
+	r.Debug("execve replayed", "args", args)
+
+	return nil
+}
+
+// Close returns an error wrapping [ErrReplayDiverged] if calls in the
+// transcript were not made.
+func (r *ReplayRunner) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n := len(r.calls) - r.next; n > 0 {
+		next := r.calls[r.next]
+		return fmt.Errorf("%w: %d recorded calls were not made, starting with %s %q",
+			ErrReplayDiverged, n, next.Method, next.Args,
+		)
+	}
+
+	return nil
+}
+
+// IsReplay returns true if the provided runner is a [ReplayRunner], or a
+// [RecordingRunner] wrapping one, meaning that tmux commands are replayed from
+// a transcript instead of being run.
+func IsReplay(r Runner) bool {
+	switch r := r.(type) {
+	case *ReplayRunner:
+		return true
+	case *RecordingRunner:
+		return IsReplay(r.Runner)
+	default:
+		return false
+	}
+}
+
+// nextCall returns the next call in the transcript if it matches the provided
+// method and arguments.
+func (r *ReplayRunner) nextCall(method string, args []string) (TranscriptCall, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	redacted := r.redaction.strings(args)
+
+	if r.next >= len(r.calls) {
+		return TranscriptCall{}, fmt.Errorf("%w: unexpected call %d: %s %q", ErrReplayDiverged, r.next+1, method, redacted)
+	}
+
+	call := r.calls[r.next]
+
+	if call.Method != method || !slices.Equal(call.Args, redacted) {
+		return TranscriptCall{}, fmt.Errorf("%w: call %d: expected %s %q, got %s %q",
+			ErrReplayDiverged, r.next+1, call.Method, call.Args, method, redacted,
+		)
+	}
+
+	r.next++
+
+	return call, nil
+}
@@ -0,0 +1,295 @@
+package tmux_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+func TestRecordingRunner_ImplementsRunnerIface(t *testing.T) {
+	_, ok := any(&tmux.RecordingRunner{}).(tmux.Runner)
+
+	require.True(t, ok, "expected RecordingRunner to implement Runner interface")
+}
+
+func TestReplayRunner_ImplementsRunnerIface(t *testing.T) {
+	_, ok := any(&tmux.ReplayRunner{}).(tmux.Runner)
+
+	require.True(t, ok, "expected ReplayRunner to implement Runner interface")
+}
+
+func TestRecordingRunner(t *testing.T) {
+	inner, err := tmux.NewRunner(
+		tmux.WithOSCommandRunner(func(_ context.Context, _ string, args ...string) ([]byte, error) {
+			switch args[0] {
+			case "new-session":
+				return []byte("$1\n"), nil
+			case "kill-server":
+				return []byte("no server running on /tmp/tmux-1000/default\n"), errors.New("exit status 1")
+			default:
+				return nil, nil
+			}
+		}),
+		tmux.WithSyscallExecRunner(func(string, []string, []string) error { return nil }),
+	)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	runner, err := tmux.NewRecordingRunner(inner, &buf)
+	require.NoError(t, err)
+
+	runner.Redact("hunter2")
+
+	output, err := runner.Run(context.Background(), "new-session", "-d", "-P", "-F", "#{session_id}")
+	require.NoError(t, err)
+	require.Equal(t, "$1\n", string(output))
+
+	_, err = runner.Run(context.Background(), "set-environment", "-t", "$1", "DB_PASS", "hunter2")
+	require.NoError(t, err)
+
+	_, err = runner.Run(context.Background(), "kill-server")
+	require.ErrorIs(t, err, tmux.ErrNoServer)
+
+	require.NoError(t, runner.Execve("attach-session", "-t", "$1"))
+
+	var calls []tmux.TranscriptCall
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var call tmux.TranscriptCall
+
+		require.NoError(t, json.Unmarshal([]byte(line), &call))
+		require.GreaterOrEqual(t, call.Dur, time.Duration(0))
+
+		call.Dur = 0
+		calls = append(calls, call)
+	}
+
+	want := []tmux.TranscriptCall{
+		{Method: tmux.MethodRun, Args: []string{"new-session", "-d", "-P", "-F", "#{session_id}"}, Output: "$1\n"},
+		{Method: tmux.MethodRun, Args: []string{"set-environment", "-t", "$1", "DB_PASS", "[REDACTED]"}},
+		{
+			Method: tmux.MethodRun,
+			Args:   []string{"kill-server"},
+			Output: "no server running on /tmp/tmux-1000/default\n",
+			Err:    "no server running on /tmp/tmux-1000/default",
+		},
+		{Method: tmux.MethodExecve, Args: []string{"attach-session", "-t", "$1"}},
+	}
+
+	require.Equal(t, want, calls)
+}
+
+func TestRecordingRunner_NilRunner(t *testing.T) {
+	runner, err := tmux.NewRecordingRunner(nil, &bytes.Buffer{})
+
+	require.ErrorIs(t, err, tmux.ErrNilRunner)
+	require.Nil(t, runner)
+}
+
+func TestReplayRunner(t *testing.T) {
+	transcript := strings.Join([]string{
+		`{"method":"run","args":["new-session","-d","-P","-F","#{session_id}"],"output":"$1\n","dur_ns":1000}`,
+		``,
+		`{"method":"run","args":["set-environment","-t","$1","DB_PASS","[REDACTED]"],"dur_ns":1000}`,
+		`{"method":"run","args":["kill-server"],"output":"no server running on /tmp/tmux-1000/default\n","error":"exit status 1","dur_ns":1000}`,
+		`{"method":"execve","args":["attach-session","-t","$1"],"dur_ns":0}`,
+	}, "\n")
+
+	runner := newReplayRunner(t, transcript)
+	runner.Redact("hunter2")
+
+	output, err := runner.Run(context.Background(), "new-session", "-d", "-P", "-F", "#{session_id}")
+	require.NoError(t, err)
+	require.Equal(t, "$1\n", string(output))
+
+	output, err = runner.Run(context.Background(), "set-environment", "-t", "$1", "DB_PASS", "hunter2")
+	require.NoError(t, err)
+	require.Empty(t, output)
+
+	output, err = runner.Run(context.Background(), "kill-server")
+	require.ErrorIs(t, err, tmux.ErrNoServer)
+	require.EqualError(t, err, "no server running on /tmp/tmux-1000/default")
+	require.Equal(t, "no server running on /tmp/tmux-1000/default\n", string(output))
+
+	require.NoError(t, runner.Execve("attach-session", "-t", "$1"))
+	require.NoError(t, runner.Close())
+}
+
+func TestReplayRunner_Diverged(t *testing.T) {
+	transcript := strings.Join([]string{
+		`{"method":"run","args":["new-session","-d","-s","main"],"dur_ns":1000}`,
+		`{"method":"execve","args":["attach-session","-t","main"],"dur_ns":0}`,
+	}, "\n")
+
+	tt := []struct {
+		name    string
+		call    func(*tmux.ReplayRunner) error
+		wantErr string
+	}{
+		{
+			"different args",
+			func(r *tmux.ReplayRunner) error {
+				_, err := r.Run(context.Background(), "new-session", "-d", "-s", "other")
+				return err
+			},
+			`replay diverged from transcript: call 1: expected run ["new-session" "-d" "-s" "main"], got run ["new-session" "-d" "-s" "other"]`,
+		},
+		{
+			"different method",
+			func(r *tmux.ReplayRunner) error {
+				return r.Execve("new-session", "-d", "-s", "main")
+			},
+			`replay diverged from transcript: call 1: expected run ["new-session" "-d" "-s" "main"], got execve ["new-session" "-d" "-s" "main"]`,
+		},
+		{
+			"unexpected call",
+			func(r *tmux.ReplayRunner) error {
+				if _, err := r.Run(context.Background(), "new-session", "-d", "-s", "main"); err != nil {
+					return err
+				}
+
+				if err := r.Execve("attach-session", "-t", "main"); err != nil {
+					return err
+				}
+
+				_, err := r.Run(context.Background(), "kill-server")
+
+				return err
+			},
+			`replay diverged from transcript: unexpected call 3: run ["kill-server"]`,
+		},
+		{
+			"calls not made",
+			func(r *tmux.ReplayRunner) error {
+				if _, err := r.Run(context.Background(), "new-session", "-d", "-s", "main"); err != nil {
+					return err
+				}
+
+				return r.Close()
+			},
+			`replay diverged from transcript: 1 recorded calls were not made, starting with execve ["attach-session" "-t" "main"]`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call(newReplayRunner(t, transcript))
+
+			require.ErrorIs(t, err, tmux.ErrReplayDiverged)
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestReplayRunner_InvalidTranscript(t *testing.T) {
+	tt := []struct {
+		name       string
+		transcript string
+		wantErr    string
+	}{
+		{
+			"invalid JSON",
+			"{\"method\":\"run\",\"args\":[]}\n{",
+			"decoding transcript line 2: unexpected end of JSON input",
+		},
+		{
+			"unknown method",
+			`{"method":"exec","args":[]}`,
+			`decoding transcript line 1: unknown method "exec"`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			base, err := tmux.NewRunner()
+			require.NoError(t, err)
+
+			runner, err := tmux.NewReplayRunner(base, strings.NewReader(tc.transcript))
+
+			require.EqualError(t, err, tc.wantErr)
+			require.Nil(t, runner)
+		})
+	}
+}
+
+func TestRecordingRunner_Replay(t *testing.T) {
+	inner, err := tmux.NewRunner(
+		tmux.WithOSCommandRunner(func(_ context.Context, _ string, args ...string) ([]byte, error) {
+			if args[0] == "select-window" {
+				return []byte("can't find window: 9\n"), errors.New("exit status 1")
+			}
+
+			return []byte(strings.Join(args, " ") + "\n"), nil
+		}),
+	)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	recorder, err := tmux.NewRecordingRunner(inner, &buf)
+	require.NoError(t, err)
+
+	calls := [][]string{
+		{"new-session", "-d", "-s", "main"},
+		{"select-window", "-t", "main:9"},
+		{"list-windows", "-t", "main"},
+	}
+
+	type result struct {
+		output string
+		err    error
+	}
+
+	var want []result
+
+	for _, args := range calls {
+		output, err := recorder.Run(context.Background(), args...)
+		want = append(want, result{string(output), err})
+	}
+
+	replayer := newReplayRunner(t, buf.String())
+
+	for i, args := range calls {
+		output, err := replayer.Run(context.Background(), args...)
+
+		require.Equal(t, want[i].output, string(output))
+
+		if want[i].err == nil {
+			require.NoError(t, err)
+			continue
+		}
+
+		require.EqualError(t, err, want[i].err.Error())
+		require.Equal(t, errors.Is(want[i].err, tmux.ErrTargetNotFound), errors.Is(err, tmux.ErrTargetNotFound))
+	}
+
+	require.NoError(t, replayer.Close())
+}
+
+func newReplayRunner(t *testing.T, transcript string) *tmux.ReplayRunner {
+	t.Helper()
+
+	base, err := tmux.NewRunner(tmux.WithOSCommandRunner(func(context.Context, string, ...string) ([]byte, error) {
+		t.Fatal("expected no commands to be run with base runner")
+		return nil, nil
+	}))
+	require.NoError(t, err)
+
+	runner, err := tmux.NewReplayRunner(base, strings.NewReader(transcript))
+	require.NoError(t, err)
+
+	return runner
+}
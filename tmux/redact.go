@@ -0,0 +1,277 @@
+package tmux
+
+import (
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultRedactPatterns are the default patterns for names of environment
+// variables with values to redact from log output.
+var DefaultRedactPatterns = []string{"*_TOKEN", "*_SECRET", "*PASSWORD*"}
+
+// redacted replaces secret values in log output.
+const redacted = "[REDACTED]"
+
+// minRedactLen is the minimum length of a secret value for it to be redacted
+// where it appears inside a longer log value. Shorter values are only redacted
+// when they make up a whole value or the value of a KEY=VALUE pair, to avoid
+// mangling unrelated log output for values such as "1".
+const minRedactLen = 6
+
+// envNameRE matches environment variable names.
+var envNameRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envAssignRE matches KEY=VALUE environment variable assignments in log
+// values, such as -e arguments to tmux commands and commands typed into panes.
+var envAssignRE = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)=("[^"]*"|'[^']*'|[^\s"']*)`)
+
+// Redaction keeps secrets out of log output.
+//
+// Secrets are values registered with [Redaction.Redact], and the values of
+// environment variables with names matching one of the redaction patterns.
+// Patterns are in the syntax of [path.Match] and are matched case-insensitively,
+// e.g. "*_TOKEN" matches API_TOKEN and github_token.
+//
+// A Redaction is safe for concurrent use, and can be shared between a
+// [DefaultRunner] and the handler of the logger it writes to (see
+// [Redaction.ReplaceAttr]). A nil Redaction redacts nothing.
+type Redaction struct {
+	values   map[string]bool
+	patterns []string
+	mu       sync.RWMutex
+}
+
+// NewRedaction creates a new [Redaction] with the provided patterns for names
+// of environment variables to redact.
+//
+// Invalid patterns never match.
+func NewRedaction(patterns ...string) *Redaction {
+	r := &Redaction{}
+	r.AddPatterns(patterns...)
+
+	return r
+}
+
+// AddPatterns adds patterns for names of environment variables to redact.
+func (r *Redaction) AddPatterns(patterns ...string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range patterns {
+		if p != "" {
+			r.patterns = append(r.patterns, strings.ToUpper(p))
+		}
+	}
+}
+
+// Redact registers values that must not be written to logs. Empty values are
+// ignored.
+func (r *Redaction) Redact(values ...string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+
+		if r.values == nil {
+			r.values = make(map[string]bool)
+		}
+
+		r.values[v] = true
+	}
+}
+
+// String returns s with secrets replaced by "[REDACTED]".
+func (r *Redaction) String(s string) string {
+	if r == nil {
+		return s
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.redactString(s)
+}
+
+// strings returns a copy of ss with secrets replaced.
+func (r *Redaction) strings(ss []string) []string {
+	res := make([]string, len(ss))
+
+	for i, s := range ss {
+		res[i] = r.String(s)
+	}
+
+	return res
+}
+
+// Env returns a copy of env with the values of secret environment variables
+// replaced by "[REDACTED]".
+//
+// Like with KEY=VALUE log values, a value is secret if it is registered with
+// [Redaction.Redact], or if the name of the variable matches one of the
+// redaction patterns.
+func (r *Redaction) Env(env map[string]string) map[string]string {
+	res := make(map[string]string, len(env))
+
+	if r != nil {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+	}
+
+	for k, v := range env {
+		switch {
+		case r == nil:
+			res[k] = v
+		case r.secretAssign(k, v):
+			res[k] = redacted
+		default:
+			res[k] = r.redactString(v)
+		}
+	}
+
+	return res
+}
+
+// Args returns a copy of the provided log arguments with secrets replaced in
+// string and string slice values.
+func (r *Redaction) Args(args []any) []any {
+	if r == nil {
+		return args
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.values) == 0 && len(r.patterns) == 0 {
+		return args
+	}
+
+	res := make([]any, len(args))
+
+	for i, arg := range args {
+		res[i] = r.redactAny(arg)
+	}
+
+	return res
+}
+
+// ReplaceAttr replaces secrets in string and string slice attribute values.
+// Values of attributes with names matching one of the redaction patterns are
+// replaced entirely.
+//
+// It has the signature of the ReplaceAttr function in [slog.HandlerOptions] so
+// that it can be used with any handler.
+func (r *Redaction) ReplaceAttr(_ []string, a slog.Attr) slog.Attr {
+	if r == nil {
+		return a
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.matchName(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() { //nolint:exhaustive // Other kinds are not redacted.
+	case slog.KindString:
+		return slog.String(a.Key, r.redactString(a.Value.String()))
+	case slog.KindAny:
+		if v, ok := a.Value.Any().([]string); ok {
+			return slog.Any(a.Key, r.redactAny(v))
+		}
+	}
+
+	return a
+}
+
+func (r *Redaction) redactAny(v any) any {
+	switch v := v.(type) {
+	case string:
+		return r.redactString(v)
+	case []string:
+		res := make([]string, len(v))
+		for i, s := range v {
+			res[i] = r.redactString(s)
+		}
+
+		return res
+	default:
+		return v
+	}
+}
+
+func (r *Redaction) redactString(s string) string {
+	if s == "" || (len(r.values) == 0 && len(r.patterns) == 0) {
+		return s
+	}
+
+	if r.values[s] {
+		return redacted
+	}
+
+	// Values of whole KEY=VALUE arguments, such as -e arguments, are taken as
+	// they are, as they are not quoted for a shell.
+	if k, v, ok := strings.Cut(s, "="); ok && envNameRE.MatchString(k) && r.secretAssign(k, v) {
+		return k + "=" + redacted
+	}
+
+	for v := range r.values {
+		if len(v) >= minRedactLen {
+			s = strings.ReplaceAll(s, v, redacted)
+		}
+	}
+
+	return envAssignRE.ReplaceAllStringFunc(s, func(m string) string {
+		k, v, _ := strings.Cut(m, "=")
+		if v != redacted && r.secretAssign(k, strings.Trim(v, `"'`)) {
+			return k + "=" + redacted
+		}
+
+		return m
+	})
+}
+
+// secretAssign returns true if v is a registered value, or a non-empty value
+// of an environment variable with a name matching a redaction pattern.
+func (r *Redaction) secretAssign(k, v string) bool {
+	return r.values[v] || (v != "" && r.matchName(k))
+}
+
+// MatchName returns true if the provided environment variable name matches one
+// of the redaction patterns.
+func (r *Redaction) MatchName(name string) bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.matchName(name)
+}
+
+func (r *Redaction) matchName(name string) bool {
+	name = strings.ToUpper(name)
+
+	for _, p := range r.patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -37,6 +37,17 @@ type Runner interface {
 	SetLogger(logger *slog.Logger)
 }
 
+// Redactor is implemented by runners that can keep secret values out of their
+// log output.
+//
+// The options that configure sessions, windows and panes with secret
+// environment variables register the values with the runner if it implements
+// this interface.
+type Redactor interface {
+	// Redact registers values that must not be written to logs.
+	Redact(values ...string)
+}
+
 // OSCommandRunner runs a command with the provided name and arguments and
 // returns the output.
 type OSCommandRunner func(ctx context.Context, name string, args ...string) (output []byte, err error)
@@ -75,6 +86,7 @@ type DefaultRunner struct {
 	tmux         string
 	tmuxOpts     []string
 	dryRun       bool
+	redaction    *Redaction
 }
 
 // NewRunner creates a new [Runner] with the provided options.
@@ -84,6 +96,7 @@ func NewRunner(opts ...RunnerOption) (*DefaultRunner, error) {
 		cmdRunner:    defaultOSCmdRunner,
 		execveRunner: syscall.Exec,
 		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		redaction:    NewRedaction(DefaultRedactPatterns...),
 	}
 
 	for _, opt := range opts {
@@ -97,8 +110,12 @@ func NewRunner(opts ...RunnerOption) (*DefaultRunner, error) {
 
 // Run runs the tmux command in a context-aware manner with the provided
 // arguments and returns the output.
+//
+// If the command fails with a known tmux error message, the returned error is
+// a [CommandError].
 func (c *DefaultRunner) Run(ctx context.Context, args ...string) ([]byte, error) {
 	start := time.Now()
+	cmdArgs := args
 
 	args = append(c.tmuxOpts, args...)
 
@@ -116,7 +133,7 @@ func (c *DefaultRunner) Run(ctx context.Context, args ...string) ([]byte, error)
 
 	c.Debug(msg, "name", c.tmux, "args", args, "output", strings.TrimSpace(string(output)), "dur", time.Since(start))
 
-	return output, err
+	return output, commandError(cmdArgs, output, err)
 }
 
 // Execve runs the tmux command with the provided arguments using the execve
@@ -165,7 +182,7 @@ func (c *DefaultRunner) Debug(msg string, args ...any) {
 		args = append(args, "dry_run", true)
 	}
 
-	c.logger.Debug(msg, args...)
+	c.logger.Debug(msg, c.redaction.Args(args)...)
 }
 
 // Log logs an info message using a [slog.Logger].
@@ -174,7 +191,7 @@ func (c *DefaultRunner) Log(msg string, args ...any) {
 		args = append(args, "dry_run", true)
 	}
 
-	c.logger.Info(msg, args...)
+	c.logger.Info(msg, c.redaction.Args(args)...)
 }
 
 // SetLogger sets the logger used by the runner.
@@ -182,6 +199,12 @@ func (c *DefaultRunner) SetLogger(logger *slog.Logger) {
 	c.logger = logger
 }
 
+// Redact registers values that must not be written to logs with the runner's
+// [Redaction].
+func (c *DefaultRunner) Redact(values ...string) {
+	c.redaction.Redact(values...)
+}
+
 // RunnerOption configures a [DefaultRunner].
 type RunnerOption func(*DefaultRunner) error
 
@@ -237,6 +260,19 @@ func WithLogger(logger *slog.Logger) RunnerOption {
 	}
 }
 
+// WithRedaction configures the runner to use the provided [Redaction] to keep
+// secrets out of its log output, which includes the arguments of tmux commands
+// logged by [DefaultRunner.Run].
+//
+// The default is a redaction with [DefaultRedactPatterns]. Redaction is
+// disabled if r is nil.
+func WithRedaction(r *Redaction) RunnerOption {
+	return func(c *DefaultRunner) error {
+		c.redaction = r
+		return nil
+	}
+}
+
 // WithDryRunMode configures the runner to run in dry-run mode.
 //
 // Dry-run mode means that the runner will not actually run any tmux commands
@@ -3,6 +3,7 @@ package tmux_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io/fs"
 	"log/slog"
 	"os"
@@ -126,6 +127,61 @@ func TestDefaultRunner_Run(t *testing.T) {
 	}
 }
 
+func TestDefaultRunner_Run_CommandError(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	tt := []struct {
+		name       string
+		output     string
+		wantErr    error
+		wantStderr string
+	}{
+		{"no server", "no server running on /tmp/tmux-1000/default\n", tmux.ErrNoServer, "no server running on /tmp/tmux-1000/default"},
+		{"no socket", "error connecting to /tmp/tmux-1000/default (No such file or directory)\n", tmux.ErrNoServer, "error connecting to /tmp/tmux-1000/default (No such file or directory)"},
+		{"duplicate session", "duplicate session: test\n", tmux.ErrSessionExists, "duplicate session: test"},
+		{"session not found", "can't find session: test\n", tmux.ErrTargetNotFound, "can't find session: test"},
+		{"window not found", "can't find window: 9\n", tmux.ErrTargetNotFound, "can't find window: 9"},
+		{"pane not found", "can't find pane: 7\n", tmux.ErrTargetNotFound, "can't find pane: 7"},
+		{"invalid option", "invalid option: bogus\n", tmux.ErrUnknownOption, "invalid option: bogus"},
+		{"no space", "no space for new pane\n", tmux.ErrSizeTooSmall, "no space for new pane"},
+		{"batch output", "@1\ntmpl:batch:next\ncan't find pane: 7\n", tmux.ErrTargetNotFound, "can't find pane: 7"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, err := tmux.NewRunner(
+				tmux.WithTmuxOptions("-L", "test"),
+				tmux.WithOSCommandRunner(func(context.Context, string, ...string) ([]byte, error) {
+					return []byte(tc.output), exitErr
+				}),
+			)
+			require.NoError(t, err)
+
+			_, err = runner.Run(context.Background(), "select-pane", "-t", "test:1.7")
+
+			require.ErrorIs(t, err, tc.wantErr)
+			require.ErrorIs(t, err, exitErr)
+			require.EqualError(t, err, tc.wantStderr)
+
+			var cmdErr tmux.CommandError
+			require.ErrorAs(t, err, &cmdErr)
+			require.Equal(t, []string{"select-pane", "-t", "test:1.7"}, cmdErr.Args())
+			require.Equal(t, tc.wantStderr, cmdErr.Stderr())
+		})
+	}
+
+	t.Run("unknown error", func(t *testing.T) {
+		runner, err := tmux.NewRunner(tmux.WithOSCommandRunner(func(context.Context, string, ...string) ([]byte, error) {
+			return []byte("unknown command: bogus\n"), exitErr
+		}))
+		require.NoError(t, err)
+
+		_, err = runner.Run(context.Background(), "bogus")
+
+		require.Equal(t, exitErr, err)
+	})
+}
+
 func TestDefaultRunner_Run_Integration(t *testing.T) {
 	runner, err := tmux.NewRunner(tmux.WithTmux("echo"))
 	require.NoError(t, err)
@@ -142,6 +198,28 @@ func TestDefaultRunner_Run_Integration(t *testing.T) {
 	require.Empty(t, out)
 }
 
+func TestDefaultRunner_Redact(t *testing.T) {
+	output := bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{
+		Level:       slog.LevelDebug,
+		ReplaceAttr: testutils.NewSlogStabilizer(t),
+	}))
+
+	runner, err := tmux.NewRunner(tmux.WithLogger(logger), tmux.WithDryRunMode(true))
+	require.NoError(t, err)
+
+	var _ tmux.Redactor = runner
+
+	runner.Redact("hunter2-token", "1", "")
+
+	_, err = runner.Run(context.Background(), "new-window", "-t", "sess:1", "-e", "API_TOKEN=hunter2-token", "-e", "DEBUG=1")
+	require.NoError(t, err)
+
+	runner.Log("hook output", "output", "token is hunter2-token", "count", 1)
+
+	testutils.NewGolden(t).RequireMatch(output.Bytes())
+}
+
 func TestDefaultRunner_Execve(t *testing.T) {
 	stubPath := t.TempDir()
 	execPath := filepath.Join(stubPath, "tmux")
@@ -0,0 +1,310 @@
+package tmux
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// shellSafeRE matches strings that do not need to be quoted for a POSIX shell.
+var shellSafeRE = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+const scriptHeader = `#!/usr/bin/env bash
+#
+# Creates the tmux session %s with its windows and panes.
+#
+# The session is only created if it does not already exist. Generated by
+# tmpl; export the configuration again instead of editing this script.
+
+set -eu
+`
+
+const scriptIDFunc = `
+# tmux_id runs a tmux command that creates a window or pane and prints the ID
+# of the window or pane from the command output.
+tmux_id() {
+	local output
+	output=$(tmux "$@") || return
+	output=${output%%,*}
+	printf '%s\n' "${output#*:}"
+}
+`
+
+const scriptAttach = `
+if [[ -n "${TMUX:-}" || "${TERM_PROGRAM:-}" == tmux || "${TERM:-}" == *tmux* ]]; then
+	tmux switch-client -t %[1]s
+else
+	tmux attach-session -t %[1]s
+fi
+`
+
+// WriteScript writes a bash script to w that creates the provided applied
+// session with its windows and panes, and runs their commands.
+//
+// The session is typically applied in dry-run mode, so that the script can be
+// written without creating the session. The script runs the same tmux
+// commands as [Session.Apply], [Window.Apply] and [Pane.Apply], except that
+// windows and panes are targeted by the IDs assigned to them when the script
+// is run. The pane-base-index option is looked up at runtime when the first
+// pane of a window is selected, like [Session.SelectActive] does.
+//
+// The session is only created if it does not already exist. The values of
+// secret environment variables are written to the script as they are, unless
+// the script is written with [ScriptWithRedaction].
+func WriteScript(w io.Writer, sess *Session, opts ...ScriptOption) error {
+	if sess == nil {
+		return ErrNilSession
+	}
+
+	if err := sess.checkState(); err != nil {
+		return fmt.Errorf("checking session state: %w", err)
+	}
+
+	o := &scriptOptions{}
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return fmt.Errorf("applying script option: %w", err)
+		}
+	}
+
+	sw := &scriptWriter{vars: map[string]string{}, redaction: o.redaction}
+	sw.printf(scriptHeader, sess.name)
+
+	if len(o.warnings) != 0 {
+		sw.printf("\n")
+
+		for _, warning := range o.warnings {
+			sw.printf("# Warning: %s\n", warning)
+		}
+	}
+
+	if len(o.tmux) != 0 && (o.tmux[0] != DefaultTmux || len(o.tmux) > 1) {
+		sw.printf("\ntmux() {\n\tcommand %s \"$@\"\n}\n", shellJoin(o.tmux))
+	}
+
+	sw.WriteString(scriptIDFunc)
+	sw.printf("\nif ! tmux has-session -t %s 2>/dev/null; then\n", ShellQuote("="+sess.name))
+	sw.command(sess.newSessionArgs(), ">/dev/null")
+
+	for i, win := range sess.windows {
+		sw.writeWindow(win, i == 0)
+	}
+
+	sw.writeSelectActive(sess)
+	sw.printf("fi\n")
+
+	if o.attach {
+		sw.printf(scriptAttach, ShellQuote(sess.name))
+	}
+
+	if _, err := io.WriteString(w, sw.String()); err != nil {
+		return fmt.Errorf("writing script: %w", err)
+	}
+
+	return nil
+}
+
+// ShellQuote returns s quoted for a POSIX shell if needed.
+func ShellQuote(s string) string {
+	if shellSafeRE.MatchString(s) {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellJoin returns the provided arguments quoted for a POSIX shell and joined
+// with spaces.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+
+	for i, arg := range args {
+		quoted[i] = ShellQuote(arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// scriptWriter builds a script from the windows and panes of a session.
+type scriptWriter struct {
+	strings.Builder
+	vars      map[string]string // Shell variables holding window and pane IDs.
+	redaction *Redaction
+	windows   int
+	panes     int
+}
+
+func (sw *scriptWriter) printf(format string, args ...any) {
+	fmt.Fprintf(sw, format, args...)
+}
+
+// command writes a tmux command with the provided arguments, followed by the
+// provided redirection if not empty.
+func (sw *scriptWriter) command(args []string, redirect string) {
+	line := "\ttmux " + sw.join(args)
+
+	if redirect != "" {
+		line += " " + redirect
+	}
+
+	sw.printf("%s\n", line)
+}
+
+// assign writes a tmux command that creates a window or pane, and assigns its
+// ID to a shell variable that is used in place of the provided ID in later
+// commands.
+func (sw *scriptWriter) assign(name, id string, args []string) {
+	sw.vars[id] = name
+	sw.printf("\t%s=$(tmux_id %s)\n", name, sw.join(args))
+}
+
+// join returns the provided arguments quoted and joined with spaces. Targets
+// that are IDs of windows or panes are replaced with references to their shell
+// variables, and secrets are replaced if the script is written with
+// [ScriptWithRedaction].
+func (sw *scriptWriter) join(args []string) string {
+	res := make([]string, len(args))
+
+	for i, arg := range args {
+		if name, ok := sw.vars[arg]; ok && i > 0 && args[i-1] == "-t" {
+			res[i] = `"$` + name + `"`
+			continue
+		}
+
+		res[i] = ShellQuote(sw.redaction.String(arg))
+	}
+
+	return strings.Join(res, " ")
+}
+
+func (sw *scriptWriter) writeWindow(w *Window, first bool) {
+	sw.windows++
+	sw.printf("\n")
+	sw.assign(fmt.Sprintf("window_%d", sw.windows), w.id, w.newWindowArgs(first))
+
+	for _, cmd := range append(w.sess.onWindowCommands(), w.cmds...) {
+		sw.command(sendKeysArgs(w.id, cmd), "")
+	}
+
+	for _, p := range w.panes {
+		if p.pane == nil {
+			sw.writePane(p, w.id)
+		}
+	}
+
+	for _, args := range w.layoutArgs(w.id) {
+		sw.command(args, "")
+	}
+}
+
+func (sw *scriptWriter) writePane(p *Pane, target string) {
+	sw.panes++
+	sw.assign(fmt.Sprintf("pane_%d", sw.panes), p.id, p.splitWindowArgs(target))
+
+	for _, cmd := range append(p.sess.onPaneCommands(), p.cmds...) {
+		sw.command(sendKeysArgs(p.id, cmd), "")
+	}
+
+	for _, child := range p.panes {
+		sw.writePane(child, p.id)
+	}
+}
+
+// writeSelectActive writes the commands for selecting the active window and
+// pane like [Session.SelectActive].
+func (sw *scriptWriter) writeSelectActive(s *Session) {
+	if len(s.windows) == 0 {
+		return
+	}
+
+	sw.printf("\n")
+
+	activeWin := s.windows[0]
+
+	if len(s.windows) > 1 {
+		for _, w := range s.windows[1:] {
+			if w.IsActive() {
+				activeWin = w
+			}
+		}
+
+		sw.command([]string{"select-window", "-t", activeWin.id}, "")
+	}
+
+	if activeWin.NumPanes() == 0 {
+		return
+	}
+
+	var activePane *Pane
+
+	for _, p := range activeWin.panes {
+		if p.IsActive() {
+			activePane = p
+		}
+	}
+
+	if activePane != nil {
+		sw.command([]string{"select-pane", "-t", activePane.id}, "")
+		return
+	}
+
+	// If no pane is set as active, select the first pane, which has the index
+	// of the pane-base-index option.
+	sw.printf("\tpane_base_index=$(tmux show-option -gqv pane-base-index)\n")
+	sw.printf("\ttmux select-pane -t \"$%s.$pane_base_index\"\n", sw.vars[activeWin.id])
+}
+
+// ScriptOption configures a script written with [WriteScript].
+type ScriptOption func(*scriptOptions) error
+
+type scriptOptions struct {
+	redaction *Redaction
+	tmux      []string
+	warnings  []string
+	attach    bool
+}
+
+// ScriptWithTmux configures the script to run tmux commands with the provided
+// tmux executable and options, such as a socket name.
+//
+// The default is to run the tmux executable found in PATH without options.
+func ScriptWithTmux(cmd ...string) ScriptOption {
+	return func(o *scriptOptions) error {
+		o.tmux = cmd
+		return nil
+	}
+}
+
+// ScriptWithAttach configures the script to attach the client to the session,
+// or switch the client to it if the script is run inside tmux, like
+// [Session.Attach].
+func ScriptWithAttach() ScriptOption {
+	return func(o *scriptOptions) error {
+		o.attach = true
+		return nil
+	}
+}
+
+// ScriptWithRedaction configures the script to replace secrets in tmux
+// command arguments with "[REDACTED]", like in log output.
+//
+// A script written with redaction shows what the script would run, but the
+// redacted values must be filled in before it is run.
+func ScriptWithRedaction(r *Redaction) ScriptOption {
+	return func(o *scriptOptions) error {
+		o.redaction = r
+		return nil
+	}
+}
+
+// ScriptWithWarnings configures the script to start with the provided
+// warnings as comments, such as parts of a configuration that the script
+// leaves out.
+func ScriptWithWarnings(warnings ...string) ScriptOption {
+	return func(o *scriptOptions) error {
+		o.warnings = append(o.warnings, warnings...)
+		return nil
+	}
+}
@@ -0,0 +1,124 @@
+package tmux_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/internal/testutils"
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+func TestWriteScript(t *testing.T) {
+	tt := []struct {
+		name       string
+		activePane bool
+		opts       []tmux.ScriptOption
+	}{
+		{"default", false, nil},
+		{"active pane", true, nil},
+		{
+			"tmux options and attach",
+			false,
+			[]tmux.ScriptOption{tmux.ScriptWithTmux("/usr/local/bin/tmux", "-L", "work"), tmux.ScriptWithAttach()},
+		},
+		{"default tmux", false, []tmux.ScriptOption{tmux.ScriptWithTmux("tmux")}},
+		{"redaction", false, []tmux.ScriptOption{tmux.ScriptWithRedaction(tmux.NewRedaction("GREETING"))}},
+		{"warnings", false, []tmux.ScriptOption{tmux.ScriptWithWarnings("hooks are not run", "secrets are included")}},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			runner, err := tmux.NewRunner(tmux.WithDryRunMode(true))
+			require.NoError(t, err)
+
+			ctx := context.Background()
+
+			sess, err := tmux.NewSession(runner,
+				tmux.SessionWithName("project"),
+				tmux.SessionWithPath("/home/user/project"),
+				tmux.SessionWithSize(120, 40),
+				tmux.SessionWithOnPaneCommand("source .env"),
+				tmux.SessionWithEnv(map[string]string{"GREETING": "it's here"}),
+			)
+			require.NoError(t, err)
+			require.NoError(t, sess.Apply(ctx))
+
+			code, err := tmux.NewWindow(runner, sess,
+				tmux.WindowWithName("code"),
+				tmux.WindowWithCommands("nvim ."),
+				tmux.WindowWithLayout("main-vertical"),
+				tmux.WindowWithMainPaneSize("60%"),
+			)
+			require.NoError(t, err)
+			require.NoError(t, code.Apply(ctx))
+
+			tests, err := tmux.NewPane(runner, code, nil,
+				tmux.PaneWithPath("/home/user/project/test"),
+				tmux.PaneWithSize("30%"),
+				tmux.PaneWithHorizontalDirection(),
+				tmux.PaneWithCommands("make test-watch"),
+			)
+			require.NoError(t, err)
+			require.NoError(t, tests.Apply(ctx))
+
+			logsOpts := []tmux.PaneOption{tmux.PaneWithCommands("tail -f log/test.log")}
+			if tc.activePane {
+				logsOpts = append(logsOpts, tmux.PaneAsActive())
+			}
+
+			logs, err := tmux.NewPane(runner, code, tests, logsOpts...)
+			require.NoError(t, err)
+			require.NoError(t, logs.Apply(ctx))
+			require.NoError(t, code.SelectLayout(ctx))
+
+			shell, err := tmux.NewWindow(runner, sess, tmux.WindowWithName("shell"))
+			require.NoError(t, err)
+			require.NoError(t, shell.Apply(ctx))
+
+			var buf bytes.Buffer
+
+			require.NoError(t, tmux.WriteScript(&buf, sess, tc.opts...))
+
+			testutils.NewGolden(t).RequireMatch(buf.Bytes())
+		})
+	}
+}
+
+func TestWriteScript_NotApplied(t *testing.T) {
+	runner, err := tmux.NewRunner(tmux.WithDryRunMode(true))
+	require.NoError(t, err)
+
+	sess, err := tmux.NewSession(runner, tmux.SessionWithName("project"))
+	require.NoError(t, err)
+
+	err = tmux.WriteScript(&bytes.Buffer{}, sess)
+
+	require.ErrorIs(t, err, tmux.ErrSessionNotApplied)
+}
+
+func TestShellQuote(t *testing.T) {
+	tt := []struct {
+		s    string
+		want string
+	}{
+		{"new-window", "new-window"},
+		{"proj:^", "'proj:^'"},
+		{"nvim .", "'nvim .'"},
+		{"echo 'hi'", `'echo '\''hi'\'''`},
+		{"", "''"},
+		{"$HOME", "'$HOME'"},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.s, func(t *testing.T) {
+			require.Equal(t, tc.want, tmux.ShellQuote(tc.s))
+		})
+	}
+}
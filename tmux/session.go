@@ -1,8 +1,11 @@
 package tmux
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +21,8 @@ type Session struct {
 	paneCmd string
 	anyCmd  string
 	env     map[string]string
+	width   int
+	height  int
 	windows []*Window
 	state   state
 }
@@ -61,27 +66,21 @@ func (s *Session) Apply(ctx context.Context) error {
 		return nil
 	}
 
-	args := []string{"new-session", "-d", "-P", "-F", sessionOutputFormat}
-
-	if s.name != "" {
-		args = append(args, "-s", s.name)
-	}
-
-	output, err := s.tmux.Run(ctx, args...)
+	output, err := s.tmux.Run(ctx, s.newSessionArgs()...)
 	if err != nil {
 		return fmt.Errorf("running new-session command: %w", err)
 	}
 
-	if s.tmux.IsDryRun() {
+	if s.tmux.IsDryRun() && len(bytes.TrimSpace(output)) == 0 {
 		output = []byte(s.dryRunRecord())
 	}
 
-	records, err := parseOutput(output)
+	record, err := parseOutputRecord(output)
 	if err != nil {
 		return fmt.Errorf("parsing new-session command output: %w", err)
 	}
 
-	if err := s.update(records[0]); err != nil {
+	if err := s.update(record); err != nil {
 		return fmt.Errorf("updating session data: %w", err)
 	}
 
@@ -147,7 +146,7 @@ func (s *Session) SelectActive(ctx context.Context) error {
 	}
 
 	if s.NumWindows() == 1 {
-		return s.windows[0].selectPane(ctx)
+		return s.windows[0].selectPane(ctx, newBatch(s.tmux))
 	}
 
 	activeWin := s.windows[0]
@@ -161,11 +160,78 @@ func (s *Session) SelectActive(ctx context.Context) error {
 	return activeWin.Select(ctx)
 }
 
+// Rename renames the session by invoking the rename-session command using its
+// internal [Runner] instance.
+//
+// If the session is not applied, the method returns [ErrSessionNotApplied].
+//
+// https://man.archlinux.org/man/tmux.1#rename-session
+func (s *Session) Rename(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if name == "" {
+		return fmt.Errorf("session name cannot be empty")
+	}
+
+	if err := s.checkState(); err != nil {
+		return fmt.Errorf("checking session state: %w", err)
+	}
+
+	if _, err := s.tmux.Run(ctx, "rename-session", "-t", s.target(), name); err != nil {
+		return fmt.Errorf("running rename-session command: %w", err)
+	}
+
+	oldName := s.name
+	s.name = name
+
+	s.log("session renamed", "old_name", oldName)
+
+	return nil
+}
+
+// Configure configures the session with the provided options.
+//
+// This is useful for sessions loaded with [GetSessions], which are not
+// configured with hook commands or environment variables, before creating new
+// windows and panes in them.
+//
+// NOTE: The options only affect windows and panes created afterwards; the
+// session itself is not changed by invoking any tmux commands.
+func (s *Session) Configure(opts ...SessionOption) error {
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return fmt.Errorf("applying session option: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ID returns the session's unique ID as reported by tmux, e.g. $1.
+//
+// The ID is only known for applied sessions.
+func (s *Session) ID() string {
+	return s.id
+}
+
 // Name returns the session name.
 func (s *Session) Name() string {
 	return s.name
 }
 
+// Path returns the session's working directory.
+func (s *Session) Path() string {
+	return s.path
+}
+
+// Windows returns the session's windows ordered by creation, or by index for
+// windows loaded with [GetWindows] or moved with [Window.Move].
+func (s *Session) Windows() []*Window {
+	return append([]*Window{}, s.windows...)
+}
+
 // NumWindows returns the number of windows in the session.
 func (s *Session) NumWindows() int {
 	return len(s.windows)
@@ -251,6 +317,64 @@ func (s *Session) addWindow(w *Window) {
 	s.windows = append(s.windows, w)
 }
 
+// removeWindow removes a window from the session.
+func (s *Session) removeWindow(w *Window) {
+	for i, win := range s.windows {
+		if win == w {
+			s.windows = append(s.windows[:i], s.windows[i+1:]...)
+			return
+		}
+	}
+}
+
+// sortWindows sorts the session's windows by their index.
+//
+// Windows with unknown indexes keep their relative order.
+func (s *Session) sortWindows() {
+	sort.SliceStable(s.windows, func(i, j int) bool {
+		a, errA := strconv.Atoi(s.windows[i].index)
+		b, errB := strconv.Atoi(s.windows[j].index)
+
+		if errA != nil || errB != nil {
+			return false
+		}
+
+		return a < b
+	})
+}
+
+// newSessionArgs returns the arguments of the new-session command that creates
+// the session.
+func (s *Session) newSessionArgs() []string {
+	args := []string{"new-session", "-d", "-P", "-F", sessionOutputFormat}
+
+	if s.name != "" {
+		args = append(args, "-s", s.name)
+	}
+
+	if s.width > 0 {
+		args = append(args, "-x", strconv.Itoa(s.width))
+	}
+
+	if s.height > 0 {
+		args = append(args, "-y", strconv.Itoa(s.height))
+	}
+
+	return args
+}
+
+// target returns the target to use when invoking tmux commands on the session.
+//
+// The session ID is preferred as it does not change when the session is
+// renamed.
+func (s *Session) target() string {
+	if s.id != "" {
+		return s.id
+	}
+
+	return s.name
+}
+
 // checkState checks that the session is applied and not closed.
 //
 // Returns [ErrSessionClosed] if the session is closed.
@@ -309,7 +433,7 @@ func (s *Session) log(msg string, args ...any) {
 }
 
 // dryRunRecord returns an output record string to use when running in dry-run
-// mode.
+// mode with a runner that does not simulate command output.
 func (s *Session) dryRunRecord() string {
 	return outputRecord{
 		"session_id":   "$0",
@@ -374,18 +498,68 @@ func SessionWithOnAnyCommand(cmd string) SessionOption {
 	}
 }
 
+// SessionWithSize configures the [Session] with the size of the terminal it
+// will be attached to.
+//
+// Since sessions are created detached, tmux uses its default-size option for
+// the initial window size unless a size is provided. Pane sizes given as
+// percentages are calculated from the initial window size, so providing the
+// real terminal size makes them come out as expected when the session is
+// attached.
+//
+// A width or height of zero or less leaves the dimension to tmux.
+func SessionWithSize(width, height int) SessionOption {
+	return func(s *Session) error {
+		s.width = width
+		s.height = height
+
+		return nil
+	}
+}
+
 // SessionWithEnv configures the [Session] environment variables.
 //
 // Environment variables are inherited from session to window to pane. If a
 // window or pane is confiured with a similarly named environment variable, it
 // will take precedence over the session environment variable.
+//
+// References to environment variables in the values, such as $HOME, are
+// expanded with the environment of the current process when the option is
+// applied, not when the tmux commands are run. The option can be given more
+// than once: variables are added to variables configured by earlier options,
+// replacing variables with the same name.
 func SessionWithEnv(env map[string]string) SessionOption {
 	return func(s *Session) error {
-		s.env = env
+		s.env = mergeMaps(s.env, expandEnv(env))
 		return nil
 	}
 }
 
+// SessionWithRawEnv configures the [Session] with environment variables with values
+// that are used as they are, such as values loaded from files or commands.
+//
+// Unlike [SessionWithEnv], references to environment variables in the values are
+// not expanded. Variables are added to variables configured by earlier
+// options, replacing variables with the same name.
+func SessionWithRawEnv(env map[string]string) SessionOption {
+	return func(s *Session) error {
+		s.env = mergeMaps(s.env, env)
+		return nil
+	}
+}
+
+// SessionWithSecretEnv configures the [Session] with environment variables holding
+// secret values.
+//
+// Like with [SessionWithRawEnv], the values are used as they are. They are also
+// registered with the runner for redaction if it implements [Redactor].
+func SessionWithSecretEnv(env map[string]string) SessionOption {
+	return func(s *Session) error {
+		redactEnv(s.tmux, env)
+		return SessionWithRawEnv(env)(s)
+	}
+}
+
 // GetSessions returns a list of current tmux sessions by invoking the
 // list-sessions command using the provided [Runner] instance.
 //
@@ -422,3 +596,22 @@ func GetSessions(ctx context.Context, runner Runner) ([]*Session, error) {
 
 	return sessions, nil
 }
+
+// FindSession returns the current tmux session matching the provided target,
+// which can be either a session name or a session ID such as $1.
+//
+// Returns [ErrSessionNotFound] if no session matches the target.
+func FindSession(ctx context.Context, runner Runner, target string) (*Session, error) {
+	sessions, err := GetSessions(ctx, runner)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range sessions {
+		if s.id == target || s.name == target {
+			return s, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, target)
+}
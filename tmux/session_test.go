@@ -0,0 +1,96 @@
+package tmux_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+func TestFindSession(t *testing.T) {
+	runner := newStubRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:main,session_path:/home/user\n" +
+			"session_id:$2,session_name:project,session_path:/home/user/project",
+	})
+
+	tt := []struct {
+		name     string
+		target   string
+		wantID   string
+		wantName string
+		wantErr  error
+	}{
+		{"by name", "project", "$2", "project", nil},
+		{"by ID", "$1", "$1", "main", nil},
+		{"not found", "other", "", "", tmux.ErrSessionNotFound},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sess, err := tmux.FindSession(context.Background(), runner, tc.target)
+
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Nil(t, sess)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.wantID, sess.ID())
+			require.Equal(t, tc.wantName, sess.Name())
+			require.True(t, sess.IsApplied())
+		})
+	}
+}
+
+func TestSession_Rename(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions":  "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":   "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"rename-session": "",
+	})
+
+	windows := getWindows(t, runner, "test")
+	sess := windows[0].Session()
+
+	require.NoError(t, sess.Rename(context.Background(), "renamed"))
+	require.Equal(t, []string{"rename-session -t $1 renamed"}, (*cmds)[2:])
+	require.Equal(t, "renamed", sess.Name())
+	require.Equal(t, "renamed:code", windows[0].Name())
+
+	unapplied, err := tmux.NewSession(runner, tmux.SessionWithName("new"))
+	require.NoError(t, err)
+	require.ErrorIs(t, unapplied.Rename(context.Background(), "other"), tmux.ErrSessionNotApplied)
+}
+
+func TestSession_Apply_Size(t *testing.T) {
+	tt := []struct {
+		name     string
+		width    int
+		height   int
+		wantArgs string
+	}{
+		{"width and height", 200, 50, " -s test -x 200 -y 50"},
+		{"width only", 200, 0, " -s test -x 200"},
+		{"no size", 0, 0, " -s test"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, cmds := newRecordingRunner(t, map[string]string{
+				"new-session": "session_id:$1,session_name:test,session_path:/home/user/project",
+			})
+
+			sess, err := tmux.NewSession(runner, tmux.SessionWithName("test"), tmux.SessionWithSize(tc.width, tc.height))
+			require.NoError(t, err)
+			require.NoError(t, sess.Apply(context.Background()))
+
+			require.Len(t, *cmds, 1)
+			require.True(t, strings.HasSuffix((*cmds)[0], tc.wantArgs), "unexpected command: %s", (*cmds)[0])
+		})
+	}
+}
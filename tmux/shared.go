@@ -8,7 +8,8 @@ import (
 )
 
 // envArgs returns a slice of tmux command arguments to set the provided
-// environment variables.
+// environment variables. Values are used as they are, as environment variable
+// references are expanded when the variables are configured.
 //
 // The arguments are sorted by key to make the output deterministic.
 func envArgs(envs ...map[string]string) []string {
@@ -24,13 +25,19 @@ func envArgs(envs ...map[string]string) []string {
 	args := make([]string, 0, len(m)*2)
 
 	for _, k := range keys {
-		eVal := fmt.Sprintf("%s=%s", k, os.ExpandEnv(m[k]))
+		eVal := fmt.Sprintf("%s=%s", k, m[k])
 		args = append(args, "-e", eVal)
 	}
 
 	return args
 }
 
+// sendKeysArgs returns the arguments of the send-keys command that runs the
+// provided command in the target window or pane.
+func sendKeysArgs(target, cmd string) []string {
+	return []string{"send-keys", "-t", target, cmd, "C-m"}
+}
+
 // mergeMaps merges the provided maps into a single map.
 func mergeMaps(maps ...map[string]string) map[string]string {
 	res := make(map[string]string)
@@ -44,6 +51,71 @@ func mergeMaps(maps ...map[string]string) map[string]string {
 	return res
 }
 
+// expandEnv returns a copy of the provided environment variables with
+// references to environment variables in the values expanded.
+func expandEnv(env map[string]string) map[string]string {
+	res := make(map[string]string, len(env))
+
+	for k, v := range env {
+		res[k] = os.ExpandEnv(v)
+	}
+
+	return res
+}
+
+// redactEnv registers the values of the provided environment variables with
+// the runner for redaction if it implements [Redactor].
+func redactEnv(r Runner, env map[string]string) {
+	rd, ok := r.(Redactor)
+	if !ok {
+		return
+	}
+
+	for _, v := range env {
+		rd.Redact(v)
+	}
+}
+
+// removePane returns the provided panes without the pane p.
+func removePane(panes []*Pane, p *Pane) []*Pane {
+	for i, pane := range panes {
+		if pane == p {
+			return append(panes[:i], panes[i+1:]...)
+		}
+	}
+
+	return panes
+}
+
+// swapPaneRefs swaps the references to the panes a and b in the provided
+// slices, where as is the slice containing a and bs is the slice containing b.
+//
+// The slices may be the same slice.
+func swapPaneRefs(as, bs []*Pane, a, b *Pane) {
+	i, j := -1, -1
+
+	for k, p := range as {
+		if p == a {
+			i = k
+		}
+	}
+
+	for k, p := range bs {
+		if p == b {
+			j = k
+		}
+	}
+
+	switch {
+	case i != -1 && j != -1:
+		as[i], bs[j] = b, a
+	case i != -1:
+		as[i] = b
+	case j != -1:
+		bs[j] = a
+	}
+}
+
 // inTmux returns true if the application is running inside tmux.
 func inTmux() bool {
 	if os.Getenv("TERM_PROGRAM") == "tmux" {
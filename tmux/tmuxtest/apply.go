@@ -0,0 +1,55 @@
+package tmuxtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/michenriksen/tmpl/config"
+)
+
+// ApplyConfig loads the configuration file at path and applies it with a new
+// [Runner] created with the provided options. It returns a [Transcript] of the
+// result.
+//
+// All sessions in the configuration are applied like with the up command.
+// The test is failed immediately if the configuration cannot be loaded or
+// applied.
+func ApplyConfig(tb testing.TB, path string, opts ...Option) Transcript {
+	tb.Helper()
+
+	o, err := newOptions(opts)
+	if err != nil {
+		tb.Fatalf("applying configuration: %v", err)
+	}
+
+	cfg, err := config.FromFile(path, o.cfgOpts...)
+	if err != nil {
+		tb.Fatalf("loading configuration: %v", err)
+	}
+
+	r := NewRunner(tb, opts...)
+
+	results, err := config.Up(context.Background(), cfg, r)
+	if err != nil {
+		tb.Fatalf("applying configuration: %v", err)
+	}
+
+	for _, res := range results {
+		if res.Err != nil {
+			tb.Fatalf("applying session %s: %v", res.Name, res.Err)
+		}
+	}
+
+	return r.Transcript()
+}
+
+// WithConfigOptions configures [ApplyConfig] to load the configuration with
+// the provided options, such as [config.WithVars] and [config.WithProfile].
+//
+// The option has no effect on [NewRunner].
+func WithConfigOptions(opts ...config.FromFileOption) Option {
+	return func(o *options) error {
+		o.cfgOpts = append(o.cfgOpts, opts...)
+		return nil
+	}
+}
@@ -0,0 +1,263 @@
+// Package tmuxtest provides utilities for testing tmpl configurations without
+// a tmux server.
+//
+// A [Runner] runs tmux commands against an in-memory tmux server that behaves
+// like tmux, and records the commands it runs. [ApplyConfig] applies a
+// configuration file with a new runner and returns a [Transcript] of the result
+// to make assertions on:
+//
+//	func TestConfig(t *testing.T) {
+//	    tr := tmuxtest.ApplyConfig(t, ".tmpl.yaml")
+//
+//	    tr.Pane("editor").Ran("nvim")
+//	    tr.Pane("server.1").HasEnv("APP_ENV", "development")
+//	    tr.RequireGolden()
+//	}
+package tmuxtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/michenriksen/tmpl/config"
+	"github.com/michenriksen/tmpl/internal/faketmux"
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+// socketDir is the socket directory of the tmux server of a [Runner]. It is
+// fixed so that transcripts do not depend on the environment.
+const socketDir = "/tmp/tmux-tmuxtest"
+
+// Runner is a [tmux.Runner] that runs tmux commands against an in-memory tmux
+// server and records them.
+//
+// The runner is in dry-run mode, so hooks and env_from commands in applied
+// configurations are not run on the host. Unlike the dry-run mode of tmpl,
+// commands get the same output as from tmux.
+//
+// A Runner is safe for concurrent use.
+type Runner struct {
+	tb        testing.TB
+	srv       *faketmux.Server
+	redaction *tmux.Redaction
+
+	mu       sync.Mutex
+	commands []Command
+}
+
+// NewRunner creates a new [Runner] with the provided options.
+//
+// The test is failed immediately if an option cannot be applied.
+func NewRunner(tb testing.TB, opts ...Option) *Runner {
+	tb.Helper()
+
+	o, err := newOptions(opts)
+	if err != nil {
+		tb.Fatalf("creating runner: %v", err)
+	}
+
+	r := &Runner{tb: tb, redaction: tmux.NewRedaction(tmux.DefaultRedactPatterns...)}
+
+	srvOpts := []faketmux.ServerOption{
+		faketmux.WithLogger(o.logger),
+		faketmux.WithRedaction(r.redaction),
+		faketmux.WithDryRunMode(true),
+		faketmux.WithSocketDir(socketDir),
+	}
+
+	if o.dir != "" {
+		srvOpts = append(srvOpts, faketmux.WithWorkingDir(o.dir))
+	}
+
+	srv, err := faketmux.NewServer(srvOpts...)
+	if err != nil {
+		tb.Fatalf("creating runner: %v", err)
+	}
+
+	r.srv = srv
+
+	return r
+}
+
+// Run runs the tmux command with the provided arguments and records it.
+func (r *Runner) Run(ctx context.Context, args ...string) ([]byte, error) {
+	output, err := r.srv.Run(ctx, args...)
+	r.record(Command{Args: args, Output: string(output)}, err)
+
+	return output, err //nolint:wrapcheck // Errors are returned like from tmux.
+}
+
+// Execve runs the tmux command with the provided arguments and records it.
+//
+// The current process is not replaced. The attached session is recorded in
+// the [Transcript] instead.
+func (r *Runner) Execve(args ...string) error {
+	err := r.srv.Execve(args...)
+	r.record(Command{Args: args, Execve: true}, err)
+
+	return err //nolint:wrapcheck // Errors are returned like from tmux.
+}
+
+// IsDryRun returns true.
+func (r *Runner) IsDryRun() bool {
+	return r.srv.IsDryRun()
+}
+
+// Debug writes a debug message using a [slog.Logger].
+func (r *Runner) Debug(msg string, args ...any) {
+	r.srv.Debug(msg, args...)
+}
+
+// Log writes an info message using a [slog.Logger].
+func (r *Runner) Log(msg string, args ...any) {
+	r.srv.Log(msg, args...)
+}
+
+// SetLogger sets the logger used by the runner.
+func (r *Runner) SetLogger(logger *slog.Logger) {
+	r.srv.SetLogger(logger)
+}
+
+// Redact registers values that must not be written to logs or transcripts.
+func (r *Runner) Redact(values ...string) {
+	r.redaction.Redact(values...)
+}
+
+// Commands returns the commands run by the runner in the order they were run.
+func (r *Runner) Commands() []Command {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.commands)
+}
+
+// Transcript returns a [Transcript] of the commands run by the runner and the
+// current state of the tmux sessions.
+//
+// Secrets are redacted from the transcript like from log output (see
+// [tmux.Redaction]).
+func (r *Runner) Transcript() Transcript {
+	tr := Transcript{tb: r.tb, Attached: r.srv.Attached()}
+
+	for _, cmd := range r.Commands() {
+		tr.Commands = append(tr.Commands, r.redactCommand(cmd))
+	}
+
+	for _, sess := range r.srv.Sessions() {
+		tr.Sessions = append(tr.Sessions, r.session(sess))
+	}
+
+	return tr
+}
+
+func (r *Runner) record(cmd Command, err error) {
+	cmd.Args = slices.Clone(cmd.Args)
+
+	if err != nil {
+		cmd.Err = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.commands = append(r.commands, cmd)
+}
+
+func (r *Runner) redactCommand(cmd Command) Command {
+	args := make([]string, len(cmd.Args))
+
+	for i, arg := range cmd.Args {
+		args[i] = r.redaction.String(arg)
+	}
+
+	cmd.Args = args
+	cmd.Output = r.redaction.String(cmd.Output)
+	cmd.Err = r.redaction.String(cmd.Err)
+
+	return cmd
+}
+
+func (r *Runner) session(sess faketmux.Session) Session {
+	res := Session{Name: sess.Name, Path: sess.Path, Env: r.redaction.Env(sess.Env)}
+
+	for _, w := range sess.Windows {
+		win := Window{
+			Name:   w.Name,
+			Index:  w.Index,
+			Layout: w.Layout,
+			Active: w.Active,
+		}
+
+		for _, p := range w.Panes {
+			cmds := make([]string, len(p.Commands))
+
+			for i, cmd := range p.Commands {
+				cmds[i] = r.redaction.String(cmd)
+			}
+
+			win.Panes = append(win.Panes, Pane{
+				tb:       r.tb,
+				Target:   fmt.Sprintf("%s:%s.%d", sess.Name, w.Name, p.Index),
+				Index:    p.Index,
+				Path:     p.Path,
+				Width:    p.Width,
+				Height:   p.Height,
+				Active:   p.Active,
+				Env:      r.redaction.Env(p.Env),
+				Command:  r.redaction.String(p.Command),
+				Commands: cmds,
+			})
+		}
+
+		res.Windows = append(res.Windows, win)
+	}
+
+	return res
+}
+
+// Option configures a [Runner].
+type Option func(*options) error
+
+type options struct {
+	dir     string
+	logger  *slog.Logger
+	cfgOpts []config.FromFileOption
+}
+
+func newOptions(opts []Option) (*options, error) {
+	o := &options{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, fmt.Errorf("applying option: %w", err)
+		}
+	}
+
+	return o, nil
+}
+
+// WithWorkingDir configures the runner to use dir as the working directory of
+// the tmux server, which is the default path of new sessions.
+//
+// The default is the current working directory.
+func WithWorkingDir(dir string) Option {
+	return func(o *options) error {
+		o.dir = dir
+		return nil
+	}
+}
+
+// WithLogger configures the runner to use the provided logger.
+//
+// The default is a logger that discards all output.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) error {
+		o.logger = logger
+		return nil
+	}
+}
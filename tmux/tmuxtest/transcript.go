@@ -0,0 +1,269 @@
+package tmuxtest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/michenriksen/tmpl/internal/testutils"
+)
+
+// Transcript is a record of the tmux commands run by a [Runner] and the state
+// of the tmux sessions they resulted in.
+//
+// The methods for looking up sessions, windows and panes fail the test
+// immediately if they are not found.
+type Transcript struct {
+	tb       testing.TB
+	Commands []Command `json:"commands"`           // Commands in the order they were run.
+	Sessions []Session `json:"sessions"`           // Sessions sorted by name.
+	Attached string    `json:"attached,omitempty"` // Session the client attached to.
+}
+
+// Command is a tmux command run by a [Runner].
+type Command struct {
+	Args   []string `json:"args"`             // Arguments to tmux.
+	Output string   `json:"output,omitempty"` // Output of the command.
+	Err    string   `json:"error,omitempty"`  // Error message if the command failed.
+	Execve bool     `json:"execve,omitempty"` // Whether the command replaced the process.
+}
+
+// Session is the state of a tmux session in a [Transcript].
+type Session struct {
+	Name    string            `json:"name"`
+	Path    string            `json:"path"`
+	Env     map[string]string `json:"env,omitempty"`
+	Windows []Window          `json:"windows"`
+}
+
+// Window is the state of a tmux window in a [Transcript].
+type Window struct {
+	Name   string `json:"name"`
+	Index  int    `json:"index"`
+	Layout string `json:"layout"`
+	Active bool   `json:"active"`
+	Panes  []Pane `json:"panes"`
+}
+
+// Pane is the state of a tmux pane in a [Transcript].
+//
+// Commands holds the lines of input entered in the pane, such as the commands
+// and hook commands of a configuration. Command holds the shell command the
+// pane was started with, if any.
+type Pane struct {
+	tb       testing.TB
+	Target   string            `json:"target"` // Target in the form session:window.pane.
+	Index    int               `json:"index"`
+	Path     string            `json:"path"`
+	Width    int               `json:"width"`
+	Height   int               `json:"height"`
+	Active   bool              `json:"active"`
+	Env      map[string]string `json:"env,omitempty"`
+	Command  string            `json:"command,omitempty"`
+	Commands []string          `json:"commands,omitempty"`
+}
+
+// Session returns the session with the provided name.
+func (tr Transcript) Session(name string) Session {
+	tr.tb.Helper()
+
+	for _, sess := range tr.Sessions {
+		if sess.Name == name {
+			return sess
+		}
+	}
+
+	tr.tb.Fatalf("session %q not found in transcript", name)
+
+	return Session{}
+}
+
+// Window returns the window for the provided target in the form
+// [session:]window, where window is a window name or index.
+//
+// The session can be omitted if the window name or index is unique among all
+// sessions.
+func (tr Transcript) Window(target string) Window {
+	tr.tb.Helper()
+
+	sessName, winName, ok := strings.Cut(target, ":")
+	if !ok {
+		sessName, winName = "", target
+	}
+
+	var res []Window
+
+	for _, sess := range tr.Sessions {
+		if sessName != "" && sess.Name != sessName {
+			continue
+		}
+
+		for _, w := range sess.Windows {
+			if w.Name == winName || strconv.Itoa(w.Index) == winName {
+				res = append(res, w)
+			}
+		}
+	}
+
+	switch len(res) {
+	case 0:
+		tr.tb.Fatalf("window %q not found in transcript", target)
+	case 1:
+		return res[0]
+	default:
+		tr.tb.Fatalf("window %q is ambiguous; include the session name in the target", target)
+	}
+
+	return Window{}
+}
+
+// Pane returns the pane for the provided target in the form
+// [session:]window[.pane], where pane is a pane index.
+//
+// If the pane index is omitted, the first pane of the window is returned,
+// which is the pane running the command of a window configuration.
+func (tr Transcript) Pane(target string) Pane {
+	tr.tb.Helper()
+
+	winTarget, paneIdx := target, ""
+
+	// Window names can include periods, so only a numeric suffix is taken as
+	// the pane index.
+	if i := strings.LastIndex(target, "."); i > strings.LastIndex(target, ":") {
+		if _, err := strconv.Atoi(target[i+1:]); err == nil {
+			winTarget, paneIdx = target[:i], target[i+1:]
+		}
+	}
+
+	w := tr.Window(winTarget)
+
+	if paneIdx == "" {
+		return w.Panes[0]
+	}
+
+	for _, p := range w.Panes {
+		if strconv.Itoa(p.Index) == paneIdx {
+			return p
+		}
+	}
+
+	tr.tb.Fatalf("pane %q not found in transcript", target)
+
+	return Pane{}
+}
+
+// Ran asserts that the command was entered in the pane, and fails the test if
+// it was not. The command matches an entered line if it is equal to the line,
+// or to the beginning of the line up to a space, so that "nvim" matches
+// "nvim .".
+//
+// Returns true if the assertion holds.
+func (p Pane) Ran(cmd string) bool {
+	p.tb.Helper()
+
+	for _, line := range append([]string{p.Command}, p.Commands...) {
+		if line == cmd || strings.HasPrefix(line, cmd+" ") {
+			return true
+		}
+	}
+
+	p.tb.Errorf("expected pane %s to run %q; commands: %q", p.Target, cmd, p.Commands)
+
+	return false
+}
+
+// NotRan asserts that the command was not entered in the pane, and fails the
+// test if it was. Commands are matched like with [Pane.Ran].
+//
+// Returns true if the assertion holds.
+func (p Pane) NotRan(cmd string) bool {
+	p.tb.Helper()
+
+	for _, line := range append([]string{p.Command}, p.Commands...) {
+		if line == cmd || strings.HasPrefix(line, cmd+" ") {
+			p.tb.Errorf("expected pane %s to not run %q; commands: %q", p.Target, cmd, p.Commands)
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasEnv asserts that the pane has the environment variable with the provided
+// value, and fails the test if it does not.
+//
+// The values of secret environment variables are "[REDACTED]".
+//
+// Returns true if the assertion holds.
+func (p Pane) HasEnv(key, value string) bool {
+	p.tb.Helper()
+
+	if v, ok := p.Env[key]; !ok || v != value {
+		p.tb.Errorf("expected pane %s to have environment variable %s=%q; env: %v", p.Target, key, value, p.Env)
+		return false
+	}
+
+	return true
+}
+
+// InPath asserts that the pane was started in the provided directory, and fails
+// the test if it was not.
+//
+// Returns true if the assertion holds.
+func (p Pane) InPath(path string) bool {
+	p.tb.Helper()
+
+	if p.Path != path {
+		p.tb.Errorf("expected pane %s to be in path %q; path: %q", p.Target, path, p.Path)
+		return false
+	}
+
+	return true
+}
+
+// AssertGolden asserts that the transcript matches the golden file of the
+// test in the testdata/golden directory, and fails the test if it does not.
+//
+// The user's home directory is replaced with "~" in the paths of the
+// transcript, so that the golden file does not depend on the environment. Run
+// the test with the UPDATE_GOLDEN environment variable set to create or update
+// the golden file.
+func (tr Transcript) AssertGolden() {
+	tr.tb.Helper()
+	testutils.NewGolden(tr.tb).AssertMatch(tr.stable())
+}
+
+// RequireGolden is like [Transcript.AssertGolden], but fails the test
+// immediately if the transcript does not match the golden file.
+func (tr Transcript) RequireGolden() {
+	tr.tb.Helper()
+	testutils.NewGolden(tr.tb).RequireMatch(tr.stable())
+}
+
+// stable returns the transcript as a JSON value with the user's home directory
+// replaced with "~".
+func (tr Transcript) stable() any {
+	tr.tb.Helper()
+
+	data, err := json.Marshal(tr)
+	if err != nil {
+		tr.tb.Fatalf("marshaling transcript: %v", err)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil && filepath.Clean(home) != "/" {
+		re := regexp.MustCompile(regexp.QuoteMeta(filepath.Clean(home)) + `\b`)
+		data = re.ReplaceAll(data, []byte("~"))
+	}
+
+	var res any
+
+	if err := json.Unmarshal(data, &res); err != nil {
+		tr.tb.Fatalf("unmarshaling transcript: %v", err)
+	}
+
+	return res
+}
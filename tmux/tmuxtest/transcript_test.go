@@ -0,0 +1,116 @@
+package tmuxtest_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/tmux/tmuxtest"
+)
+
+func TestApplyConfig(t *testing.T) {
+	dir := setupHome(t)
+
+	tr := tmuxtest.ApplyConfig(t, filepath.Join("testdata", "tmpl.yaml"), tmuxtest.WithWorkingDir(dir))
+
+	require.Len(t, tr.Session("project").Windows, 2)
+
+	editor := tr.Window("editor")
+	require.True(t, editor.Active)
+	require.Len(t, editor.Panes, 2)
+
+	tr.Pane("editor").Ran("nvim")
+	tr.Pane("editor").Ran("echo 'on_window'")
+	tr.Pane("editor").NotRan("make")
+	tr.Pane("editor").InPath(filepath.Join(dir, "project"))
+	tr.Pane("editor").HasEnv("APP_ENV", "development")
+	tr.Pane("project:editor.1").Ran("make test-watch")
+	tr.Pane("project:editor.1").HasEnv("APP_ENV", "test")
+
+	tr.Pane("server").Ran("./server")
+	tr.Pane("server").InPath(filepath.Join(dir, "project", "cmd"))
+	tr.Pane("server").HasEnv("API_TOKEN", "[REDACTED]")
+	tr.Pane("server.1").Ran("tail -f server.log")
+	tr.Pane("server.1").HasEnv("API_TOKEN", "[REDACTED]")
+
+	tr.RequireGolden()
+}
+
+func TestPane_Assertions(t *testing.T) {
+	dir := setupHome(t)
+
+	tt := []struct {
+		name    string
+		assert  func(tmuxtest.Pane) bool
+		wantErr string
+	}{
+		{
+			"ran",
+			func(p tmuxtest.Pane) bool { return p.Ran("nvim .") },
+			"",
+		},
+		{
+			"ran prefix of word",
+			func(p tmuxtest.Pane) bool { return p.Ran("nv") },
+			`expected pane project:editor.0 to run "nv"; commands: ["echo 'on_window'" "nvim ."]`,
+		},
+		{
+			"not ran",
+			func(p tmuxtest.Pane) bool { return p.NotRan("echo") },
+			`expected pane project:editor.0 to not run "echo"; commands: ["echo 'on_window'" "nvim ."]`,
+		},
+		{
+			"has env",
+			func(p tmuxtest.Pane) bool { return p.HasEnv("APP_ENV", "test") },
+			`expected pane project:editor.0 to have environment variable APP_ENV="test"; env: map[APP_ENV:development]`,
+		},
+		{
+			"in path",
+			func(p tmuxtest.Pane) bool { return p.InPath("/nope") },
+			fmt.Sprintf(`expected pane project:editor.0 to be in path "/nope"; path: %q`, filepath.Join(dir, "project")),
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			tb := &recordingTB{TB: t}
+
+			tr := tmuxtest.ApplyConfig(tb, filepath.Join("testdata", "tmpl.yaml"), tmuxtest.WithWorkingDir(dir))
+
+			ok := tc.assert(tr.Pane("editor"))
+
+			require.Equal(t, tc.wantErr == "", ok)
+			require.Equal(t, tc.wantErr, tb.errMsg)
+		})
+	}
+}
+
+// recordingTB is a [testing.TB] that records the error message of a failed
+// assertion instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errMsg string
+}
+
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.errMsg = fmt.Sprintf(format, args...)
+}
+
+// setupHome stubs HOME and the current working directory with a temporary
+// directory containing the directories of the test configuration.
+func setupHome(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "project", "cmd"), 0o755))
+
+	t.Setenv("HOME", dir)
+	t.Setenv("TMPL_PWD", dir)
+
+	return dir
+}
@@ -4,13 +4,19 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 var windowOutputFormat = outputFormat(
 	"window_id", "window_name", "window_path", "window_index",
-	"window_width", "window_height",
+	"window_width", "window_height", "window_layout",
 )
 
+// windowListOutputFormat is used for loading existing windows, and includes
+// the flag for whether the window is the active window of its session.
+var windowListOutputFormat = windowOutputFormat + "," + outputFormat("window_active")
+
 // Window represents a tmux window.
 type Window struct {
 	tmux   Runner
@@ -22,6 +28,9 @@ type Window struct {
 	index  string
 	width  string
 	height string
+	layout string
+	selLay string
+	mainSz string
 	env    map[string]string
 	panes  []*Pane
 	active bool
@@ -67,6 +76,9 @@ func NewWindow(runner Runner, session *Session, opts ...WindowOption) (*Window,
 // flag to override the default initial window created by the new-session
 // command.
 //
+// If the window is named, its commands are sent with the new-window command in
+// a single tmux invocation.
+//
 // https://man.archlinux.org/man/tmux.1#new-window
 func (w *Window) Apply(ctx context.Context) error {
 	if err := ctx.Err(); err != nil {
@@ -81,48 +93,45 @@ func (w *Window) Apply(ctx context.Context) error {
 		return nil
 	}
 
-	args := []string{"new-window", "-P", "-F", windowOutputFormat}
+	b := newBatch(w.tmux)
+	b.addWithOutput(w.created, w.newWindowArgs(w.sess.NumWindows() == 0)...)
 
-	if w.sess.NumWindows() == 0 {
-		args = append(args, "-k", "-t", fmt.Sprintf("%s:^", w.sess.Name()))
-	} else {
-		args = append(args, "-t", fmt.Sprintf("%s:", w.sess.Name()))
-	}
+	cmds := append(w.sess.onWindowCommands(), w.cmds...)
 
-	args = append(args, envArgs(w.sess.env, w.env)...)
+	// The window can only be targeted before it is created if it is named.
+	if w.name == "" {
+		if err := b.run(ctx); err != nil {
+			return err
+		}
 
-	if w.name != "" {
-		args = append(args, "-n", w.name)
+		return w.RunCommands(ctx, cmds...)
 	}
 
-	if w.path != "" {
-		args = append(args, "-c", w.path)
-	}
+	w.queueCommands(b, cmds)
 
-	output, err := w.tmux.Run(ctx, args...)
-	if err != nil {
-		return fmt.Errorf("running new-window command: %w", err)
-	}
+	return b.run(ctx)
+}
 
-	if w.tmux.IsDryRun() {
+// created updates the window from the output of the new-window command and
+// adds it to its session.
+func (w *Window) created(output []byte) error {
+	if w.tmux.IsDryRun() && len(bytes.TrimSpace(output)) == 0 {
 		output = []byte(w.dryRunRecord())
 	}
 
-	records, err := parseOutput(output)
+	record, err := parseOutputRecord(output)
 	if err != nil {
 		return fmt.Errorf("parsing new-window command output: %w", err)
 	}
 
-	if err := w.update(records[0]); err != nil {
+	if err := w.update(record); err != nil {
 		return fmt.Errorf("updating window data: %w", err)
 	}
 
 	w.sess.addWindow(w)
 	w.log("window created")
 
-	cmds := append(w.sess.onWindowCommands(), w.cmds...)
-
-	return w.RunCommands(ctx, cmds...)
+	return nil
 }
 
 // Select selects the window by invoking the select-window command using its
@@ -143,19 +152,19 @@ func (w *Window) Select(ctx context.Context) error {
 		return fmt.Errorf("checking window state: %w", err)
 	}
 
-	if _, err := w.tmux.Run(ctx, "select-window", "-t", w.Name()); err != nil {
-		return fmt.Errorf("running select-window command: %w", err)
-	}
+	b := newBatch(w.tmux)
+	b.add(func() {
+		w.log("window selected")
+	}, "select-window", "-t", w.Name())
 
-	w.log("window selected")
-
-	return w.selectPane(ctx)
+	return w.selectPane(ctx, b)
 }
 
-// selectPane selects the pane configured as the active pane.
+// selectPane selects the pane configured as the active pane, after running
+// the commands queued in the provided batch.
 //
 // If no pane is configured as the active pane, the first pane is selected.
-func (w *Window) selectPane(ctx context.Context) error {
+func (w *Window) selectPane(ctx context.Context, b *batch) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
@@ -165,7 +174,7 @@ func (w *Window) selectPane(ctx context.Context) error {
 	}
 
 	if w.NumPanes() == 0 {
-		return nil
+		return b.run(ctx)
 	}
 
 	var activePane *Pane
@@ -176,23 +185,187 @@ func (w *Window) selectPane(ctx context.Context) error {
 		}
 	}
 
-	// If no pane is set as active, select the first pane.
-	if activePane == nil {
-		baseIndex, err := w.tmux.Run(ctx, "show-option", "-gqv", "pane-base-index")
-		if err != nil {
-			return fmt.Errorf("running show-option command: %w", err)
-		}
+	if activePane != nil {
+		activePane.queueSelect(b)
+		return b.run(ctx)
+	}
+
+	// If no pane is set as active, select the first pane, which has the index
+	// of the pane-base-index option.
+	var baseIndex []byte
+
+	b.addWithOutput(func(output []byte) error {
+		baseIndex = bytes.TrimSpace(output)
+		return nil
+	}, "show-option", "-gqv", "pane-base-index")
+
+	if err := b.run(ctx); err != nil {
+		return err
+	}
+
+	pTarget := fmt.Sprintf("%s.%s", w.Name(), baseIndex)
+	if _, err := w.tmux.Run(ctx, "select-pane", "-t", pTarget); err != nil {
+		return fmt.Errorf("running select-pane command: %w", err)
+	}
+
+	return nil
+}
+
+// SelectLayout arranges the window's panes according to the configured layout
+// by invoking the select-layout command using its internal [Runner] instance.
+//
+// If the window is configured with a main pane size, the main-pane-width or
+// main-pane-height window option is set first, depending on the layout.
+//
+// The layout should be selected after all panes have been created. If the
+// window is not configured with a layout, the method is a no-op.
+//
+// If the window is not applied, the method returns [ErrWindowNotApplied].
+//
+// https://man.archlinux.org/man/tmux.1#select-layout
+func (w *Window) SelectLayout(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := w.checkState(); err != nil {
+		return fmt.Errorf("checking window state: %w", err)
+	}
+
+	if w.selLay == "" {
+		return nil
+	}
 
-		pTarget := fmt.Sprintf("%s.%s", w.Name(), bytes.TrimSpace(baseIndex))
-		if _, err := w.tmux.Run(ctx, "select-pane", "-t", pTarget); err != nil {
-			return fmt.Errorf("running select-pane command: %w", err)
+	b := newBatch(w.tmux)
+	cmds := w.layoutArgs(w.target())
+
+	for _, args := range cmds[:len(cmds)-1] {
+		b.add(nil, args...)
+	}
+
+	b.add(func() {
+		w.log("window layout selected", "layout", w.selLay)
+	}, cmds[len(cmds)-1]...)
+
+	return b.run(ctx)
+}
+
+// Configure configures the window with the provided options.
+//
+// This is useful for windows loaded with [GetWindows], which are not
+// configured with commands, environment variables or a layout.
+//
+// NOTE: The window itself is not changed by invoking any tmux commands.
+func (w *Window) Configure(opts ...WindowOption) error {
+	for _, opt := range opts {
+		if err := opt(w); err != nil {
+			return fmt.Errorf("applying window option: %w", err)
 		}
+	}
+
+	return nil
+}
 
+// Rename renames the window by invoking the rename-window command using its
+// internal [Runner] instance.
+//
+// If the window is not applied, the method returns [ErrWindowNotApplied].
+//
+// https://man.archlinux.org/man/tmux.1#rename-window
+func (w *Window) Rename(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if name == "" {
+		return fmt.Errorf("window name cannot be empty")
+	}
+
+	if err := w.checkState(); err != nil {
+		return fmt.Errorf("checking window state: %w", err)
+	}
+
+	if _, err := w.tmux.Run(ctx, "rename-window", "-t", w.target(), name); err != nil {
+		return fmt.Errorf("running rename-window command: %w", err)
+	}
+
+	oldName := w.Name()
+	w.name = name
+
+	w.log("window renamed", "old_name", oldName)
+
+	return nil
+}
+
+// Move moves the window to the provided index within its session by invoking
+// the move-window command using its internal [Runner] instance.
+//
+// The session's windows are kept ordered by index, so moving a window also
+// changes its position in the session.
+//
+// If the window is not applied, the method returns [ErrWindowNotApplied].
+//
+// https://man.archlinux.org/man/tmux.1#move-window
+func (w *Window) Move(ctx context.Context, index int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := w.checkState(); err != nil {
+		return fmt.Errorf("checking window state: %w", err)
+	}
+
+	dst := fmt.Sprintf("%s:%d", w.sess.target(), index)
+
+	if _, err := w.tmux.Run(ctx, "move-window", "-s", w.target(), "-t", dst); err != nil {
+		return fmt.Errorf("running move-window command: %w", err)
+	}
+
+	w.index = strconv.Itoa(index)
+	w.sess.sortWindows()
+
+	w.log("window moved", "window_index", w.index)
+
+	return nil
+}
+
+// Close closes the window by invoking the kill-window command using its
+// internal [Runner] instance.
+//
+// The window is removed from its session, and any subsequent calls to
+// command-invoking methods on the window or any of its panes will return an
+// [ErrWindowClosed] error. If the window was the last window of its session,
+// tmux destroys the session, and the session is marked as closed as well.
+//
+// If the window is already closed, this method is a no-op. If the window is
+// not applied, the method returns [ErrWindowNotApplied].
+//
+// https://man.archlinux.org/man/tmux.1#kill-window
+func (w *Window) Close(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if w.IsClosed() {
 		return nil
 	}
 
-	if err := activePane.Select(ctx); err != nil {
-		return fmt.Errorf("selecting active window pane: %w", err)
+	if err := w.checkState(); err != nil {
+		return fmt.Errorf("checking window state: %w", err)
+	}
+
+	if _, err := w.tmux.Run(ctx, "kill-window", "-t", w.target()); err != nil {
+		return fmt.Errorf("running kill-window command: %w", err)
+	}
+
+	w.log("window closed")
+
+	w.state = stateClosed
+	w.panes = nil
+	w.sess.removeWindow(w)
+
+	if w.sess.NumWindows() == 0 {
+		w.sess.state = stateClosed
 	}
 
 	return nil
@@ -201,7 +374,8 @@ func (w *Window) selectPane(ctx context.Context) error {
 // RunCommands runs the provided commands inside the window by invoking the
 // send-keys tmux command using its internal [Runner] instance.
 //
-// The commands are automatically followed by a carriage return.
+// The commands are automatically followed by a carriage return, and are all
+// sent with a single tmux invocation.
 //
 // If the window is not applied, the method returns [ErrWindowNotApplied].
 //
@@ -221,16 +395,22 @@ func (w *Window) RunCommands(ctx context.Context, cmds ...string) error {
 		return fmt.Errorf("checking window state: %w", err)
 	}
 
+	b := newBatch(w.tmux)
+	w.queueCommands(b, cmds)
+
+	return b.run(ctx)
+}
+
+// queueCommands queues send-keys commands for running the provided commands
+// inside the window in the provided batch.
+func (w *Window) queueCommands(b *batch, cmds []string) {
 	for _, cmd := range cmds {
-		args := []string{"send-keys", "-t", w.Name(), cmd, "C-m"}
-		if _, err := w.tmux.Run(ctx, args...); err != nil {
-			return fmt.Errorf("running send-keys command: %w", err)
-		}
+		cmd := cmd
 
-		w.log("window send-keys", "cmd", cmd+"<cr>")
+		b.add(func() {
+			w.log("window send-keys", "cmd", cmd+"<cr>")
+		}, sendKeysArgs(w.Name(), cmd)...)
 	}
-
-	return nil
 }
 
 // Name returns the window's fully qualified name.
@@ -241,6 +421,63 @@ func (w *Window) Name() string {
 	return fmt.Sprintf("%s:%s", w.sess.Name(), w.name)
 }
 
+// ID returns the window's unique ID as reported by tmux, e.g. @1.
+//
+// The ID is only known for applied windows.
+func (w *Window) ID() string {
+	return w.id
+}
+
+// Index returns the window's index within its session.
+//
+// The index is only known for applied windows.
+func (w *Window) Index() string {
+	return w.index
+}
+
+// Width returns the window's width in cells.
+//
+// The width is only known for applied windows.
+func (w *Window) Width() string {
+	return w.width
+}
+
+// Height returns the window's height in cells.
+//
+// The height is only known for applied windows.
+func (w *Window) Height() string {
+	return w.height
+}
+
+// Session returns the session the window belongs to.
+func (w *Window) Session() *Session {
+	return w.sess
+}
+
+// ShortName returns the window's name without the session name.
+func (w *Window) ShortName() string {
+	return w.name
+}
+
+// Path returns the window's working directory.
+func (w *Window) Path() string {
+	return w.path
+}
+
+// Panes returns the panes split from the window's initial pane. The initial
+// pane itself is not included.
+func (w *Window) Panes() []*Pane {
+	return append([]*Pane{}, w.panes...)
+}
+
+// Layout returns the window's layout string as reported by tmux.
+//
+// The layout is only known for applied windows, and is not updated when the
+// window's panes change.
+func (w *Window) Layout() string {
+	return w.layout
+}
+
 // IsApplied returns true if the window has been applied with [Window.Apply].
 func (w *Window) IsApplied() bool {
 	return w.state == stateApplied
@@ -252,7 +489,8 @@ func (w *Window) IsClosed() bool {
 }
 
 // IsActive returns true if the window is configured as the active window of its
-// session.
+// session. For windows loaded with [GetWindows], it returns true if the window
+// was the active window when loaded.
 func (w *Window) IsActive() bool {
 	return w.active
 }
@@ -277,6 +515,7 @@ func (w *Window) update(record outputRecord) error {
 		"window_index":  &w.index,
 		"window_width":  &w.width,
 		"window_height": &w.height,
+		"window_layout": &w.layout,
 	}
 
 	for k, v := range record {
@@ -294,15 +533,123 @@ func (w *Window) update(record outputRecord) error {
 	return nil
 }
 
+// refresh updates the window's internal state by invoking the
+// display-message command using its internal [Runner] instance.
+//
+// In dry-run mode, the internal state is left unchanged unless the runner
+// simulates the command output.
+func (w *Window) refresh(ctx context.Context) error {
+	output, err := w.tmux.Run(ctx, "display-message", "-p", "-t", w.target(), windowOutputFormat)
+	if err != nil {
+		return fmt.Errorf("running display-message command: %w", err)
+	}
+
+	if w.tmux.IsDryRun() && len(bytes.TrimSpace(output)) == 0 {
+		return nil
+	}
+
+	record, err := parseOutputRecord(output)
+	if err != nil {
+		return fmt.Errorf("parsing display-message command output: %w", err)
+	}
+
+	return w.update(record)
+}
+
+// mergedEnv returns the environment variables of the window merged with the
+// variables inherited from its session.
+func (w *Window) mergedEnv() map[string]string {
+	return mergeMaps(w.sess.env, w.env)
+}
+
 func (w *Window) addPane(p *Pane) {
 	w.panes = append(w.panes, p)
 }
 
+// newWindowArgs returns the arguments of the new-window command that creates
+// the window.
+//
+// The first window of the session is created with the -k flag to replace the
+// initial window created by the new-session command.
+func (w *Window) newWindowArgs(first bool) []string {
+	args := []string{"new-window", "-P", "-F", windowOutputFormat}
+
+	if first {
+		args = append(args, "-k", "-t", fmt.Sprintf("%s:^", w.sess.Name()))
+	} else {
+		args = append(args, "-t", fmt.Sprintf("%s:", w.sess.Name()))
+	}
+
+	args = append(args, envArgs(w.mergedEnv())...)
+
+	if w.name != "" {
+		args = append(args, "-n", w.name)
+	}
+
+	if w.path != "" {
+		args = append(args, "-c", w.path)
+	}
+
+	return args
+}
+
+// layoutArgs returns the arguments of the tmux commands that select the
+// window's layout on the provided target, or nil if the window is not
+// configured with a layout.
+//
+// The select-layout command is always the last command.
+func (w *Window) layoutArgs(target string) [][]string {
+	if w.selLay == "" {
+		return nil
+	}
+
+	var cmds [][]string
+
+	if opt := mainPaneOption(w.selLay); opt != "" && w.mainSz != "" {
+		cmds = append(cmds, []string{"set-option", "-w", "-t", target, opt, w.mainSz})
+	}
+
+	return append(cmds, []string{"select-layout", "-t", target, w.selLay})
+}
+
+// mainPaneOption returns the window option that controls the main pane size
+// for the provided layout, or an empty string if the layout has no main pane.
+func mainPaneOption(layout string) string {
+	switch {
+	case strings.HasPrefix(layout, "main-horizontal"):
+		return "main-pane-height"
+	case strings.HasPrefix(layout, "main-vertical"):
+		return "main-pane-width"
+	default:
+		return ""
+	}
+}
+
+func (w *Window) removePane(p *Pane) {
+	w.panes = removePane(w.panes, p)
+}
+
+// target returns the target to use when invoking tmux commands on the window.
+//
+// The window ID is preferred as it does not change when the window is renamed
+// or moved.
+func (w *Window) target() string {
+	if w.id != "" {
+		return w.id
+	}
+
+	return w.Name()
+}
+
 func (w *Window) checkState() error {
-	if w.IsClosed() {
+	if w.sess.IsClosed() {
 		return ErrSessionClosed
 	}
 
+	if w.state == stateClosed {
+		return ErrWindowClosed
+	}
+
 	if !w.IsApplied() {
 		return ErrWindowNotApplied
 	}
@@ -315,7 +662,7 @@ func (w *Window) log(msg string, args ...any) {
 }
 
 // dryRunRecord returns an output record string to use when running in dry-run
-// mode.
+// mode with a runner that does not simulate command output.
 func (w *Window) dryRunRecord() string {
 	wID := w.sess.NumWindows() + 1
 
@@ -378,14 +725,160 @@ func WindowAsActive() WindowOption {
 	}
 }
 
+// WindowWithLayout configures the [Window] with a layout to arrange its panes
+// with when [Window.SelectLayout] is called.
+//
+// The layout can be the name of a preset layout, such as main-vertical or
+// tiled, or a custom layout string as reported by the list-windows command.
+//
+// https://man.archlinux.org/man/tmux.1#WINDOWS_AND_PANES
+func WindowWithLayout(layout string) WindowOption {
+	return func(w *Window) error {
+		if layout == "" {
+			return fmt.Errorf("window layout cannot be empty")
+		}
+
+		w.selLay = layout
+
+		return nil
+	}
+}
+
+// WindowWithMainPaneSize configures the size of the main pane for the
+// main-horizontal and main-vertical layouts, and their mirrored variants.
+//
+// The size can be specified as a number of cells or as a percentage of the
+// window size (e.g. 60%). It is used as the main-pane-height window option for
+// main-horizontal layouts, and main-pane-width for main-vertical layouts.
+func WindowWithMainPaneSize(size string) WindowOption {
+	return func(w *Window) error {
+		w.mainSz = size
+		return nil
+	}
+}
+
 // WindowWithEnv configures the [Window] with environment variables.
 //
-// Environment variables are inherited from session to window to pane. If a
-// an environment variable is is named the same as an inherited variable, it
-// will take precedence.
+// Environment variables are inherited from session to window to pane. If an
+// environment variable is named the same as an inherited variable, it will
+// take precedence.
+//
+// References to environment variables in the values, such as $HOME, are
+// expanded with the environment of the current process when the option is
+// applied, not when the tmux commands are run. The option can be given more
+// than once: variables are added to variables configured by earlier options,
+// replacing variables with the same name.
 func WindowWithEnv(env map[string]string) WindowOption {
 	return func(w *Window) error {
-		w.env = env
+		w.env = mergeMaps(w.env, expandEnv(env))
+		return nil
+	}
+}
+
+// WindowWithRawEnv configures the [Window] with environment variables with values
+// that are used as they are, such as values loaded from files or commands.
+//
+// Unlike [WindowWithEnv], references to environment variables in the values are
+// not expanded. Variables are added to variables configured by earlier
+// options, replacing variables with the same name.
+func WindowWithRawEnv(env map[string]string) WindowOption {
+	return func(w *Window) error {
+		w.env = mergeMaps(w.env, env)
 		return nil
 	}
 }
+
+// WindowWithSecretEnv configures the [Window] with environment variables holding
+// secret values.
+//
+// Like with [WindowWithRawEnv], the values are used as they are. They are also
+// registered with the runner for redaction if it implements [Redactor].
+func WindowWithSecretEnv(env map[string]string) WindowOption {
+	return func(w *Window) error {
+		redactEnv(w.tmux, env)
+		return WindowWithRawEnv(env)(w)
+	}
+}
+
+// GetWindows returns the windows of the provided session by invoking the
+// list-windows command using the provided [Runner] instance.
+//
+// The session must be applied before being passed to this function. The
+// returned windows are applied, marked as active if they are the session's
+// active window, and replace any windows currently tracked by
+// the session, so that windows created afterwards are added to the existing
+// ones instead of replacing the initial window.
+//
+// https://man.archlinux.org/man/tmux.1#list-windows
+func GetWindows(ctx context.Context, runner Runner, session *Session) ([]*Window, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if runner == nil {
+		return nil, ErrNilRunner
+	}
+
+	if session == nil {
+		return nil, ErrNilSession
+	}
+
+	if err := session.checkState(); err != nil {
+		return nil, fmt.Errorf("checking session state: %w", err)
+	}
+
+	output, err := runner.Run(ctx, "list-windows", "-t", session.target(), "-F", windowListOutputFormat)
+	if err != nil {
+		return nil, fmt.Errorf("running list-windows command: %w", err)
+	}
+
+	records, err := parseOutput(output)
+	if err != nil {
+		return nil, fmt.Errorf("parsing list-windows command output: %w", err)
+	}
+
+	windows := make([]*Window, len(records))
+
+	for i, record := range records {
+		w := &Window{tmux: runner, sess: session, state: stateApplied}
+		if err := w.update(record); err != nil {
+			return nil, fmt.Errorf("updating window data: %w", err)
+		}
+
+		w.active = record["window_active"] == "1"
+
+		windows[i] = w
+	}
+
+	session.windows = append([]*Window{}, windows...)
+
+	return windows, nil
+}
+
+// FindWindow returns the window of the provided session matching the provided
+// target, which can be either a window name, a window index, or a window ID
+// such as @1.
+//
+// The session's windows are loaded with [GetWindows] before matching.
+//
+// Returns [ErrWindowNotFound] if no window matches the target.
+func FindWindow(ctx context.Context, runner Runner, session *Session, target string) (*Window, error) {
+	windows, err := GetWindows(ctx, runner, session)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, w := range windows {
+		if w.id == target || w.name == target {
+			return w, nil
+		}
+	}
+
+	for _, w := range windows {
+		if w.index == target {
+			return w, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s:%s", ErrWindowNotFound, session.Name(), target)
+}
@@ -0,0 +1,323 @@
+package tmux_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/michenriksen/tmpl/internal/faketmux"
+	"github.com/michenriksen/tmpl/tmux"
+)
+
+func TestGetWindows(t *testing.T) {
+	runner := newStubRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows": "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24," +
+			"window_layout:c3e1\\,80x24\\,0\\,0{40x24\\,0\\,0\\,1\\,39x24\\,41\\,0\\,2}\n" +
+			"window_id:@2,window_name:logs\\,errors\\\\,window_index:1,window_width:80,window_height:24," +
+			"window_layout:b25f\\,80x24\\,0\\,0\\,3",
+	})
+
+	sess := getSession(t, runner, "test")
+
+	windows, err := tmux.GetWindows(context.Background(), runner, sess)
+	require.NoError(t, err)
+	require.Len(t, windows, 2)
+
+	require.Equal(t, "@1", windows[0].ID())
+	require.Equal(t, "0", windows[0].Index())
+	require.Equal(t, "80", windows[0].Width())
+	require.Equal(t, "24", windows[0].Height())
+	require.Equal(t, "test:code", windows[0].Name())
+	require.Equal(t, "code", windows[0].ShortName())
+	require.Equal(t, "c3e1,80x24,0,0{40x24,0,0,1,39x24,41,0,2}", windows[0].Layout())
+	require.True(t, windows[0].IsApplied())
+
+	require.Equal(t, `test:logs,errors\`, windows[1].Name())
+	require.Equal(t, "1", windows[1].Index())
+	require.Equal(t, "b25f,80x24,0,0,3", windows[1].Layout())
+
+	require.Equal(t, 2, sess.NumWindows())
+}
+
+func TestGetPanes(t *testing.T) {
+	runner := newStubRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"list-panes": "pane_id:%1,pane_index:0,pane_width:40,pane_height:24,pane_current_path:/home/user/project\n" +
+			"pane_id:%2,pane_index:1,pane_width:39,pane_height:24,pane_current_path:/home/user/project/cmd",
+	})
+
+	sess := getSession(t, runner, "test")
+
+	windows, err := tmux.GetWindows(context.Background(), runner, sess)
+	require.NoError(t, err)
+	require.Len(t, windows, 1)
+
+	panes, err := tmux.GetPanes(context.Background(), runner, windows[0])
+	require.NoError(t, err)
+	require.Len(t, panes, 2)
+
+	require.Equal(t, "%1", panes[0].ID())
+	require.Equal(t, "40", panes[0].Width())
+	require.Equal(t, "24", panes[0].Height())
+	require.Same(t, windows[0], panes[0].Window())
+	require.Equal(t, "test:code.0", panes[0].Name())
+	require.Equal(t, "/home/user/project", panes[0].CurrentPath())
+	require.Equal(t, "test:code.1", panes[1].Name())
+	require.Equal(t, "1", panes[1].Index())
+	require.Equal(t, "/home/user/project/cmd", panes[1].CurrentPath())
+
+	// The initial pane is represented by the window itself.
+	require.Equal(t, 1, windows[0].NumPanes())
+}
+
+func TestInitialPane(t *testing.T) {
+	// Pane %3 was split before the initial pane %1 with split-window -b.
+	runner := newStubRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"list-panes": "pane_id:%3,pane_index:0,pane_width:20,pane_height:24\n" +
+			"pane_id:%1,pane_index:1,pane_width:39,pane_height:24\n" +
+			"pane_id:%2,pane_index:2,pane_width:19,pane_height:24",
+	})
+
+	windows, err := tmux.GetWindows(context.Background(), runner, getSession(t, runner, "test"))
+	require.NoError(t, err)
+
+	panes, err := tmux.GetPanes(context.Background(), runner, windows[0])
+	require.NoError(t, err)
+	require.Len(t, panes, 3)
+
+	require.Same(t, panes[1], tmux.InitialPane(panes))
+	require.Equal(t, []*tmux.Pane{panes[0], panes[2]}, windows[0].Panes())
+	require.Nil(t, tmux.InitialPane(nil))
+}
+
+func TestGetPanes_DuplicateWindowNames(t *testing.T) {
+	srv, err := faketmux.NewServer(faketmux.WithWorkingDir("/home/user/project"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	paths := []string{"/home/user/project", "/home/user/project/cmd"}
+
+	// Build the session by hand, as tmpl does not create windows with
+	// duplicate names itself.
+	_, err = srv.Run(ctx, "new-session", "-d", "-s", "test", "-n", "zsh", "-c", paths[0])
+	require.NoError(t, err)
+	_, err = srv.Run(ctx, "new-window", "-t", "test:", "-n", "zsh", "-c", paths[1])
+	require.NoError(t, err)
+
+	for i, target := range []string{"test:0", "test:1"} {
+		_, err = srv.Run(ctx, "split-window", "-d", "-t", target, "-c", paths[i])
+		require.NoError(t, err)
+	}
+
+	sess := getSession(t, srv, "test")
+
+	windows, err := tmux.GetWindows(ctx, srv, sess)
+	require.NoError(t, err)
+	require.Len(t, windows, 2)
+
+	for i, path := range paths {
+		require.Equal(t, "zsh", windows[i].ShortName())
+
+		panes, err := tmux.GetPanes(ctx, srv, windows[i])
+		require.NoError(t, err)
+		require.Len(t, panes, 2)
+		require.Equal(t, path, panes[1].CurrentPath())
+	}
+}
+
+func TestFindWindow(t *testing.T) {
+	runner := newStubRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows": "window_id:@1,window_name:code,window_index:1,window_width:80,window_height:24\n" +
+			"window_id:@2,window_name:shell,window_index:2,window_width:80,window_height:24",
+	})
+
+	sess := getSession(t, runner, "test")
+
+	tt := []struct {
+		name    string
+		target  string
+		wantID  string
+		wantErr error
+	}{
+		{"by name", "shell", "@2", nil},
+		{"by ID", "@1", "@1", nil},
+		{"by index", "2", "@2", nil},
+		{"not found", "logs", "", tmux.ErrWindowNotFound},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			win, err := tmux.FindWindow(context.Background(), runner, sess, tc.target)
+
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				require.Nil(t, win)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.wantID, win.ID())
+			require.Same(t, sess, win.Session())
+		})
+	}
+}
+
+// newStubRunner returns a [tmux.Runner] that returns the stub output mapped to
+// the tmux sub-command of each invocation, and fails the test on unexpected
+// sub-commands.
+func newStubRunner(t *testing.T, outputs map[string]string) tmux.Runner {
+	t.Helper()
+
+	runner, err := tmux.NewRunner(tmux.WithOSCommandRunner(func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		output, ok := outputs[args[0]]
+		if !ok {
+			t.Fatalf("unexpected command: %s", strings.Join(args, " "))
+		}
+
+		return []byte(output), nil
+	}))
+	require.NoError(t, err)
+
+	return runner
+}
+
+// getSession returns the current session with the provided name.
+func getSession(t *testing.T, runner tmux.Runner, name string) *tmux.Session {
+	t.Helper()
+
+	sessions, err := tmux.GetSessions(context.Background(), runner)
+	require.NoError(t, err)
+
+	for _, s := range sessions {
+		if s.Name() == name {
+			return s
+		}
+	}
+
+	t.Fatalf("expected session %q to exist", name)
+
+	return nil
+}
+
+func TestWindow_Rename(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+		"rename-window": "",
+	})
+
+	win := getWindows(t, runner, "test")[0]
+
+	require.NoError(t, win.Rename(context.Background(), "editor"))
+	require.Equal(t, []string{"rename-window -t @1 editor"}, (*cmds)[2:])
+	require.Equal(t, "test:editor", win.Name())
+
+	require.Error(t, win.Rename(context.Background(), ""))
+}
+
+func TestWindow_Move(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows": "window_id:@1,window_name:code,window_index:1,window_width:80,window_height:24\n" +
+			"window_id:@2,window_name:shell,window_index:2,window_width:80,window_height:24",
+		"move-window": "",
+	})
+
+	windows := getWindows(t, runner, "test")
+
+	require.NoError(t, windows[0].Move(context.Background(), 5))
+	require.Equal(t, []string{"move-window -s @1 -t $1:5"}, (*cmds)[2:])
+	require.Equal(t, "5", windows[0].Index())
+
+	require.Equal(t, []*tmux.Window{windows[1], windows[0]}, windows[0].Session().Windows())
+}
+
+func TestWindow_Close(t *testing.T) {
+	runner, cmds := newRecordingRunner(t, map[string]string{
+		"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+		"list-windows": "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24\n" +
+			"window_id:@2,window_name:shell,window_index:1,window_width:80,window_height:24",
+		"list-panes": "pane_id:%1,pane_index:0,pane_width:40,pane_height:24\n" +
+			"pane_id:%2,pane_index:1,pane_width:39,pane_height:24",
+		"kill-window": "",
+	})
+
+	windows := getWindows(t, runner, "test")
+	sess := windows[0].Session()
+	panes := getPanes(t, runner, windows[0])
+
+	require.NoError(t, windows[0].Close(context.Background()))
+	require.Contains(t, *cmds, "kill-window -t @1")
+	require.True(t, windows[0].IsClosed())
+	require.Equal(t, 1, sess.NumWindows())
+	require.False(t, sess.IsClosed())
+
+	require.ErrorIs(t, windows[0].RunCommands(context.Background(), "ls"), tmux.ErrWindowClosed)
+	require.ErrorIs(t, panes[1].Select(context.Background()), tmux.ErrWindowClosed)
+
+	require.NoError(t, windows[0].Close(context.Background()), "expected closing a closed window to be a no-op")
+
+	require.NoError(t, windows[1].Close(context.Background()))
+	require.True(t, sess.IsClosed(), "expected session to be closed with its last window")
+}
+
+func TestWindow_SelectLayout(t *testing.T) {
+	tt := []struct {
+		name     string
+		opts     []tmux.WindowOption
+		wantCmds []string
+	}{
+		{
+			"preset layout",
+			[]tmux.WindowOption{tmux.WindowWithLayout("tiled")},
+			[]string{"select-layout -t @1 tiled"},
+		},
+		{
+			"main-vertical with main pane size",
+			[]tmux.WindowOption{tmux.WindowWithLayout("main-vertical"), tmux.WindowWithMainPaneSize("60%")},
+			[]string{"set-option -w -t @1 main-pane-width 60% ; select-layout -t @1 main-vertical"},
+		},
+		{
+			"main-horizontal with main pane size",
+			[]tmux.WindowOption{tmux.WindowWithLayout("main-horizontal"), tmux.WindowWithMainPaneSize("20")},
+			[]string{"set-option -w -t @1 main-pane-height 20 ; select-layout -t @1 main-horizontal"},
+		},
+		{
+			"custom layout",
+			[]tmux.WindowOption{tmux.WindowWithLayout("b25f,80x24,0,0,3")},
+			[]string{"select-layout -t @1 b25f,80x24,0,0,3"},
+		},
+		{
+			"no layout",
+			nil,
+			nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, cmds := newRecordingRunner(t, map[string]string{
+				"list-sessions": "session_id:$1,session_name:test,session_path:/home/user/project",
+				"list-windows":  "window_id:@1,window_name:code,window_index:0,window_width:80,window_height:24",
+				"set-option":    "",
+				"select-layout": "",
+			})
+
+			win := getWindows(t, runner, "test")[0]
+			require.NoError(t, win.Configure(tc.opts...))
+
+			*cmds = nil
+
+			require.NoError(t, win.SelectLayout(context.Background()))
+			require.Equal(t, tc.wantCmds, *cmds)
+		})
+	}
+}